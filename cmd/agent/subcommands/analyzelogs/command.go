@@ -7,10 +7,12 @@
 package analyzelogs
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"time"
 
 	"go.uber.org/fx"
@@ -31,11 +33,11 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/workloadmeta/defaults"
 	workloadmetafx "github.com/DataDog/datadog-agent/comp/core/workloadmeta/fx"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/agentimpl"
+	logsconfig "github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
-	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/schedulers/ad"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
@@ -43,6 +45,9 @@ import (
 
 const defaultCoreConfigPath = "bin/agent/dist/datadog.yaml"
 
+// stdinInput is the input path reading the logs to analyze from stdin
+const stdinInput = "-"
+
 // CliParams holds the command-line argument and dependencies for the analyze-logs subcommand.
 type CliParams struct {
 	*command.GlobalParams
@@ -55,6 +60,15 @@ type CliParams struct {
 
 	// inactivityTimeout represents the time in seconds that the program will wait for new logs before exiting
 	inactivityTimeout time.Duration
+
+	// inputPath is the path of the file, or "-" for stdin, analyzed instead of the files of the logs configuration
+	inputPath string
+
+	// outputFormat is the format of the output, "text" or "json"
+	outputFormat string
+
+	// expectPath is the path of a golden file the output is compared to
+	expectPath string
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
@@ -67,7 +81,13 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "analyze-logs",
 		Short: "Analyze logs configuration in isolation",
-		Long:  `Run a Datadog agent logs configuration and print the results to stdout`,
+		Long: `Run a Datadog agent logs configuration and print the results to stdout.
+
+The logs of a fixture file, or of stdin, can be analyzed instead of the files of
+the configuration with --input. The json output format describes every processed
+log, including the processing rules which applied to it and the lines it was
+read from, and the output can be compared to a golden file with --expect, the
+command failing when they differ.`,
 		RunE: func(_ *cobra.Command, args []string) error {
 			if len(args) < 1 {
 				return fmt.Errorf("log config file path is required")
@@ -91,17 +111,47 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	cmd.Flags().StringVarP(&cliParams.CoreConfigPath, "core-config", "C", defaultCoreConfigPath, "Path to the core configuration file (optional)")
 	// Add flag for inactivity timeout (optional)
 	cmd.Flags().DurationVarP(&cliParams.inactivityTimeout, "inactivity-timeout", "t", defaultInactivityTimeout, "Time that the program will wait for new logs before exiting (optional)")
+	cmd.Flags().StringVar(&cliParams.inputPath, "input", "", `Path of a file to analyze instead of the files of the logs configuration, "-" for stdin (optional)`)
+	cmd.Flags().StringVar(&cliParams.outputFormat, "format", textFormat, `Output format, "text" or "json" (optional)`)
+	cmd.Flags().StringVar(&cliParams.expectPath, "expect", "", "Path of a golden file the output is compared to, the command fails if they differ (optional)")
 
 	return []*cobra.Command{cmd}
 }
 
 // runAnalyzeLogs initializes the launcher and sends the log config file path to the source provider.
 func runAnalyzeLogs(cliParams *CliParams, config config.Component, ac autodiscovery.Component) error {
+	var input []byte
+	if cliParams.inputPath == stdinInput {
+		path, cleanup, err := copyToTempFile(os.Stdin)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		cliParams.inputPath = path
+	}
+	if cliParams.inputPath != "" {
+		var err error
+		if input, err = os.ReadFile(cliParams.inputPath); err != nil {
+			return err
+		}
+	}
+	analyzer, err := newAnalyzer(cliParams.outputFormat, input)
+	if err != nil {
+		return err
+	}
+
 	outputChan, launchers, pipelineProvider, err := runAnalyzeLogsHelper(cliParams, config, ac)
 	if err != nil {
 		return err
 	}
 
+	// the output is buffered to be compared to the golden file
+	var output io.Writer = os.Stdout
+	var buffer bytes.Buffer
+	if cliParams.expectPath != "" {
+		output = &buffer
+	}
+
 	// Set up an inactivity timeout
 	inactivityTimeout := cliParams.inactivityTimeout
 	idleTimer := time.NewTimer(inactivityTimeout)
@@ -109,15 +159,11 @@ func runAnalyzeLogs(cliParams *CliParams, config config.Component, ac autodiscov
 	for {
 		select {
 		case msg := <-outputChan:
-			parsedMessage := processor.JSONPayload
-			err := json.Unmarshal(msg.GetContent(), &parsedMessage)
-			if err != nil {
+			if err := analyzer.write(output, msg); err != nil {
 				fmt.Printf("Failed to parse message: %v\n", err)
 				continue
 			}
 
-			fmt.Println(parsedMessage.Message)
-
 			// Reset the inactivity timer every time a message is processed
 			if !idleTimer.Stop() {
 				<-idleTimer.C
@@ -127,11 +173,48 @@ func runAnalyzeLogs(cliParams *CliParams, config config.Component, ac autodiscov
 			// Timeout reached, signal quit
 			launchers.Stop()
 			pipelineProvider.Stop()
+			if cliParams.expectPath == "" {
+				return nil
+			}
+			diff, err := compareGolden(cliParams.expectPath, buffer.Bytes())
+			if err != nil {
+				return err
+			}
+			if diff != "" {
+				fmt.Print(diff)
+				return fmt.Errorf("the output doesn't match the golden file %s", cliParams.expectPath)
+			}
 			return nil
 		}
 	}
 }
 
+// copyToTempFile copies r to a file named "stdin" in a temporary directory, so
+// that it can be tailed. The returned function removes the directory.
+func copyToTempFile(r io.Reader) (string, func(), error) {
+	dir, err := os.MkdirTemp("", "analyze-logs")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	path := filepath.Join(dir, "stdin")
+	f, err := os.Create(path)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
+
 // Used to make testing easier
 func runAnalyzeLogsHelper(cliParams *CliParams, config config.Component, ac autodiscovery.Component) (chan *message.Message, *launchers.Launchers, pipeline.Provider, error) {
 	configSource := sources.NewConfigSources()
@@ -140,6 +223,16 @@ func runAnalyzeLogsHelper(cliParams *CliParams, config config.Component, ac auto
 		return nil, nil, nil, err
 	}
 
+	if cliParams.inputPath != "" {
+		// the input is tailed with the configuration of the source
+		if len(sources) != 1 {
+			return nil, nil, nil, fmt.Errorf("an input can only be analyzed with a logs configuration defining a single source, found %d", len(sources))
+		}
+		sources[0].Config.Type = logsconfig.FileType
+		sources[0].Config.Path = cliParams.inputPath
+		sources[0].Config.TailingMode = "beginning"
+	}
+
 	for _, source := range sources {
 		err := source.Config.Validate()
 		if err != nil {
@@ -151,7 +244,8 @@ func runAnalyzeLogsHelper(cliParams *CliParams, config config.Component, ac auto
 		}
 		configSource.AddSource(source)
 	}
-	return agentimpl.SetUpLaunchers(config, configSource)
+	// the dropped logs are only reported by the json output
+	return agentimpl.SetUpLaunchers(config, configSource, cliParams.outputFormat == jsonFormat)
 }
 
 func getSources(ac autodiscovery.Component, cliParams *CliParams) ([]*sources.LogSource, error) {
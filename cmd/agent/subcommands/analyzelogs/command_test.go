@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -39,6 +40,19 @@ func TestCommand(t *testing.T) {
 			require.Equal(t, "path/to/log/config.yaml", cliParams.LogConfigPath)
 			require.Equal(t, time.Duration(5)*time.Second, cliParams.inactivityTimeout)
 			require.Equal(t, defaultCoreConfigPath, cliParams.CoreConfigPath)
+			require.Equal(t, textFormat, cliParams.outputFormat)
+		})
+}
+
+func TestCommandGoldenFile(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"analyze-logs", "--input", "-", "--format", "json", "--expect", "testdata/app.golden", "path/to/log/config.yaml"},
+		runAnalyzeLogs,
+		func(_ core.BundleParams, cliParams *CliParams) {
+			require.Equal(t, "-", cliParams.inputPath)
+			require.Equal(t, jsonFormat, cliParams.outputFormat)
+			require.Equal(t, "testdata/app.golden", cliParams.expectPath)
 		})
 }
 
@@ -202,3 +216,52 @@ func TestRunAnalyzeLogsInvalidConfig(t *testing.T) {
 	_, _, _, err := runAnalyzeLogsHelper(cliParams, config, ac)
 	assert.Error(t, err)
 }
+
+func TestRunAnalyzeLogsWithInput(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "input.log")
+	require.NoError(t, os.WriteFile(inputPath, []byte("GET /\nGET /healthcheck\n"), 0644))
+	configPath := filepath.Join(tempDir, "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`logs:
+  - type: file
+    path: /var/log/app/*.log
+    service: web
+    source: nginx
+    log_processing_rules:
+      - type: exclude_at_match
+        name: exclude_healthchecks
+        pattern: healthcheck
+`), 0644))
+
+	config := config.NewMock(t)
+	adsched := scheduler.NewController()
+	ac := fxutil.Test[autodiscovery.Mock](t,
+		fx.Supply(autodiscoveryimpl.MockParams{Scheduler: adsched}),
+		secretsimpl.MockModule(),
+		autodiscoveryimpl.MockModule(),
+		workloadmetafxmock.MockModule(workloadmeta.NewParams()),
+		core.MockBundle(),
+		taggerfxmock.MockModule(),
+	)
+
+	cliParams := &CliParams{
+		LogConfigPath: configPath,
+		inputPath:     inputPath,
+		outputFormat:  jsonFormat,
+	}
+	outputChan, launcher, pipelineProvider, err := runAnalyzeLogsHelper(cliParams, config, ac)
+	require.NoError(t, err)
+	defer pipelineProvider.Stop()
+	defer launcher.Stop()
+
+	// the input is tailed instead of the path of the configuration, and the
+	// logs dropped by processing rules are reported in the json output
+	msg := <-outputChan
+	assert.Equal(t, inputPath, msg.Origin.LogSource.Config.Path)
+	assert.Equal(t, "6", msg.Origin.Offset)
+	assert.Nil(t, msg.ProcessingTrace)
+	msg = <-outputChan
+	assert.Equal(t, "23", msg.Origin.Offset)
+	require.NotNil(t, msg.ProcessingTrace)
+	assert.Equal(t, "exclude_healthchecks", msg.ProcessingTrace.DroppedBy)
+}
@@ -0,0 +1,149 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package analyzelogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
+)
+
+const (
+	// textFormat prints the content of the logs sent by the agent
+	textFormat = "text"
+	// jsonFormat prints a JSON object per processed log, including the logs
+	// dropped by processing rules
+	jsonFormat = "json"
+)
+
+// analyzedLog is the structured representation of a processed log. It doesn't
+// include the timestamp nor the hostname of the log, so that the output of a
+// given input is always the same, whatever the host.
+type analyzedLog struct {
+	Content   string     `json:"content"`
+	Status    string     `json:"status"`
+	Service   string     `json:"service,omitempty"`
+	Source    string     `json:"source,omitempty"`
+	Tags      []string   `json:"tags"`
+	Rules     []string   `json:"rules,omitempty"`
+	DroppedBy string     `json:"dropped_by,omitempty"`
+	Lines     *lineRange `json:"lines,omitempty"`
+}
+
+// lineRange is the range of lines of the input a log was read from, which shows
+// how multiline aggregation grouped the lines.
+type lineRange struct {
+	First int `json:"first"`
+	Last  int `json:"last"`
+}
+
+// analyzer writes the logs processed by the pipeline in the requested format.
+type analyzer struct {
+	format string
+	// input is the content of the analyzed input, nil when tailing the files
+	// of the logs configuration
+	input []byte
+	// offset is the input offset the previous log ended at
+	offset int64
+}
+
+func newAnalyzer(format string, input []byte) (*analyzer, error) {
+	if format != textFormat && format != jsonFormat {
+		return nil, fmt.Errorf("unknown output format %q, expected %q or %q", format, textFormat, jsonFormat)
+	}
+	return &analyzer{format: format, input: input}, nil
+}
+
+// write writes the given processed log to w.
+func (a *analyzer) write(w io.Writer, msg *message.Message) error {
+	parsedMessage := processor.JSONPayload
+	if err := json.Unmarshal(msg.GetContent(), &parsedMessage); err != nil {
+		return err
+	}
+	lines := a.lines(msg)
+
+	if a.format == textFormat {
+		if msg.ProcessingTrace == nil || msg.ProcessingTrace.DroppedBy == "" {
+			fmt.Fprintln(w, parsedMessage.Message)
+		}
+		return nil
+	}
+
+	analyzed := analyzedLog{
+		Content: parsedMessage.Message,
+		Status:  parsedMessage.Status,
+		Service: parsedMessage.Service,
+		Source:  parsedMessage.Source,
+		Tags:    []string{},
+		Lines:   lines,
+	}
+	if parsedMessage.Tags != "" {
+		analyzed.Tags = strings.Split(parsedMessage.Tags, ",")
+		sort.Strings(analyzed.Tags)
+	}
+	if msg.ProcessingTrace != nil {
+		analyzed.Rules = msg.ProcessingTrace.RuleHits
+		analyzed.DroppedBy = msg.ProcessingTrace.DroppedBy
+	}
+	data, err := json.Marshal(analyzed)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// lines returns the range of lines of the input the log was read from, using
+// the offset of the end of the log. The tailer sends the logs in order, so the
+// log starts where the previous one ended.
+func (a *analyzer) lines(msg *message.Message) *lineRange {
+	if a.input == nil || msg.Origin == nil {
+		return nil
+	}
+	end, err := strconv.ParseInt(msg.Origin.Offset, 10, 64)
+	if err != nil || end < a.offset || end > int64(len(a.input)) {
+		return nil
+	}
+	start := a.offset
+	a.offset = end
+
+	// skip the empty lines the tailer ignored
+	for start < end && (a.input[start] == '\n' || a.input[start] == '\r') {
+		start++
+	}
+	first := bytes.Count(a.input[:start], []byte{'\n'}) + 1
+	last := first + bytes.Count(bytes.TrimRight(a.input[start:end], "\r\n"), []byte{'\n'})
+	return &lineRange{First: first, Last: last}
+}
+
+// compareGolden compares the output to the content of the golden file at path,
+// and returns the differences as a unified diff, empty when they match.
+func compareGolden(path string, output []byte) (string, error) {
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(expected, output) {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(expected)),
+		B:        difflib.SplitLines(string(output)),
+		FromFile: path,
+		ToFile:   "output",
+		Context:  3,
+	})
+}
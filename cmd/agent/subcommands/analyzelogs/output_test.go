@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package analyzelogs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logsconfig "github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// newProcessedMessage returns a message as sent by the processor, read up to
+// the given offset of the input.
+func newProcessedMessage(t *testing.T, content string, offset int, trace *message.ProcessingTrace) *message.Message {
+	source := sources.NewLogSource("", &logsconfig.LogsConfig{
+		Service: "web",
+		Source:  "nginx",
+		Tags:    []string{"env:prod", "app:shop"},
+	})
+	origin := message.NewOrigin(source)
+	origin.Offset = strconv.Itoa(offset)
+	msg := message.NewMessage([]byte(content), origin, message.StatusInfo, 0)
+	msg.ProcessingTrace = trace
+
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	msg.SetRendered(rendered)
+	require.NoError(t, processor.JSONEncoder.Encode(msg, "my-host"))
+	return msg
+}
+
+func TestAnalyzerJSON(t *testing.T) {
+	a, err := newAnalyzer(jsonFormat, []byte("GET /\nGET /healthcheck\n"))
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	require.NoError(t, a.write(&output, newProcessedMessage(t, "GET /", 6, &message.ProcessingTrace{RuleHits: []string{"extract"}})))
+	require.NoError(t, a.write(&output, newProcessedMessage(t, "GET /healthcheck", 23, &message.ProcessingTrace{RuleHits: []string{"exclude"}, DroppedBy: "exclude"})))
+
+	expected := `{"content":"GET /","status":"info","service":"web","source":"nginx","tags":["app:shop","env:prod"],"rules":["extract"],"lines":{"first":1,"last":1}}
+{"content":"GET /healthcheck","status":"info","service":"web","source":"nginx","tags":["app:shop","env:prod"],"rules":["exclude"],"dropped_by":"exclude","lines":{"first":2,"last":2}}
+`
+	assert.Equal(t, expected, output.String())
+}
+
+func TestAnalyzerText(t *testing.T) {
+	a, err := newAnalyzer(textFormat, nil)
+	require.NoError(t, err)
+
+	var output bytes.Buffer
+	require.NoError(t, a.write(&output, newProcessedMessage(t, "GET /", 6, nil)))
+	require.NoError(t, a.write(&output, newProcessedMessage(t, "GET /healthcheck", 23, &message.ProcessingTrace{DroppedBy: "exclude"})))
+
+	// the dropped logs are not printed
+	assert.Equal(t, "GET /\n", output.String())
+}
+
+func TestAnalyzerUnknownFormat(t *testing.T) {
+	_, err := newAnalyzer("yaml", nil)
+	assert.Error(t, err)
+}
+
+func TestAnalyzerLines(t *testing.T) {
+	input := "first\n\nsecond\n  at foo\nthird\n"
+	a, err := newAnalyzer(jsonFormat, []byte(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, &lineRange{First: 1, Last: 1}, a.lines(newProcessedMessage(t, "first", 6, nil)))
+	// the empty line is skipped, and the multiline log spans two lines
+	assert.Equal(t, &lineRange{First: 3, Last: 4}, a.lines(newProcessedMessage(t, `second\n  at foo`, 23, nil)))
+	assert.Equal(t, &lineRange{First: 5, Last: 5}, a.lines(newProcessedMessage(t, "third", len(input), nil)))
+	// offsets out of the input are ignored
+	assert.Nil(t, a.lines(newProcessedMessage(t, "other", len(input)+1, nil)))
+
+	a, err = newAnalyzer(jsonFormat, nil)
+	require.NoError(t, err)
+	assert.Nil(t, a.lines(newProcessedMessage(t, "first", 6, nil)), "lines are only known for inputs")
+}
+
+func TestCompareGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte("a\nb\nc\n"), 0644))
+
+	diff, err := compareGolden(path, []byte("a\nb\nc\n"))
+	require.NoError(t, err)
+	assert.Empty(t, diff)
+
+	diff, err = compareGolden(path, []byte("a\nB\nc\n"))
+	require.NoError(t, err)
+	assert.Contains(t, diff, "-b\n")
+	assert.Contains(t, diff, "+B\n")
+
+	_, err = compareGolden(filepath.Join(t.TempDir(), "missing.jsonl"), nil)
+	assert.Error(t, err)
+}
@@ -29,6 +29,11 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
+type limiterFlags struct {
+	json     bool
+	nentries int
+}
+
 type topFlags struct {
 	path               string
 	nmetrics           int
@@ -78,6 +83,26 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 		},
 	})
 
+	limiterFlags := limiterFlags{}
+
+	limiterCmd := &cobra.Command{
+		Use:   "limiter",
+		Short: "Display the metrics and origins with most contexts compared to the limits of the context limiter",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return fxutil.OneShot(contextLimiter,
+				fx.Supply(&limiterFlags),
+				fx.Supply(core.BundleParams{
+					ConfigParams: cconfig.NewAgentParams(globalParams.ConfFilePath, cconfig.WithExtraConfFiles(globalParams.ExtraConfFilePath), cconfig.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					LogParams:    log.ForOneShot(command.LoggerName, topFlags.logLevelDefaultOff.Value(), true)}),
+				core.Bundle(),
+			)
+		},
+	}
+	limiterCmd.Flags().BoolVarP(&limiterFlags.json, "json", "j", false, "print out raw json")
+	limiterCmd.Flags().IntVarP(&limiterFlags.nentries, "num-entries", "n", 10, "number of metrics and origins to show")
+
+	c.AddCommand(limiterCmd)
+
 	return []*cobra.Command{c}
 }
 
@@ -120,6 +145,78 @@ func dumpContexts(config cconfig.Component, _ log.Component) error {
 	return nil
 }
 
+func contextLimiter(config cconfig.Component, flags *limiterFlags, _ log.Component) error {
+	c := util.GetClient()
+	addr, err := pkgconfigsetup.GetIPCAddress(pkgconfigsetup.Datadog())
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://%v:%v/agent/dogstatsd-context-limiter", addr, config.GetInt("cmd_port"))
+	if err = util.SetAuthToken(config); err != nil {
+		return err
+	}
+
+	body, err := util.DoGet(c, url, util.LeaveConnectionOpen)
+	if err != nil {
+		return err
+	}
+
+	if flags.json {
+		fmt.Println(string(body))
+		return nil
+	}
+
+	var report aggregator.ContextLimiterReport
+	if err = json.Unmarshal(body, &report); err != nil {
+		return err
+	}
+	fmt.Print(formatLimiterReport(report, flags.nentries))
+	return nil
+}
+
+// formatLimiterReport renders the first entries of the report of the context
+// limiter, which are sorted from the worst offender.
+func formatLimiterReport(report aggregator.ContextLimiterReport, limit int) string {
+	if !report.Enabled {
+		return "The context limiter is disabled, see the dogstatsd_context_limiter settings.\n"
+	}
+
+	var b strings.Builder
+	sections := []struct {
+		title string
+		stats []aggregator.ContextLimiterStats
+		name  func(aggregator.ContextLimiterStats) string
+	}{
+		{"Metric name", report.Metrics, func(s aggregator.ContextLimiterStats) string {
+			if len(s.HighCardinalityTags) == 0 {
+				return s.Name
+			}
+			return fmt.Sprintf("%s (high cardinality tags: %s)", s.Name, strings.Join(s.HighCardinalityTags, ", "))
+		}},
+		{"Origin", report.Origins, func(s aggregator.ContextLimiterStats) string { return strings.Join(s.Origin, ",") }},
+	}
+	for i, section := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, " % 10s\t% 10s\t% 10s\t% 10s\t%s\n", "Contexts", "Limit", "Dropped", "Collapsed", section.title)
+		stats := section.stats
+		if len(stats) > limit {
+			stats = stats[:limit]
+		}
+		for _, s := range stats {
+			fmt.Fprintf(&b, " % 10d\t% 10d\t% 10d\t% 10d\t%s\n", s.Contexts, s.Limit, s.Dropped, s.Collapsed, section.name(s))
+		}
+		if len(section.stats) == 0 {
+			b.WriteString(" (none close to the limit)\n")
+		} else if len(section.stats) > limit {
+			fmt.Fprintf(&b, " (%d more)\n", len(section.stats)-limit)
+		}
+	}
+	return b.String()
+}
+
 type metric struct {
 	count uint
 	tags  map[string]struct{}
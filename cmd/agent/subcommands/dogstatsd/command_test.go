@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
@@ -31,4 +32,32 @@ func TestCommand(t *testing.T) {
 			assert.Equal(t, 1, f.nmetrics)
 			assert.Equal(t, 2, f.ntags)
 		})
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd", "limiter", "--json", "-n", "3"},
+		contextLimiter,
+		func(f *limiterFlags) {
+			assert.True(t, f.json)
+			assert.Equal(t, 3, f.nentries)
+		})
+}
+
+func TestFormatLimiterReport(t *testing.T) {
+	assert.Contains(t, formatLimiterReport(aggregator.ContextLimiterReport{}, 10), "disabled")
+
+	report := aggregator.ContextLimiterReport{
+		Enabled: true,
+		Metrics: []aggregator.ContextLimiterStats{
+			{Name: "http.requests", Contexts: 120, Limit: 100, Collapsed: 42, HighCardinalityTags: []string{"request_id"}},
+			{Name: "http.latency", Contexts: 60, Limit: 100},
+		},
+	}
+	expected := `   Contexts	     Limit	   Dropped	 Collapsed	Metric name
+        120	       100	         0	        42	http.requests (high cardinality tags: request_id)
+ (1 more)
+
+   Contexts	     Limit	   Dropped	 Collapsed	Origin
+ (none close to the limit)
+`
+	assert.Equal(t, expected, formatLimiterReport(report, 1))
 }
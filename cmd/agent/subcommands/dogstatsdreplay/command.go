@@ -7,6 +7,7 @@
 package dogstatsdreplay
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
 	"fmt"
@@ -35,6 +36,7 @@ import (
 
 const (
 	defaultIterations = 1
+	defaultSpeed      = 1.0
 )
 
 // cliParams are the command-line arguments for this subcommand
@@ -47,6 +49,10 @@ type cliParams struct {
 	dsdVerboseReplay    bool
 	dsdMmapReplay       bool
 	dsdReplayIterations int
+	dsdReplaySpeed      float64
+	dsdReplayMetrics    []string
+	dsdReplayPids       []int32
+	dsdReplayExportPath string
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
@@ -70,13 +76,42 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	dogstatsdReplayCmd.Flags().StringVarP(&cliParams.dsdReplayFilePath, "file", "f", "", "Input file with traffic captured with dogstatsd-capture.")
 	dogstatsdReplayCmd.Flags().BoolVarP(&cliParams.dsdVerboseReplay, "verbose", "v", false, "Verbose replay.")
 	dogstatsdReplayCmd.Flags().BoolVarP(&cliParams.dsdMmapReplay, "mmap", "m", true, "Mmap file for replay. Set to false to load the entire file into memory instead")
-	dogstatsdReplayCmd.Flags().IntVarP(&cliParams.dsdReplayIterations, "loops", "l", defaultIterations, "Number of iterations to replay.")
+	dogstatsdReplayCmd.Flags().IntVarP(&cliParams.dsdReplayIterations, "loops", "l", defaultIterations, "Number of iterations to replay, 0 to loop until interrupted.")
+	dogstatsdReplayCmd.Flags().Float64VarP(&cliParams.dsdReplaySpeed, "speed", "s", defaultSpeed, "Factor by which the original pace of the capture is sped up (above 1) or slowed down (below 1), 0 to replay as fast as possible.")
+	dogstatsdReplayCmd.Flags().StringSliceVar(&cliParams.dsdReplayMetrics, "metric", nil, "Only replay the metrics whose name matches one of these glob patterns, e.g. 'app.requests.*'.")
+	dogstatsdReplayCmd.Flags().Int32SliceVar(&cliParams.dsdReplayPids, "pid", nil, "Only replay the packets sent by one of these PIDs.")
+	dogstatsdReplayCmd.Flags().StringVar(&cliParams.dsdReplayExportPath, "export", "", "Export the packets of the capture as JSON lines to this file, '-' for the standard output, instead of replaying them.")
 
 	return []*cobra.Command{dogstatsdReplayCmd}
 }
 
 //nolint:revive // TODO(AML) Fix revive linter
 func dogstatsdReplay(_ log.Component, config config.Component, cliParams *cliParams) error {
+	if cliParams.dsdReplaySpeed < 0 {
+		return fmt.Errorf("invalid replay speed %v, it must be positive", cliParams.dsdReplaySpeed)
+	}
+	filter, err := replay.NewTrafficFilter(cliParams.dsdReplayMetrics, cliParams.dsdReplayPids)
+	if err != nil {
+		return err
+	}
+
+	depth := 10
+	reader, err := replay.NewTrafficCaptureReader(cliParams.dsdReplayFilePath, depth, cliParams.dsdMmapReplay)
+	if reader != nil {
+		defer reader.Close()
+	}
+
+	if err != nil {
+		fmt.Printf("could not open: %s\n", cliParams.dsdReplayFilePath)
+		return err
+	}
+	reader.SetFilter(filter)
+	reader.SetSpeed(cliParams.dsdReplaySpeed)
+
+	if cliParams.dsdReplayExportPath != "" {
+		return exportCapture(reader, cliParams.dsdReplayExportPath)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -122,17 +157,6 @@ func dogstatsdReplay(_ log.Component, config config.Component, cliParams *cliPar
 
 	cli := pb.NewAgentSecureClient(apiconn)
 
-	depth := 10
-	reader, err := replay.NewTrafficCaptureReader(cliParams.dsdReplayFilePath, depth, cliParams.dsdMmapReplay)
-	if reader != nil {
-		defer reader.Close()
-	}
-
-	if err != nil {
-		fmt.Printf("could not open: %s\n", cliParams.dsdReplayFilePath)
-		return err
-	}
-
 	s := pkgconfigsetup.Datadog().GetString("dogstatsd_socket")
 	if s == "" {
 		return fmt.Errorf("Dogstatsd UNIX socket disabled")
@@ -225,3 +249,31 @@ func dogstatsdReplay(_ log.Component, config config.Component, cliParams *cliPar
 	fmt.Println("replay done")
 	return err
 }
+
+// exportCapture writes the packets of the capture as JSON lines to the file
+// at path, or to the standard output for '-'.
+func exportCapture(reader *replay.TrafficCaptureReader, path string) error {
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	exported, err := reader.ExportJSON(w)
+	if err != nil {
+		return fmt.Errorf("unable to export the capture: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if path != "-" {
+		fmt.Printf("%d packets exported to %s\n", exported, path)
+	}
+	return nil
+}
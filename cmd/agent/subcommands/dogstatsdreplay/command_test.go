@@ -26,3 +26,16 @@ func TestCommand(t *testing.T) {
 			require.Equal(t, false, secretParams.Enabled)
 		})
 }
+
+func TestCommandFilters(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-replay", "-f", "capture.dog", "--speed", "2.5", "--metric", "app.*,db.queries", "--pid", "42", "--export", "-"},
+		dogstatsdReplay,
+		func(cliParams *cliParams, _ core.BundleParams, _ secrets.Params) {
+			require.Equal(t, 2.5, cliParams.dsdReplaySpeed)
+			require.Equal(t, []string{"app.*", "db.queries"}, cliParams.dsdReplayMetrics)
+			require.Equal(t, []int32{42}, cliParams.dsdReplayPids)
+			require.Equal(t, "-", cliParams.dsdReplayExportPath)
+		})
+}
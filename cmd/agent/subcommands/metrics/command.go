@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package metrics implements 'agent metrics'.
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	cconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+type queryFlags struct {
+	name               string
+	tags               []string
+	json               bool
+	logLevelDefaultOff command.LogLevelDefaultOff
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	c := &cobra.Command{
+		Use:   "metrics",
+		Short: "Inspect the metrics of the aggregator",
+	}
+
+	queryFlags := queryFlags{}
+
+	queryCmd := &cobra.Command{
+		Use:   "query [name pattern]",
+		Short: "Display the last flushed values of the series and sketches of DogStatsD and of the checks",
+		Long: `Display the last flushed values of the series and sketches of the DogStatsD time samplers and of the check samplers,
+whose name matches the glob pattern, e.g. 'app.requests.*', and whose tags match the --tag glob patterns, e.g. 'env:*'.
+The values are only recorded when aggregator_metrics_query_enabled is set to true.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				queryFlags.name = args[0]
+			}
+			return fxutil.OneShot(queryMetrics,
+				fx.Supply(&queryFlags),
+				fx.Supply(core.BundleParams{
+					ConfigParams: cconfig.NewAgentParams(globalParams.ConfFilePath, cconfig.WithExtraConfFiles(globalParams.ExtraConfFilePath), cconfig.WithFleetPoliciesDirPath(globalParams.FleetPoliciesDirPath)),
+					LogParams:    log.ForOneShot(command.LoggerName, queryFlags.logLevelDefaultOff.Value(), true)}),
+				core.Bundle(),
+			)
+		},
+	}
+	queryFlags.logLevelDefaultOff.Register(queryCmd)
+	queryCmd.Flags().StringSliceVarP(&queryFlags.tags, "tag", "t", nil, "only show the metrics with a tag matching each of these glob patterns")
+	queryCmd.Flags().BoolVarP(&queryFlags.json, "json", "j", false, "print out raw json")
+
+	c.AddCommand(queryCmd)
+
+	return []*cobra.Command{c}
+}
+
+func queryMetrics(config cconfig.Component, flags *queryFlags, _ log.Component) error {
+	query := aggregator.MetricsQuery{Name: flags.name, Tags: flags.tags}
+	if err := query.Validate(); err != nil {
+		return err
+	}
+
+	c := util.GetClient()
+	addr, err := pkgconfigsetup.GetIPCAddress(pkgconfigsetup.Datadog())
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{}
+	if query.Name != "" {
+		params.Set("name", query.Name)
+	}
+	for _, tag := range query.Tags {
+		params.Add("tag", tag)
+	}
+	endpoint := fmt.Sprintf("https://%v:%v/agent/metrics-query?%s", addr, config.GetInt("cmd_port"), params.Encode())
+	if err = util.SetAuthToken(config); err != nil {
+		return err
+	}
+
+	body, err := util.DoGet(c, endpoint, util.LeaveConnectionOpen)
+	if err != nil {
+		return err
+	}
+
+	if flags.json {
+		fmt.Println(string(body))
+		return nil
+	}
+
+	var result []aggregator.QueriedMetric
+	if err = json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	fmt.Print(formatQueriedMetrics(result))
+	return nil
+}
+
+// formatQueriedMetrics renders the last flushed values of the metrics, with
+// their context and the sampler which flushed them.
+func formatQueriedMetrics(result []aggregator.QueriedMetric) string {
+	if len(result) == 0 {
+		return "No flushed metric matches the query.\n"
+	}
+
+	var b strings.Builder
+	for i, m := range result {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s (%s)\n", m.Name, m.Type)
+		if m.Sketch != nil {
+			fmt.Fprintf(&b, "  Value:    count=%d sum=%s min=%s max=%s avg=%s\n", m.Sketch.Count,
+				formatFloat(m.Sketch.Sum), formatFloat(m.Sketch.Min), formatFloat(m.Sketch.Max), formatFloat(m.Sketch.Avg))
+		} else if m.Value != nil {
+			fmt.Fprintf(&b, "  Value:    %s\n", formatFloat(*m.Value))
+		}
+		fmt.Fprintf(&b, "  Flushed:  %s\n", time.Unix(int64(m.Timestamp), 0).UTC().Format(time.RFC3339))
+		if m.Host != "" {
+			fmt.Fprintf(&b, "  Host:     %s\n", m.Host)
+		}
+		fmt.Fprintf(&b, "  Tags:     %s\n", strings.Join(m.Tags, ", "))
+		if len(m.OriginTags) > 0 {
+			fmt.Fprintf(&b, "  Origin:   %s\n", strings.Join(m.OriginTags, ", "))
+		}
+		fmt.Fprintf(&b, "  Source:   %s\n", m.Source)
+		fmt.Fprintf(&b, "  Sampler:  %s\n", m.Sampler)
+	}
+	return b.String()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
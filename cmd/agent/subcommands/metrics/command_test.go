@@ -0,0 +1,73 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	pkgmetrics "github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"metrics", "query", "app.*", "-t", "env:prod", "--tag", "service:*", "--json"},
+		queryMetrics,
+		func(f *queryFlags) {
+			assert.Equal(t, "app.*", f.name)
+			assert.Equal(t, []string{"env:prod", "service:*"}, f.tags)
+			assert.True(t, f.json)
+		})
+}
+
+func TestFormatQueriedMetrics(t *testing.T) {
+	assert.Equal(t, "No flushed metric matches the query.\n", formatQueriedMetrics(nil))
+
+	value := 5.5
+	result := []aggregator.QueriedMetric{
+		{
+			Name:       "app.latency",
+			Type:       "sketch",
+			Tags:       []string{"env:prod"},
+			OriginTags: []string{"container_id:abc"},
+			Source:     pkgmetrics.MetricSourceDogstatsd,
+			Sampler:    "time_sampler:0",
+			Timestamp:  1700000000,
+			Sketch:     &aggregator.SketchSummary{Count: 2, Sum: 4, Min: 1, Max: 3, Avg: 2},
+		},
+		{
+			Name:      "app.requests",
+			Type:      "gauge",
+			Host:      "my-host",
+			Tags:      []string{"env:prod"},
+			Sampler:   "check_sampler:app:1234",
+			Timestamp: 1700000000,
+			Value:     &value,
+		},
+	}
+	expected := `app.latency (sketch)
+  Value:    count=2 sum=4 min=1 max=3 avg=2
+  Flushed:  2023-11-14T22:13:20Z
+  Tags:     env:prod
+  Origin:   container_id:abc
+  Source:   dogstatsd
+  Sampler:  time_sampler:0
+
+app.requests (gauge)
+  Value:    5.5
+  Flushed:  2023-11-14T22:13:20Z
+  Host:     my-host
+  Tags:     env:prod
+  Source:   <unknown>
+  Sampler:  check_sampler:app:1234
+`
+	assert.Equal(t, expected, formatQueriedMetrics(result))
+}
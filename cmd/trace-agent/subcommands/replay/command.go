@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package replay implements the 'replay' subcommand for the 'trace-agent' command.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/subcommands"
+	"github.com/DataDog/datadog-agent/comp/api/authtoken/fetchonlyimpl"
+	coreconfig "github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	logfx "github.com/DataDog/datadog-agent/comp/core/log/fx"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/comp/core/secrets/secretsimpl"
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/fx-noop"
+	"github.com/DataDog/datadog-agent/comp/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/replay"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+	"github.com/DataDog/datadog-agent/pkg/util/option"
+)
+
+// cliParams are the command-line arguments for this subcommand.
+type cliParams struct {
+	// files are the capture files to replay, in order.
+	files []string
+
+	// realtime replays the payloads with the delays they were received with.
+	realtime bool
+}
+
+// MakeCommand returns the replay subcommand for the 'trace-agent' command.
+func MakeCommand(globalParamsGetter func() *subcommands.GlobalParams) *cobra.Command {
+	cliParams := &cliParams{}
+	replayCmd := &cobra.Command{
+		Use:   "replay <capture file>...",
+		Short: "Replay the payloads of capture files through a local trace-agent.",
+		Long: `Replays the payloads recorded by the trace-agent when apm_config.payload_capture is enabled,
+through a local trace-agent sending its payloads to a stand-in of the intake. The sampling
+decisions and the stats computed are printed as JSON, to be compared across trace-agent versions.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			cliParams.files = args
+			return runReplayFct(globalParamsGetter(), cliParams, replayCaptures)
+		},
+	}
+	replayCmd.Flags().BoolVar(&cliParams.realtime, "realtime", false, "replay the payloads with the delays they were received with")
+
+	return replayCmd
+}
+
+func runReplayFct(params *subcommands.GlobalParams, cliParams *cliParams, fct interface{}) error {
+	return fxutil.OneShot(fct,
+		fx.Supply(cliParams),
+		config.Module(),
+		fx.Supply(coreconfig.NewAgentParams(params.ConfPath, coreconfig.WithFleetPoliciesDirPath(params.FleetPoliciesDirPath))),
+		fx.Supply(log.ForOneShot(params.LoggerName, "off", true)),
+		fx.Supply(option.None[secrets.Component]()),
+		fx.Supply(secrets.NewEnabledParams()),
+		coreconfig.Module(),
+		secretsimpl.Module(),
+		nooptagger.Module(),
+		fetchonlyimpl.Module(),
+		logfx.Module(),
+	)
+}
+
+func replayCaptures(config config.Component, cliParams *cliParams) error {
+	tracecfg := config.Object()
+	if tracecfg == nil {
+		return fmt.Errorf("Unable to successfully parse config")
+	}
+	res, err := replay.Run(context.Background(), tracecfg, cliParams.files, replay.Options{Realtime: cliParams.realtime})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
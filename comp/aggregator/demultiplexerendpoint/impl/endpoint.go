@@ -3,12 +3,13 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2023-present Datadog, Inc.
 
-// Package demultiplexerendpointimpl component provides the /dogstatsd-contexts-dump API endpoint that can register via Fx value groups.
+// Package demultiplexerendpointimpl component provides the /dogstatsd-contexts-dump, /dogstatsd-context-limiter and /metrics-query API endpoints that can register via Fx value groups.
 package demultiplexerendpointimpl
 
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"path"
@@ -19,6 +20,7 @@ import (
 	api "github.com/DataDog/datadog-agent/comp/api/api/def"
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
 )
 
@@ -37,7 +39,9 @@ type demultiplexerEndpoint struct {
 
 // Provides defines the output of the demultiplexerendpoint component
 type Provides struct {
-	Endpoint api.AgentEndpointProvider
+	Endpoint        api.AgentEndpointProvider
+	LimiterEndpoint api.AgentEndpointProvider
+	QueryEndpoint   api.AgentEndpointProvider
 }
 
 // NewComponent creates a new demultiplexerendpoint component
@@ -49,7 +53,9 @@ func NewComponent(reqs Requires) Provides {
 	}
 
 	return Provides{
-		Endpoint: api.NewAgentEndpointProvider(endpoint.dumpDogstatsdContexts, "/dogstatsd-contexts-dump", "POST"),
+		Endpoint:        api.NewAgentEndpointProvider(endpoint.dumpDogstatsdContexts, "/dogstatsd-contexts-dump", "POST"),
+		LimiterEndpoint: api.NewAgentEndpointProvider(endpoint.dogstatsdContextLimiter, "/dogstatsd-context-limiter", "GET"),
+		QueryEndpoint:   api.NewAgentEndpointProvider(endpoint.queryMetrics, "/metrics-query", "GET"),
 	}
 }
 
@@ -70,6 +76,44 @@ func (demuxendpoint demultiplexerEndpoint) dumpDogstatsdContexts(w http.Response
 	w.Write(resp)
 }
 
+func (demuxendpoint demultiplexerEndpoint) dogstatsdContextLimiter(w http.ResponseWriter, _ *http.Request) {
+	resp, err := json.Marshal(demuxendpoint.demux.DogstatsdContextLimiterReport())
+	if err != nil {
+		httputils.SetJSONError(w, demuxendpoint.log.Errorf("Failed to serialize response: %v", err), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// queryMetrics returns the last flushed values of the metrics whose name
+// matches the `name` parameter and whose tags match the `tag` parameters.
+func (demuxendpoint demultiplexerEndpoint) queryMetrics(w http.ResponseWriter, r *http.Request) {
+	if !demuxendpoint.config.GetBool("aggregator_metrics_query_enabled") {
+		httputils.SetJSONError(w, errors.New("the metrics query is disabled, set aggregator_metrics_query_enabled to true to enable it"), 400)
+		return
+	}
+
+	query := aggregator.MetricsQuery{
+		Name: r.URL.Query().Get("name"),
+		Tags: r.URL.Query()["tag"],
+	}
+	if err := query.Validate(); err != nil {
+		httputils.SetJSONError(w, err, 400)
+		return
+	}
+
+	resp, err := json.Marshal(demuxendpoint.demux.QueryMetrics(query))
+	if err != nil {
+		httputils.SetJSONError(w, demuxendpoint.log.Errorf("Failed to serialize response: %v", err), 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
 func (demuxendpoint demultiplexerEndpoint) writeDogstatsdContexts() (string, error) {
 	path := path.Join(demuxendpoint.config.GetString("run_path"), "dogstatsd_contexts.json.zstd")
 
@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/def"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// NewGraphiteListener returns an idle listener of the Graphite plaintext
+// protocol, over TCP. Its messages are newline delimited like the ones of the
+// TCP listener, its packets have the Graphite source so that the server parses
+// them as Graphite metrics.
+func NewGraphiteListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, capture replay.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	return newTCPListener("graphite", packets.Graphite, newLineFramer, cfg.GetString("dogstatsd_graphite_port"), nil, packetOut, sharedPacketPoolManager, cfg, capture, telemetryStore, packetsTelemetryStore)
+}
+
+// NewGraphitePickleListener returns an idle listener of the Carbon pickle
+// protocol, over TCP. Its connections carry length-prefixed pickled batches of
+// `(path, (timestamp, value))` tuples, which are converted to Graphite
+// plaintext messages so that the server parses them like the ones of the
+// Graphite listener.
+func NewGraphitePickleListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, capture replay.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	return newTCPListener("graphite-pickle", packets.Graphite, newPickleFramer, cfg.GetString("dogstatsd_graphite_pickle_port"), nil, packetOut, sharedPacketPoolManager, cfg, capture, telemetryStore, packetsTelemetryStore)
+}
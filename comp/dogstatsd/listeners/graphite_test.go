@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func TestGraphiteListenerReceive(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{"dogstatsd_graphite_port": RandomPortName})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	packetsChannel := make(chan packets.Packets)
+	l, err := NewGraphiteListener(packetsChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, nil, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	l.Listen()
+	defer l.Stop()
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("servers.web1.cpu 42 1700000000\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	packet := receivePacket(t, packetsChannel)
+	assert.Equal(t, "servers.web1.cpu 42 1700000000", string(packet.Contents))
+	assert.Equal(t, packets.Graphite, packet.Source)
+	assert.Equal(t, "graphite", packet.ListenerID)
+}
+
+func TestGraphitePickleListenerReceive(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{"dogstatsd_graphite_pickle_port": RandomPortName})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	packetsChannel := make(chan packets.Packets)
+	l, err := NewGraphitePickleListener(packetsChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, nil, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	l.Listen()
+	defer l.Stop()
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	_, err = conn.Write(pickleBatch(testPickles["protocol 2"]))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	packet := receivePacket(t, packetsChannel)
+	assert.Equal(t, "servers.web1.cpu 42.5 1700000000\nservers.web1.mem 7 1700000001", string(packet.Contents))
+	assert.Equal(t, packets.Graphite, packet.Source)
+	assert.Equal(t, "graphite-pickle", packet.ListenerID)
+}
@@ -0,0 +1,576 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// pickleHeaderSize is the size of the big-endian length prefixing each
+	// pickled batch.
+	pickleHeaderSize = 4
+	// maxPickleBatchSize is the maximum size of a pickled batch, the same as
+	// Carbon's.
+	maxPickleBatchSize = 1 << 20
+)
+
+var (
+	pickleExpvars             = expvar.NewMap("dogstatsd-graphite-pickle")
+	pickleBatchDecodingErrors = expvar.Int{}
+	pickleDatapointsDiscarded = expvar.Int{}
+	errPickleStackUnderflow   = errors.New("pickle stack underflow")
+	errPickleUnexpectedValue  = errors.New("unexpected value on the pickle stack")
+)
+
+func init() {
+	pickleExpvars.Set("BatchDecodingErrors", &pickleBatchDecodingErrors)
+	pickleExpvars.Set("DatapointsDiscarded", &pickleDatapointsDiscarded)
+}
+
+// pickleFramer splits a stream of the Carbon pickle protocol into pickled
+// batches, and emits their datapoints as Graphite plaintext messages. The
+// buffer grows to hold the largest batch received, up to maxPickleBatchSize.
+type pickleFramer struct {
+	buffer []byte
+	// filled is the size of the partial batch at the beginning of the buffer
+	filled int
+	// skipped is the number of bytes of an oversized batch left to skip
+	skipped int
+	// message is the scratch buffer the messages are written into
+	message []byte
+}
+
+func newPickleFramer(bufferSize int) framer {
+	return &pickleFramer{buffer: make([]byte, max(bufferSize, pickleHeaderSize))}
+}
+
+// free returns the part of the buffer the next read is done into
+func (f *pickleFramer) free() []byte {
+	return f.buffer[f.filled:]
+}
+
+// advance handles n bytes read into the free part of the buffer, and emits the
+// datapoints of the complete batches. It returns whether a batch larger than
+// maxPickleBatchSize has been dropped.
+func (f *pickleFramer) advance(n int, emit func([]byte)) bool {
+	end := f.filled + n
+	begin := 0
+	dropped := false
+	needed := 0
+	for {
+		if f.skipped > 0 {
+			skip := min(f.skipped, end-begin)
+			f.skipped -= skip
+			begin += skip
+			if f.skipped > 0 {
+				break
+			}
+		}
+		if end-begin < pickleHeaderSize {
+			break
+		}
+		length := int(binary.BigEndian.Uint32(f.buffer[begin:]))
+		if length > maxPickleBatchSize {
+			begin += pickleHeaderSize
+			f.skipped = length
+			dropped = true
+			continue
+		}
+		if end-begin < pickleHeaderSize+length {
+			needed = pickleHeaderSize + length
+			break
+		}
+		f.emitBatch(f.buffer[begin+pickleHeaderSize:begin+pickleHeaderSize+length], emit)
+		begin += pickleHeaderSize + length
+	}
+	f.filled = copy(f.buffer, f.buffer[begin:end])
+
+	if needed > len(f.buffer) {
+		buffer := make([]byte, needed)
+		copy(buffer, f.buffer[:f.filled])
+		f.buffer = buffer
+	}
+	return dropped
+}
+
+// flush drops the partial batch at the beginning of the buffer, if any, as it
+// can't be unpickled.
+func (f *pickleFramer) flush(_ func([]byte)) {
+	f.filled = 0
+	f.skipped = 0
+}
+
+// emitBatch unpickles a batch and emits its datapoints as Graphite plaintext
+// messages. The datapoints which aren't a path with a numeric timestamp and
+// value are discarded, as Carbon does.
+func (f *pickleFramer) emitBatch(batch []byte, emit func([]byte)) {
+	datapoints, err := unpickleList(batch)
+	if err != nil {
+		log.Debugf("dogstatsd-graphite-pickle: dropping an invalid pickled batch: %v", err)
+		pickleBatchDecodingErrors.Add(1)
+		return
+	}
+	for _, datapoint := range datapoints {
+		message, ok := appendGraphiteMessage(f.message[:0], datapoint)
+		if !ok {
+			pickleDatapointsDiscarded.Add(1)
+			continue
+		}
+		f.message = message
+		emit(message)
+	}
+}
+
+// appendGraphiteMessage appends the `<path> <value> <timestamp>` message of a
+// `(path, (timestamp, value))` datapoint to b.
+func appendGraphiteMessage(b []byte, datapoint any) ([]byte, bool) {
+	pair, ok := asPickleSequence(datapoint)
+	if !ok || len(pair) != 2 {
+		return b, false
+	}
+	path, ok := pair[0].(string)
+	if !ok || path == "" || strings.IndexFunc(path, unicode.IsSpace) >= 0 {
+		return b, false
+	}
+	point, ok := asPickleSequence(pair[1])
+	if !ok || len(point) != 2 {
+		return b, false
+	}
+	timestamp, ok := asPickleNumber(point[0])
+	if !ok {
+		return b, false
+	}
+	value, ok := asPickleNumber(point[1])
+	if !ok {
+		return b, false
+	}
+	b = append(b, path...)
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, value, 'g', -1, 64)
+	b = append(b, ' ')
+	b = strconv.AppendFloat(b, timestamp, 'f', -1, 64)
+	return b, true
+}
+
+// pickleMark is pushed on the stack by the MARK opcode, it delimits the items
+// of the collection built by the following opcode.
+type pickleMark struct{}
+
+// pickleList is a list, which can be appended to after having been memoized.
+type pickleList struct {
+	items []any
+}
+
+// pickleTuple is a tuple.
+type pickleTuple []any
+
+// asPickleSequence returns the items of a list or a tuple.
+func asPickleSequence(v any) ([]any, bool) {
+	switch v := v.(type) {
+	case *pickleList:
+		return v.items, true
+	case pickleTuple:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+// asPickleNumber returns the value of an integer or a float, as Python's
+// float() would for the datapoints of Carbon, strings excepted.
+func asPickleNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	default:
+		return 0, false
+	}
+}
+
+// unpickler decodes the subset of the pickle format needed to read lists and
+// tuples of strings and numbers, for the protocols 0 to 5. The opcodes which
+// could build other objects, or call Python code, are refused.
+type unpickler struct {
+	data  []byte
+	pos   int
+	stack []any
+	memo  map[int]any
+}
+
+// unpickleList decodes a pickled list.
+func unpickleList(data []byte) ([]any, error) {
+	u := &unpickler{data: data, memo: make(map[int]any)}
+	v, err := u.load()
+	if err != nil {
+		return nil, err
+	}
+	list, ok := v.(*pickleList)
+	if !ok {
+		return nil, fmt.Errorf("expected a pickled list, got %T", v)
+	}
+	return list.items, nil
+}
+
+func (u *unpickler) read(n uint64) ([]byte, error) {
+	if n > uint64(len(u.data)-u.pos) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := u.data[u.pos : u.pos+int(n)]
+	u.pos += int(n)
+	return b, nil
+}
+
+func (u *unpickler) readUint(size uint64) (uint64, error) {
+	b, err := u.read(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		n = n<<8 | uint64(b[i])
+	}
+	return n, nil
+}
+
+func (u *unpickler) readLine() (string, error) {
+	i := bytes.IndexByte(u.data[u.pos:], '\n')
+	if i < 0 {
+		return "", io.ErrUnexpectedEOF
+	}
+	line := string(u.data[u.pos : u.pos+i])
+	u.pos += i + 1
+	return line, nil
+}
+
+// readSized reads a string prefixed by its little-endian length.
+func (u *unpickler) readSized(lengthSize uint64) (string, error) {
+	length, err := u.readUint(lengthSize)
+	if err != nil {
+		return "", err
+	}
+	b, err := u.read(length)
+	return string(b), err
+}
+
+func (u *unpickler) push(v any) {
+	u.stack = append(u.stack, v)
+}
+
+func (u *unpickler) pop() (any, error) {
+	if len(u.stack) == 0 {
+		return nil, errPickleStackUnderflow
+	}
+	v := u.stack[len(u.stack)-1]
+	u.stack = u.stack[:len(u.stack)-1]
+	return v, nil
+}
+
+func (u *unpickler) top() (any, error) {
+	if len(u.stack) == 0 {
+		return nil, errPickleStackUnderflow
+	}
+	return u.stack[len(u.stack)-1], nil
+}
+
+// popMark pops the items pushed since the last mark, and the mark.
+func (u *unpickler) popMark() ([]any, error) {
+	for i := len(u.stack) - 1; i >= 0; i-- {
+		if _, ok := u.stack[i].(pickleMark); ok {
+			items := append([]any(nil), u.stack[i+1:]...)
+			u.stack = u.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, errors.New("pickle mark not found")
+}
+
+// appendToList appends items to the list at the top of the stack.
+func (u *unpickler) appendToList(items ...any) error {
+	v, err := u.top()
+	if err != nil {
+		return err
+	}
+	list, ok := v.(*pickleList)
+	if !ok {
+		return errPickleUnexpectedValue
+	}
+	list.items = append(list.items, items...)
+	return nil
+}
+
+func (u *unpickler) memoize(index int) error {
+	v, err := u.top()
+	if err != nil {
+		return err
+	}
+	u.memo[index] = v
+	return nil
+}
+
+func (u *unpickler) pushMemoized(index int) error {
+	v, ok := u.memo[index]
+	if !ok {
+		return fmt.Errorf("pickle memo %d not found", index)
+	}
+	u.push(v)
+	return nil
+}
+
+// load runs the opcodes until STOP, and returns the value left on the stack.
+func (u *unpickler) load() (any, error) {
+	for {
+		b, err := u.read(1)
+		if err != nil {
+			return nil, err
+		}
+		switch op := b[0]; op {
+		case '.': // STOP
+			if len(u.stack) != 1 {
+				return nil, errPickleUnexpectedValue
+			}
+			return u.stack[0], nil
+		case 0x80: // PROTO
+			_, err = u.read(1)
+		case 0x95: // FRAME
+			_, err = u.read(8)
+
+		case '(': // MARK
+			u.push(pickleMark{})
+		case '0': // POP
+			_, err = u.pop()
+		case '1': // POP_MARK
+			_, err = u.popMark()
+		case '2': // DUP
+			var v any
+			if v, err = u.top(); err == nil {
+				u.push(v)
+			}
+
+		case ']': // EMPTY_LIST
+			u.push(&pickleList{})
+		case 'l': // LIST
+			var items []any
+			if items, err = u.popMark(); err == nil {
+				u.push(&pickleList{items: items})
+			}
+		case 'a': // APPEND
+			var v any
+			if v, err = u.pop(); err == nil {
+				err = u.appendToList(v)
+			}
+		case 'e': // APPENDS
+			var items []any
+			if items, err = u.popMark(); err == nil {
+				err = u.appendToList(items...)
+			}
+		case ')': // EMPTY_TUPLE
+			u.push(pickleTuple{})
+		case 't': // TUPLE
+			var items []any
+			if items, err = u.popMark(); err == nil {
+				u.push(pickleTuple(items))
+			}
+		case 0x85, 0x86, 0x87: // TUPLE1, TUPLE2, TUPLE3
+			n := int(op - 0x84)
+			if len(u.stack) < n {
+				return nil, errPickleStackUnderflow
+			}
+			items := append(pickleTuple(nil), u.stack[len(u.stack)-n:]...)
+			u.stack = u.stack[:len(u.stack)-n]
+			u.push(items)
+
+		case 'N': // NONE
+			u.push(nil)
+		case 0x88: // NEWTRUE
+			u.push(true)
+		case 0x89: // NEWFALSE
+			u.push(false)
+		case 'I': // INT
+			var line string
+			if line, err = u.readLine(); err == nil {
+				switch line {
+				case "00":
+					u.push(false)
+				case "01":
+					u.push(true)
+				default:
+					var n int64
+					if n, err = strconv.ParseInt(line, 10, 64); err == nil {
+						u.push(n)
+					}
+				}
+			}
+		case 'L': // LONG
+			var line string
+			if line, err = u.readLine(); err == nil {
+				var n int64
+				if n, err = strconv.ParseInt(strings.TrimSuffix(line, "L"), 10, 64); err == nil {
+					u.push(n)
+				}
+			}
+		case 'J': // BININT
+			var n uint64
+			if n, err = u.readUint(4); err == nil {
+				u.push(int64(int32(uint32(n))))
+			}
+		case 'K': // BININT1
+			var n uint64
+			if n, err = u.readUint(1); err == nil {
+				u.push(int64(n))
+			}
+		case 'M': // BININT2
+			var n uint64
+			if n, err = u.readUint(2); err == nil {
+				u.push(int64(n))
+			}
+		case 0x8a, 0x8b: // LONG1, LONG4
+			lengthSize := uint64(1)
+			if op == 0x8b {
+				lengthSize = 4
+			}
+			var length uint64
+			if length, err = u.readUint(lengthSize); err == nil {
+				if length > 8 {
+					return nil, fmt.Errorf("pickled integer of %d bytes too large", length)
+				}
+				var b []byte
+				if b, err = u.read(length); err == nil {
+					u.push(decodePickleLong(b))
+				}
+			}
+		case 'F': // FLOAT
+			var line string
+			if line, err = u.readLine(); err == nil {
+				var f float64
+				if f, err = strconv.ParseFloat(line, 64); err == nil {
+					u.push(f)
+				}
+			}
+		case 'G': // BINFLOAT
+			var b []byte
+			if b, err = u.read(8); err == nil {
+				u.push(math.Float64frombits(binary.BigEndian.Uint64(b)))
+			}
+
+		case 'S': // STRING
+			var line string
+			if line, err = u.readLine(); err == nil {
+				var s string
+				if s, err = unquotePickleString(line); err == nil {
+					u.push(s)
+				}
+			}
+		case 'V': // UNICODE, raw-unicode-escape encoded
+			var line string
+			if line, err = u.readLine(); err == nil {
+				u.push(line)
+			}
+		case 'T', 'B', 'X': // BINSTRING, BINBYTES, BINUNICODE
+			var s string
+			if s, err = u.readSized(4); err == nil {
+				u.push(s)
+			}
+		case 'U', 'C', 0x8c: // SHORT_BINSTRING, SHORT_BINBYTES, SHORT_BINUNICODE
+			var s string
+			if s, err = u.readSized(1); err == nil {
+				u.push(s)
+			}
+		case 0x8d, 0x8e: // BINUNICODE8, BINBYTES8
+			var s string
+			if s, err = u.readSized(8); err == nil {
+				u.push(s)
+			}
+
+		case 'p': // PUT
+			var line string
+			if line, err = u.readLine(); err == nil {
+				var index int
+				if index, err = strconv.Atoi(line); err == nil {
+					err = u.memoize(index)
+				}
+			}
+		case 'q', 'r': // BINPUT, LONG_BINPUT
+			size := uint64(1)
+			if op == 'r' {
+				size = 4
+			}
+			var index uint64
+			if index, err = u.readUint(size); err == nil {
+				err = u.memoize(int(index))
+			}
+		case 0x94: // MEMOIZE
+			err = u.memoize(len(u.memo))
+		case 'g': // GET
+			var line string
+			if line, err = u.readLine(); err == nil {
+				var index int
+				if index, err = strconv.Atoi(line); err == nil {
+					err = u.pushMemoized(index)
+				}
+			}
+		case 'h', 'j': // BINGET, LONG_BINGET
+			size := uint64(1)
+			if op == 'j' {
+				size = 4
+			}
+			var index uint64
+			if index, err = u.readUint(size); err == nil {
+				err = u.pushMemoized(int(index))
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported pickle opcode %#x", op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// decodePickleLong decodes a little-endian two's complement integer of at most
+// 8 bytes.
+func decodePickleLong(b []byte) int64 {
+	if len(b) == 0 {
+		return 0
+	}
+	var n uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		n = n<<8 | uint64(b[i])
+	}
+	if shift := 64 - 8*len(b); shift > 0 {
+		// sign extension
+		return int64(n<<shift) >> shift
+	}
+	return int64(n)
+}
+
+// unquotePickleString decodes the quoted string of the STRING opcode, which is
+// the repr() of a Python 2 string.
+func unquotePickleString(quoted string) (string, error) {
+	if len(quoted) < 2 || quoted[0] != quoted[len(quoted)-1] || (quoted[0] != '\'' && quoted[0] != '"') {
+		return "", fmt.Errorf("invalid pickled string %q", quoted)
+	}
+	s := quoted[1 : len(quoted)-1]
+	if !strings.ContainsRune(s, '\\') {
+		return s, nil
+	}
+	s = strings.ReplaceAll(s, `\'`, `'`)
+	return strconv.Unquote(`"` + strings.ReplaceAll(s, `"`, `\"`) + `"`)
+}
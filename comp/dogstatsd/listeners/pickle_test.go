@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the pickles are the ones of Python's pickle.dumps for
+// [("servers.web1.cpu", (1700000000, 42.5)), ("servers.web1.mem", (1700000001.0, 7))]
+var testPickles = map[string]string{
+	"protocol 0": "(lp0\x0a(Vservers.web1.cpu\x0ap1\x0a(I1700000000\x0aF42.5\x0atp2\x0atp3\x0aa(Vservers.web1.mem\x0ap4\x0a(F1700000001.0\x0aI7\x0atp5\x0atp6\x0aa.",
+	"protocol 2": "\x80\x02]q\x00(X\x10\x00\x00\x00servers.web1.cpuq\x01J\x00\xf1SeG@E@\x00\x00\x00\x00\x00\x86q\x02\x86q\x03X\x10\x00\x00\x00servers.web1.memq\x04GA\xd9T\xfc@@\x00\x00K\x07\x86q\x05\x86q\x06e.",
+	"protocol 4": "\x80\x04\x95L\x00\x00\x00\x00\x00\x00\x00]\x94(\x8c\x10servers.web1.cpu\x94J\x00\xf1SeG@E@\x00\x00\x00\x00\x00\x86\x94\x86\x94\x8c\x10servers.web1.mem\x94GA\xd9T\xfc@@\x00\x00K\x07\x86\x94\x86\x94e.",
+}
+
+func pickleBatch(pickle string) []byte {
+	batch := binary.BigEndian.AppendUint32(nil, uint32(len(pickle)))
+	return append(batch, pickle...)
+}
+
+func framePickles(f framer, stream []byte, readSize int) ([]string, int) {
+	var messages []string
+	emit := func(message []byte) { messages = append(messages, string(message)) }
+	dropped := 0
+	for len(stream) > 0 {
+		free := f.free()
+		n := copy(free[:min(len(free), readSize)], stream)
+		stream = stream[n:]
+		if f.advance(n, emit) {
+			dropped++
+		}
+	}
+	f.flush(emit)
+	return messages, dropped
+}
+
+func TestPickleFramer(t *testing.T) {
+	expected := []string{"servers.web1.cpu 42.5 1700000000", "servers.web1.mem 7 1700000001"}
+	for name, pickle := range testPickles {
+		t.Run(name, func(t *testing.T) {
+			stream := append(pickleBatch(pickle), pickleBatch(pickle)...)
+			// the batches are larger than the buffer, and read in small chunks
+			messages, dropped := framePickles(newPickleFramer(16), stream, 7)
+			assert.Equal(t, append(expected, expected...), messages)
+			assert.Zero(t, dropped)
+		})
+	}
+}
+
+func TestPickleFramerDropsOversizedBatches(t *testing.T) {
+	stream := binary.BigEndian.AppendUint32(nil, maxPickleBatchSize+1)
+	stream = append(stream, make([]byte, maxPickleBatchSize+1)...)
+	stream = append(stream, pickleBatch(testPickles["protocol 2"])...)
+	// the last batch is incomplete
+	stream = append(stream, pickleBatch(testPickles["protocol 2"])[:10]...)
+
+	messages, dropped := framePickles(newPickleFramer(8192), stream, 8192)
+	assert.Equal(t, []string{"servers.web1.cpu 42.5 1700000000", "servers.web1.mem 7 1700000001"}, messages)
+	assert.Equal(t, 1, dropped)
+}
+
+func TestPickleFramerDiscardsInvalidDatapoints(t *testing.T) {
+	// [("ok", (1, 2)), ("bad path", (1, 2)), ("s", ("1", "2")), "x", [b"bytes.path", [3, 4.0]]]
+	pickle := "\x80\x03]q\x00(X\x02\x00\x00\x00okq\x01K\x01K\x02\x86q\x02\x86q\x03X\x08\x00\x00\x00bad pathq\x04h\x02\x86q\x05X\x01\x00\x00\x00sq\x06X\x01\x00\x00\x001q\x07X\x01\x00\x00\x002q\x08\x86q\x09\x86q\x0aX\x01\x00\x00\x00xq\x0b]q\x0c(C\x0abytes.pathq\x0d]q\x0e(K\x03G@\x10\x00\x00\x00\x00\x00\x00eee."
+	messages, dropped := framePickles(newPickleFramer(8192), pickleBatch(pickle), 8192)
+	assert.Equal(t, []string{"ok 2 1", "bytes.path 4 3"}, messages)
+	assert.Zero(t, dropped)
+}
+
+func TestUnpickleList(t *testing.T) {
+	// [("a.b", (2**40, -3))]
+	items, err := unpickleList([]byte("\x80\x02]q\x00X\x03\x00\x00\x00a.bq\x01\x8a\x06\x00\x00\x00\x00\x00\x01J\xfd\xff\xff\xff\x86q\x02\x86q\x03a."))
+	require.NoError(t, err)
+	assert.Equal(t, []any{pickleTuple{"a.b", pickleTuple{int64(1 << 40), int64(-3)}}}, items)
+
+	items, err = unpickleList([]byte("(lp0\nS'it\\'s'\np1\naS\"a\\nb\"\np2\naL-12L\na."))
+	require.NoError(t, err)
+	assert.Equal(t, []any{"it's", "a\nb", int64(-12)}, items)
+}
+
+func TestUnpickleListErrors(t *testing.T) {
+	for name, pickle := range map[string]string{
+		// [("a", (1, E()))], E reducing to os.system("true")
+		"global":       "\x80\x02]q\x00X\x01\x00\x00\x00aq\x01K\x01cposix\x0asystem\x0aq\x02X\x04\x00\x00\x00trueq\x03\x85q\x04Rq\x05\x86q\x06\x86q\x07a.",
+		"not a list":   "\x80\x02K\x01.",
+		"truncated":    "\x80\x02]q\x00X\x10\x00\x00\x00serv",
+		"no stop":      "\x80\x02]q\x00",
+		"underflow":    "\x80\x02a.",
+		"missing mark": "\x80\x02]e.",
+		"missing memo": "\x80\x02h\x05.",
+	} {
+		_, err := unpickleList([]byte(pickle))
+		assert.Error(t, err, name)
+	}
+}
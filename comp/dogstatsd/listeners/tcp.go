@@ -0,0 +1,340 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/def"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	tcpExpvars             = expvar.NewMap("dogstatsd-tcp")
+	tcpPacketReadingErrors = expvar.Int{}
+	tcpPackets             = expvar.Int{}
+	tcpBytes               = expvar.Int{}
+	tcpOversizedMessages   = expvar.Int{}
+)
+
+func init() {
+	tcpExpvars.Set("PacketReadingErrors", &tcpPacketReadingErrors)
+	tcpExpvars.Set("Packets", &tcpPackets)
+	tcpExpvars.Set("Bytes", &tcpBytes)
+	tcpExpvars.Set("OversizedMessages", &tcpOversizedMessages)
+}
+
+// TCPListener implements the StatsdListener interface for TCP streams.
+// Messages are delimited by newlines, and the connections can be secured with
+// TLS, optionally verifying the certificates of the clients.
+// Origin detection is not implemented for TCP, the origin of the metrics is
+// only taken from their tags.
+type TCPListener struct {
+	// name is the name of the listener in logs and listener IDs
+	name                     string
+	source                   packets.SourceType
+	newFramer                func(bufferSize int) framer
+	listener                 net.Listener
+	connTracker              *ConnectionTracker
+	packetOut                chan packets.Packets
+	sharedPacketPoolManager  *packets.PoolManager[packets.Packet]
+	bufferSize               int
+	packetBufferSize         uint
+	packetBufferFlushTimeout time.Duration
+	telemetryWithListenerID  bool
+	trafficCapture           replay.Component // Currently ignored
+	listenWg                 sync.WaitGroup
+	connWg                   sync.WaitGroup
+	telemetryStore           *TelemetryStore
+	packetsTelemetryStore    *packets.TelemetryStore
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, capture replay.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	tlsConfig, err := buildTCPTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newTCPListener("tcp", packets.TCP, newLineFramer, cfg.GetString("dogstatsd_tcp_port"), tlsConfig, packetOut, sharedPacketPoolManager, cfg, capture, telemetryStore, packetsTelemetryStore)
+}
+
+// newTCPListener returns an idle listener on the given port, whose connections
+// are split into messages by the framers returned by newFramer, and whose
+// packets have the given source.
+func newTCPListener(name string, source packets.SourceType, newFramer func(bufferSize int) framer, port string, tlsConfig *tls.Config, packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, capture replay.Component, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	var url string
+
+	if port == RandomPortName {
+		port = "0"
+	}
+
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%s", port)
+	} else {
+		url = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+	}
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	l := &TCPListener{
+		name:                     name,
+		source:                   source,
+		newFramer:                newFramer,
+		listener:                 listener,
+		connTracker:              NewConnectionTracker(name, 1*time.Second),
+		packetOut:                packetOut,
+		sharedPacketPoolManager:  sharedPacketPoolManager,
+		bufferSize:               cfg.GetInt("dogstatsd_buffer_size"),
+		packetBufferSize:         uint(cfg.GetInt("dogstatsd_packet_buffer_size")),
+		packetBufferFlushTimeout: cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout"),
+		telemetryWithListenerID:  cfg.GetBool("dogstatsd_telemetry_enabled_listener_id"),
+		trafficCapture:           capture,
+		telemetryStore:           telemetryStore,
+		packetsTelemetryStore:    packetsTelemetryStore,
+	}
+	log.Debugf("dogstatsd-%s: %s successfully initialized (TLS: %t)", name, listener.Addr(), tlsConfig != nil)
+	return l, nil
+}
+
+// buildTCPTLSConfig returns the TLS configuration of the TCP listener, nil when
+// TLS is disabled. The certificates of the clients are required and verified
+// when a client CA is configured.
+func buildTCPTLSConfig(cfg model.Reader) (*tls.Config, error) {
+	certFile := cfg.GetString("dogstatsd_tcp_tls.cert_file")
+	keyFile := cfg.GetString("dogstatsd_tcp_tls.key_file")
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load the TLS certificate: %s", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if caFile := cfg.GetString("dogstatsd_tcp_tls.client_ca_file"); caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the client CA: %s", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no valid certificate found in the client CA %s", caFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *TCPListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	l.listenWg.Add(1)
+	go func() {
+		defer l.listenWg.Done()
+		l.listen()
+	}()
+}
+
+func (l *TCPListener) listen() {
+	l.connTracker.Start()
+	log.Infof("dogstatsd-%s: starting to listen on %s", l.name, l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Errorf("dogstatsd-%s: error accepting connection: %v", l.name, err)
+			}
+			return
+		}
+		// the connection is tracked before the accept loop can exit, so that
+		// it's closed when the listener is stopped
+		l.connTracker.Track(conn)
+		l.connWg.Add(1)
+		go func() {
+			defer l.connWg.Done()
+			l.handleConnection(conn)
+		}()
+	}
+}
+
+// handleConnection reads the messages of a connection until it's closed. The
+// messages of each connection are assembled into packets separately.
+func (l *TCPListener) handleConnection(conn net.Conn) {
+	listenerID := l.name + "-" + conn.RemoteAddr().String()
+	tlmListenerID := l.name
+	if l.telemetryWithListenerID {
+		tlmListenerID = listenerID
+	}
+
+	packetsBuffer := packets.NewBuffer(l.packetBufferSize, l.packetBufferFlushTimeout, l.packetOut, tlmListenerID, l.packetsTelemetryStore)
+	packetAssembler := packets.NewAssembler(l.packetBufferFlushTimeout, packetsBuffer, l.sharedPacketPoolManager, l.source)
+	l.telemetryStore.tlmTCPConnections.Inc(tlmListenerID)
+	defer func() {
+		l.connTracker.Close(conn)
+		packetAssembler.Flush()
+		packetAssembler.Close()
+		packetsBuffer.Flush()
+		packetsBuffer.Close()
+		l.telemetryStore.tlmTCPConnections.Dec(tlmListenerID)
+		if l.telemetryWithListenerID {
+			l.clearTelemetry(tlmListenerID)
+		}
+	}()
+
+	log.Debugf("dogstatsd-%s: starting to handle %s", l.name, conn.RemoteAddr())
+	framer := l.newFramer(l.bufferSize)
+	for {
+		n, err := conn.Read(framer.free())
+		t1 := time.Now()
+
+		if n > 0 {
+			tcpPackets.Add(1)
+			tcpBytes.Add(int64(n))
+			l.telemetryStore.tlmTCPPackets.Inc(tlmListenerID, "ok")
+			l.telemetryStore.tlmTCPPacketsBytes.Add(float64(n), tlmListenerID)
+
+			// packetAssembler merges the messages together and sends them when its buffer is full
+			if dropped := framer.advance(n, packetAssembler.AddMessage); dropped {
+				log.Debugf("dogstatsd-%s: dropping a message from %s larger than the buffer size (%d bytes)", l.name, conn.RemoteAddr(), l.bufferSize)
+				tcpOversizedMessages.Add(1)
+			}
+		}
+
+		if err != nil {
+			// the last message of the connection doesn't have to be newline terminated
+			framer.flush(packetAssembler.AddMessage)
+
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				log.Warnf("dogstatsd-%s: error reading from %s: %v", l.name, conn.RemoteAddr(), err)
+				tcpPacketReadingErrors.Add(1)
+				l.telemetryStore.tlmTCPPackets.Inc(tlmListenerID, "error")
+			}
+			log.Debugf("dogstatsd-%s: connection from %s closed", l.name, conn.RemoteAddr())
+			return
+		}
+
+		l.telemetryStore.tlmListener.Observe(float64(time.Since(t1).Nanoseconds()), tlmListenerID, "tcp", l.name)
+	}
+}
+
+// Stop closes the TCP listener and its connections, and stops listening
+func (l *TCPListener) Stop() {
+	_ = l.listener.Close()
+	l.listenWg.Wait()
+	l.connTracker.Stop()
+	l.connWg.Wait()
+}
+
+func (l *TCPListener) clearTelemetry(id string) {
+	// Since the listener id is volatile we need to make sure we clear the telemetry.
+	l.telemetryStore.tlmListener.Delete(id, "tcp", l.name)
+	l.telemetryStore.tlmTCPConnections.Delete(id)
+	l.telemetryStore.tlmTCPPackets.Delete(id, "error")
+	l.telemetryStore.tlmTCPPackets.Delete(id, "ok")
+	l.telemetryStore.tlmTCPPacketsBytes.Delete(id)
+}
+
+// framer splits the stream of a connection into messages.
+type framer interface {
+	// free returns the part of the buffer the next read is done into
+	free() []byte
+	// advance handles n bytes read into the free part of the buffer, and
+	// emits the complete messages. It returns whether data has been dropped
+	// for being larger than what the framer can hold.
+	advance(n int, emit func([]byte)) bool
+	// flush emits what's left of the stream once the connection is closed.
+	flush(emit func([]byte))
+}
+
+// lineFramer splits a stream into newline delimited messages. The partial
+// message at the end of a read is kept at the beginning of the buffer until
+// the next read completes it.
+type lineFramer struct {
+	buffer []byte
+	// filled is the size of the partial message at the beginning of the buffer
+	filled int
+	// discarding is true while skipping a message larger than the buffer
+	discarding bool
+}
+
+func newLineFramer(bufferSize int) framer {
+	return &lineFramer{buffer: make([]byte, bufferSize)}
+}
+
+// free returns the part of the buffer the next read is done into
+func (f *lineFramer) free() []byte {
+	return f.buffer[f.filled:]
+}
+
+// advance handles n bytes read into the free part of the buffer, and emits the
+// complete messages, without their last newline. It returns whether a message
+// larger than the buffer has been dropped.
+func (f *lineFramer) advance(n int, emit func([]byte)) bool {
+	end := f.filled + n
+	begin := 0
+	if f.discarding {
+		i := bytes.IndexByte(f.buffer[f.filled:end], '\n')
+		if i < 0 {
+			f.filled = 0
+			return false
+		}
+		begin = f.filled + i + 1
+		f.discarding = false
+	}
+
+	if last := bytes.LastIndexByte(f.buffer[begin:end], '\n'); last >= 0 {
+		if last > 0 {
+			emit(f.buffer[begin : begin+last])
+		}
+		begin += last + 1
+	}
+	f.filled = copy(f.buffer, f.buffer[begin:end])
+
+	if f.filled == len(f.buffer) {
+		// the buffer is full of a single message, the rest of it is skipped
+		f.filled = 0
+		f.discarding = true
+		return true
+	}
+	return false
+}
+
+// flush emits the partial message at the beginning of the buffer, if any.
+func (f *lineFramer) flush(emit func([]byte)) {
+	if f.filled > 0 && !f.discarding {
+		emit(f.buffer[:f.filled])
+	}
+	f.filled = 0
+}
@@ -0,0 +1,231 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/api/security"
+)
+
+func newTestTCPListener(t *testing.T, overrides map[string]interface{}) (*TCPListener, chan packets.Packets) {
+	overrides["dogstatsd_tcp_port"] = RandomPortName
+	deps := fulfillDepsWithConfig(t, overrides)
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	packetsChannel := make(chan packets.Packets)
+	l, err := NewTCPListener(packetsChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, nil, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	return l, packetsChannel
+}
+
+func receivePacket(t *testing.T, packetsChannel chan packets.Packets) *packets.Packet {
+	select {
+	case pkts := <-packetsChannel:
+		require.Len(t, pkts, 1)
+		return pkts[0]
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timeout on receive channel")
+	}
+	return nil
+}
+
+func TestNewTCPListener(t *testing.T) {
+	l, _ := newTestTCPListener(t, map[string]interface{}{})
+	assert.NotNil(t, l)
+	l.Stop()
+}
+
+func TestTCPListenerReceive(t *testing.T) {
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{})
+	l.Listen()
+	defer l.Stop()
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("daemon:666|g|#sometag1:somevalue1\ndaemon:"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("999|g|#sometag1:somevalue1\ndaemon:1|c"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// the messages are assembled once the connection is closed, the last one
+	// doesn't have to be newline terminated
+	packet := receivePacket(t, packetsChannel)
+	assert.Equal(t, "daemon:666|g|#sometag1:somevalue1\ndaemon:999|g|#sometag1:somevalue1\ndaemon:1|c", string(packet.Contents))
+	assert.Equal(t, packets.TCP, packet.Source)
+	assert.Equal(t, packets.NoOrigin, packet.Origin)
+	assert.Equal(t, "tcp", packet.ListenerID)
+}
+
+func TestTCPListenerStopClosesConnections(t *testing.T) {
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{})
+	l.Listen()
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("daemon:1|c\n"))
+	require.NoError(t, err)
+
+	go func() {
+		for range packetsChannel {
+		}
+	}()
+	stopped := make(chan struct{})
+	go func() {
+		l.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "the listener didn't stop")
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate, valid for server and
+// client authentication, and returns the paths of the certificate and its key.
+func writeTestCertificate(t *testing.T) (string, string) {
+	_, certPEM, key, err := security.GenerateRootCert([]string{"127.0.0.1"}, 2048)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	return certFile, keyFile
+}
+
+func TestTCPListenerTLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_tls.cert_file":      certFile,
+		"dogstatsd_tcp_tls.key_file":       keyFile,
+		"dogstatsd_tcp_tls.client_ca_file": certFile,
+	})
+	l.Listen()
+	defer l.Stop()
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	caPEM, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	rootCAs := x509.NewCertPool()
+	require.True(t, rootCAs.AppendCertsFromPEM(caPEM))
+
+	// clients without a certificate are rejected: with TLS 1.3 the client is done
+	// with the handshake before the server checks its certificate, the rejection
+	// shows up on the first read
+	conn, err := tls.Dial("tcp", l.LocalAddr(), &tls.Config{RootCAs: rootCAs})
+	if err == nil {
+		_, _ = conn.Write([]byte("rejected:1|c\n"))
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	conn, err = tls.Dial("tcp", l.LocalAddr(), &tls.Config{RootCAs: rootCAs, Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("daemon:1|c\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	packet := receivePacket(t, packetsChannel)
+	assert.Equal(t, "daemon:1|c", string(packet.Contents))
+	assert.Equal(t, "tcp", packet.ListenerID)
+
+	// nothing was received from the rejected client
+	select {
+	case pkts := <-packetsChannel:
+		assert.Fail(t, "unexpected packet", "%q", pkts[0].Contents)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTCPListenerInvalidTLSConfig(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_tcp_port":          RandomPortName,
+		"dogstatsd_tcp_tls.cert_file": filepath.Join(t.TempDir(), "missing.pem"),
+		"dogstatsd_tcp_tls.key_file":  filepath.Join(t.TempDir(), "missing.pem"),
+	})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	_, err := NewTCPListener(nil, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, nil, telemetryStore, packetsTelemetryStore)
+	assert.Error(t, err)
+}
+
+func TestLineFramer(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		bufferSize int
+		reads      []string
+		expected   []string
+		dropped    int
+	}{
+		{
+			name:       "messages split across reads",
+			bufferSize: 16,
+			reads:      []string{"a:1|c\nb:2", "|c\nc:3|c"},
+			expected:   []string{"a:1|c", "b:2|c", "c:3|c"},
+		},
+		{
+			name:       "several messages in a read",
+			bufferSize: 32,
+			reads:      []string{"a:1|c\nb:2|c\nc:3", "|c\n"},
+			expected:   []string{"a:1|c\nb:2|c", "c:3|c"},
+		},
+		{
+			name:       "message larger than the buffer",
+			bufferSize: 8,
+			reads:      []string{"a:1|c\nxxxxxxxxxxxxxxxxxxxx\nb:2|c\n"},
+			expected:   []string{"a:1|c", "b:2|c"},
+			dropped:    1,
+		},
+		{
+			name:       "oversized last message",
+			bufferSize: 8,
+			reads:      []string{"a:1|c\nxxxxxxxxxxxxxxxxxxxx"},
+			expected:   []string{"a:1|c"},
+			dropped:    1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			framer := newLineFramer(test.bufferSize)
+			var messages []string
+			emit := func(message []byte) { messages = append(messages, string(message)) }
+			dropped := 0
+			for _, read := range test.reads {
+				for len(read) > 0 {
+					n := copy(framer.free(), read)
+					read = read[n:]
+					if framer.advance(n, emit) {
+						dropped++
+					}
+				}
+			}
+			framer.flush(emit)
+			assert.Equal(t, test.expected, messages)
+			assert.Equal(t, test.dropped, dropped)
+		})
+	}
+}
@@ -11,11 +11,14 @@ package mapper
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 )
 
 var (
-	allowedWildcardMatchPattern = regexp.MustCompile(`^[a-zA-Z0-9\-_*.]+$`)
+	allowedWildcardMatchPattern = regexp.MustCompile(`^[a-zA-Z0-9\-_*.{}]+$`)
+	// namedWildcardSegment matches the named segments of wildcard patterns, like `{job_name}`
+	namedWildcardSegment = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
 )
 
 const (
@@ -23,6 +26,49 @@ const (
 	matchTypeRegex    = "regex"
 )
 
+const (
+	// ActionMap maps the metric, and stops the mapping
+	ActionMap = "map"
+	// ActionDrop drops the metric
+	ActionDrop = "drop"
+	// ActionKeep stops the mapping, keeping the metric as mapped by the previous mappings
+	ActionKeep = "keep"
+	// ActionContinue maps the metric, and continues with the next mappings
+	ActionContinue = "continue"
+)
+
+// MetricType is a type metrics can be mapped to
+type MetricType int
+
+const (
+	// MetricTypeUnchanged keeps the type of the metric
+	MetricTypeUnchanged MetricType = iota
+	// MetricTypeGauge maps the metric to a gauge
+	MetricTypeGauge
+	// MetricTypeCount maps the metric to a count
+	MetricTypeCount
+	// MetricTypeHistogram maps the metric to a histogram
+	MetricTypeHistogram
+	// MetricTypeDistribution maps the metric to a distribution
+	MetricTypeDistribution
+	// MetricTypeTiming maps the metric to a timing
+	MetricTypeTiming
+)
+
+// metricTypeNames are the names of the metric types in the mappings, in the
+// order of the MetricType constants
+var metricTypeNames = []string{"gauge", "count", "histogram", "distribution", "timing"}
+
+// parseMetricType returns the metric type of a mapping, false when the name is
+// not a metric type metrics can be mapped to
+func parseMetricType(name string) (MetricType, bool) {
+	if name == "" {
+		return MetricTypeUnchanged, true
+	}
+	i := slices.Index(metricTypeNames, name)
+	return MetricType(i + 1), i >= 0
+}
+
 //
 // Those two structs are used to pull data from the configuration into typed struct. We currently load the data from the
 // configuration into MappingProfileConfig and then convert it to MappingProfile.
@@ -40,10 +86,13 @@ type MappingProfileConfig struct {
 
 // MetricMapping represent one mapping rule
 type MetricMappingConfig struct {
-	Match     string            `mapstructure:"match" json:"match" yaml:"match"`
-	MatchType string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
-	Name      string            `mapstructure:"name" json:"name" yaml:"name"`
-	Tags      map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
+	Match       string            `mapstructure:"match" json:"match" yaml:"match"`
+	MatchType   string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
+	Name        string            `mapstructure:"name" json:"name" yaml:"name"`
+	Tags        map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
+	Action      string            `mapstructure:"action" json:"action" yaml:"action"`
+	MetricType  string            `mapstructure:"metric_type" json:"metric_type" yaml:"metric_type"`
+	CaptureTags bool              `mapstructure:"capture_tags" json:"capture_tags" yaml:"capture_tags"`
 }
 
 // MetricMapper contains mappings and cache instance
@@ -61,15 +110,22 @@ type MappingProfile struct {
 
 // MetricMapping represent one mapping rule
 type MetricMapping struct {
-	name  string
-	tags  map[string]string
-	regex *regexp.Regexp
+	name        string
+	tags        map[string]string
+	regex       *regexp.Regexp
+	action      string
+	metricType  MetricType
+	captureTags bool
 }
 
 // MapResult represent the outcome of the mapping
 type MapResult struct {
-	Name    string
-	Tags    []string
+	Name string
+	Tags []string
+	// MetricType is the type the metric is mapped to
+	MetricType MetricType
+	// Drop is true when the metric must be dropped
+	Drop    bool
 	matched bool
 }
 
@@ -96,9 +152,20 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			if matchType != matchTypeWildcard && matchType != matchTypeRegex {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid match type, must be `wildcard` or `regex`", profile.Name, i)
 			}
-			if currentMapping.Name == "" {
+			action := currentMapping.Action
+			if action == "" {
+				action = ActionMap
+			}
+			if action != ActionMap && action != ActionDrop && action != ActionKeep && action != ActionContinue {
+				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid action, must be `map`, `drop`, `keep` or `continue`", profile.Name, i)
+			}
+			if currentMapping.Name == "" && action == ActionMap {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: name is required", profile.Name, i)
 			}
+			metricType, ok := parseMetricType(currentMapping.MetricType)
+			if !ok {
+				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid metric type, must be one of %s", profile.Name, i, strings.Join(metricTypeNames, ", "))
+			}
 			if currentMapping.Match == "" {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: match is required", profile.Name, i)
 			}
@@ -106,7 +173,14 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			if err != nil {
 				return nil, err
 			}
-			profile.Mappings = append(profile.Mappings, &MetricMapping{name: currentMapping.Name, tags: currentMapping.Tags, regex: regex})
+			profile.Mappings = append(profile.Mappings, &MetricMapping{
+				name:        currentMapping.Name,
+				tags:        currentMapping.Tags,
+				regex:       regex,
+				action:      action,
+				metricType:  metricType,
+				captureTags: currentMapping.CaptureTags,
+			})
 		}
 		profiles = append(profiles, profile)
 	}
@@ -127,6 +201,11 @@ func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
 		}
 		matchRe = strings.Replace(matchRe, ".", "\\.", -1)
 		matchRe = strings.Replace(matchRe, "*", "([^.]*)", -1)
+		// named segments can be used like named groups of regexes
+		matchRe = namedWildcardSegment.ReplaceAllString(matchRe, "(?P<$1>[^.]*)")
+		if strings.ContainsAny(matchRe, "{}") {
+			return nil, fmt.Errorf("invalid wildcard match pattern `%s`, segment names must be enclosed in `{}` and only contain letters, digits and `_`", matchRe)
+		}
 	}
 	regex, err := regexp.Compile("^" + matchRe + "$")
 	if err != nil {
@@ -148,32 +227,57 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 			}
 			return nil
 		}
-		for _, mapping := range profile.Mappings {
-			matches := mapping.regex.FindStringSubmatchIndex(metricName)
-			if len(matches) == 0 {
-				continue
-			}
-
-			name := string(mapping.regex.ExpandString(
-				[]byte{},
-				mapping.name,
-				metricName,
-				matches,
-			))
-
-			tags := make([]string, 0, len(mapping.tags))
-			for tagKey, tagValueExpr := range mapping.tags {
-				tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
-				tags = append(tags, tagKey+":"+tagValue)
-			}
-
-			mapResult := &MapResult{Name: name, matched: true, Tags: tags}
-			m.cache.add(metricName, mapResult)
+		mapResult := profile.mapMetric(metricName)
+		m.cache.add(metricName, mapResult)
+		if mapResult.matched {
 			return mapResult
 		}
-		mapResult := &MapResult{matched: false}
-		m.cache.add(metricName, mapResult)
 		return nil
 	}
 	return nil
 }
+
+// mapMetric applies the mappings of the profile to the metric name, until a
+// mapping matching the name doesn't have the continue action.
+func (p *MappingProfile) mapMetric(metricName string) *MapResult {
+	result := &MapResult{Name: metricName, Tags: []string{}}
+	for _, mapping := range p.Mappings {
+		matches := mapping.regex.FindStringSubmatchIndex(metricName)
+		if len(matches) == 0 {
+			continue
+		}
+
+		switch mapping.action {
+		case ActionDrop:
+			return &MapResult{Drop: true, matched: true}
+		case ActionKeep:
+			return result
+		}
+
+		if mapping.name != "" {
+			result.Name = string(mapping.regex.ExpandString([]byte{}, mapping.name, metricName, matches))
+		}
+		for tagKey, tagValueExpr := range mapping.tags {
+			tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
+			result.Tags = append(result.Tags, tagKey+":"+tagValue)
+		}
+		if mapping.captureTags {
+			// the named captures are added as tags, unless a tag with the same key is configured
+			for i, tagKey := range mapping.regex.SubexpNames() {
+				if _, configured := mapping.tags[tagKey]; tagKey == "" || configured || matches[2*i] < 0 {
+					continue
+				}
+				result.Tags = append(result.Tags, tagKey+":"+metricName[matches[2*i]:matches[2*i+1]])
+			}
+		}
+		if mapping.metricType != MetricTypeUnchanged {
+			result.MetricType = mapping.metricType
+		}
+		result.matched = true
+
+		if mapping.action != ActionContinue {
+			return result
+		}
+	}
+	return result
+}
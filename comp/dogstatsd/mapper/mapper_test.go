@@ -329,6 +329,102 @@ dogstatsd_mapper_profiles:
 				{Name: "foo.bar1.duration", Tags: []string{"bar:bar", "foo:foo_name"}, matched: true},
 			},
 		},
+		{
+			name: "Named wildcard segments",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.{job_type}.duration.{job_name}"
+        name: "test.job.duration"
+        capture_tags: true
+        tags:
+          job_name: "job_${job_name}"
+          status: "ok"
+`,
+			packets: []string{
+				"test.batch.duration.my_job",
+			},
+			expectedResults: []MapResult{
+				{Name: "test.job.duration", Tags: []string{"job_name:job_my_job", "job_type:batch", "status:ok"}, matched: true},
+			},
+		},
+		{
+			name: "Regex named groups",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: 'test\.(?P<shard>[0-9]+)\.(cache|db)\.hits'
+        match_type: regex
+        name: "test.$2.hits"
+        capture_tags: true
+`,
+			packets: []string{
+				"test.12.cache.hits",
+			},
+			expectedResults: []MapResult{
+				{Name: "test.cache.hits", Tags: []string{"shard:12"}, matched: true},
+			},
+		},
+		{
+			name: "Drop and keep",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.internal.important"
+        action: keep
+      - match: "test.internal.*"
+        action: drop
+      - match: "test.*.count"
+        name: "test.count"
+        metric_type: count
+        tags:
+          service: "$1"
+`,
+			packets: []string{
+				"test.internal.important",
+				"test.internal.debug",
+				"test.web.count",
+			},
+			expectedResults: []MapResult{
+				{Drop: true, matched: true},
+				{Name: "test.count", Tags: []string{"service:web"}, MetricType: MetricTypeCount, matched: true},
+			},
+		},
+		{
+			name: "Continue",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.{env}.*.*"
+        action: continue
+        capture_tags: true
+        metric_type: distribution
+      - match: "test.*.http.*"
+        name: "test.http.$2"
+        tags:
+          protocol: "http"
+      - match: "test.*.debug.*"
+        action: keep
+`,
+			packets: []string{
+				"test.prod.http.latency",
+				"test.prod.debug.latency",
+				"test.prod.grpc.latency",
+			},
+			expectedResults: []MapResult{
+				{Name: "test.http.latency", Tags: []string{"env:prod", "protocol:http"}, MetricType: MetricTypeDistribution, matched: true},
+				{Name: "test.prod.debug.latency", Tags: []string{"env:prod"}, MetricType: MetricTypeDistribution, matched: true},
+				{Name: "test.prod.grpc.latency", Tags: []string{"env:prod"}, MetricType: MetricTypeDistribution, matched: true},
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -504,6 +600,52 @@ dogstatsd_mapper_profiles:
 			},
 			expectedError: "missing prefix for profile",
 		},
+		{
+			name: "Invalid action",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration"
+        action: ignore
+`,
+			packets: []string{
+				"test.job.duration",
+			},
+			expectedError: "invalid action",
+		},
+		{
+			name: "Invalid metric type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration"
+        name: "test.job.duration"
+        metric_type: set
+`,
+			packets: []string{
+				"test.job.duration",
+			},
+			expectedError: "invalid metric type",
+		},
+		{
+			name: "Invalid named segment",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.{job-name}.duration"
+        name: "test.job.duration"
+`,
+			packets: []string{
+				"test.job.duration",
+			},
+			expectedError: "segment names must be enclosed in `{}`",
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -85,6 +85,13 @@ func (p *Assembler) flush() {
 	p.packetLength = 0
 }
 
+// Flush sends the messages assembled so far to the packets buffer
+func (p *Assembler) Flush() {
+	p.Lock()
+	p.flush()
+	p.Unlock()
+}
+
 // Close closes the packet assembler
 func (p *Assembler) Close() {
 	p.Lock()
@@ -152,6 +152,23 @@ func TestPacketBufferHasCorrectSource(t *testing.T) {
 	assert.Equal(t, UDP, packets[0].Source)
 }
 
+func TestPacketAssemblerFlush(t *testing.T) {
+	telemetryComponent := fxutil.Test[telemetry.Component](t, telemetryimpl.MockModule())
+	packetsTelemetryStore := NewTelemetryStore(nil, telemetryComponent)
+	out := make(chan Packets, 16)
+	psb := NewBuffer(1, 1*time.Hour, out, "tcp", packetsTelemetryStore)
+	pp := NewPool(sampleBatchSize, packetsTelemetryStore)
+	pb := NewAssembler(1*time.Hour, psb, NewPoolManager[Packet](pp), TCP)
+
+	pb.AddMessage([]byte("test"))
+	pb.Flush()
+
+	packets := <-out
+	assert.Equal(t, []byte("test"), packets[0].Contents)
+	assert.Equal(t, TCP, packets[0].Source)
+	assert.Equal(t, "tcp", packets[0].ListenerID)
+}
+
 func TestPacketBufferEmptySecond(t *testing.T) {
 	telemetryComponent := fxutil.Test[telemetry.Component](t, telemetryimpl.MockModule())
 	packetsTelemetryStore := NewTelemetryStore(nil, telemetryComponent)
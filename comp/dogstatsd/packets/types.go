@@ -21,6 +21,10 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// TCP listener
+	TCP
+	// Graphite listener, its packets hold Graphite plaintext messages
+	Graphite
 )
 
 // Packet represents a statsd packet ready to process,
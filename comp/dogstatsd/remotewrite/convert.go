@@ -0,0 +1,398 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	nameLabel   = "__name__"
+	bucketLabel = "le"
+
+	bucketSuffix = "_bucket"
+	totalSuffix  = "_total"
+	sumSuffix    = "_sum"
+	countSuffix  = "_count"
+
+	// cumulativeExpiry is the time after which the last value of a series
+	// which hasn't been received anymore is forgotten, as is the type of a
+	// family whose metadata hasn't been received anymore
+	cumulativeExpiry = 15 * time.Minute
+)
+
+// seriesKind is how the samples of a series are converted
+type seriesKind int
+
+const (
+	gaugeSeries seriesKind = iota
+	counterSeries
+	bucketSeries
+)
+
+// cumulative is the last value of a counter or of a histogram bucket
+type cumulative struct {
+	value    float64
+	lastSeen time.Time
+}
+
+// familyType is the type of a metric family, from the metadata of the requests
+// or guessed from the names of its series.
+type familyType struct {
+	mtype    prompb.MetricMetadata_MetricType
+	lastSeen time.Time
+}
+
+// bucket is the cumulative count of a bucket of a histogram
+type bucket struct {
+	upperBound float64
+	key        string
+	value      float64
+}
+
+// histogramPoint holds the buckets of a histogram at a timestamp
+type histogramPoint struct {
+	name      string
+	tags      []string
+	timestamp int64
+	buckets   []bucket
+}
+
+// converter converts the series of remote write requests into metric samples:
+//   - gauges, and unknown types, are sent as gauges
+//   - counters are sent as counts of their increase since the previous value
+//   - the buckets of classic histograms are sent as distributions of the
+//     increase of their counts since the previous values
+//
+// The types of the series are taken from the metadata of the requests. As
+// Prometheus only sends them periodically, they are kept, and the types of the
+// series without metadata are guessed from their name: `_total` for counters,
+// `_bucket` with a `le` label for histograms, whose `_sum` and `_count` are
+// counters.
+//
+// Safe for concurrent usage.
+type converter struct {
+	namespace     string
+	labelsToTags  map[string]string
+	excludeLabels map[string]struct{}
+	hostname      string
+
+	mu          sync.Mutex
+	familyTypes map[string]*familyType
+	cumulatives map[string]*cumulative
+	lastExpiry  time.Time
+}
+
+func newConverter(namespace string, labelsToTags map[string]string, excludeLabels []string, hostname string) *converter {
+	c := &converter{
+		namespace:     namespace,
+		labelsToTags:  labelsToTags,
+		excludeLabels: make(map[string]struct{}, len(excludeLabels)),
+		hostname:      hostname,
+		familyTypes:   map[string]*familyType{},
+		cumulatives:   map[string]*cumulative{},
+	}
+	for _, label := range excludeLabels {
+		c.excludeLabels[label] = struct{}{}
+	}
+	return c
+}
+
+// convert converts the series of the request, and calls emit with the samples
+// of each point. All the samples of the buckets of a histogram at a timestamp
+// are emitted at once.
+func (c *converter) convert(req *prompb.WriteRequest, now time.Time, emit func(...metrics.MetricSample)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, metadata := range req.Metadata {
+		c.familyTypes[metadata.MetricFamilyName] = &familyType{mtype: metadata.Type, lastSeen: now}
+	}
+	// without metadata, the families with buckets are histograms
+	for _, series := range req.Timeseries {
+		name := labelValue(series.Labels, nameLabel)
+		if !strings.HasSuffix(name, bucketSuffix) || labelValue(series.Labels, bucketLabel) == "" {
+			continue
+		}
+		family := strings.TrimSuffix(name, bucketSuffix)
+		if known, found := c.familyTypes[family]; found {
+			known.lastSeen = now
+		} else {
+			c.familyTypes[family] = &familyType{mtype: prompb.MetricMetadata_HISTOGRAM, lastSeen: now}
+		}
+	}
+
+	histograms := map[string]*histogramPoint{}
+	var histogramKeys []string
+
+	for _, series := range req.Timeseries {
+		name := labelValue(series.Labels, nameLabel)
+		if name == "" {
+			continue
+		}
+		kind := c.seriesKind(name, series.Labels)
+		key := seriesKey(series.Labels)
+		tags := c.tags(series.Labels, kind == bucketSeries)
+
+		if kind == bucketSeries {
+			upperBound, err := strconv.ParseFloat(labelValue(series.Labels, bucketLabel), 64)
+			if err != nil {
+				continue
+			}
+			family := strings.TrimSuffix(name, bucketSuffix)
+			histogramKey := seriesKey(withoutLabel(series.Labels, bucketLabel))
+			for _, sample := range series.Samples {
+				if math.IsNaN(sample.Value) {
+					// staleness marker
+					continue
+				}
+				pointKey := histogramKey + "@" + strconv.FormatInt(sample.Timestamp, 10)
+				point, found := histograms[pointKey]
+				if !found {
+					point = &histogramPoint{name: c.metricName(family), tags: tags, timestamp: sample.Timestamp}
+					histograms[pointKey] = point
+					histogramKeys = append(histogramKeys, pointKey)
+				}
+				point.buckets = append(point.buckets, bucket{upperBound: upperBound, key: key, value: sample.Value})
+			}
+			continue
+		}
+
+		for _, sample := range series.Samples {
+			if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+				continue
+			}
+			metricSample := metrics.MetricSample{
+				Name:       c.metricName(name),
+				Value:      sample.Value,
+				Mtype:      metrics.GaugeType,
+				Tags:       tags,
+				Host:       c.hostname,
+				SampleRate: 1,
+				Timestamp:  float64(sample.Timestamp) / 1000,
+				Source:     metrics.MetricSourcePrometheus,
+			}
+			if kind == counterSeries {
+				increase, ok := c.increase(key, sample.Value, now)
+				if !ok {
+					continue
+				}
+				metricSample.Value = increase
+				metricSample.Mtype = metrics.CountType
+			}
+			emit(metricSample)
+		}
+	}
+
+	for _, pointKey := range histogramKeys {
+		if samples := c.histogramSamples(histograms[pointKey], now); len(samples) > 0 {
+			emit(samples...)
+		}
+	}
+
+	c.expire(now)
+}
+
+// seriesKind returns how the samples of the series are converted.
+func (c *converter) seriesKind(name string, labels []prompb.Label) seriesKind {
+	if strings.HasSuffix(name, bucketSuffix) && labelValue(labels, bucketLabel) != "" {
+		if familyType, found := c.familyType(strings.TrimSuffix(name, bucketSuffix)); !found || familyType == prompb.MetricMetadata_HISTOGRAM {
+			return bucketSeries
+		}
+	}
+
+	if familyType, found := c.familyType(name); found {
+		if familyType == prompb.MetricMetadata_COUNTER {
+			return counterSeries
+		}
+		return gaugeSeries
+	}
+	// the families of counters are named without their suffix in OpenMetrics
+	if familyType, found := c.familyType(strings.TrimSuffix(name, totalSuffix)); found && familyType == prompb.MetricMetadata_COUNTER {
+		return counterSeries
+	}
+	// the sums and counts of histograms and summaries are counters
+	for _, suffix := range []string{sumSuffix, countSuffix} {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		if familyType, _ := c.familyType(strings.TrimSuffix(name, suffix)); familyType == prompb.MetricMetadata_HISTOGRAM || familyType == prompb.MetricMetadata_SUMMARY {
+			return counterSeries
+		}
+	}
+	if strings.HasSuffix(name, totalSuffix) {
+		return counterSeries
+	}
+	return gaugeSeries
+}
+
+// familyType returns the type of a metric family, if it's known.
+func (c *converter) familyType(family string) (prompb.MetricMetadata_MetricType, bool) {
+	familyType, found := c.familyTypes[family]
+	if !found {
+		return prompb.MetricMetadata_UNKNOWN, false
+	}
+	return familyType.mtype, true
+}
+
+// histogramSamples returns the distribution samples of the increase of the
+// buckets of a histogram, none the first time the histogram is received.
+func (c *converter) histogramSamples(point *histogramPoint, now time.Time) []metrics.MetricSample {
+	sort.Slice(point.buckets, func(i, j int) bool { return point.buckets[i].upperBound < point.buckets[j].upperBound })
+
+	increases := make([]float64, len(point.buckets))
+	complete := true
+	for i, b := range point.buckets {
+		increase, ok := c.increase(b.key, b.value, now)
+		increases[i] = increase
+		complete = complete && ok
+	}
+	if !complete {
+		return nil
+	}
+
+	var samples []metrics.MetricSample
+	previousIncrease, lowerBound := 0.0, 0.0
+	for i, b := range point.buckets {
+		// the buckets are cumulative
+		count := math.Round(increases[i] - previousIncrease)
+		previousIncrease = math.Max(previousIncrease, increases[i])
+		if count >= 1 {
+			samples = append(samples, metrics.MetricSample{
+				Name:       point.name,
+				Value:      bucketValue(lowerBound, b.upperBound, i == 0),
+				Mtype:      metrics.DistributionType,
+				Tags:       point.tags,
+				Host:       c.hostname,
+				SampleRate: 1 / count,
+				Timestamp:  float64(point.timestamp) / 1000,
+				Source:     metrics.MetricSourcePrometheus,
+			})
+		}
+		if !math.IsInf(b.upperBound, 1) {
+			lowerBound = b.upperBound
+		}
+	}
+	return samples
+}
+
+// bucketValue returns the value the observations of a bucket are counted as:
+// the middle of the bucket, its upper bound for the first bucket when it's not
+// positive, and its lower bound for the `+Inf` bucket.
+func bucketValue(lowerBound, upperBound float64, first bool) float64 {
+	switch {
+	case math.IsInf(upperBound, 1):
+		return lowerBound
+	case first && upperBound <= 0:
+		return upperBound
+	default:
+		return (lowerBound + upperBound) / 2
+	}
+}
+
+// increase returns the increase of a counter since its previous value, false
+// the first time the counter is received. A counter lower than its previous
+// value has been reset, its increase is its value.
+func (c *converter) increase(key string, value float64, now time.Time) (float64, bool) {
+	previous, found := c.cumulatives[key]
+	if !found {
+		c.cumulatives[key] = &cumulative{value: value, lastSeen: now}
+		return 0, false
+	}
+	increase := value - previous.value
+	if value < previous.value {
+		increase = value
+	}
+	previous.value = value
+	previous.lastSeen = now
+	return increase, true
+}
+
+// expire forgets the last values of the series, and the types of the families,
+// which haven't been received for a while.
+func (c *converter) expire(now time.Time) {
+	if now.Sub(c.lastExpiry) < cumulativeExpiry/2 {
+		return
+	}
+	for key, cumulative := range c.cumulatives {
+		if now.Sub(cumulative.lastSeen) > cumulativeExpiry {
+			delete(c.cumulatives, key)
+		}
+	}
+	for family, familyType := range c.familyTypes {
+		if now.Sub(familyType.lastSeen) > cumulativeExpiry {
+			delete(c.familyTypes, family)
+		}
+	}
+	c.lastExpiry = now
+}
+
+func (c *converter) metricName(name string) string {
+	if c.namespace == "" {
+		return name
+	}
+	return c.namespace + "." + name
+}
+
+// tags returns the tags of the labels of a series, with the names of the
+// labels mapped to tag keys.
+func (c *converter) tags(labels []prompb.Label, bucket bool) []string {
+	tags := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label.Name == nameLabel || (bucket && label.Name == bucketLabel) {
+			continue
+		}
+		if _, excluded := c.excludeLabels[label.Name]; excluded {
+			continue
+		}
+		key := label.Name
+		if tagKey, found := c.labelsToTags[label.Name]; found {
+			key = tagKey
+		}
+		tags = append(tags, key+":"+label.Value)
+	}
+	return tags
+}
+
+func labelValue(labels []prompb.Label, name string) string {
+	for _, label := range labels {
+		if label.Name == name {
+			return label.Value
+		}
+	}
+	return ""
+}
+
+func withoutLabel(labels []prompb.Label, name string) []prompb.Label {
+	filtered := make([]prompb.Label, 0, len(labels))
+	for _, label := range labels {
+		if label.Name != name {
+			filtered = append(filtered, label)
+		}
+	}
+	return filtered
+}
+
+// seriesKey identifies a series by its labels, which Prometheus sends sorted.
+func seriesKey(labels []prompb.Label) string {
+	var builder strings.Builder
+	for _, label := range labels {
+		builder.WriteString(label.Name)
+		builder.WriteByte(0)
+		builder.WriteString(label.Value)
+		builder.WriteByte(0)
+	}
+	return builder.String()
+}
@@ -0,0 +1,177 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func series(name string, value float64, timestamp int64, labels ...string) prompb.TimeSeries {
+	ts := prompb.TimeSeries{
+		Labels:  []prompb.Label{{Name: nameLabel, Value: name}},
+		Samples: []prompb.Sample{{Value: value, Timestamp: timestamp}},
+	}
+	for i := 0; i+1 < len(labels); i += 2 {
+		ts.Labels = append(ts.Labels, prompb.Label{Name: labels[i], Value: labels[i+1]})
+	}
+	return ts
+}
+
+func convertAll(c *converter, req *prompb.WriteRequest, now time.Time) []metrics.MetricSample {
+	var samples []metrics.MetricSample
+	c.convert(req, now, func(s ...metrics.MetricSample) {
+		samples = append(samples, s...)
+	})
+	return samples
+}
+
+func TestConvertGauges(t *testing.T) {
+	c := newConverter("prom", map[string]string{"instance": "prom_instance"}, []string{"replica"}, "my-host")
+
+	samples := convertAll(c, &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		series("node_memory_free_bytes", 1024, 1700000000500, "instance", "node1:9100", "job", "node", "replica", "a"),
+		series("up", math.NaN(), 1700000000500),
+		{Samples: []prompb.Sample{{Value: 1, Timestamp: 1700000000500}}},
+	}}, time.Now())
+
+	require.Len(t, samples, 1)
+	assert.Equal(t, metrics.MetricSample{
+		Name:       "prom.node_memory_free_bytes",
+		Value:      1024,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"prom_instance:node1:9100", "job:node"},
+		Host:       "my-host",
+		SampleRate: 1,
+		Timestamp:  1700000000.5,
+		Source:     metrics.MetricSourcePrometheus,
+	}, samples[0])
+}
+
+func TestConvertCounters(t *testing.T) {
+	c := newConverter("", nil, nil, "")
+	now := time.Now()
+
+	request := func(value float64, timestamp int64) *prompb.WriteRequest {
+		return &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+			series("http_requests_total", value, timestamp, "code", "200"),
+			// typed by the metadata
+			series("process_cpu_seconds", value, timestamp),
+		}, Metadata: []prompb.MetricMetadata{
+			{Type: prompb.MetricMetadata_COUNTER, MetricFamilyName: "process_cpu_seconds"},
+		}}
+	}
+
+	// the first values are only recorded
+	assert.Empty(t, convertAll(c, request(10, 1000), now))
+
+	samples := convertAll(c, request(25, 16000), now)
+	require.Len(t, samples, 2)
+	for _, sample := range samples {
+		assert.Equal(t, metrics.CountType, sample.Mtype)
+		assert.Equal(t, float64(15), sample.Value)
+		assert.Equal(t, float64(16), sample.Timestamp)
+	}
+	assert.Equal(t, []string{"code:200"}, samples[0].Tags)
+
+	// the counters have been reset
+	samples = convertAll(c, request(5, 31000), now)
+	require.Len(t, samples, 2)
+	assert.Equal(t, float64(5), samples[0].Value)
+}
+
+func TestConvertHistograms(t *testing.T) {
+	c := newConverter("", nil, nil, "")
+	now := time.Now()
+
+	request := func(timestamp int64, counts ...float64) *prompb.WriteRequest {
+		req := &prompb.WriteRequest{}
+		for i, le := range []string{"0.1", "0.5", "+Inf"} {
+			req.Timeseries = append(req.Timeseries, series("request_duration_seconds_bucket", counts[i], timestamp, "job", "api", "le", le))
+		}
+		req.Timeseries = append(req.Timeseries,
+			series("request_duration_seconds_count", counts[2], timestamp, "job", "api"),
+			series("request_duration_seconds_sum", 100, timestamp, "job", "api"),
+		)
+		return req
+	}
+
+	assert.Empty(t, convertAll(c, request(10000, 1, 3, 4), now))
+
+	samples := convertAll(c, request(25000, 5, 7, 12), now)
+	require.Len(t, samples, 4)
+
+	// the sum and the count are counters
+	assert.Equal(t, "request_duration_seconds_count", samples[0].Name)
+	assert.Equal(t, metrics.CountType, samples[0].Mtype)
+	assert.Equal(t, float64(8), samples[0].Value)
+	assert.Equal(t, "request_duration_seconds_sum", samples[1].Name)
+	assert.Equal(t, metrics.CountType, samples[1].Mtype)
+	assert.Equal(t, float64(0), samples[1].Value)
+
+	// the buckets are a distribution
+	for _, sample := range samples[2:] {
+		assert.Equal(t, "request_duration_seconds", sample.Name)
+		assert.Equal(t, metrics.DistributionType, sample.Mtype)
+		assert.Equal(t, []string{"job:api"}, sample.Tags)
+		assert.Equal(t, float64(25), sample.Timestamp)
+	}
+	// 4 observations in the first bucket, none in the second, 4 over 0.5
+	assert.Equal(t, 0.05, samples[2].Value)
+	assert.Equal(t, 0.25, samples[2].SampleRate)
+	assert.Equal(t, 0.5, samples[3].Value)
+	assert.Equal(t, 0.25, samples[3].SampleRate)
+}
+
+func TestConvertHistogramWithMetadata(t *testing.T) {
+	c := newConverter("", nil, nil, "")
+	now := time.Now()
+
+	// the buckets of gauge histograms are gauges
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{series("queue_size_bucket", 3, 1000, "le", "10")},
+		Metadata:   []prompb.MetricMetadata{{Type: prompb.MetricMetadata_GAUGEHISTOGRAM, MetricFamilyName: "queue_size"}},
+	}
+	samples := convertAll(c, req, now)
+	require.Len(t, samples, 1)
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.Equal(t, []string{"le:10"}, samples[0].Tags)
+}
+
+func TestConvertExpiry(t *testing.T) {
+	c := newConverter("", nil, nil, "")
+	now := time.Now()
+
+	convertAll(c, &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{series("a_total", 1, 1000)}}, now)
+	convertAll(c, &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{series("b_total", 1, 1000)}}, now.Add(cumulativeExpiry))
+	assert.Len(t, c.cumulatives, 2)
+
+	convertAll(c, &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{series("b_total", 2, 2000)}}, now.Add(2*cumulativeExpiry))
+	assert.Len(t, c.cumulatives, 1)
+	assert.Contains(t, c.cumulatives, seriesKey([]prompb.Label{{Name: nameLabel, Value: "b_total"}}))
+}
+
+func TestConvertFamilyTypesExpiry(t *testing.T) {
+	c := newConverter("", nil, nil, "")
+	now := time.Now()
+
+	convertAll(c, &prompb.WriteRequest{Metadata: []prompb.MetricMetadata{{Type: prompb.MetricMetadata_COUNTER, MetricFamilyName: "a"}}}, now)
+	convertAll(c, &prompb.WriteRequest{Metadata: []prompb.MetricMetadata{{Type: prompb.MetricMetadata_COUNTER, MetricFamilyName: "b"}}}, now.Add(cumulativeExpiry))
+	assert.Len(t, c.familyTypes, 2)
+
+	// the types of the families whose metadata isn't received anymore are forgotten
+	convertAll(c, &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{series("c_bucket", 1, 1000, "le", "10")}}, now.Add(2*cumulativeExpiry))
+	assert.Len(t, c.familyTypes, 2)
+	assert.Contains(t, c.familyTypes, "b")
+	assert.Contains(t, c.familyTypes, "c")
+}
@@ -0,0 +1,210 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package remotewrite implements a receiver of the Prometheus remote write
+// protocol, sending the received samples through the no-aggregation pipeline.
+package remotewrite
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// WritePath is the path of the remote write endpoint
+const WritePath = "/api/v1/write"
+
+var (
+	remoteWriteExpvars       = expvar.NewMap("prometheus_remote_write")
+	remoteWriteRequests      = expvar.Int{}
+	remoteWriteRequestErrors = expvar.Int{}
+	remoteWriteSamples       = expvar.Int{}
+)
+
+func init() {
+	remoteWriteExpvars.Set("Requests", &remoteWriteRequests)
+	remoteWriteExpvars.Set("RequestErrors", &remoteWriteRequestErrors)
+	remoteWriteExpvars.Set("Samples", &remoteWriteSamples)
+}
+
+// Receiver receives the snappy compressed WriteRequest of Prometheus over
+// HTTP, and sends their samples with their timestamp through the
+// no-aggregation pipeline of the demultiplexer.
+type Receiver struct {
+	listener       net.Listener
+	server         *http.Server
+	demux          aggregator.Demultiplexer
+	converter      *converter
+	maxRequestSize int64
+}
+
+// NewReceiver returns an idle receiver listening on the port of the
+// dogstatsd_prometheus_remote_write settings.
+func NewReceiver(cfg model.Reader, demux aggregator.Demultiplexer, hostname string) (*Receiver, error) {
+	port := cfg.GetString("dogstatsd_prometheus_remote_write.port")
+	if port == listeners.RandomPortName {
+		port = "0"
+	}
+	var addr string
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		addr = fmt.Sprintf(":%s", port)
+	} else {
+		addr = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	r := &Receiver{
+		listener: listener,
+		demux:    demux,
+		converter: newConverter(
+			cfg.GetString("dogstatsd_prometheus_remote_write.namespace"),
+			cfg.GetStringMapString("dogstatsd_prometheus_remote_write.label_to_tag"),
+			cfg.GetStringSlice("dogstatsd_prometheus_remote_write.exclude_labels"),
+			hostname,
+		),
+		maxRequestSize: cfg.GetInt64("dogstatsd_prometheus_remote_write.max_request_size"),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(WritePath, r.handleWrite)
+	r.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return r, nil
+}
+
+// LocalAddr returns the local network address of the receiver.
+func (r *Receiver) LocalAddr() string {
+	return r.listener.Addr().String()
+}
+
+// Listen starts serving the requests in its own goroutine.
+func (r *Receiver) Listen() {
+	log.Infof("prometheus-remote-write: starting to listen on %s", r.listener.Addr())
+	go func() {
+		if err := r.server.Serve(r.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("prometheus-remote-write: error serving requests: %v", err)
+		}
+	}()
+}
+
+// Stop stops the receiver, waiting for the requests being handled.
+func (r *Receiver) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.server.Shutdown(ctx); err != nil {
+		log.Warnf("prometheus-remote-write: error stopping the receiver: %v", err)
+	}
+}
+
+func (r *Receiver) handleWrite(w http.ResponseWriter, req *http.Request) {
+	remoteWriteRequests.Add(1)
+	if req.Method != http.MethodPost {
+		remoteWriteRequestErrors.Add(1)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	compressed, err := io.ReadAll(io.LimitReader(req.Body, r.maxRequestSize+1))
+	if err != nil {
+		remoteWriteRequestErrors.Add(1)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(compressed)) > r.maxRequestSize {
+		remoteWriteRequestErrors.Add(1)
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	writeRequest, err := decodeWriteRequest(compressed, r.maxRequestSize)
+	if err != nil {
+		remoteWriteRequestErrors.Add(1)
+		log.Debugf("prometheus-remote-write: invalid request from %s: %v", req.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.send(writeRequest)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeWriteRequest decompresses and unmarshals a WriteRequest.
+func decodeWriteRequest(compressed []byte, maxSize int64) (*prompb.WriteRequest, error) {
+	size, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %v", err)
+	}
+	if int64(size) > maxSize {
+		return nil, fmt.Errorf("decompressed request too large: %d bytes", size)
+	}
+	decompressed, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy payload: %v", err)
+	}
+	var writeRequest prompb.WriteRequest
+	if err := writeRequest.Unmarshal(decompressed); err != nil {
+		return nil, fmt.Errorf("invalid WriteRequest: %v", err)
+	}
+	return &writeRequest, nil
+}
+
+// send converts the series of the request into metric samples, and sends them
+// in batches through the no-aggregation pipeline. The samples of a histogram
+// point are sent in the same batch, so that they end up in the same sketch.
+func (r *Receiver) send(writeRequest *prompb.WriteRequest) {
+	pool := r.demux.GetMetricSamplePool()
+	batch := pool.GetBatch()
+	count := 0
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		r.demux.SendSamplesWithoutAggregation(batch[:count])
+		remoteWriteSamples.Add(int64(count))
+		batch = pool.GetBatch()
+		count = 0
+	}
+
+	r.converter.convert(writeRequest, time.Now(), func(samples ...metrics.MetricSample) {
+		if count+len(samples) > len(batch) {
+			flush()
+		}
+		for _, sample := range samples {
+			if count == len(batch) {
+				flush()
+			}
+			batch[count] = sample
+			count++
+		}
+	})
+
+	if count == 0 {
+		pool.PutBatch(batch)
+		return
+	}
+	r.demux.SendSamplesWithoutAggregation(batch[:count])
+	remoteWriteSamples.Add(int64(count))
+}
@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package remotewrite
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// fakeDemux collects the samples sent through the no-aggregation pipeline
+type fakeDemux struct {
+	aggregator.Demultiplexer
+	pool *metrics.MetricSamplePool
+
+	mu      sync.Mutex
+	samples []metrics.MetricSample
+}
+
+func (d *fakeDemux) GetMetricSamplePool() *metrics.MetricSamplePool {
+	return d.pool
+}
+
+func (d *fakeDemux) SendSamplesWithoutAggregation(samples metrics.MetricSampleBatch) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.samples = append(d.samples, samples...)
+}
+
+func newTestReceiver(t *testing.T) (*Receiver, *fakeDemux) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("dogstatsd_prometheus_remote_write.port", listeners.RandomPortName)
+	cfg.SetWithoutSource("dogstatsd_prometheus_remote_write.namespace", "prom")
+
+	demux := &fakeDemux{pool: metrics.NewMetricSamplePool(32, false)}
+	r, err := NewReceiver(cfg, demux, "my-host")
+	require.NoError(t, err)
+	r.Listen()
+	t.Cleanup(r.Stop)
+	return r, demux
+}
+
+func post(t *testing.T, r *Receiver, body []byte) *http.Response {
+	resp, err := http.Post("http://"+r.LocalAddr()+WritePath, "application/x-protobuf", bytes.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestReceiverWrite(t *testing.T) {
+	r, demux := newTestReceiver(t)
+
+	writeRequest := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		series("temperature_celsius", 21.5, 1700000000000, "room", "kitchen"),
+		series("temperature_celsius", 19, 1700000000000, "room", "bedroom"),
+	}}
+	payload, err := writeRequest.Marshal()
+	require.NoError(t, err)
+
+	resp := post(t, r, snappy.Encode(nil, payload))
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	demux.mu.Lock()
+	defer demux.mu.Unlock()
+	require.Len(t, demux.samples, 2)
+	assert.Equal(t, "prom.temperature_celsius", demux.samples[0].Name)
+	assert.Equal(t, 21.5, demux.samples[0].Value)
+	assert.Equal(t, []string{"room:kitchen"}, demux.samples[0].Tags)
+	assert.Equal(t, "my-host", demux.samples[0].Host)
+	assert.Equal(t, float64(1700000000), demux.samples[0].Timestamp)
+	assert.Equal(t, float64(19), demux.samples[1].Value)
+}
+
+func TestReceiverInvalidRequests(t *testing.T) {
+	r, demux := newTestReceiver(t)
+
+	resp, err := http.Get("http://" + r.LocalAddr() + WritePath)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// not snappy compressed
+	resp = post(t, r, []byte("not a write request"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// not a WriteRequest
+	resp = post(t, r, snappy.Encode(nil, []byte{0xff, 0xff, 0xff}))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	demux.mu.Lock()
+	defer demux.mu.Unlock()
+	assert.Empty(t, demux.samples)
+}
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// exportedPacket is the JSON representation of a captured packet
+type exportedPacket struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Pid         int32     `json:"pid,omitempty"`
+	ContainerID string    `json:"container_id,omitempty"`
+	Messages    []string  `json:"messages"`
+}
+
+// ExportJSON writes the packets of the capture matching the filter of the
+// reader as JSON lines, one object per packet holding its timestamp, the PID
+// and container ID of its sender when known, and its messages. It returns the
+// number of exported packets. The reader is rewound, and must not be read
+// concurrently.
+func (tc *TrafficCaptureReader) ExportJSON(w io.Writer) (int, error) {
+	// the state is only available with the recent versions of the captures
+	pidMap, _, _ := tc.ReadState()
+
+	tc.Lock()
+	nano := tc.Version >= minNanoVersion
+	filter := tc.filter
+	tc.Unlock()
+
+	tc.Seek(0)
+	encoder := json.NewEncoder(w)
+	exported := 0
+	for {
+		msg, err := tc.ReadNext()
+		if err == io.EOF {
+			return exported, nil
+		} else if err != nil {
+			return exported, err
+		}
+
+		if filter != nil {
+			if msg = filter.Filter(msg); msg == nil {
+				continue
+			}
+		}
+
+		packet := exportedPacket{
+			Pid:         msg.Pid,
+			ContainerID: pidMap[msg.Pid],
+		}
+		if nano {
+			packet.Timestamp = time.Unix(0, msg.Timestamp).UTC()
+		} else {
+			packet.Timestamp = time.Unix(msg.Timestamp, 0).UTC()
+		}
+		for _, message := range strings.Split(string(msg.Payload[:msg.PayloadSize]), "\n") {
+			if message != "" {
+				packet.Messages = append(packet.Messages, message)
+			}
+		}
+
+		if err := encoder.Encode(packet); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+}
@@ -0,0 +1,115 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+var (
+	eventPrefix        = []byte("_e{")
+	serviceCheckPrefix = []byte("_sc|")
+)
+
+// TrafficFilter selects the captured packets to replay or export, by the
+// names of their metrics and by the PID of their sender.
+type TrafficFilter struct {
+	namePatterns []string
+	pids         map[int32]struct{}
+}
+
+// NewTrafficFilter returns a filter keeping the metrics whose name matches one
+// of the glob patterns (as in path.Match, `*` matching any sequence of
+// characters but `/`), sent by one of the PIDs. An empty list of patterns or
+// of PIDs doesn't filter anything.
+func NewTrafficFilter(namePatterns []string, pids []int32) (*TrafficFilter, error) {
+	for _, pattern := range namePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid metric name pattern %q: %v", pattern, err)
+		}
+	}
+
+	f := &TrafficFilter{
+		namePatterns: namePatterns,
+		pids:         make(map[int32]struct{}, len(pids)),
+	}
+	for _, pid := range pids {
+		f.pids[pid] = struct{}{}
+	}
+	return f, nil
+}
+
+// Filter returns the packet with only the messages matching the filter, nil
+// if none matches. As a packet can hold several messages, the returned packet
+// is a copy when some of them have been filtered out. Events are filtered out
+// when there are name patterns, service checks are matched by their name.
+func (f *TrafficFilter) Filter(msg *pb.UnixDogstatsdMsg) *pb.UnixDogstatsdMsg {
+	if len(f.pids) > 0 {
+		if _, found := f.pids[msg.Pid]; !found {
+			return nil
+		}
+	}
+	if len(f.namePatterns) == 0 {
+		return msg
+	}
+
+	payload := msg.Payload[:msg.PayloadSize]
+	filtered := make([]byte, 0, len(payload))
+	for _, message := range bytes.Split(payload, []byte{'\n'}) {
+		if len(message) == 0 || !f.matchName(messageName(message)) {
+			continue
+		}
+		if len(filtered) > 0 {
+			filtered = append(filtered, '\n')
+		}
+		filtered = append(filtered, message...)
+	}
+
+	if len(filtered) == 0 {
+		return nil
+	}
+	if len(filtered) == len(payload) {
+		return msg
+	}
+	return &pb.UnixDogstatsdMsg{
+		Timestamp:     msg.Timestamp,
+		PayloadSize:   int32(len(filtered)),
+		Payload:       filtered,
+		Pid:           msg.Pid,
+		AncillarySize: msg.AncillarySize,
+		Ancillary:     msg.Ancillary,
+	}
+}
+
+func (f *TrafficFilter) matchName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, pattern := range f.namePatterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// messageName returns the name of a metric or of a service check, an empty
+// name for an event.
+func messageName(message []byte) string {
+	if bytes.HasPrefix(message, eventPrefix) {
+		return ""
+	}
+	if bytes.HasPrefix(message, serviceCheckPrefix) {
+		name, _, _ := bytes.Cut(message[len(serviceCheckPrefix):], []byte{'|'})
+		return string(name)
+	}
+	name, _, _ := bytes.Cut(message, []byte{':'})
+	return string(name)
+}
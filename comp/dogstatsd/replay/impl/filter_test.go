@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+func newMsg(pid int32, payload string) *pb.UnixDogstatsdMsg {
+	return &pb.UnixDogstatsdMsg{
+		Timestamp:   1,
+		PayloadSize: int32(len(payload)),
+		Payload:     []byte(payload),
+		Pid:         pid,
+	}
+}
+
+func TestTrafficFilterNames(t *testing.T) {
+	f, err := NewTrafficFilter([]string{"app.requests.*", "db.queries"}, nil)
+	require.NoError(t, err)
+
+	msg := newMsg(42, "app.requests.count:1|c|#env:prod\ndb.queries:3|c\ndb.queries.slow:1|c")
+	filtered := f.Filter(msg)
+	require.NotNil(t, filtered)
+	assert.Equal(t, "app.requests.count:1|c|#env:prod\ndb.queries:3|c", string(filtered.Payload[:filtered.PayloadSize]))
+	assert.Equal(t, int32(42), filtered.Pid)
+	assert.Equal(t, msg.Timestamp, filtered.Timestamp)
+
+	// all the messages match
+	msg = newMsg(42, "db.queries:3|c")
+	assert.Same(t, msg, f.Filter(msg))
+
+	// service checks are matched by their name, events never
+	assert.NotNil(t, f.Filter(newMsg(42, "_sc|db.queries|0")))
+	assert.Nil(t, f.Filter(newMsg(42, "_e{10,4}:db.queries|text")))
+	assert.Nil(t, f.Filter(newMsg(42, "other.metric:1|g")))
+}
+
+func TestTrafficFilterPids(t *testing.T) {
+	f, err := NewTrafficFilter(nil, []int32{42, 43})
+	require.NoError(t, err)
+
+	msg := newMsg(42, "any.metric:1|g")
+	assert.Same(t, msg, f.Filter(msg))
+	assert.Nil(t, f.Filter(newMsg(44, "any.metric:1|g")))
+}
+
+func TestTrafficFilterInvalidPattern(t *testing.T) {
+	_, err := NewTrafficFilter([]string{"app.[requests"}, nil)
+	assert.Error(t, err)
+}
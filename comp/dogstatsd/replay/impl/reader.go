@@ -28,6 +28,8 @@ type TrafficCaptureReader struct {
 	fuse        chan struct{}
 	offset      uint32
 	mmap        bool
+	speed       float64
+	filter      *TrafficFilter
 
 	sync.Mutex
 }
@@ -50,6 +52,7 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	} else {
 		tsResolution = time.Nanosecond
 	}
+	speed, filter := tc.speed, tc.filter
 	tc.Unlock()
 
 	first := int64(0)
@@ -71,12 +74,20 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 			break
 		}
 
+		if filter != nil {
+			if msg = filter.Filter(msg); msg == nil {
+				continue
+			}
+		}
+
 		if first == 0 {
 			first = msg.Timestamp
 		}
 
-		t := time.Duration(msg.Timestamp-first) * tsResolution
-		time.Sleep(t - time.Since(start))
+		if speed > 0 {
+			t := time.Duration(float64(time.Duration(msg.Timestamp-first)*tsResolution) / speed)
+			time.Sleep(t - time.Since(start))
+		}
 
 		tc.Traffic <- msg
 
@@ -89,6 +100,24 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	}
 }
 
+// SetSpeed sets the factor by which the original pace of the capture is sped
+// up (above 1) or slowed down (below 1) by Read. A factor of 0 replays the
+// packets as fast as possible.
+func (tc *TrafficCaptureReader) SetSpeed(speed float64) {
+	tc.Lock()
+	defer tc.Unlock()
+
+	tc.speed = speed
+}
+
+// SetFilter sets the filter of the packets sent by Read, nil to send all of them.
+func (tc *TrafficCaptureReader) SetFilter(filter *TrafficFilter) {
+	tc.Lock()
+	defer tc.Unlock()
+
+	tc.filter = filter
+}
+
 // Close cleans up any resources used by the TrafficCaptureReader, should not normally
 // be called directly.
 func (tc *TrafficCaptureReader) Close() error {
@@ -6,10 +6,15 @@
 package replayimpl
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func readerTest(t *testing.T, path string, mmap bool) {
@@ -91,3 +96,57 @@ func TestSeek(t *testing.T) {
 	assert.Equal(t, cnt*i, total)
 
 }
+
+func TestReadSpeedAndFilter(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 32, false)
+	require.NoError(t, err)
+	defer tc.Close()
+
+	// nothing is sent by this PID
+	filter, err := NewTrafficFilter(nil, []int32{-1})
+	require.NoError(t, err)
+	tc.SetFilter(filter)
+	tc.SetSpeed(0)
+
+	ready := make(chan struct{})
+	go tc.Read(ready)
+	<-ready
+
+	select {
+	case <-tc.Done:
+	case msg := <-tc.Traffic:
+		assert.Fail(t, "unexpected packet", "%v", msg)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "timeout replaying the capture")
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog.zstd", 1, false)
+	require.NoError(t, err)
+	defer tc.Close()
+
+	var out bytes.Buffer
+	exported, err := tc.ExportJSON(&out)
+	require.NoError(t, err)
+
+	// all the packets are exported, one per line
+	tc.Seek(0)
+	count := 0
+	for _, err := tc.ReadNext(); err != io.EOF; _, err = tc.ReadNext() {
+		require.NoError(t, err)
+		count++
+	}
+	assert.Equal(t, count, exported)
+
+	lines := 0
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var packet exportedPacket
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &packet))
+		assert.False(t, packet.Timestamp.IsZero())
+		assert.NotEmpty(t, packet.Messages)
+		lines++
+	}
+	assert.Equal(t, exported, lines)
+}
@@ -31,6 +31,7 @@ type enrichConfig struct {
 	metricPrefix              string
 	metricPrefixBlacklist     []string
 	metricBlocklist           blocklist
+	tagRules                  tagRules
 	defaultHostname           string
 	entityIDPrecedenceEnabled bool
 	serverlessMode            bool
@@ -126,6 +127,10 @@ func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSam
 		return []metrics.MetricSample{}
 	}
 
+	if len(conf.tagRules) > 0 {
+		tags = conf.tagRules.apply(metricName, tags)
+	}
+
 	if conf.serverlessMode { // we don't want to set the host while running in serverless mode
 		hostnameFromTags = ""
 	}
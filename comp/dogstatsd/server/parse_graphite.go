@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// isGraphitePacket returns whether the packet has been received by the
+// Graphite listener.
+func isGraphitePacket(packet *packets.Packet) bool {
+	return packet.Source == packets.Graphite
+}
+
+// parseGraphiteMetricSample parses a Graphite plaintext message:
+//
+//	<path> <value> <timestamp>
+//
+// The path can hold tags, with the Graphite tagged series syntax:
+//
+//	<path>;<tag>=<value>;<tag>=<value>
+//
+// As with Carbon, a timestamp of -1 is replaced by the current time.
+func parseGraphiteMetricSample(message []byte, now time.Time) (dogstatsdMetricSample, error) {
+	fields := bytes.Fields(message)
+	if len(fields) != 3 {
+		return dogstatsdMetricSample{}, errors.New("invalid graphite message format, expected `<path> <value> <timestamp>`")
+	}
+
+	name, rawTags, hasTags := strings.Cut(string(fields[0]), ";")
+	if name == "" {
+		return dogstatsdMetricSample{}, errors.New("invalid graphite message format: empty metric path")
+	}
+	var tags []string
+	if hasTags {
+		for _, rawTag := range strings.Split(rawTags, ";") {
+			key, value, ok := strings.Cut(rawTag, "=")
+			if !ok || key == "" || value == "" {
+				return dogstatsdMetricSample{}, fmt.Errorf("invalid graphite tag %q, expected `<tag>=<value>`", rawTag)
+			}
+			tags = append(tags, key+":"+value)
+		}
+	}
+
+	value, err := strconv.ParseFloat(string(fields[1]), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return dogstatsdMetricSample{}, fmt.Errorf("invalid graphite value %q", fields[1])
+	}
+
+	rawTimestamp, err := strconv.ParseFloat(string(fields[2]), 64)
+	if err != nil {
+		return dogstatsdMetricSample{}, fmt.Errorf("invalid graphite timestamp %q", fields[2])
+	}
+	timestamp := now
+	if rawTimestamp != -1 {
+		if rawTimestamp < 1 {
+			return dogstatsdMetricSample{}, fmt.Errorf("invalid graphite timestamp %q", fields[2])
+		}
+		timestamp = time.Unix(int64(rawTimestamp), 0)
+	}
+
+	return dogstatsdMetricSample{
+		name:       name,
+		value:      value,
+		metricType: gaugeType,
+		sampleRate: 1,
+		tags:       tags,
+		ts:         timestamp,
+	}, nil
+}
+
+// parseGraphiteMessage parses a Graphite message into metric samples. The path
+// goes through the mapper, and the metrics are gauges with a timestamp, which
+// are sent through the no-aggregation pipeline when it's enabled.
+func (s *server) parseGraphiteMessage(metricSamples []metrics.MetricSample, message []byte, listenerID string) ([]metrics.MetricSample, error) {
+	sample, err := parseGraphiteMetricSample(message, time.Now())
+	if err != nil {
+		dogstatsdGraphiteParseErrors.Add(1)
+		s.tlmProcessedError.Inc()
+		return metricSamples, err
+	}
+
+	if !s.mapMetricSample(&sample) {
+		return metricSamples, nil
+	}
+	// Graphite metrics are always gauges
+	sample.metricType = gaugeType
+
+	metricSamples = enrichMetricSample(metricSamples, sample, packets.NoOrigin, 0, listenerID, s.enrichConfig)
+	for idx := range metricSamples {
+		metricSamples[idx].Tags = append(metricSamples[idx].Tags, s.extraTags...)
+		dogstatsdGraphitePackets.Add(1)
+		s.tlmProcessedOk.Inc()
+	}
+	return metricSamples, nil
+}
+
+// parseGraphitePacketMessage parses a message received by the Graphite
+// listener, and appends its samples to the batcher.
+func (s *server) parseGraphitePacketMessage(batcher dogstatsdBatcher, message []byte, listenerID string, samples metrics.MetricSampleBatch) metrics.MetricSampleBatch {
+	var err error
+	samples, err = s.parseGraphiteMessage(samples[0:0], message, listenerID)
+	if err != nil {
+		s.errLog("Dogstatsd: error parsing graphite message '%q': %s", message, err)
+		return samples
+	}
+	for idx := range samples {
+		s.Debug.StoreMetricStats(samples[idx])
+		batcher.appendLateSample(samples[idx])
+	}
+	return samples
+}
@@ -0,0 +1,93 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestParseGraphiteMetricSample(t *testing.T) {
+	now := time.Unix(1700000100, 0)
+
+	sample, err := parseGraphiteMetricSample([]byte("servers.web1.cpu.user 42.5 1700000000"), now)
+	require.NoError(t, err)
+	assert.Equal(t, "servers.web1.cpu.user", sample.name)
+	assert.Equal(t, 42.5, sample.value)
+	assert.Equal(t, gaugeType, sample.metricType)
+	assert.Equal(t, float64(1), sample.sampleRate)
+	assert.Empty(t, sample.tags)
+	assert.Equal(t, time.Unix(1700000000, 0), sample.ts)
+
+	sample, err = parseGraphiteMetricSample([]byte("cpu.user;host=web1;dc=us-east-1  7\t-1"), now)
+	require.NoError(t, err)
+	assert.Equal(t, "cpu.user", sample.name)
+	assert.Equal(t, float64(7), sample.value)
+	assert.Equal(t, []string{"host:web1", "dc:us-east-1"}, sample.tags)
+	assert.Equal(t, now, sample.ts)
+}
+
+func TestParseGraphiteMetricSampleErrors(t *testing.T) {
+	for _, message := range []string{
+		"",
+		"cpu.user 42",
+		"cpu.user 42 1700000000 extra",
+		";host=web1 42 1700000000",
+		"cpu.user;host 42 1700000000",
+		"cpu.user;=web1 42 1700000000",
+		"cpu.user abc 1700000000",
+		"cpu.user NaN 1700000000",
+		"cpu.user 42 abc",
+		"cpu.user 42 0",
+	} {
+		t.Run(message, func(t *testing.T) {
+			_, err := parseGraphiteMetricSample([]byte(message), time.Now())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseGraphiteMessage(t *testing.T) {
+	deps := fulfillDepsWithConfigYaml(t, `
+dogstatsd_port: __random__
+dogstatsd_tags: ["source:graphite"]
+dogstatsd_mapper_profiles:
+  - name: servers
+    prefix: 'servers.'
+    mappings:
+      - match: "servers.{host}.debug.*"
+        action: drop
+      - match: "servers.{server}.cpu.*"
+        name: "system.cpu.$2"
+        metric_type: count
+        capture_tags: true
+`)
+	s := deps.Server.(*server)
+	requireStart(t, s)
+
+	samples, err := s.parseGraphiteMessage(nil, []byte("servers.web1.cpu.user;dc=us1 42 1700000000"), "graphite-test")
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.Equal(t, "system.cpu.user", samples[0].Name)
+	assert.Equal(t, float64(42), samples[0].Value)
+	// the metric type of the mappings is ignored, Graphite metrics are gauges
+	assert.Equal(t, metrics.GaugeType, samples[0].Mtype)
+	assert.Equal(t, float64(1700000000), samples[0].Timestamp)
+	assert.Equal(t, "graphite-test", samples[0].ListenerID)
+	assert.ElementsMatch(t, []string{"dc:us1", "server:web1", "source:graphite"}, samples[0].Tags)
+
+	samples, err = s.parseGraphiteMessage(nil, []byte("servers.web1.debug.gc 1 1700000000"), "graphite-test")
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+
+	_, err = s.parseGraphiteMessage(nil, []byte("servers.web1.cpu.user"), "graphite-test")
+	assert.Error(t, err)
+}
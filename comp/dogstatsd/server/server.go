@@ -26,6 +26,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/pidmap"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/remotewrite"
 	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/def"
 	serverdebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
@@ -51,6 +52,9 @@ var (
 	dogstatsdEventPackets             = expvar.Int{}
 	dogstatsdMetricParseErrors        = expvar.Int{}
 	dogstatsdMetricPackets            = expvar.Int{}
+	dogstatsdMetricMapperDrops        = expvar.Int{}
+	dogstatsdGraphiteParseErrors      = expvar.Int{}
+	dogstatsdGraphitePackets          = expvar.Int{}
 	dogstatsdPacketsLastSec           = expvar.Int{}
 	dogstatsdUnterminatedMetricErrors = expvar.Int{}
 
@@ -178,6 +182,9 @@ func initTelemetry() {
 	dogstatsdExpvars.Set("EventPackets", &dogstatsdEventPackets)
 	dogstatsdExpvars.Set("MetricParseErrors", &dogstatsdMetricParseErrors)
 	dogstatsdExpvars.Set("MetricPackets", &dogstatsdMetricPackets)
+	dogstatsdExpvars.Set("MetricMapperDrops", &dogstatsdMetricMapperDrops)
+	dogstatsdExpvars.Set("GraphiteParseErrors", &dogstatsdGraphiteParseErrors)
+	dogstatsdExpvars.Set("GraphitePackets", &dogstatsdGraphitePackets)
 	dogstatsdExpvars.Set("UnterminatedMetricErrors", &dogstatsdUnterminatedMetricErrors)
 }
 
@@ -224,6 +231,11 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 		cfg.GetBool("statsd_metric_blocklist_match_prefix"),
 	)
 
+	tagRules, err := getDogstatsdTagRules(cfg)
+	if err != nil {
+		log.Errorf("Dogstatsd: the tag rules are ignored: %s", err)
+	}
+
 	defaultHostname, err := hostname.Get(context.TODO())
 	if err != nil {
 		log.Errorf("Dogstatsd: unable to determine default hostname: %s", err.Error())
@@ -296,6 +308,7 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 			metricPrefix:              metricPrefix,
 			metricPrefixBlacklist:     metricPrefixBlacklist,
 			metricBlocklist:           metricBlocklist,
+			tagRules:                  tagRules,
 			entityIDPrecedenceEnabled: entityIDPrecedenceEnabled,
 			defaultHostname:           defaultHostname,
 			serverlessMode:            serverless,
@@ -397,6 +410,42 @@ func (s *server) start(context.Context) error {
 		}
 	}
 
+	if s.config.GetString("dogstatsd_tcp_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init TCP listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
+	if s.config.GetString("dogstatsd_graphite_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_graphite_port") > 0 {
+		graphiteListener, err := listeners.NewGraphiteListener(packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init Graphite listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, graphiteListener)
+		}
+	}
+
+	if s.config.GetString("dogstatsd_graphite_pickle_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_graphite_pickle_port") > 0 {
+		graphitePickleListener, err := listeners.NewGraphitePickleListener(packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init Graphite pickle listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, graphitePickleListener)
+		}
+	}
+
+	if s.config.GetString("dogstatsd_prometheus_remote_write.port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_prometheus_remote_write.port") > 0 {
+		remoteWriteReceiver, err := remotewrite.NewReceiver(s.config, s.demultiplexer, s.enrichConfig.defaultHostname)
+		if err != nil {
+			s.log.Errorf("Can't init Prometheus remote write receiver: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, remoteWriteReceiver)
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry, s.telemetry)
@@ -639,6 +688,10 @@ func (s *server) parsePackets(batcher dogstatsdBatcher, parser *parser, packets
 			if s.Statistics != nil {
 				s.Statistics.StatEvent(1)
 			}
+			if isGraphitePacket(packet) {
+				samples = s.parseGraphitePacketMessage(batcher, message, packet.ListenerID, samples)
+				continue
+			}
 			messageType := findMessageType(message)
 
 			switch messageType {
@@ -750,13 +803,11 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 		return metricSamples, err
 	}
 
-	if s.mapper != nil {
-		mapResult := s.mapper.Map(sample.name)
-		if mapResult != nil {
-			s.log.Tracef("Dogstatsd mapper: metric mapped from %q to %q with tags %v", sample.name, mapResult.Name, mapResult.Tags)
-			sample.name = mapResult.Name
-			sample.tags = append(sample.tags, mapResult.Tags...)
+	if !s.mapMetricSample(&sample) {
+		if len(sample.values) > 0 {
+			s.sharedFloat64List.put(sample.values)
 		}
+		return metricSamples, nil
 	}
 
 	metricSamples = enrichMetricSample(metricSamples, sample, origin, processID, listenerID, s.enrichConfig)
@@ -779,6 +830,49 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 	return metricSamples, nil
 }
 
+// mapMetricSample applies the mapper to the name of the sample, it returns
+// false when the sample is dropped by the mapper.
+func (s *server) mapMetricSample(sample *dogstatsdMetricSample) bool {
+	if s.mapper == nil {
+		return true
+	}
+	mapResult := s.mapper.Map(sample.name)
+	if mapResult == nil {
+		return true
+	}
+	if mapResult.Drop {
+		s.log.Tracef("Dogstatsd mapper: metric %q dropped", sample.name)
+		dogstatsdMetricMapperDrops.Add(1)
+		return false
+	}
+	s.log.Tracef("Dogstatsd mapper: metric mapped from %q to %q with tags %v", sample.name, mapResult.Name, mapResult.Tags)
+	sample.name = mapResult.Name
+	sample.tags = append(sample.tags, mapResult.Tags...)
+	// the values of sets aren't numbers, they can't be mapped to another type
+	if mappedType, ok := mappedMetricType(mapResult.MetricType); ok && sample.metricType != setType {
+		sample.metricType = mappedType
+	}
+	return true
+}
+
+// mappedMetricType returns the type of the samples mapped to the given type,
+// false when the mapping keeps their type.
+func mappedMetricType(mtype mapper.MetricType) (metricType, bool) {
+	switch mtype {
+	case mapper.MetricTypeGauge:
+		return gaugeType, true
+	case mapper.MetricTypeCount:
+		return countType, true
+	case mapper.MetricTypeHistogram:
+		return histogramType, true
+	case mapper.MetricTypeDistribution:
+		return distributionType, true
+	case mapper.MetricTypeTiming:
+		return timingType, true
+	}
+	return 0, false
+}
+
 func (s *server) parseEventMessage(parser *parser, message []byte, origin string, processID uint32) (*event.Event, error) {
 	sample, err := parser.parseEvent(message)
 	if err != nil {
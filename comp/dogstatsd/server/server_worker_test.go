@@ -332,6 +332,32 @@ dogstatsd_mapper_profiles:
 			},
 			expectedCacheSize: 1000,
 		},
+		{
+			name: "Drop and metric type",
+			config: `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.debug.*"
+        action: drop
+      - match: "test.{job_name}.duration"
+        name: "test.job.duration"
+        metric_type: distribution
+        capture_tags: true
+`,
+			packets: [][]byte{
+				[]byte("test.debug.foo:666|g"),
+				[]byte("test.my_job.duration:666|g"),
+				[]byte("test.my_job.duration:666|s"),
+			},
+			expectedSamples: []*tMetricSample{
+				defaultMetric().withName("test.job.duration").withType(metrics.DistributionType).withTags([]string{"job_name:my_job"}),
+				defaultMetric().withName("test.job.duration").withType(metrics.SetType).withValue(0).withRawValue("666").withTags([]string{"job_name:my_job"}),
+			},
+			expectedCacheSize: 1000,
+		},
 		{
 			name: "Cache size",
 			config: `
@@ -367,6 +393,7 @@ dogstatsd_mapper_profiles:
 			var b batcherMock
 			s.parsePackets(&b, parser, genTestPackets(scenario.packets...), metrics.MetricSampleBatch{})
 
+			require.Len(t, b.samples, len(scenario.expectedSamples))
 			for idx, sample := range b.samples {
 				scenario.expectedSamples[idx].testMetric(t, sample)
 			}
@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+)
+
+const (
+	// tagRuleDrop removes the tags with the given keys
+	tagRuleDrop = "drop"
+	// tagRuleKeep removes the tags without the given keys
+	tagRuleKeep = "keep"
+	// tagRuleRename replaces the given keys by a new key
+	tagRuleRename = "rename"
+	// tagRuleRewrite replaces the parts of the values of the given keys
+	// matching a regex
+	tagRuleRewrite = "rewrite"
+)
+
+// tagRuleConfig is a rule of the dogstatsd_tag_rules setting.
+type tagRuleConfig struct {
+	Action         string   `mapstructure:"action" json:"action" yaml:"action"`
+	MetricPrefixes []string `mapstructure:"metric_prefixes" json:"metric_prefixes" yaml:"metric_prefixes"`
+	TagKeys        []string `mapstructure:"tag_keys" json:"tag_keys" yaml:"tag_keys"`
+	NewKey         string   `mapstructure:"new_key" json:"new_key" yaml:"new_key"`
+	Pattern        string   `mapstructure:"pattern" json:"pattern" yaml:"pattern"`
+	Replacement    string   `mapstructure:"replacement" json:"replacement" yaml:"replacement"`
+}
+
+// tagRule drops, keeps, renames or rewrites the tags of the metrics whose
+// name starts with one of its prefixes, or of all the metrics when it has no
+// prefix.
+type tagRule struct {
+	action         string
+	metricPrefixes []string
+	tagKeys        []string
+	newKey         string
+	regex          *regexp.Regexp
+	replacement    string
+}
+
+// tagRules are applied in order to the tags sent with the metrics, before the
+// contexts of the metrics are computed. The tags added by origin detection are
+// not affected.
+type tagRules []tagRule
+
+// getDogstatsdTagRules returns the rules of the dogstatsd_tag_rules setting.
+func getDogstatsdTagRules(cfg model.Reader) (tagRules, error) {
+	var configs []tagRuleConfig
+	if !cfg.IsSet("dogstatsd_tag_rules") {
+		return nil, nil
+	}
+	if err := structure.UnmarshalKey(cfg, "dogstatsd_tag_rules", &configs); err != nil {
+		return nil, fmt.Errorf("Could not parse dogstatsd_tag_rules: %v", err)
+	}
+	return newTagRules(configs)
+}
+
+func newTagRules(configs []tagRuleConfig) (tagRules, error) {
+	rules := make(tagRules, 0, len(configs))
+	for i, config := range configs {
+		if len(config.TagKeys) == 0 {
+			return nil, fmt.Errorf("tag rule %d: tag_keys is required", i)
+		}
+		rule := tagRule{
+			action:         config.Action,
+			metricPrefixes: config.MetricPrefixes,
+			tagKeys:        config.TagKeys,
+		}
+
+		switch config.Action {
+		case tagRuleDrop, tagRuleKeep:
+		case tagRuleRename:
+			if config.NewKey == "" {
+				return nil, fmt.Errorf("tag rule %d: new_key is required to rename tags", i)
+			}
+			rule.newKey = config.NewKey
+		case tagRuleRewrite:
+			if config.Pattern == "" {
+				return nil, fmt.Errorf("tag rule %d: pattern is required to rewrite tags", i)
+			}
+			regex, err := regexp.Compile(config.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("tag rule %d: invalid pattern: %v", i, err)
+			}
+			rule.regex = regex
+			rule.replacement = config.Replacement
+		default:
+			return nil, fmt.Errorf("tag rule %d: unknown action %q, expected %q, %q, %q or %q", i, config.Action, tagRuleDrop, tagRuleKeep, tagRuleRename, tagRuleRewrite)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (r *tagRule) appliesTo(metricName string) bool {
+	if len(r.metricPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range r.metricPrefixes {
+		if strings.HasPrefix(metricName, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// apply applies the rules to the tags of the given metric. The tags are
+// modified in place, and the resulting tags are returned.
+func (rules tagRules) apply(metricName string, tags []string) []string {
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.appliesTo(metricName) {
+			continue
+		}
+
+		n := 0
+		for _, tag := range tags {
+			key, value, hasValue := strings.Cut(tag, ":")
+			if !slices.Contains(rule.tagKeys, key) {
+				if rule.action != tagRuleKeep {
+					tags[n] = tag
+					n++
+				}
+				continue
+			}
+
+			switch rule.action {
+			case tagRuleDrop:
+				continue
+			case tagRuleRename:
+				tag = rule.newKey
+				if hasValue {
+					tag += ":" + value
+				}
+			case tagRuleRewrite:
+				if !hasValue {
+					break
+				}
+				if rewritten := rule.regex.ReplaceAllString(value, rule.replacement); rewritten != value {
+					tag = key + ":" + rewritten
+				}
+			}
+			tags[n] = tag
+			n++
+		}
+		tags = tags[:n]
+	}
+	return tags
+}
@@ -0,0 +1,141 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func TestTagRulesApply(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		rules    []tagRuleConfig
+		metric   string
+		tags     []string
+		expected []string
+	}{
+		{
+			name:     "drop",
+			rules:    []tagRuleConfig{{Action: tagRuleDrop, TagKeys: []string{"pod_name", "container_id"}}},
+			metric:   "http.requests",
+			tags:     []string{"env:prod", "pod_name:web-1", "container_id:abc", "container_id"},
+			expected: []string{"env:prod"},
+		},
+		{
+			name:     "drop scoped to a metric prefix",
+			rules:    []tagRuleConfig{{Action: tagRuleDrop, MetricPrefixes: []string{"jvm.", "http."}, TagKeys: []string{"pod_name"}}},
+			metric:   "db.queries",
+			tags:     []string{"env:prod", "pod_name:web-1"},
+			expected: []string{"env:prod", "pod_name:web-1"},
+		},
+		{
+			name:     "keep",
+			rules:    []tagRuleConfig{{Action: tagRuleKeep, MetricPrefixes: []string{"http."}, TagKeys: []string{"env", "status"}}},
+			metric:   "http.requests",
+			tags:     []string{"env:prod", "request_id:123", "status:200", "debug"},
+			expected: []string{"env:prod", "status:200"},
+		},
+		{
+			name:     "rename",
+			rules:    []tagRuleConfig{{Action: tagRuleRename, TagKeys: []string{"environment", "stage"}, NewKey: "env"}},
+			metric:   "http.requests",
+			tags:     []string{"environment:prod", "stage", "service:web"},
+			expected: []string{"env:prod", "env", "service:web"},
+		},
+		{
+			name:     "rewrite",
+			rules:    []tagRuleConfig{{Action: tagRuleRewrite, TagKeys: []string{"path"}, Pattern: `/users/[0-9]+`, Replacement: "/users/:id"}},
+			metric:   "http.requests",
+			tags:     []string{"path:/users/42/orders", "path:/health", "path", "other:/users/42"},
+			expected: []string{"path:/users/:id/orders", "path:/health", "path", "other:/users/42"},
+		},
+		{
+			name: "rules applied in order",
+			rules: []tagRuleConfig{
+				{Action: tagRuleRename, TagKeys: []string{"url"}, NewKey: "path"},
+				{Action: tagRuleRewrite, TagKeys: []string{"path"}, Pattern: `^/([a-z]+)/.*$`, Replacement: "/$1"},
+				{Action: tagRuleDrop, TagKeys: []string{"url"}},
+			},
+			metric:   "http.requests",
+			tags:     []string{"url:/users/42"},
+			expected: []string{"path:/users"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := newTagRules(test.rules)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, rules.apply(test.metric, test.tags))
+		})
+	}
+}
+
+func TestNewTagRulesErrors(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		rule  tagRuleConfig
+		error string
+	}{
+		{"missing tag keys", tagRuleConfig{Action: tagRuleDrop}, "tag_keys is required"},
+		{"unknown action", tagRuleConfig{Action: "remove", TagKeys: []string{"a"}}, `unknown action "remove"`},
+		{"rename without new key", tagRuleConfig{Action: tagRuleRename, TagKeys: []string{"a"}}, "new_key is required"},
+		{"rewrite without pattern", tagRuleConfig{Action: tagRuleRewrite, TagKeys: []string{"a"}}, "pattern is required"},
+		{"invalid pattern", tagRuleConfig{Action: tagRuleRewrite, TagKeys: []string{"a"}, Pattern: "("}, "invalid pattern"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newTagRules([]tagRuleConfig{test.rule})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.error)
+		})
+	}
+}
+
+func TestGetDogstatsdTagRules(t *testing.T) {
+	cfg := configmock.NewFromYAML(t, `
+dogstatsd_tag_rules:
+  - action: drop
+    metric_prefixes: ["http."]
+    tag_keys: [pod_name, container_id]
+  - action: rewrite
+    tag_keys: [path]
+    pattern: '[0-9]+'
+    replacement: 'N'
+`)
+	rules, err := getDogstatsdTagRules(cfg)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, tagRuleDrop, rules[0].action)
+	assert.Equal(t, []string{"http."}, rules[0].metricPrefixes)
+	assert.Equal(t, []string{"pod_name", "container_id"}, rules[0].tagKeys)
+	assert.Equal(t, "[0-9]+", rules[1].regex.String())
+	assert.Equal(t, "N", rules[1].replacement)
+}
+
+func TestGetDogstatsdTagRulesUnset(t *testing.T) {
+	rules, err := getDogstatsdTagRules(configmock.New(t))
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+func TestEnrichMetricSampleTagRules(t *testing.T) {
+	rules, err := newTagRules([]tagRuleConfig{{Action: tagRuleDrop, MetricPrefixes: []string{"custom."}, TagKeys: []string{"pod_name"}}})
+	require.NoError(t, err)
+	conf := enrichConfig{
+		metricPrefix: "custom.",
+		tagRules:     rules,
+	}
+
+	// the rules apply to the name of the metric with the namespace
+	sample, err := parseAndEnrichSingleMetricMessage(t, []byte("my.metric:1|g|#env:prod,pod_name:web-1,host:my-host"), conf)
+	require.NoError(t, err)
+	assert.Equal(t, "custom.my.metric", sample.Name)
+	assert.Equal(t, "my-host", sample.Host)
+	assert.Equal(t, []string{"env:prod"}, sample.Tags)
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/metadata/inventoryagent"
 	rctypes "github.com/DataDog/datadog-agent/comp/remote-config/rcclient/types"
 	logscompression "github.com/DataDog/datadog-agent/comp/serializer/logscompression/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
@@ -87,6 +88,9 @@ type dependencies struct {
 	SchedulerProviders []schedulers.Scheduler `group:"log-agent-scheduler"`
 	Tagger             tagger.Component
 	Compression        logscompression.Component
+	// SenderManager is used to send the metrics generated from logs, it's not
+	// provided when the agent has no aggregator.
+	SenderManager sender.SenderManager `optional:"true"`
 }
 
 type provides struct {
@@ -126,6 +130,7 @@ type logAgent struct {
 	schedulerProviders        []schedulers.Scheduler
 	integrationsLogs          integrations.Component
 	compression               logscompression.Component
+	senderManager             sender.SenderManager
 
 	// make sure this is done only once, when we're ready
 	prepareSchedulers sync.Once
@@ -158,6 +163,7 @@ func newLogsAgent(deps dependencies) provides {
 			integrationsLogs:   integrationsLogs,
 			tagger:             deps.Tagger,
 			compression:        deps.Compression,
+			senderManager:      deps.SenderManager,
 		}
 		deps.Lc.Append(fx.Hook{
 			OnStart: logsAgent.start,
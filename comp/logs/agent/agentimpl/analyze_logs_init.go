@@ -25,14 +25,15 @@ import (
 )
 
 // SetUpLaunchers intializes the launcher. The launchers schedule the tailers to read the log files provided by the analyze-logs command
-func SetUpLaunchers(conf configComponent.Component, sourceProvider *sources.ConfigSources) (chan *message.Message, *launchers.Launchers, pipeline.Provider, error) {
+// When traceRules is set, the messages dropped by processing rules are sent to the output channel too.
+func SetUpLaunchers(conf configComponent.Component, sourceProvider *sources.ConfigSources, traceRules bool) (chan *message.Message, *launchers.Launchers, pipeline.Provider, error) {
 	processingRules, err := config.GlobalProcessingRules(conf)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
 	diagnosticMessageReceiver := diagnostic.NewBufferedMessageReceiver(nil, nil)
-	pipelineProvider := pipeline.NewProcessorOnlyProvider(diagnosticMessageReceiver, processingRules, conf, nil)
+	pipelineProvider := pipeline.NewProcessorOnlyProvider(diagnosticMessageReceiver, processingRules, conf, nil, traceRules)
 
 	// setup the launchers
 	lnchrs := launchers.NewLaunchers(nil, pipelineProvider, nil, nil)
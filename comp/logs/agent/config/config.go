@@ -172,7 +172,9 @@ func buildTCPEndpoints(coreConfig pkgconfigmodel.Reader, logsConfig *LogsConfigK
 	}
 
 	additionals := loadTCPAdditionalEndpoints(main, logsConfig)
-	return NewEndpoints(main, additionals, useProto, false), nil
+	endpoints := NewEndpoints(main, additionals, useProto, false)
+	endpoints.OTLPEndpoints = logsConfig.getOTLPEndpoints()
+	return endpoints, nil
 }
 
 // BuildHTTPEndpoints returns the HTTP endpoints to send logs to.
@@ -266,7 +268,9 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 	batchMaxContentSize := logsConfig.batchMaxContentSize()
 	inputChanSize := logsConfig.inputChanSize()
 
-	return NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize), nil
+	endpoints := NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize)
+	endpoints.OTLPEndpoints = logsConfig.getOTLPEndpoints()
+	return endpoints, nil
 }
 
 type defaultParseAddressFunc func(string) (host string, port int, err error)
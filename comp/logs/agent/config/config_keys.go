@@ -173,6 +173,44 @@ func (l *LogsConfigKeys) getAdditionalEndpoints() ([]unmarshalEndpoint, string)
 	return endpoints, configKey
 }
 
+// getOTLPEndpoints returns the valid OTLP endpoints, defaulting their protocol to HTTP.
+func (l *LogsConfigKeys) getOTLPEndpoints() []OTLPEndpoint {
+	var endpoints []OTLPEndpoint
+	var err error
+	configKey := l.getConfigKey("otlp_endpoints")
+	raw := l.getConfig().Get(configKey)
+	if raw == nil {
+		return nil
+	}
+	if s, ok := raw.(string); ok && s != "" {
+		err = json.Unmarshal([]byte(s), &endpoints)
+	} else {
+		err = structure.UnmarshalKey(l.getConfig(), configKey, &endpoints)
+	}
+	if err != nil {
+		log.Warnf("Could not parse otlp_endpoints for logs: %v", err)
+		return nil
+	}
+
+	valid := make([]OTLPEndpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.Endpoint == "" {
+			log.Warnf("Ignoring OTLP logs endpoint without an address")
+			continue
+		}
+		switch endpoint.Protocol {
+		case "":
+			endpoint.Protocol = OTLPProtocolHTTP
+		case OTLPProtocolHTTP, OTLPProtocolGRPC:
+		default:
+			log.Warnf("Ignoring OTLP logs endpoint %s with unsupported protocol %q, expected %q or %q", endpoint.Endpoint, endpoint.Protocol, OTLPProtocolHTTP, OTLPProtocolGRPC)
+			continue
+		}
+		valid = append(valid, endpoint)
+	}
+	return valid
+}
+
 func (l *LogsConfigKeys) expectedTagsDuration() time.Duration {
 	return l.getConfig().GetDuration(l.getConfigKey("expected_tags_duration"))
 }
@@ -112,3 +112,45 @@ func TestGetAdditionalEndpoints(t *testing.T) {
 	assert.Equal(t, expected, endpoints)
 	assert.Equal(t, "logs_config.additional_endpoints", path)
 }
+
+func TestGetOTLPEndpoints(t *testing.T) {
+	configMock, l := getLogsConfigKeys(t)
+	assert.Empty(t, l.getOTLPEndpoints())
+
+	configMock.SetWithoutSource("logs_config.otlp_endpoints", `[
+		{"endpoint": "https://collector:4318", "headers": {"x-token": "secret"}},
+		{"endpoint": "collector:4317", "protocol": "grpc", "insecure": true},
+		{"endpoint": "collector:4317", "protocol": "thrift"},
+		{"protocol": "grpc"}
+	]`)
+
+	expected := []OTLPEndpoint{
+		{
+			Endpoint: "https://collector:4318",
+			Protocol: OTLPProtocolHTTP,
+			Headers:  map[string]string{"x-token": "secret"},
+		},
+		{
+			Endpoint: "collector:4317",
+			Protocol: OTLPProtocolGRPC,
+			Insecure: true,
+		},
+	}
+	assert.Equal(t, expected, l.getOTLPEndpoints())
+
+	// Test with a regular setup from the configuration file
+	configMock.UnsetForSource("logs_config.otlp_endpoints", model.SourceUnknown)
+	configMock.SetWithoutSource("logs_config.otlp_endpoints",
+		[]map[string]interface{}{
+			{
+				"endpoint": "https://collector:4318",
+				"headers":  map[string]interface{}{"x-token": "secret"},
+			},
+			{
+				"endpoint": "collector:4317",
+				"protocol": "grpc",
+				"insecure": true,
+			},
+		})
+	assert.Equal(t, expected, l.getOTLPEndpoints())
+}
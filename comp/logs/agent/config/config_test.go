@@ -521,6 +521,18 @@ func (suite *ConfigTestSuite) TestMultipleTCPEndpointsInConf() {
 	suite.compareEndpoints(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestTCPEndpointsWithOTLPEndpoints() {
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.force_use_tcp", true)
+	suite.config.SetWithoutSource("logs_config.otlp_endpoints", `[{"endpoint": "collector:4317", "protocol": "grpc"}]`)
+
+	endpoints, err := BuildEndpoints(suite.config, HTTPConnectivityFailure, "test-track", "test-proto", "test-source")
+
+	suite.Nil(err)
+	suite.False(endpoints.UseHTTP)
+	suite.Equal([]OTLPEndpoint{{Endpoint: "collector:4317", Protocol: OTLPProtocolGRPC}}, endpoints.OTLPEndpoints)
+}
+
 func (suite *ConfigTestSuite) TestEndpointsSetLogsDDUrl() {
 	suite.config.SetWithoutSource("api_key", "123")
 	suite.config.SetWithoutSource("compliance_config.endpoints.logs_dd_url", "my-proxy:443")
@@ -83,6 +83,33 @@ type unmarshalEndpoint struct {
 	Endpoint `mapstructure:",squash"`
 }
 
+// OTLPProtocol is the protocol used to send logs to an OpenTelemetry collector.
+type OTLPProtocol string
+
+const (
+	// OTLPProtocolHTTP sends logs as protobuf over HTTP.
+	OTLPProtocolHTTP OTLPProtocol = "http/protobuf"
+	// OTLPProtocolGRPC sends logs over gRPC.
+	OTLPProtocolGRPC OTLPProtocol = "grpc"
+)
+
+// OTLPEndpoint holds the settings of an OpenTelemetry collector logs are dual-shipped to. OTLP endpoints are always
+// unreliable: logs failing to be sent to them are dropped.
+type OTLPEndpoint struct {
+	// Endpoint is the URL of the collector. For gRPC, a host:port address is accepted as well.
+	Endpoint string       `mapstructure:"endpoint" json:"endpoint"`
+	Protocol OTLPProtocol `mapstructure:"protocol" json:"protocol"`
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string `mapstructure:"headers" json:"headers"`
+	// Insecure disables TLS when the gRPC endpoint has no scheme.
+	Insecure bool `mapstructure:"insecure" json:"insecure"`
+}
+
+// GetStatus returns the OTLP endpoint status
+func (e *OTLPEndpoint) GetStatus(prefix string) string {
+	return fmt.Sprintf("%sSending logs in OTLP (%s) to %s", prefix, e.Protocol, e.Endpoint)
+}
+
 // NewEndpoint returns a new Endpoint with the minimal field initialized.
 func NewEndpoint(apiKey string, apiKeyConfigPath string, host string, port int, useSSL bool) Endpoint {
 	apiKey = pkgconfigutils.SanitizeAPIKey(apiKey)
@@ -329,6 +356,7 @@ type Endpoints struct {
 	BatchMaxSize           int
 	BatchMaxContentSize    int
 	InputChanSize          int
+	OTLPEndpoints          []OTLPEndpoint
 }
 
 // GetStatus returns the endpoints status, one line per endpoint
@@ -340,6 +368,9 @@ func (e *Endpoints) GetStatus() []string {
 	for _, endpoint := range e.GetUnReliableEndpoints() {
 		result = append(result, endpoint.GetStatus("Unreliable: ", e.UseHTTP))
 	}
+	for _, endpoint := range e.OTLPEndpoints {
+		result = append(result, endpoint.GetStatus("Unreliable: "))
+	}
 	return result
 }
 
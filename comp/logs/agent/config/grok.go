@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// grokPatterns are the patterns available in `%{PATTERN}` and `%{PATTERN:field}`
+// references of extract_fields processing rules.  They are a subset of the
+// usual grok base patterns, rewritten for RE2.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?\d+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"NUMBER":            `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"BASE16NUM":         `(?:0[xX])?[0-9A-Fa-f]+`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`,
+	"IP":                `(?:(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)|(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"`,
+	"URIPATH":           `(?:/[^\s?#]*)+`,
+	"HTTPMETHOD":        `\b(?:GET|HEAD|POST|PUT|DELETE|CONNECT|OPTIONS|TRACE|PATCH)\b`,
+	"LOGLEVEL":          `(?i:trace|debug|info(?:rmation)?|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+	"TIMESTAMP_ISO8601": `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:[.,]\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`,
+}
+
+// grokReference matches `%{PATTERN}` and `%{PATTERN:field}` references.
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::(\w+))?\}`)
+
+// compileGrokPattern compiles a regular expression in which grok references are
+// replaced by the pattern they refer to, `%{PATTERN:field}` becoming a capture
+// group named after the field.
+func compileGrokPattern(pattern string) (*regexp.Regexp, error) {
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		parts := grokReference.FindStringSubmatch(ref)
+		re, found := grokPatterns[parts[1]]
+		if !found {
+			err = fmt.Errorf("unknown grok pattern %s", parts[1])
+			return ref
+		}
+		if parts[2] == "" {
+			return "(?:" + re + ")"
+		}
+		return "(?P<" + parts[2] + ">" + re + ")"
+	})
+	if err != nil {
+		return nil, err
+	}
+	return regexp.Compile(expanded)
+}
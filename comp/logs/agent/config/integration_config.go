@@ -33,6 +33,9 @@ const (
 	UTF16LE string = "utf-16-le"
 	// SHIFTJIS for Shift JIS (Japanese) encoding
 	SHIFTJIS string = "shift-jis"
+
+	// SyslogFormat for RFC 5424 and RFC 3164 syslog messages
+	SyslogFormat string = "syslog"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -44,6 +47,7 @@ type LogsConfig struct {
 
 	Port        int    // Network
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout" yaml:"idle_timeout"` // Network
+	Format      string `mapstructure:"format" json:"format" yaml:"format"`                   // Network
 	Path        string // File, Journald
 
 	Encoding     string           `mapstructure:"encoding" json:"encoding" yaml:"encoding"`                   // File
@@ -142,9 +146,11 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case TCPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("Format: %#v,"), c.Format)
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("Format: %#v,"), c.Format)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
@@ -201,6 +207,7 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Type            string            `json:"type,omitempty"`
 		Port            int               `json:"port,omitempty"`           // Network
+		Format          string            `json:"format,omitempty"`         // Network
 		Path            string            `json:"path,omitempty"`           // File, Journald
 		Encoding        string            `json:"encoding,omitempty"`       // File
 		ExcludePaths    []string          `json:"exclude_paths,omitempty"`  // File
@@ -214,6 +221,7 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	}{
 		Type:            c.Type,
 		Port:            c.Port,
+		Format:          c.Format,
 		Path:            c.Path,
 		Encoding:        c.Encoding,
 		ExcludePaths:    c.ExcludePaths,
@@ -288,6 +296,8 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case (c.Type == TCPType || c.Type == UDPType) && c.Format != "" && c.Format != SyslogFormat:
+		return fmt.Errorf("invalid format '%v' for %v source", c.Format, c.Type)
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
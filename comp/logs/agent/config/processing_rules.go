@@ -8,6 +8,8 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"strconv"
+	"time"
 )
 
 // Processing rule types
@@ -16,6 +18,17 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	ExtractFields  = "extract_fields"
+	Sample         = "sample"
+	RateLimit      = "rate_limit"
+	Dedupe         = "dedupe"
+	LogToMetric    = "log_to_metric"
+)
+
+// Types of the metrics generated by log_to_metric rules
+const (
+	MetricTypeCount        = "count"
+	MetricTypeDistribution = "distribution"
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
@@ -25,16 +38,41 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder" yaml:"replace_placeholder"`
 	Pattern            string
+	// StatusField, ServiceField and TimestampField name the fields captured by an
+	// extract_fields rule to use as the status, service and timestamp of the log.
+	StatusField    string `mapstructure:"status_field" json:"status_field" yaml:"status_field"`
+	ServiceField   string `mapstructure:"service_field" json:"service_field" yaml:"service_field"`
+	TimestampField string `mapstructure:"timestamp_field" json:"timestamp_field" yaml:"timestamp_field"`
+	// TimestampFormat is the Go layout used to parse the TimestampField, RFC 3339 by default.
+	TimestampFormat string `mapstructure:"timestamp_format" json:"timestamp_format" yaml:"timestamp_format"`
+	// SampleRate is the number of matching messages for each message kept by a sample rule.
+	SampleRate int `mapstructure:"sample_rate" json:"sample_rate" yaml:"sample_rate"`
+	// MaxMessagesPerSecond and MaxBytesPerSecond are the limits applied per log source by a rate_limit rule.
+	MaxMessagesPerSecond float64 `mapstructure:"max_messages_per_second" json:"max_messages_per_second" yaml:"max_messages_per_second"`
+	MaxBytesPerSecond    float64 `mapstructure:"max_bytes_per_second" json:"max_bytes_per_second" yaml:"max_bytes_per_second"`
+	// Window is the duration during which a dedupe rule drops the repetitions of a message.
+	Window string `mapstructure:"window" json:"window" yaml:"window"`
+	// MetricName and MetricType are the name and type, count by default, of the metric generated by a log_to_metric rule.
+	MetricName string `mapstructure:"metric_name" json:"metric_name" yaml:"metric_name"`
+	MetricType string `mapstructure:"metric_type" json:"metric_type" yaml:"metric_type"`
+	// ValueGroup is the name or number of the capture group holding the value of the metric, counts are incremented
+	// by one when it's not set.
+	ValueGroup string `mapstructure:"value_group" json:"value_group" yaml:"value_group"`
+	// DropLog drops the logs matching a log_to_metric rule once the metric is generated.
+	DropLog bool `mapstructure:"drop_log" json:"drop_log" yaml:"drop_log"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	// ValueIndex is the index of the ValueGroup capture group, -1 when not set.
+	ValueIndex int
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, optional for the sample, rate_limit and dedupe
+// types which otherwise apply to every message
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -42,8 +80,20 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, ExtractFields:
 			break
+		case LogToMetric:
+			if err := validateLogToMetricRule(rule); err != nil {
+				return err
+			}
+			continue
+		case Sample, RateLimit, Dedupe:
+			if err := validateThrottlingRule(rule); err != nil {
+				return err
+			}
+			if rule.Pattern == "" {
+				continue
+			}
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -53,6 +103,12 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
+		if rule.Type == ExtractFields {
+			if err := validateExtractFieldsRule(rule); err != nil {
+				return err
+			}
+			continue
+		}
 		_, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
@@ -61,9 +117,102 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 	return nil
 }
 
+// validateThrottlingRule validates the settings of the sample, rate_limit and
+// dedupe rules.
+func validateThrottlingRule(rule *ProcessingRule) error {
+	switch rule.Type {
+	case Sample:
+		if rule.SampleRate < 1 {
+			return fmt.Errorf("sample_rate must be a positive integer for processing rule `%s`", rule.Name)
+		}
+	case RateLimit:
+		if rule.MaxMessagesPerSecond < 0 || rule.MaxBytesPerSecond < 0 || (rule.MaxMessagesPerSecond == 0 && rule.MaxBytesPerSecond == 0) {
+			return fmt.Errorf("max_messages_per_second or max_bytes_per_second must be positive for processing rule `%s`", rule.Name)
+		}
+	case Dedupe:
+		window, err := time.ParseDuration(rule.Window)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("invalid window %s for processing rule `%s`", rule.Window, rule.Name)
+		}
+	}
+	return nil
+}
+
+// validateLogToMetricRule validates the metric generated by a log_to_metric
+// rule, and makes sure its pattern has the capture group holding the value.
+func validateLogToMetricRule(rule *ProcessingRule) error {
+	if rule.Pattern == "" {
+		return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+	}
+	if rule.MetricName == "" {
+		return fmt.Errorf("metric_name must be set for processing rule `%s`", rule.Name)
+	}
+	switch rule.MetricType {
+	case "", MetricTypeCount:
+	case MetricTypeDistribution:
+		if rule.ValueGroup == "" {
+			return fmt.Errorf("value_group must be set for the distribution of processing rule `%s`", rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric_type %s is not supported for processing rule `%s`", rule.MetricType, rule.Name)
+	}
+	if rule.ValueGroup != "" && captureGroupIndex(re, rule.ValueGroup) < 0 {
+		return fmt.Errorf("value_group %s is not captured by the pattern of processing rule `%s`", rule.ValueGroup, rule.Name)
+	}
+	return nil
+}
+
+// captureGroupIndex returns the index of the capture group of re with the
+// given name or number, or -1 if there is none.
+func captureGroupIndex(re *regexp.Regexp, group string) int {
+	if index, err := strconv.Atoi(group); err == nil {
+		if index < 1 || index > re.NumSubexp() {
+			return -1
+		}
+		return index
+	}
+	return re.SubexpIndex(group)
+}
+
+// validateExtractFieldsRule makes sure the pattern of an extract_fields rule
+// captures at least one field, including the fields it promotes.
+func validateExtractFieldsRule(rule *ProcessingRule) error {
+	re, err := compileGrokPattern(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+	}
+	captured := make(map[string]bool)
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			captured[name] = true
+		}
+	}
+	if len(captured) == 0 {
+		return fmt.Errorf("pattern %s for processing rule `%s` must capture at least one named field", rule.Pattern, rule.Name)
+	}
+	for _, field := range []string{rule.StatusField, rule.ServiceField, rule.TimestampField} {
+		if field != "" && !captured[field] {
+			return fmt.Errorf("field %s is not captured by the pattern of processing rule `%s`", field, rule.Name)
+		}
+	}
+	return nil
+}
+
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.Type == ExtractFields {
+			re, err := compileGrokPattern(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			rule.Regex = re
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
@@ -74,6 +223,17 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 		case MaskSequences:
 			rule.Regex = re
 			rule.Placeholder = []byte(rule.ReplacePlaceholder)
+		case Sample, RateLimit, Dedupe:
+			// without a pattern, the rule applies to every message
+			if rule.Pattern != "" {
+				rule.Regex = re
+			}
+		case LogToMetric:
+			rule.Regex = re
+			rule.ValueIndex = -1
+			if rule.ValueGroup != "" {
+				rule.ValueIndex = captureGroupIndex(re, rule.ValueGroup)
+			}
 		case MultiLine:
 			rule.Regex, err = regexp.Compile("^" + rule.Pattern)
 			if err != nil {
@@ -33,3 +33,65 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateThrottlingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "sample", Type: Sample, SampleRate: 10},
+		{Name: "sample", Type: Sample, SampleRate: 10, Pattern: "DEBUG"},
+		{Name: "rate_limit", Type: RateLimit, MaxMessagesPerSecond: 100},
+		{Name: "rate_limit", Type: RateLimit, MaxBytesPerSecond: 1024},
+		{Name: "dedupe", Type: Dedupe, Window: "10s"},
+	}
+	for _, rule := range validRules {
+		assert.NoError(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+
+	invalidRules := []*ProcessingRule{
+		{Name: "sample", Type: Sample},
+		{Name: "sample", Type: Sample, SampleRate: 10, Pattern: "(?=abf)"},
+		{Name: "rate_limit", Type: RateLimit},
+		{Name: "rate_limit", Type: RateLimit, MaxMessagesPerSecond: -1, MaxBytesPerSecond: 1024},
+		{Name: "dedupe", Type: Dedupe},
+		{Name: "dedupe", Type: Dedupe, Window: "0s"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateLogToMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "count", Type: LogToMetric, Pattern: "ERROR", MetricName: "app.errors"},
+		{Name: "count", Type: LogToMetric, Pattern: "ERROR", MetricName: "app.errors", MetricType: MetricTypeCount, DropLog: true},
+		{Name: "count", Type: LogToMetric, Pattern: "sent ([0-9]+) bytes", MetricName: "app.bytes", ValueGroup: "1"},
+		{Name: "distribution", Type: LogToMetric, Pattern: "took (?P<duration>[0-9.]+)ms", MetricName: "app.duration", MetricType: MetricTypeDistribution, ValueGroup: "duration"},
+	}
+	for _, rule := range validRules {
+		assert.NoError(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no pattern", Type: LogToMetric, MetricName: "app.errors"},
+		{Name: "invalid pattern", Type: LogToMetric, Pattern: "(?=abf)", MetricName: "app.errors"},
+		{Name: "no metric name", Type: LogToMetric, Pattern: "ERROR"},
+		{Name: "unknown metric type", Type: LogToMetric, Pattern: "ERROR", MetricName: "app.errors", MetricType: "gauge"},
+		{Name: "no value group", Type: LogToMetric, Pattern: "took ([0-9.]+)ms", MetricName: "app.duration", MetricType: MetricTypeDistribution},
+		{Name: "unknown group number", Type: LogToMetric, Pattern: "took ([0-9.]+)ms", MetricName: "app.duration", MetricType: MetricTypeDistribution, ValueGroup: "2"},
+		{Name: "unknown group name", Type: LogToMetric, Pattern: "took (?P<duration>[0-9.]+)ms", MetricName: "app.duration", MetricType: MetricTypeDistribution, ValueGroup: "time"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestCompileLogToMetricRules(t *testing.T) {
+	rules := []*ProcessingRule{
+		{Type: LogToMetric, Pattern: "ERROR", MetricName: "app.errors"},
+		{Type: LogToMetric, Pattern: "took (?P<duration>[0-9.]+)ms", MetricName: "app.duration", ValueGroup: "duration"},
+		{Type: LogToMetric, Pattern: "(GET|POST) took ([0-9.]+)ms", MetricName: "app.duration", ValueGroup: "2"},
+	}
+	assert.NoError(t, CompileProcessingRules(rules))
+	assert.Equal(t, -1, rules[0].ValueIndex)
+	assert.Equal(t, 1, rules[1].ValueIndex)
+	assert.Equal(t, 2, rules[2].ValueIndex)
+}
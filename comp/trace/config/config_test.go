@@ -986,6 +986,44 @@ func TestLoadEnv(t *testing.T) {
 		assert.Equal(t, true, cfg.ErrorTrackingStandalone)
 	})
 
+	env = "DD_APM_OTLP_TRACES_OUTPUT_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+		t.Setenv("DD_APM_OTLP_TRACES_OUTPUT_ENDPOINT", "https://collector:4318/v1/traces")
+		t.Setenv("DD_APM_OTLP_TRACES_OUTPUT_PROTOCOL", "http")
+		t.Setenv("DD_APM_OTLP_TRACES_OUTPUT_HEADERS", `{"x-tenant":"acme"}`)
+
+		config := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/undocumented.yaml"},
+		}))
+		cfg := config.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.OTLPTracesOutput.Enabled)
+		assert.Equal(t, "https://collector:4318/v1/traces", cfg.OTLPTracesOutput.Endpoint)
+		assert.Equal(t, "http", cfg.OTLPTracesOutput.Protocol)
+		assert.Equal(t, map[string]string{"x-tenant": "acme"}, cfg.OTLPTracesOutput.Headers)
+		assert.False(t, cfg.OTLPTracesOutput.Insecure)
+	})
+
+	env = "DD_APM_PAYLOAD_CAPTURE_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+		t.Setenv("DD_APM_PAYLOAD_CAPTURE_DIR", "/tmp/trace-capture")
+		t.Setenv("DD_APM_PAYLOAD_CAPTURE_MAX_FILES", "3")
+
+		config := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/undocumented.yaml"},
+		}))
+		cfg := config.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.PayloadCapture.Enabled)
+		assert.Equal(t, "/tmp/trace-capture", cfg.PayloadCapture.Dir)
+		assert.Equal(t, int64(64*1024*1024), cfg.PayloadCapture.MaxFileSize)
+		assert.Equal(t, 3, cfg.PayloadCapture.MaxFiles)
+	})
+
 	for _, envKey := range []string{
 		"DD_IGNORE_RESOURCE", // deprecated
 		"DD_APM_IGNORE_RESOURCES",
@@ -1047,6 +1085,85 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(t, cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_SPAN_RULES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `[{"name":"health","match":{"service":"web","meta":{"http.route":"/health"}},"actions":[{"type":"drop_span"}]}]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*traceconfig.SpanRule{{
+			Name:    "health",
+			Match:   traceconfig.SpanRuleMatch{Service: "web", Meta: map[string]string{"http.route": "/health"}},
+			Actions: []*traceconfig.SpanRuleAction{{Type: "drop_span"}},
+		}}, cfg.SpanRules)
+	})
+
+	env = "DD_APM_SPAN_RULES_FILE"
+	t.Run(env, func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "span_rules.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+- name: pii
+  actions:
+    - type: hash_tag
+      key: user.*
+`), 0644))
+		t.Setenv(env, path)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*traceconfig.SpanRule{{
+			Name:    "pii",
+			Actions: []*traceconfig.SpanRuleAction{{Type: "hash_tag", Key: "user.*"}},
+		}}, cfg.SpanRules)
+	})
+
+	env = "DD_APM_SPAN_RULES"
+	t.Run(env+" invalid", func(t *testing.T) {
+		// the invalid rules are all ignored, along with the ones of the file
+		path := filepath.Join(t.TempDir(), "span_rules.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+- name: pii
+  actions:
+    - type: hash_tag
+      key: user.*
+`), 0644))
+		t.Setenv("DD_APM_SPAN_RULES_FILE", path)
+		t.Setenv(env, `[{"name":"health","actions":[{"type":"unknown"}]}]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Empty(t, cfg.SpanRules)
+	})
+
+	env = "DD_APM_SPAN_RULES_FILE"
+	t.Run(env+" missing", func(t *testing.T) {
+		t.Setenv(env, filepath.Join(t.TempDir(), "missing.yaml"))
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Empty(t, cfg.SpanRules)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, `important1 important2:value1`)
@@ -1646,6 +1763,82 @@ func TestLoadEnv(t *testing.T) {
 		assert.Equal(t, expected, actualParsed)
 	})
 
+	env = "DD_APM_OBFUSCATION_GRAPHQL_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.enabled"))
+		assert.True(t, cfg.Obfuscation.GraphQL.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_GRAPHQL_KEEP_VALUES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `["first", "after"]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		expected := []string{"first", "after"}
+		actualConfig := pkgconfigsetup.Datadog().GetStringSlice("apm_config.obfuscation.graphql.keep_values")
+		actualParsed := cfg.Obfuscation.GraphQL.KeepValues
+		assert.Equal(t, expected, actualConfig)
+		assert.Equal(t, expected, actualParsed)
+	})
+
+	env = "DD_APM_OBFUSCATION_CASSANDRA_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cassandra.enabled"))
+		assert.True(t, cfg.Obfuscation.CQL.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_AWS_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.aws.enabled"))
+		assert.True(t, cfg.Obfuscation.AWS.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_AWS_OBFUSCATE_PATHS"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `["$.Item", "$.Key"]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		expected := []string{"$.Item", "$.Key"}
+		actualConfig := pkgconfigsetup.Datadog().GetStringSlice("apm_config.obfuscation.aws.obfuscate_paths")
+		actualParsed := cfg.Obfuscation.AWS.ObfuscatePaths
+		assert.Equal(t, expected, actualConfig)
+		assert.Equal(t, expected, actualParsed)
+	})
+
 	env = "DD_APM_OBFUSCATION_REDIS_ENABLED"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, "true")
@@ -24,7 +24,7 @@ func remote(c corecompcfg.Component, ipcAddress string, at authtoken.Component)
 		at.Get,
 		at.GetTLSClientConfig,
 		rc.WithAgent(rcClientName, version.AgentVersion),
-		rc.WithProducts(state.ProductAPMSampling, state.ProductAgentConfig),
+		rc.WithProducts(state.ProductAPMSampling, state.ProductAgentConfig, state.ProductAPMSpanRules),
 		rc.WithPollInterval(rcClientPollInterval),
 		rc.WithDirectorRootOverride(c.GetString("site"), c.GetString("remote_configuration.director_root")),
 	)
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"go.opentelemetry.io/collector/component/componenttest"
+	"gopkg.in/yaml.v3"
 
 	"github.com/DataDog/opentelemetry-mapping-go/pkg/otlp/attributes"
 
@@ -34,6 +35,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config/structure"
 	"github.com/DataDog/datadog-agent/pkg/config/utils"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/filters"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 	"github.com/DataDog/datadog-agent/pkg/util/fargate"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
@@ -313,10 +315,79 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.ProbabilisticSamplerHashSeed = uint32(core.GetInt("apm_config.probabilistic_sampler.hash_seed"))
 	}
 
+	if core.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSampling.Enabled = core.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if core.IsSet("apm_config.tail_sampling.decision_wait") {
+		c.TailSampling.DecisionWait = getDuration(core.GetInt("apm_config.tail_sampling.decision_wait"))
+	}
+	if core.IsSet("apm_config.tail_sampling.max_traces") {
+		c.TailSampling.MaxTraces = core.GetInt("apm_config.tail_sampling.max_traces")
+	}
+	if core.IsSet("apm_config.tail_sampling.max_bytes") {
+		c.TailSampling.MaxBytes = core.GetInt("apm_config.tail_sampling.max_bytes")
+	}
+	if k := "apm_config.tail_sampling.policies"; core.IsSet(k) {
+		policies := make([]*config.TailSamplingPolicy, 0)
+		if err := structure.UnmarshalKey(core, k, &policies); err != nil {
+			log.Errorf("Bad format for %q it should be a list of policies of the form '[{\"name\": \"slow\", \"type\": \"latency\", \"threshold_ms\": 500}]', error: %v", k, err)
+		} else {
+			if err := checkTailSamplingPolicies(policies); err != nil {
+				return fmt.Errorf("tail_sampling: %s", err)
+			}
+			c.TailSampling.Policies = policies
+		}
+	}
+
 	if core.IsSet("apm_config.error_tracking_standalone.enabled") {
 		c.ErrorTrackingStandalone = core.GetBool("apm_config.error_tracking_standalone.enabled")
 	}
 
+	if core.IsSet("apm_config.otlp_traces_output.enabled") {
+		c.OTLPTracesOutput.Enabled = core.GetBool("apm_config.otlp_traces_output.enabled")
+	}
+	if core.IsSet("apm_config.otlp_traces_output.endpoint") {
+		c.OTLPTracesOutput.Endpoint = core.GetString("apm_config.otlp_traces_output.endpoint")
+	}
+	if core.IsSet("apm_config.otlp_traces_output.protocol") {
+		c.OTLPTracesOutput.Protocol = core.GetString("apm_config.otlp_traces_output.protocol")
+	}
+	if core.IsSet("apm_config.otlp_traces_output.headers") {
+		c.OTLPTracesOutput.Headers = core.GetStringMapString("apm_config.otlp_traces_output.headers")
+	}
+	if core.IsSet("apm_config.otlp_traces_output.insecure") {
+		c.OTLPTracesOutput.Insecure = core.GetBool("apm_config.otlp_traces_output.insecure")
+	}
+	if out := c.OTLPTracesOutput; out.Enabled {
+		if out.Endpoint == "" {
+			return errors.New("otlp_traces_output: an endpoint is required")
+		}
+		if out.Protocol != "grpc" && out.Protocol != "http" {
+			return fmt.Errorf("otlp_traces_output: unknown protocol %q, expected grpc or http", out.Protocol)
+		}
+	}
+
+	if core.IsSet("apm_config.payload_capture.enabled") {
+		c.PayloadCapture.Enabled = core.GetBool("apm_config.payload_capture.enabled")
+	}
+	if core.IsSet("apm_config.payload_capture.dir") {
+		c.PayloadCapture.Dir = core.GetString("apm_config.payload_capture.dir")
+	}
+	if core.IsSet("apm_config.payload_capture.max_file_size") {
+		c.PayloadCapture.MaxFileSize = core.GetInt64("apm_config.payload_capture.max_file_size")
+	}
+	if core.IsSet("apm_config.payload_capture.max_files") {
+		c.PayloadCapture.MaxFiles = core.GetInt("apm_config.payload_capture.max_files")
+	}
+	if capture := c.PayloadCapture; capture.Enabled {
+		if capture.Dir == "" {
+			return errors.New("payload_capture: a dir is required")
+		}
+		if capture.MaxFileSize <= 0 || capture.MaxFiles <= 0 {
+			return errors.New("payload_capture: max_file_size and max_files must be positive")
+		}
+	}
+
 	if core.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = core.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
@@ -367,6 +438,13 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		}
 	}
 
+	// as when the agent builds them, invalid span rules are ignored
+	if rules, err := loadSpanRules(core); err != nil {
+		log.Errorf("Invalid span rules, ignoring them: %v", err)
+	} else {
+		c.SpanRules = rules
+	}
+
 	if core.IsSet("bind_host") || core.IsSet("apm_config.apm_non_local_traffic") {
 		if core.IsSet("bind_host") {
 			host := core.GetString("bind_host")
@@ -467,6 +545,11 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	c.Obfuscation.Redis.RemoveAllArgs = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.redis.remove_all_args")
 	c.Obfuscation.Valkey.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.valkey.enabled")
 	c.Obfuscation.Valkey.RemoveAllArgs = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.valkey.remove_all_args")
+	c.Obfuscation.GraphQL.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.enabled")
+	c.Obfuscation.GraphQL.KeepValues = pkgconfigsetup.Datadog().GetStringSlice("apm_config.obfuscation.graphql.keep_values")
+	c.Obfuscation.CQL.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cassandra.enabled")
+	c.Obfuscation.AWS.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.aws.enabled")
+	c.Obfuscation.AWS.ObfuscatePaths = pkgconfigsetup.Datadog().GetStringSlice("apm_config.obfuscation.aws.obfuscate_paths")
 	c.Obfuscation.CreditCards.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.credit_cards.enabled")
 	c.Obfuscation.CreditCards.Luhn = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.credit_cards.luhn")
 	c.Obfuscation.CreditCards.KeepValues = pkgconfigsetup.Datadog().GetStringSlice("apm_config.obfuscation.credit_cards.keep_values")
@@ -719,6 +802,72 @@ func compileReplaceRules(rules []*config.ReplaceRule) error {
 	return nil
 }
 
+func checkTailSamplingPolicies(policies []*config.TailSamplingPolicy) error {
+	for _, p := range policies {
+		if p.Name == "" {
+			return errors.New(`all policies must have a "name"`)
+		}
+		switch p.Type {
+		case "latency":
+			if p.ThresholdMs <= 0 {
+				return fmt.Errorf("policy %q: the latency policies must have a positive \"threshold_ms\"", p.Name)
+			}
+		case "error":
+		case "attribute":
+			if p.Key == "" {
+				return fmt.Errorf("policy %q: the attribute policies must have a \"key\"", p.Name)
+			}
+		case "rate":
+			if p.Rate < 0 || p.Rate > 1 {
+				return fmt.Errorf("policy %q: the \"rate\" must be between 0 and 1", p.Name)
+			}
+		default:
+			return fmt.Errorf("policy %q: unknown type %q, expected latency, error, attribute or rate", p.Name, p.Type)
+		}
+	}
+	return nil
+}
+
+// loadSpanRules returns the span rules of apm_config.span_rules followed by
+// the ones of apm_config.span_rules_file, or an error when any of them is
+// malformed or invalid.
+func loadSpanRules(core corecompcfg.Component) ([]*config.SpanRule, error) {
+	var rules []*config.SpanRule
+	if k := "apm_config.span_rules"; core.IsSet(k) {
+		if err := structure.UnmarshalKey(core, k, &rules); err != nil {
+			return nil, fmt.Errorf("bad format for %q it should be a list of rules of the form '[{\"name\": \"rule_name\", \"match\": {\"service\": \"pattern\"}, \"actions\": [{\"type\": \"drop_span\"}]}]', error: %v", k, err)
+		}
+	}
+	if k := "apm_config.span_rules_file"; core.IsSet(k) {
+		fileRules, err := loadSpanRulesFile(core.GetString(k))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", k, err)
+		}
+		rules = append(rules, fileRules...)
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	if _, err := filters.NewSpanRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// loadSpanRulesFile returns the span rules of a YAML file holding a list of
+// rules.
+func loadSpanRulesFile(path string) ([]*config.SpanRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []*config.SpanRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+	return rules, nil
+}
+
 // getDuration returns the duration of the provided value in seconds
 func getDuration(seconds int) time.Duration {
 	return time.Duration(seconds) * time.Second
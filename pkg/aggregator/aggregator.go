@@ -161,6 +161,8 @@ var (
 		[]string{"shard", "metric_type"}, "Count the number of dogstatsd contexts in the aggregator, by metric type")
 	tlmDogstatsdContextsBytesByMtype = telemetry.NewGauge("aggregator", "dogstatsd_contexts_bytes_by_mtype",
 		[]string{"shard", "metric_type", tags.BytesKindTelemetryKey}, "Estimated count of bytes taken by contexts in the aggregator, by metric type")
+	tlmDogstatsdContextsLimited = telemetry.NewCounter("aggregator", "dogstatsd_contexts_limited",
+		[]string{"shard", "action"}, "Count the number of new dogstatsd contexts dropped or collapsed by the context limiter")
 	tlmChecksContexts = telemetry.NewGauge("aggregator", "checks_contexts",
 		[]string{"shard"}, "Count the number of checks contexts in the check aggregator")
 	tlmChecksContextsByMtype = telemetry.NewGauge("aggregator", "checks_contexts_by_mtype",
@@ -545,6 +547,19 @@ func (agg *BufferedAggregator) getSeriesAndSketches(
 	}
 }
 
+// queryCheckMetrics returns the last flushed values of the check samplers
+// matching the query.
+func (agg *BufferedAggregator) queryCheckMetrics(query MetricsQuery) []QueriedMetric {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	var result []QueriedMetric
+	for _, checkSampler := range agg.checkSamplers {
+		result = append(result, checkSampler.queryMetrics(query)...)
+	}
+	return result
+}
+
 func updateSerieTelemetry(start time.Time, serieCount uint64, err error) {
 	state := stateOk
 	if err != nil {
@@ -993,5 +1008,6 @@ func (agg *BufferedAggregator) handleRegisterSampler(id checkid.ID) {
 		agg.tagsStore,
 		id,
 		agg.tagger,
+		pkgconfigsetup.Datadog().GetBool("aggregator_metrics_query_enabled"),
 	)
 }
@@ -34,12 +34,12 @@ type CheckSampler struct {
 }
 
 // newCheckSampler returns a newly initialized CheckSampler
-func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMetrics bool, statefulTimeout time.Duration, cache *tags.Store, id checkid.ID, tagger tagger.Component) *CheckSampler {
+func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMetrics bool, statefulTimeout time.Duration, cache *tags.Store, id checkid.ID, tagger tagger.Component, metricsQuery bool) *CheckSampler {
 	return &CheckSampler{
 		id:                     id,
 		series:                 make([]*metrics.Serie, 0),
 		sketches:               make(metrics.SketchSeriesList, 0),
-		contextResolver:        newCountBasedContextResolver(expirationCount, cache, tagger, string(id)),
+		contextResolver:        newCountBasedContextResolver(expirationCount, cache, tagger, string(id), metricsQuery),
 		metrics:                metrics.NewCheckMetrics(expireMetrics, statefulTimeout),
 		sketchMap:              make(sketchMap),
 		lastBucketValue:        make(map[ckey.ContextKey]int64),
@@ -156,6 +156,7 @@ func (cs *CheckSampler) commitSeries(timestamp float64) {
 		serie.SourceTypeName = checksSourceTypeName // this source type is required for metrics coming from the checks
 		serie.Source = context.source
 
+		cs.contextResolver.resolver.recordFlushedSerie(serie)
 		cs.series = append(cs.series, serie)
 	}
 }
@@ -170,7 +171,9 @@ func (cs *CheckSampler) commitSketches(timestamp float64) {
 		pointsByCtx[ck] = append(pointsByCtx[ck], p)
 	})
 	for ck, points := range pointsByCtx {
-		cs.sketches = append(cs.sketches, cs.newSketchSeries(ck, points))
+		ss := cs.newSketchSeries(ck, points)
+		cs.contextResolver.resolver.recordFlushedSketch(ss)
+		cs.sketches = append(cs.sketches, ss)
 	}
 }
 
@@ -205,6 +208,12 @@ func (cs *CheckSampler) flush() (metrics.Series, metrics.SketchSeriesList) {
 	return series, sketches
 }
 
+// queryMetrics returns the last flushed values of the contexts of the sampler
+// matching the query.
+func (cs *CheckSampler) queryMetrics(query MetricsQuery) []QueriedMetric {
+	return cs.contextResolver.queryMetrics(query, "check_sampler:"+string(cs.id))
+}
+
 func (cs *CheckSampler) release() {
 	cs.releaseMetrics()
 	cs.contextResolver.release()
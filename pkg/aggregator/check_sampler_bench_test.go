@@ -58,7 +58,7 @@ func benchmarkAddBucket(bucketValue int64, b *testing.B) {
 	demux := InitAndStartAgentDemultiplexer(deps.Log, sharedForwarder, &orchestratorForwarder, options, eventPlatformForwarder, haAgent, deps.Compressor, taggerComponent, "hostname")
 	defer demux.Stop(true)
 
-	checkSampler := newCheckSampler(1, true, true, 1000, tags.NewStore(true, "bench"), checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1000, tags.NewStore(true, "bench"), checkid.ID("hello:world:1234"), taggerComponent, false)
 
 	bucket := &metrics.HistogramBucket{
 		Name:       "my.histogram",
@@ -78,7 +78,7 @@ func benchmarkAddBucket(bucketValue int64, b *testing.B) {
 
 func benchmarkAddBucketWideBounds(bucketValue int64, b *testing.B) {
 	taggerComponent := taggerfxmock.SetupFakeTagger(b)
-	checkSampler := newCheckSampler(1, true, true, 1000, tags.NewStore(true, "bench"), checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1000, tags.NewStore(true, "bench"), checkid.ID("hello:world:1234"), taggerComponent, false)
 
 	bounds := []float64{0, .0005, .001, .003, .005, .007, .01, .015, .02, .025, .03, .04, .05, .06, .07, .08, .09, .1, .5, 1, 5, 10}
 	bucket := &metrics.HistogramBucket{
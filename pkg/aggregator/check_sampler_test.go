@@ -34,7 +34,7 @@ func generateContextKey(sample metrics.MetricSampleContext) ckey.ContextKey {
 
 func testCheckGaugeSampling(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, false)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -98,7 +98,7 @@ func TestCheckGaugeSampling(t *testing.T) {
 
 func testCheckRateSampling(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, false)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -152,7 +152,7 @@ func TestCheckRateSampling(t *testing.T) {
 
 func testHistogramCountSampling(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, false)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -218,7 +218,7 @@ func TestHistogramCountSampling(t *testing.T) {
 
 func testCheckHistogramBucketSampling(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, false)
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:            "my.histogram",
@@ -296,7 +296,7 @@ func TestCheckHistogramBucketSampling(t *testing.T) {
 
 func testCheckHistogramBucketDontFlushFirstValue(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, false)
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:            "my.histogram",
@@ -352,7 +352,7 @@ func TestCheckHistogramBucketDontFlushFirstValue(t *testing.T) {
 
 func testCheckHistogramBucketInfinityBucket(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, false)
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:       "my.histogram",
@@ -388,7 +388,7 @@ func TestCheckHistogramBucketInfinityBucket(t *testing.T) {
 
 func testCheckDistribution(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, false)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -0,0 +1,348 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// contextLimiterDrop drops the new contexts over a limit
+	contextLimiterDrop = "drop"
+	// contextLimiterStrip collapses the new contexts over a limit into the
+	// context they have without their high cardinality tags
+	contextLimiterStrip = "strip"
+)
+
+// contextLimiter caps the number of contexts a context resolver tracks for each
+// metric name and each origin, to protect the agent from tags with an unbounded
+// number of values (request IDs, timestamps, etc.). Once a limit is reached, new
+// contexts are dropped or, with the strip strategy, collapsed into the context
+// they have without their high cardinality tags.
+//
+// The high cardinality tags of a metric are found by recording the tags of its
+// new contexts once it has half of its limit of contexts: a tag key is high
+// cardinality when most of these contexts have a different value for it.
+//
+// Origins are identified by the key of their tagger tags, which doesn't depend
+// on the tags store sharing their entries. The contexts without tagger tags
+// aren't limited by origin.
+//
+// Not safe for concurrent usage, it's owned by the context resolver.
+type contextLimiter struct {
+	id          string
+	metricLimit int
+	originLimit int
+	strip       bool
+	metrics     map[string]*metricLimits
+	origins     map[ckey.TagsKey]*originLimits
+}
+
+// limiterCounts are the counts of contexts of a metric name or of an origin.
+type limiterCounts struct {
+	contexts  int
+	dropped   uint64
+	collapsed uint64
+}
+
+// metricLimits are the counts of contexts of a metric name, and the tags
+// recorded to find its high cardinality tags.
+type metricLimits struct {
+	limiterCounts
+	// observed is the number of new contexts whose tags are in tagValues
+	observed int
+	// tagValues holds the distinct values of each tag key of the observed contexts
+	tagValues map[string]map[string]struct{}
+}
+
+// originLimits are the counts of contexts of an origin.
+type originLimits struct {
+	limiterCounts
+	tags []string
+}
+
+// ContextLimiterStats are the counts of contexts of a metric name or an origin
+// close to, or over, its limit.
+type ContextLimiterStats struct {
+	// Name is the name of the metric, empty for origins
+	Name string `json:"name,omitempty"`
+	// Origin holds the tagger tags of the origin, empty for metrics
+	Origin []string `json:"origin,omitempty"`
+	// Contexts is the number of contexts currently tracked
+	Contexts int `json:"contexts"`
+	// Limit is the maximum number of contexts
+	Limit int `json:"limit"`
+	// Dropped is the number of new contexts dropped because of the limit
+	Dropped uint64 `json:"dropped"`
+	// Collapsed is the number of new contexts whose high cardinality tags
+	// have been removed because of the limit
+	Collapsed uint64 `json:"collapsed"`
+	// HighCardinalityTags are the tag keys removed from the collapsed contexts
+	HighCardinalityTags []string `json:"high_cardinality_tags,omitempty"`
+}
+
+// ContextLimiterReport lists the metric names and origins of DogStatsD with the
+// most contexts compared to the limits of the context limiter.
+type ContextLimiterReport struct {
+	Enabled bool                  `json:"enabled"`
+	Metrics []ContextLimiterStats `json:"metrics"`
+	Origins []ContextLimiterStats `json:"origins"`
+}
+
+// newContextLimiterFromConfig returns the context limiter of a time sampler,
+// nil when no limit is configured. The contexts are sharded between the time
+// samplers by context key, so each of them gets its share of the limits.
+func newContextLimiterFromConfig(cfg model.Reader, id string, shards int) *contextLimiter {
+	metricLimit := cfg.GetInt("dogstatsd_context_limiter.metric_limit")
+	originLimit := cfg.GetInt("dogstatsd_context_limiter.origin_limit")
+	strategy := cfg.GetString("dogstatsd_context_limiter.strategy")
+
+	if strategy != contextLimiterDrop && strategy != contextLimiterStrip {
+		log.Warnf("Unknown dogstatsd_context_limiter.strategy %q, using %q", strategy, contextLimiterDrop)
+		strategy = contextLimiterDrop
+	}
+	if shards > 1 {
+		metricLimit = divideLimit(metricLimit, shards)
+		originLimit = divideLimit(originLimit, shards)
+	}
+	return newContextLimiter(id, metricLimit, originLimit, strategy)
+}
+
+func divideLimit(limit, shards int) int {
+	if limit <= 0 {
+		return limit
+	}
+	return (limit + shards - 1) / shards
+}
+
+// newContextLimiter returns a context limiter, nil when there is no limit.
+func newContextLimiter(id string, metricLimit, originLimit int, strategy string) *contextLimiter {
+	if metricLimit <= 0 && originLimit <= 0 {
+		return nil
+	}
+	return &contextLimiter{
+		id:          id,
+		metricLimit: metricLimit,
+		originLimit: originLimit,
+		strip:       strategy == contextLimiterStrip,
+		metrics:     map[string]*metricLimits{},
+		origins:     map[ckey.TagsKey]*originLimits{},
+	}
+}
+
+// exceeded returns whether a new context of the given metric name and origin
+// is over a limit, and records its metric tags when the metric is close to its
+// limit.
+func (l *contextLimiter) exceeded(name string, origin ckey.TagsKey, metricTags []string) bool {
+	over := false
+	if m := l.metrics[name]; m != nil && l.metricLimit > 0 {
+		if m.contexts >= l.metricLimit/2 && m.observed < l.metricLimit {
+			m.observe(metricTags)
+		}
+		over = m.contexts >= l.metricLimit
+	}
+	if o := l.origins[origin]; o != nil && l.originLimit > 0 {
+		over = over || o.contexts >= l.originLimit
+	}
+	return over
+}
+
+func (m *metricLimits) observe(metricTags []string) {
+	if m.tagValues == nil {
+		m.tagValues = map[string]map[string]struct{}{}
+	}
+	for _, tag := range metricTags {
+		key, value, _ := strings.Cut(tag, ":")
+		values := m.tagValues[key]
+		if values == nil {
+			values = map[string]struct{}{}
+			m.tagValues[key] = values
+		}
+		values[value] = struct{}{}
+	}
+	m.observed++
+}
+
+// highCardinalityKeys returns the tag keys to remove from the new contexts of
+// the metric over a limit, nil when they are dropped.
+func (l *contextLimiter) highCardinalityKeys(name string) []string {
+	m := l.metrics[name]
+	if !l.strip || m == nil {
+		return nil
+	}
+	return m.highCardinalityKeys()
+}
+
+func (m *metricLimits) highCardinalityKeys() []string {
+	if m.observed < 2 {
+		return nil
+	}
+	var keys []string
+	for key, values := range m.tagValues {
+		if 2*len(values) > m.observed {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// track counts a context tracked by the context resolver.
+func (l *contextLimiter) track(c *Context) {
+	m := l.metrics[c.Name]
+	if m == nil {
+		m = &metricLimits{}
+		l.metrics[c.Name] = m
+	}
+	m.contexts++
+
+	if len(c.taggerTags.Tags()) == 0 {
+		return
+	}
+	o := l.origins[c.taggerKey]
+	if o == nil {
+		o = &originLimits{tags: c.taggerTags.Tags()}
+		l.origins[c.taggerKey] = o
+	}
+	o.contexts++
+}
+
+// remove stops counting a context removed from the context resolver. The
+// counts of the metric names and origins without contexts are reset.
+func (l *contextLimiter) remove(c *Context) {
+	if m := l.metrics[c.Name]; m != nil {
+		m.contexts--
+		if m.contexts <= 0 {
+			delete(l.metrics, c.Name)
+		}
+	}
+	if o := l.origins[c.taggerKey]; o != nil {
+		o.contexts--
+		if o.contexts <= 0 {
+			delete(l.origins, c.taggerKey)
+		}
+	}
+}
+
+// countDropped counts a new context dropped because of a limit.
+func (l *contextLimiter) countDropped(name string, origin ckey.TagsKey) {
+	if m := l.metrics[name]; m != nil {
+		m.dropped++
+	}
+	if o := l.origins[origin]; o != nil {
+		o.dropped++
+	}
+	tlmDogstatsdContextsLimited.Inc(l.id, "dropped")
+}
+
+// countCollapsed counts a new context collapsed because of a limit.
+func (l *contextLimiter) countCollapsed(name string, origin ckey.TagsKey) {
+	if m := l.metrics[name]; m != nil {
+		m.collapsed++
+	}
+	if o := l.origins[origin]; o != nil {
+		o.collapsed++
+	}
+	tlmDogstatsdContextsLimited.Inc(l.id, "collapsed")
+}
+
+// report returns the metric names and origins with at least half of their
+// limit of contexts, or with contexts over the limit.
+func (l *contextLimiter) report() ContextLimiterReport {
+	report := ContextLimiterReport{Enabled: true}
+	if l.metricLimit > 0 {
+		for name, m := range l.metrics {
+			if m.contexts >= l.metricLimit/2 || m.dropped+m.collapsed > 0 {
+				report.Metrics = append(report.Metrics, ContextLimiterStats{
+					Name:                name,
+					Contexts:            m.contexts,
+					Limit:               l.metricLimit,
+					Dropped:             m.dropped,
+					Collapsed:           m.collapsed,
+					HighCardinalityTags: l.highCardinalityKeys(name),
+				})
+			}
+		}
+	}
+	if l.originLimit > 0 {
+		for _, o := range l.origins {
+			if o.contexts >= l.originLimit/2 || o.dropped+o.collapsed > 0 {
+				report.Origins = append(report.Origins, ContextLimiterStats{
+					Origin:    o.tags,
+					Contexts:  o.contexts,
+					Limit:     l.originLimit,
+					Dropped:   o.dropped,
+					Collapsed: o.collapsed,
+				})
+			}
+		}
+	}
+	return report
+}
+
+// mergeContextLimiterReports merges the reports of the time samplers, adding
+// up the counts and limits of the same metric names and origins, and sorts
+// them from the worst offender.
+func mergeContextLimiterReports(reports []ContextLimiterReport) ContextLimiterReport {
+	merged := ContextLimiterReport{
+		Metrics: []ContextLimiterStats{},
+		Origins: []ContextLimiterStats{},
+	}
+	metrics := map[string]int{}
+	origins := map[string]int{}
+	for _, report := range reports {
+		merged.Enabled = merged.Enabled || report.Enabled
+		merged.Metrics = mergeContextLimiterStats(merged.Metrics, report.Metrics, metrics, func(s ContextLimiterStats) string { return s.Name })
+		merged.Origins = mergeContextLimiterStats(merged.Origins, report.Origins, origins, func(s ContextLimiterStats) string { return strings.Join(s.Origin, ",") })
+	}
+	sortContextLimiterStats(merged.Metrics)
+	sortContextLimiterStats(merged.Origins)
+	return merged
+}
+
+func mergeContextLimiterStats(merged, stats []ContextLimiterStats, index map[string]int, key func(ContextLimiterStats) string) []ContextLimiterStats {
+	for _, s := range stats {
+		i, ok := index[key(s)]
+		if !ok {
+			index[key(s)] = len(merged)
+			merged = append(merged, s)
+			continue
+		}
+		m := &merged[i]
+		m.Contexts += s.Contexts
+		m.Limit += s.Limit
+		m.Dropped += s.Dropped
+		m.Collapsed += s.Collapsed
+		for _, tag := range s.HighCardinalityTags {
+			if !slices.Contains(m.HighCardinalityTags, tag) {
+				m.HighCardinalityTags = append(m.HighCardinalityTags, tag)
+			}
+		}
+		sort.Strings(m.HighCardinalityTags)
+	}
+	return merged
+}
+
+// sortContextLimiterStats sorts the stats by number of limited contexts, and
+// then by number of contexts.
+func sortContextLimiterStats(stats []ContextLimiterStats) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		li, lj := stats[i].Dropped+stats[i].Collapsed, stats[j].Dropped+stats[j].Collapsed
+		if li != lj {
+			return li > lj
+		}
+		if stats[i].Contexts != stats[j].Contexts {
+			return stats[i].Contexts > stats[j].Contexts
+		}
+		return stats[i].Name+strings.Join(stats[i].Origin, ",") < stats[j].Name+strings.Join(stats[j].Origin, ",")
+	})
+}
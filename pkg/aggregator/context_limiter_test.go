@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func newLimitedContextResolver(metricLimit, originLimit int, strategy string) *timestampContextResolver {
+	cr := newTimestampContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", 2, 4, false)
+	cr.setLimiter(newContextLimiter("test", metricLimit, originLimit, strategy))
+	return cr
+}
+
+func TestContextLimiterDropByMetric(t *testing.T) {
+	cr := newLimitedContextResolver(2, 0, contextLimiterDrop)
+
+	_, ok := cr.trackContext(&mockSample{"foo", nil, []string{"id:1"}}, 0)
+	assert.True(t, ok)
+	_, ok = cr.trackContext(&mockSample{"foo", nil, []string{"id:2"}}, 0)
+	assert.True(t, ok)
+	_, ok = cr.trackContext(&mockSample{"foo", nil, []string{"id:3"}}, 0)
+	assert.False(t, ok)
+	// the tracked contexts and the other metrics are not limited
+	_, ok = cr.trackContext(&mockSample{"foo", nil, []string{"id:1"}}, 0)
+	assert.True(t, ok)
+	_, ok = cr.trackContext(&mockSample{"bar", nil, []string{"id:3"}}, 0)
+	assert.True(t, ok)
+	assert.Equal(t, 3, cr.length())
+
+	report := cr.limiterReport()
+	assert.True(t, report.Enabled)
+	assert.ElementsMatch(t, []ContextLimiterStats{
+		{Name: "foo", Contexts: 2, Limit: 2, Dropped: 1},
+		{Name: "bar", Contexts: 1, Limit: 2},
+	}, report.Metrics)
+}
+
+func TestContextLimiterStrip(t *testing.T) {
+	cr := newLimitedContextResolver(4, 0, contextLimiterStrip)
+
+	var collapsedKeys []ckey.ContextKey
+	for i := 0; i < 10; i++ {
+		key, ok := cr.trackContext(&mockSample{"foo", nil, []string{"env:prod", fmt.Sprintf("request_id:%d", i)}}, 0)
+		require.True(t, ok)
+		if i >= 4 {
+			collapsedKeys = append(collapsedKeys, key)
+		}
+	}
+
+	// the contexts over the limit are collapsed into the context without the request ID
+	assert.Equal(t, 5, cr.length())
+	for _, key := range collapsedKeys {
+		assert.Equal(t, collapsedKeys[0], key)
+	}
+	context, found := cr.resolver.contextsByKey[collapsedKeys[0]]
+	require.True(t, found)
+	assert.Equal(t, []string{"env:prod"}, context.context.metricTags.Tags())
+
+	assert.Equal(t, []ContextLimiterStats{{
+		Name:                "foo",
+		Contexts:            5,
+		Limit:               4,
+		Collapsed:           6,
+		HighCardinalityTags: []string{"request_id"},
+	}}, cr.limiterReport().Metrics)
+}
+
+func TestContextLimiterStripWithoutHighCardinalityTags(t *testing.T) {
+	cr := newLimitedContextResolver(10, 0, contextLimiterStrip)
+
+	// each tag has two values, the contexts can't be collapsed
+	for i := 0; i < 16; i++ {
+		cr.trackContext(&mockSample{"foo", nil, []string{
+			fmt.Sprintf("a:%d", i&1),
+			fmt.Sprintf("b:%d", i>>1&1),
+			fmt.Sprintf("c:%d", i>>2&1),
+			fmt.Sprintf("d:%d", i>>3&1),
+		}}, 0)
+	}
+	assert.Equal(t, 10, cr.length())
+	report := cr.limiterReport()
+	require.Len(t, report.Metrics, 1)
+	assert.Equal(t, uint64(6), report.Metrics[0].Dropped)
+	assert.Equal(t, uint64(0), report.Metrics[0].Collapsed)
+}
+
+func TestContextLimiterDropByOrigin(t *testing.T) {
+	cr := newLimitedContextResolver(0, 2, contextLimiterDrop)
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		cr.trackContext(&mockSample{name, []string{"pod_name:web"}, nil}, 0)
+		cr.trackContext(&mockSample{name, []string{"pod_name:db"}, []string{"env:prod"}}, 0)
+		// the metrics without origin are not limited
+		cr.trackContext(&mockSample{name, nil, nil}, 0)
+	}
+	assert.Equal(t, 7, cr.length())
+
+	report := cr.limiterReport()
+	assert.Empty(t, report.Metrics)
+	assert.ElementsMatch(t, []ContextLimiterStats{
+		{Origin: []string{"pod_name:web"}, Contexts: 2, Limit: 2, Dropped: 1},
+		{Origin: []string{"pod_name:db"}, Contexts: 2, Limit: 2, Dropped: 1},
+	}, report.Origins)
+}
+
+func TestContextLimiterDropByOriginWithoutTagsStore(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("aggregator_use_tags_store", false)
+	// each context gets its own entry of tagger tags when the store is disabled
+	cr := newTimestampContextResolver(nooptagger.NewComponent(), tags.NewStore(cfg.GetBool("aggregator_use_tags_store"), "test"), "test", 2, 4, false)
+	cr.setLimiter(newContextLimiter("test", 0, 2, contextLimiterDrop))
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		cr.trackContext(&mockSample{name, []string{"pod_name:web"}, nil}, 0)
+	}
+	assert.Equal(t, 2, cr.length())
+	assert.Equal(t, []ContextLimiterStats{
+		{Origin: []string{"pod_name:web"}, Contexts: 2, Limit: 2, Dropped: 1},
+	}, cr.limiterReport().Origins)
+
+	// the origin is reset once its contexts expire
+	cr.expireContexts(10)
+	assert.Empty(t, cr.resolver.limiter.origins)
+}
+
+func TestContextLimiterExpiry(t *testing.T) {
+	cr := newLimitedContextResolver(1, 1, contextLimiterDrop)
+
+	_, ok := cr.trackContext(&mockSample{"foo", []string{"pod_name:web"}, []string{"id:1"}}, 0)
+	assert.True(t, ok)
+	_, ok = cr.trackContext(&mockSample{"foo", []string{"pod_name:web"}, []string{"id:2"}}, 0)
+	assert.False(t, ok)
+
+	// the counts are reset once the contexts expire
+	cr.expireContexts(10)
+	assert.Empty(t, cr.resolver.limiter.metrics)
+	assert.Empty(t, cr.resolver.limiter.origins)
+	_, ok = cr.trackContext(&mockSample{"foo", []string{"pod_name:web"}, []string{"id:2"}}, 10)
+	assert.True(t, ok)
+}
+
+func TestMergeContextLimiterReports(t *testing.T) {
+	merged := mergeContextLimiterReports([]ContextLimiterReport{{
+		Enabled: true,
+		Metrics: []ContextLimiterStats{
+			{Name: "foo", Contexts: 3, Limit: 5},
+			{Name: "bar", Contexts: 5, Limit: 5, Collapsed: 2, HighCardinalityTags: []string{"request_id"}},
+		},
+		Origins: []ContextLimiterStats{{Origin: []string{"pod_name:web"}, Contexts: 5, Limit: 5, Dropped: 1}},
+	}, {
+		Enabled: true,
+		Metrics: []ContextLimiterStats{
+			{Name: "bar", Contexts: 5, Limit: 5, Collapsed: 1, HighCardinalityTags: []string{"session_id", "request_id"}},
+		},
+		Origins: []ContextLimiterStats{{Origin: []string{"pod_name:web"}, Contexts: 4, Limit: 5}},
+	}})
+
+	assert.Equal(t, ContextLimiterReport{
+		Enabled: true,
+		Metrics: []ContextLimiterStats{
+			{Name: "bar", Contexts: 10, Limit: 10, Collapsed: 3, HighCardinalityTags: []string{"request_id", "session_id"}},
+			{Name: "foo", Contexts: 3, Limit: 5},
+		},
+		Origins: []ContextLimiterStats{{Origin: []string{"pod_name:web"}, Contexts: 9, Limit: 10, Dropped: 1}},
+	}, merged)
+}
+
+func TestNewContextLimiterFromConfig(t *testing.T) {
+	cfg := configmock.New(t)
+	assert.Nil(t, newContextLimiterFromConfig(cfg, "0", 1))
+
+	cfg.SetWithoutSource("dogstatsd_context_limiter.metric_limit", 1000)
+	cfg.SetWithoutSource("dogstatsd_context_limiter.strategy", "unknown")
+	l := newContextLimiterFromConfig(cfg, "0", 3)
+	require.NotNil(t, l)
+	// the limits are shared between the time samplers
+	assert.Equal(t, 334, l.metricLimit)
+	assert.Equal(t, 0, l.originLimit)
+	assert.False(t, l.strip)
+
+	cfg.SetWithoutSource("dogstatsd_context_limiter.strategy", "strip")
+	assert.True(t, newContextLimiterFromConfig(cfg, "0", 1).strip)
+}
@@ -7,6 +7,8 @@ package aggregator
 
 import (
 	"io"
+	"slices"
+	"strings"
 	"unsafe"
 
 	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
@@ -25,8 +27,15 @@ type Context struct {
 	mtype      metrics.MetricType
 	taggerTags *tags.Entry
 	metricTags *tags.Entry
-	noIndex    bool
-	source     metrics.MetricSource
+	// taggerKey is the key of the tagger tags, identifying the origin of
+	// the context for the context limiter
+	taggerKey ckey.TagsKey
+	// histogramOverride is the override of the histogram settings matching
+	// the name of the context, resolved on its first histogram sample
+	histogramOverride         *histogramOverride
+	histogramOverrideResolved bool
+	noIndex                   bool
+	source                    metrics.MetricSource
 }
 
 type resolverEntry struct {
@@ -76,6 +85,11 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+	// limiter caps the number of contexts, nil when there is no limit
+	limiter *contextLimiter
+	// flushed holds the last flushed values of the contexts, nil when the
+	// metrics query is disabled
+	flushed map[ckey.ContextKey]*flushedValues
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -83,8 +97,10 @@ func (cr *contextResolver) generateContextKey(metricSampleContext metrics.Metric
 	return cr.keyGenerator.GenerateWithTags2(metricSampleContext.GetName(), metricSampleContext.GetHost(), cr.taggerBuffer, cr.metricBuffer)
 }
 
-func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *contextResolver {
-	return &contextResolver{
+// newContextResolver returns a context resolver, which records the last flushed
+// values of its contexts when metricsQuery is set.
+func newContextResolver(tagger tagger.Component, cache *tags.Store, id string, metricsQuery bool) *contextResolver {
+	cr := &contextResolver{
 		id:               id,
 		contextsByKey:    make(map[ckey.ContextKey]resolverEntry),
 		seendByMtype:     make([]bool, metrics.NumMetricTypes),
@@ -97,45 +113,103 @@ func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *
 		taggerBuffer:     tagset.NewHashingTagsAccumulator(),
 		metricBuffer:     tagset.NewHashingTagsAccumulator(),
 	}
+	if metricsQuery {
+		cr.flushed = make(map[ckey.ContextKey]*flushedValues)
+	}
+	return cr
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) ckey.ContextKey {
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// It returns false when the context is new and dropped by the context limiter.
+func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) (ckey.ContextKey, bool) {
 	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer, cr.tagger.EnrichTags) // tags here are not sorted and can contain duplicates
 	defer cr.taggerBuffer.Reset()
 	defer cr.metricBuffer.Reset()
 
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
 
-	if entry, ok := cr.contextsByKey[contextKey]; !ok {
-		mtype := metricSampleContext.GetMetricType()
-		context := &Context{
-			Name:       metricSampleContext.GetName(),
-			taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
-			metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
-			Host:       metricSampleContext.GetHost(),
-			mtype:      mtype,
-			noIndex:    metricSampleContext.IsNoIndex(),
-			source:     metricSampleContext.GetSource(),
-		}
-		cr.contextsByKey[contextKey] = resolverEntry{
-			lastSeen: timestamp,
-			context:  context,
-		}
-
-		cr.seendByMtype[mtype] = true
-		cr.countsByMtype[mtype]++
-		cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
-		cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
-	} else {
+	if entry, ok := cr.contextsByKey[contextKey]; ok {
 		// We can't assign to a field of a struct contained in map
 		cr.contextsByKey[contextKey] = resolverEntry{
 			lastSeen: timestamp,
 			context:  entry.context,
 		}
+		return contextKey, true
 	}
 
-	return contextKey
+	taggerTags := cr.tagsCache.Insert(taggerKey, cr.taggerBuffer)
+	if cr.limiter != nil && cr.limiter.exceeded(metricSampleContext.GetName(), taggerKey, cr.metricBuffer.Get()) {
+		var collapsed bool
+		if contextKey, metricKey, collapsed = cr.collapseContext(metricSampleContext); !collapsed {
+			cr.limiter.countDropped(metricSampleContext.GetName(), taggerKey)
+			taggerTags.Release()
+			return contextKey, false
+		}
+		cr.limiter.countCollapsed(metricSampleContext.GetName(), taggerKey)
+
+		if entry, ok := cr.contextsByKey[contextKey]; ok {
+			taggerTags.Release()
+			cr.contextsByKey[contextKey] = resolverEntry{
+				lastSeen: timestamp,
+				context:  entry.context,
+			}
+			return contextKey, true
+		}
+	}
+
+	mtype := metricSampleContext.GetMetricType()
+	context := &Context{
+		Name:       metricSampleContext.GetName(),
+		taggerTags: taggerTags,
+		metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
+		taggerKey:  taggerKey,
+		Host:       metricSampleContext.GetHost(),
+		mtype:      mtype,
+		noIndex:    metricSampleContext.IsNoIndex(),
+		source:     metricSampleContext.GetSource(),
+	}
+	cr.contextsByKey[contextKey] = resolverEntry{
+		lastSeen: timestamp,
+		context:  context,
+	}
+	if cr.limiter != nil {
+		cr.limiter.track(context)
+	}
+
+	cr.seendByMtype[mtype] = true
+	cr.countsByMtype[mtype]++
+	cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
+	cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
+
+	return contextKey, true
+}
+
+// collapseContext removes the high cardinality tags of the metric from the
+// metric tags of the sample, and returns the key of the resulting context. It
+// returns false when the limiter drops the new contexts of the metric, or when
+// the sample has none of its high cardinality tags.
+func (cr *contextResolver) collapseContext(metricSampleContext metrics.MetricSampleContext) (ckey.ContextKey, ckey.TagsKey, bool) {
+	keys := cr.limiter.highCardinalityKeys(metricSampleContext.GetName())
+	if len(keys) == 0 {
+		return 0, 0, false
+	}
+
+	metricTags := cr.metricBuffer.Get()
+	kept := make([]string, 0, len(metricTags))
+	for _, tag := range metricTags {
+		key, _, _ := strings.Cut(tag, ":")
+		if !slices.Contains(keys, key) {
+			kept = append(kept, tag)
+		}
+	}
+	if len(kept) == len(metricTags) {
+		return 0, 0, false
+	}
+
+	cr.metricBuffer.Reset()
+	cr.metricBuffer.Append(kept...)
+	contextKey, _, metricKey := cr.generateContextKey(metricSampleContext)
+	return contextKey, metricKey, true
 }
 
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -150,8 +224,12 @@ func (cr *contextResolver) length() int {
 func (cr *contextResolver) remove(expiredContextKey ckey.ContextKey) {
 	context := cr.contextsByKey[expiredContextKey].context
 	delete(cr.contextsByKey, expiredContextKey)
+	delete(cr.flushed, expiredContextKey)
 
 	if context != nil {
+		if cr.limiter != nil {
+			cr.limiter.remove(context)
+		}
 		cr.countsByMtype[context.mtype]--
 		cr.bytesByMtype[context.mtype] -= uint64(context.SizeInBytes())
 		cr.dataBytesByMtype[context.mtype] -= uint64(context.DataSizeInBytes())
@@ -221,19 +299,32 @@ type timestampContextResolver struct {
 	counterExpireTime int64
 }
 
-func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, contextExpireTime, counterExpireTime int64) *timestampContextResolver {
+func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, contextExpireTime, counterExpireTime int64, metricsQuery bool) *timestampContextResolver {
 	return &timestampContextResolver{
-		resolver: newContextResolver(tagger, cache, id),
+		resolver: newContextResolver(tagger, cache, id, metricsQuery),
 
 		contextExpireTime: contextExpireTime,
 		counterExpireTime: counterExpireTime,
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext, currentTimestamp)
-	return contextKey
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context,
+// it returns false when the context is dropped by the context limiter
+func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) (ckey.ContextKey, bool) {
+	return cr.resolver.trackContext(metricSampleContext, currentTimestamp)
+}
+
+// setLimiter sets the limiter capping the number of contexts of the resolver
+func (cr *timestampContextResolver) setLimiter(limiter *contextLimiter) {
+	cr.resolver.limiter = limiter
+}
+
+// limiterReport returns the report of the context limiter of the resolver
+func (cr *timestampContextResolver) limiterReport() ContextLimiterReport {
+	if cr.resolver.limiter == nil {
+		return ContextLimiterReport{}
+	}
+	return cr.resolver.limiter.report()
 }
 
 func (cr *timestampContextResolver) length() int {
@@ -281,9 +372,9 @@ type countBasedContextResolver struct {
 	expireCountInterval int64
 }
 
-func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, tagger tagger.Component, id string) *countBasedContextResolver {
+func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, tagger tagger.Component, id string, metricsQuery bool) *countBasedContextResolver {
 	return &countBasedContextResolver{
-		resolver:            newContextResolver(tagger, cache, id),
+		resolver:            newContextResolver(tagger, cache, id, metricsQuery),
 		expireCount:         0,
 		expireCountInterval: int64(expireCountInterval),
 	}
@@ -300,7 +391,7 @@ func (cr *countBasedContextResolver) updateMetrics(countsByMTypeGauge telemetry.
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
 func (cr *countBasedContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext, cr.expireCount)
+	contextKey, _ := cr.resolver.trackContext(metricSampleContext, cr.expireCount)
 	return contextKey
 }
 
@@ -7,8 +7,11 @@ package aggregator
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"path"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
@@ -44,3 +47,196 @@ func (cr *contextResolver) dumpContexts(dest io.Writer) error {
 
 	return nil
 }
+
+// MetricsQuery selects the metrics returned by a query of the last flushed
+// values of the aggregator.
+type MetricsQuery struct {
+	// Name is a glob pattern the names of the metrics must match, as in
+	// path.Match. An empty pattern matches all the names.
+	Name string
+	// Tags are glob patterns which must each match one of the tags of the
+	// metrics, including the tags of their origin.
+	Tags []string
+}
+
+// Validate returns an error if a pattern of the query is malformed.
+func (q MetricsQuery) Validate() error {
+	for _, pattern := range append([]string{q.Name}, q.Tags...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (q MetricsQuery) matches(name string, c *Context) bool {
+	if q.Name != "" {
+		if matched, _ := path.Match(q.Name, name); !matched {
+			return false
+		}
+	}
+	for _, pattern := range q.Tags {
+		found := c.Tags().Find(func(tag string) bool {
+			matched, _ := path.Match(pattern, tag)
+			return matched
+		})
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// SketchSummary summarizes a flushed sketch.
+type SketchSummary struct {
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+}
+
+// QueriedMetric is the last flushed value of a series or of a sketch.
+type QueriedMetric struct {
+	Name string `json:"name"`
+	Host string `json:"host,omitempty"`
+	// Type is the type of the series, or "sketch"
+	Type string   `json:"type"`
+	Tags []string `json:"tags"`
+	// OriginTags are the tags of the origin of the metric, added by the tagger
+	OriginTags []string             `json:"origin_tags,omitempty"`
+	Source     metrics.MetricSource `json:"source"`
+	// Sampler is the sampler which flushed the metric: `time_sampler:<id>` for
+	// DogStatsD, `check_sampler:<check id>` for checks
+	Sampler   string         `json:"sampler"`
+	Timestamp float64        `json:"timestamp"`
+	Value     *float64       `json:"value,omitempty"`
+	Sketch    *SketchSummary `json:"sketch,omitempty"`
+}
+
+// flushedPoint is the last flushed point of a series of a context, as a
+// context can be flushed as several series, e.g. for histograms.
+type flushedPoint struct {
+	nameSuffix string
+	mtype      metrics.APIMetricType
+	timestamp  float64
+	value      float64
+}
+
+// flushedValues are the last flushed values of a context
+type flushedValues struct {
+	series          []flushedPoint
+	sketchTimestamp int64
+	sketch          *SketchSummary
+}
+
+func (cr *contextResolver) flushedValues(key ckey.ContextKey) *flushedValues {
+	values := cr.flushed[key]
+	if values == nil {
+		values = &flushedValues{}
+		cr.flushed[key] = values
+	}
+	return values
+}
+
+// recordFlushedSerie records the last point of a flushed series, when the
+// metrics query is enabled.
+func (cr *contextResolver) recordFlushedSerie(serie *metrics.Serie) {
+	if cr.flushed == nil || len(serie.Points) == 0 {
+		return
+	}
+	last := serie.Points[0]
+	for _, p := range serie.Points[1:] {
+		if p.Ts > last.Ts {
+			last = p
+		}
+	}
+	point := flushedPoint{nameSuffix: serie.NameSuffix, mtype: serie.MType, timestamp: last.Ts, value: last.Value}
+
+	values := cr.flushedValues(serie.ContextKey)
+	for i := range values.series {
+		if values.series[i].nameSuffix == point.nameSuffix && values.series[i].mtype == point.mtype {
+			values.series[i] = point
+			return
+		}
+	}
+	values.series = append(values.series, point)
+}
+
+// recordFlushedSketch records a summary of the last point of a flushed sketch,
+// when the metrics query is enabled.
+func (cr *contextResolver) recordFlushedSketch(ss *metrics.SketchSeries) {
+	if cr.flushed == nil {
+		return
+	}
+	var last *metrics.SketchPoint
+	for i := range ss.Points {
+		if ss.Points[i].Sketch != nil && (last == nil || ss.Points[i].Ts > last.Ts) {
+			last = &ss.Points[i]
+		}
+	}
+	if last == nil {
+		return
+	}
+	values := cr.flushedValues(ss.ContextKey)
+	values.sketchTimestamp = last.Ts
+	values.sketch = &SketchSummary{
+		Count: last.Sketch.Basic.Cnt,
+		Sum:   last.Sketch.Basic.Sum,
+		Min:   last.Sketch.Basic.Min,
+		Max:   last.Sketch.Basic.Max,
+		Avg:   last.Sketch.Basic.Avg,
+	}
+}
+
+// queryMetrics returns the last flushed values of the contexts matching the
+// query.
+func (cr *contextResolver) queryMetrics(query MetricsQuery, sampler string) []QueriedMetric {
+	var result []QueriedMetric
+	for key, values := range cr.flushed {
+		c, found := cr.get(key)
+		if !found {
+			continue
+		}
+		// the tags are copied as the result is used out of the sampler
+		metric := QueriedMetric{
+			Host:       c.Host,
+			Tags:       append([]string{}, c.metricTags.Tags()...),
+			OriginTags: append([]string(nil), c.taggerTags.Tags()...),
+			Source:     c.source,
+			Sampler:    sampler,
+		}
+		for _, point := range values.series {
+			if !query.matches(c.Name+point.nameSuffix, c) {
+				continue
+			}
+			value := point.value
+			metric.Name = c.Name + point.nameSuffix
+			metric.Type = point.mtype.String()
+			metric.Timestamp = point.timestamp
+			metric.Value = &value
+			result = append(result, metric)
+		}
+		if values.sketch != nil && query.matches(c.Name, c) {
+			metric.Name = c.Name
+			metric.Type = "sketch"
+			metric.Timestamp = float64(values.sketchTimestamp)
+			metric.Value = nil
+			metric.Sketch = values.sketch
+			result = append(result, metric)
+		}
+	}
+	return result
+}
+
+// queryMetrics returns the last flushed values of the contexts matching the
+// query.
+func (cr *timestampContextResolver) queryMetrics(query MetricsQuery, sampler string) []QueriedMetric {
+	return cr.resolver.queryMetrics(query, sampler)
+}
+
+// queryMetrics returns the last flushed values of the contexts matching the
+// query.
+func (cr *countBasedContextResolver) queryMetrics(query MetricsQuery, sampler string) []QueriedMetric {
+	return cr.resolver.queryMetrics(query, sampler)
+}
@@ -75,12 +75,12 @@ func testTrackContext(t *testing.T, store *tags.Store) {
 		SampleRate: 1,
 	}
 
-	contextResolver := newContextResolver(nooptagger.NewComponent(), store, "test")
+	contextResolver := newContextResolver(nooptagger.NewComponent(), store, "test", false)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 0)
-	contextKey2 := contextResolver.trackContext(&mSample2, 0)
-	contextKey3 := contextResolver.trackContext(&mSample3, 0)
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 0)
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 0)
+	contextKey3, _ := contextResolver.trackContext(&mSample3, 0)
 
 	// When we look up the 2 keys, they return the correct contexts
 	context1 := contextResolver.contextsByKey[contextKey1].context
@@ -98,8 +98,8 @@ func testTrackContext(t *testing.T, store *tags.Store) {
 
 	// If the struct changes it's ok to change these, but be careful if you notice that
 	// the size increases a lot.
-	assert.Equal(t, uint64(0x90), contextResolver.bytesByMtype[metrics.GaugeType])
-	assert.Equal(t, uint64(0x48), contextResolver.bytesByMtype[metrics.CountType])
+	assert.Equal(t, uint64(0xb0), contextResolver.bytesByMtype[metrics.GaugeType])
+	assert.Equal(t, uint64(0x58), contextResolver.bytesByMtype[metrics.CountType])
 	assert.Equal(t, uint64(0), contextResolver.bytesByMtype[metrics.RateType])
 	assert.Equal(t, uint64(0x2b), contextResolver.dataBytesByMtype[metrics.GaugeType])
 	assert.Equal(t, uint64(0x26), contextResolver.dataBytesByMtype[metrics.CountType])
@@ -139,12 +139,12 @@ func testExpireContexts(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4)
+	contextResolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4, false)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 4) // expires after 6
-	contextKey2 := contextResolver.trackContext(&mSample2, 6) // expires after 8
-	contextKey3 := contextResolver.trackContext(&mSample3, 6) // expires after 10
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 4) // expires after 6
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 6) // expires after 8
+	contextKey3, _ := contextResolver.trackContext(&mSample3, 6) // expires after 10
 
 	// With an expireTimestap of 3, both contexts are still valid
 	contextResolver.expireContexts(4)
@@ -191,7 +191,7 @@ func testCountBasedExpireContexts(t *testing.T, store *tags.Store) {
 	mSample1 := metrics.MetricSample{Name: "my.metric.name1"}
 	mSample2 := metrics.MetricSample{Name: "my.metric.name2"}
 	mSample3 := metrics.MetricSample{Name: "my.metric.name3"}
-	contextResolver := newCountBasedContextResolver(2, store, nooptagger.NewComponent(), "test")
+	contextResolver := newCountBasedContextResolver(2, store, nooptagger.NewComponent(), "test", false)
 
 	contextKey1 := contextResolver.trackContext(&mSample1)
 	contextKey2 := contextResolver.trackContext(&mSample2)
@@ -216,9 +216,9 @@ func TestCountBasedExpireContexts(t *testing.T) {
 }
 
 func testTagDeduplication(t *testing.T, store *tags.Store) {
-	resolver := newContextResolver(nooptagger.NewComponent(), store, "test")
+	resolver := newContextResolver(nooptagger.NewComponent(), store, "test", false)
 
-	ckey := resolver.trackContext(&metrics.MetricSample{
+	ckey, _ := resolver.trackContext(&metrics.MetricSample{
 		Name: "foo",
 		Tags: []string{"bar", "bar"},
 	}, 0)
@@ -254,7 +254,7 @@ func (s *mockSample) GetTags(tb, mb tagset.TagsAccumulator, _ metrics.EnrichTags
 }
 
 func TestOriginTelemetry(t *testing.T) {
-	r := newContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test")
+	r := newContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", false)
 	r.trackContext(&mockSample{"foo", []string{"foo"}, []string{"ook"}}, 0)
 	r.trackContext(&mockSample{"foo", []string{"foo"}, []string{"eek"}}, 0)
 	r.trackContext(&mockSample{"foo", []string{"bar"}, []string{"ook"}}, 0)
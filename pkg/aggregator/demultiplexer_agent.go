@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -42,6 +43,8 @@ type DemultiplexerWithAggregator interface {
 	GetEventPlatformForwarder() (eventplatform.Forwarder, error)
 	GetEventsAndServiceChecksChannels() (chan []*event.Event, chan []*servicecheck.ServiceCheck)
 	DumpDogstatsdContexts(io.Writer) error
+	DogstatsdContextLimiterReport() ContextLimiterReport
+	QueryMetrics(MetricsQuery) []QueriedMetric
 }
 
 // AgentDemultiplexer is the demultiplexer implementation for the main Agent.
@@ -67,6 +70,10 @@ type AgentDemultiplexer struct {
 
 	hostTagProvider *HostTagProvider
 
+	// rollupRules drop tags from some metrics and merge their contexts during
+	// the flushes, nil when there is no rollup
+	rollupRules rollupRules
+
 	// sharded statsd time samplers
 	statsd
 }
@@ -175,7 +182,8 @@ func initAgentDemultiplexer(log log.Component,
 		// the sampler
 		tagsStore := tags.NewStore(pkgconfigsetup.Datadog().GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
 
-		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname)
+		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname, pkgconfigsetup.Datadog().GetBool("aggregator_metrics_query_enabled"))
+		statsdSampler.setContextLimiter(newContextLimiterFromConfig(pkgconfigsetup.Datadog(), statsdSampler.idString, statsdPipelinesCount))
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -196,6 +204,11 @@ func initAgentDemultiplexer(log log.Component,
 		)
 	}
 
+	rollupRules, err := getRollupRules(pkgconfigsetup.Datadog())
+	if err != nil {
+		log.Errorf("The rollups of the aggregator are disabled: %v", err)
+	}
+
 	// --
 	demux := &AgentDemultiplexer{
 		log:       log,
@@ -216,6 +229,7 @@ func initAgentDemultiplexer(log log.Component,
 
 		hostTagProvider: NewHostTagProvider(),
 		senders:         newSenders(agg),
+		rollupRules:     rollupRules,
 
 		// statsd time samplers
 		statsd: statsd{
@@ -412,6 +426,18 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 		series,
 		sketches,
 		func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+			// merge the contexts of the rollups across all the samplers
+			// ---------------------------------------------------------
+
+			if len(d.rollupRules) > 0 {
+				rollup := newRollup(d.rollupRules)
+				// the merged series and sketches are sent once all the samplers
+				// have been flushed
+				defer rollup.flush(seriesSink, sketchesSink)
+				seriesSink = rollupSerieSink{rollup: rollup, sink: seriesSink}
+				sketchesSink = rollupSketchesSink{rollup: rollup, sink: sketchesSink}
+			}
+
 			// flush DogStatsD pipelines (statsd/time samplers)
 			// ------------------------------------------------
 
@@ -563,6 +589,37 @@ func (d *AgentDemultiplexer) DumpDogstatsdContexts(dest io.Writer) error {
 	return nil
 }
 
+// DogstatsdContextLimiterReport returns the metric names and origins with the most
+// contexts compared to the limits of the context limiter.
+func (d *AgentDemultiplexer) DogstatsdContextLimiterReport() ContextLimiterReport {
+	reports := make([]ContextLimiterReport, 0, len(d.statsd.workers))
+	for _, w := range d.statsd.workers {
+		reports = append(reports, w.contextLimiterReport())
+	}
+	return mergeContextLimiterReports(reports)
+}
+
+// QueryMetrics returns the last flushed values of the series and sketches of
+// the DogStatsD time samplers and of the check samplers matching the query,
+// sorted by name.
+func (d *AgentDemultiplexer) QueryMetrics(query MetricsQuery) []QueriedMetric {
+	var result []QueriedMetric
+	for _, w := range d.statsd.workers {
+		result = append(result, w.queryMetrics(query)...)
+	}
+	if d.aggregator != nil {
+		result = append(result, d.aggregator.queryCheckMetrics(query)...)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Sampler < result[j].Sampler
+	})
+	return result
+}
+
 // GetSender returns a sender.Sender with passed ID, properly registered with the aggregator
 // If no error is returned here, DestroySender must be called with the same ID
 // once the sender is not used anymore
@@ -9,9 +9,11 @@ package aggregator
 
 import (
 	"fmt"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/fx"
 
@@ -157,6 +159,48 @@ func TestMetricSampleTypeConversion(t *testing.T) {
 		}
 		require.Equal(test.apiMetricType, rv, fmt.Sprintf("Wrong conversion for %s", test.metricType.String()))
 	}
+
+	// the counts of the Prometheus remote write receiver are supported
+	rv, supported := metricSampleAPIType(metrics.MetricSample{Mtype: metrics.CountType, Source: metrics.MetricSourcePrometheus})
+	require.True(supported)
+	require.Equal(metrics.APICountType, rv)
+}
+
+func TestNoAggSketches(t *testing.T) {
+	sketches := newNoAggSketches()
+	for _, sample := range []metrics.MetricSample{
+		{Name: "latency", Value: 1, Mtype: metrics.DistributionType, Timestamp: 1657099121, SampleRate: 1},
+		{Name: "latency", Value: 2, Mtype: metrics.DistributionType, Timestamp: 1657099125, SampleRate: 0.5},
+		{Name: "latency", Value: 3, Mtype: metrics.DistributionType, Timestamp: 1657099131, SampleRate: 1},
+		{Name: "latency", Value: 4, Mtype: metrics.DistributionType, Timestamp: 1657099121, SampleRate: 1, Host: "other"},
+	} {
+		sketches.insert(&sample, []string{"env:prod", "env:prod"})
+	}
+
+	var sink metrics.SketchSeriesList
+	sketches.flush(&sink)
+	require.Len(t, sink, 3)
+	sort.Slice(sink, func(i, j int) bool {
+		if sink[i].Points[0].Ts != sink[j].Points[0].Ts {
+			return sink[i].Points[0].Ts < sink[j].Points[0].Ts
+		}
+		return sink[i].Host < sink[j].Host
+	})
+
+	// the samples of the same context and bucket are in the same sketch
+	assert.Equal(t, int64(1657099120), sink[0].Points[0].Ts)
+	assert.Equal(t, "", sink[0].Host)
+	assert.Equal(t, int64(3), sink[0].Points[0].Sketch.Basic.Cnt)
+	assert.Equal(t, []string{"env:prod"}, sink[0].Tags.UnsafeToReadOnlySliceString())
+	assert.Equal(t, int64(bucketSize), sink[0].Interval)
+	assert.Equal(t, "other", sink[1].Host)
+	assert.Equal(t, int64(1), sink[1].Points[0].Sketch.Basic.Cnt)
+	assert.Equal(t, int64(1657099130), sink[2].Points[0].Ts)
+
+	// the sketches are reset once flushed
+	sink = nil
+	sketches.flush(&sink)
+	assert.Empty(t, sink)
 }
 
 type DemultiplexerAgentTestDeps struct {
@@ -0,0 +1,112 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"sort"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+var histogramAggregates = []string{"max", "min", "median", "avg", "sum", "count"}
+
+// histogramOverrideConfig is an override of the histogram_overrides setting.
+type histogramOverrideConfig struct {
+	MetricNames []string `mapstructure:"metric_names" json:"metric_names" yaml:"metric_names"`
+	// Aggregates and Percentiles are the ones of the histogram_aggregates and
+	// histogram_percentiles settings when nil, none when empty
+	Aggregates   []string  `mapstructure:"aggregates" json:"aggregates" yaml:"aggregates"`
+	Percentiles  []float64 `mapstructure:"percentiles" json:"percentiles" yaml:"percentiles"`
+	Distribution bool      `mapstructure:"distribution" json:"distribution" yaml:"distribution"`
+}
+
+// histogramOverride replaces the aggregates and the percentiles of the
+// histograms whose name matches one of its glob patterns, or turns them into
+// distributions.
+type histogramOverride struct {
+	metricNames  []string
+	config       metrics.HistogramConfig
+	distribution bool
+}
+
+// histogramOverrides are the overrides of the histogram settings, the first
+// override matching the name of a metric applies.
+type histogramOverrides []*histogramOverride
+
+// getHistogramOverrides returns the overrides of the histogram_overrides
+// setting.
+func getHistogramOverrides(cfg model.Config) (histogramOverrides, error) {
+	if !cfg.IsSet("histogram_overrides") {
+		return nil, nil
+	}
+	var configs []histogramOverrideConfig
+	if err := structure.UnmarshalKey(cfg, "histogram_overrides", &configs); err != nil {
+		return nil, fmt.Errorf("could not parse histogram_overrides: %v", err)
+	}
+	return newHistogramOverrides(configs, metrics.DefaultHistogramConfig(cfg))
+}
+
+func newHistogramOverrides(configs []histogramOverrideConfig, defaults metrics.HistogramConfig) (histogramOverrides, error) {
+	overrides := make(histogramOverrides, 0, len(configs))
+	for i, config := range configs {
+		if len(config.MetricNames) == 0 {
+			return nil, fmt.Errorf("histogram override %d: metric_names is required", i)
+		}
+		for _, pattern := range config.MetricNames {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("histogram override %d: invalid metric name pattern %q: %v", i, pattern, err)
+			}
+		}
+
+		override := &histogramOverride{
+			metricNames:  config.MetricNames,
+			config:       defaults,
+			distribution: config.Distribution,
+		}
+		if config.Aggregates != nil {
+			for _, aggregate := range config.Aggregates {
+				if !slices.Contains(histogramAggregates, aggregate) {
+					return nil, fmt.Errorf("histogram override %d: unknown aggregate %q", i, aggregate)
+				}
+			}
+			override.config.Aggregates = config.Aggregates
+		}
+		if config.Percentiles != nil {
+			percentiles := make([]float64, 0, len(config.Percentiles))
+			for _, p := range config.Percentiles {
+				if p < 0 || p > 1 {
+					return nil, fmt.Errorf("histogram override %d: percentile %v must be between 0 and 1", i, p)
+				}
+				// rounded to avoid the floating point errors of the '*100'
+				// (0.29 would become 28.999999999999996)
+				percentiles = append(percentiles, math.Round(p*1e8)/1e6)
+			}
+			sort.Float64s(percentiles)
+			override.config.Percentiles = slices.Compact(percentiles)
+		}
+		overrides = append(overrides, override)
+	}
+	return overrides, nil
+}
+
+// match returns the first override matching the metric name, nil if none
+// does.
+func (o histogramOverrides) match(name string) *histogramOverride {
+	for _, override := range o {
+		for _, pattern := range override.metricNames {
+			if matched, _ := path.Match(pattern, name); matched {
+				return override
+			}
+		}
+	}
+	return nil
+}
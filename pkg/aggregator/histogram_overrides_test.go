@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestNewHistogramOverrides(t *testing.T) {
+	defaults := metrics.HistogramConfig{Aggregates: []string{"max", "avg"}, Percentiles: []float64{95}}
+	overrides, err := newHistogramOverrides([]histogramOverrideConfig{
+		{MetricNames: []string{"http.latency.*"}, Percentiles: []float64{0.999, 0.29, 0.5}},
+		{MetricNames: []string{"queue.*"}, Aggregates: []string{}, Percentiles: []float64{}},
+		{MetricNames: []string{"payload.size"}, Distribution: true},
+	}, defaults)
+	require.NoError(t, err)
+	require.Len(t, overrides, 3)
+
+	assert.Equal(t, []string{"max", "avg"}, overrides[0].config.Aggregates)
+	assert.Equal(t, []float64{29, 50, 99.9}, overrides[0].config.Percentiles)
+	assert.Empty(t, overrides[1].config.Aggregates)
+	assert.Empty(t, overrides[1].config.Percentiles)
+	assert.True(t, overrides[2].distribution)
+
+	assert.Same(t, overrides[0], overrides.match("http.latency.p"))
+	assert.Same(t, overrides[1], overrides.match("queue.size"))
+	assert.Nil(t, overrides.match("http.requests"))
+
+	for _, config := range []histogramOverrideConfig{
+		{Aggregates: []string{"max"}},
+		{MetricNames: []string{"http.[latency"}},
+		{MetricNames: []string{"http.*"}, Aggregates: []string{"p99"}},
+		{MetricNames: []string{"http.*"}, Percentiles: []float64{99}},
+	} {
+		_, err := newHistogramOverrides([]histogramOverrideConfig{config}, defaults)
+		assert.Error(t, err)
+	}
+}
@@ -7,9 +7,11 @@ package aggregator
 
 import (
 	"expvar"
+	"math"
 	"time"
 
 	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/util"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
@@ -46,6 +48,9 @@ type noAggregationStreamWorker struct {
 	taggerBuffer *tagset.HashlessTagsAccumulator
 	metricBuffer *tagset.HashlessTagsAccumulator
 
+	// sketches holds the distributions of the batch of samples being streamed
+	sketches noAggSketches
+
 	samplesChan chan metrics.MetricSampleBatch
 	stopChan    chan trigger
 
@@ -96,6 +101,8 @@ func newNoAggregationStreamWorker(maxMetricsPerPayload int, _ *metrics.MetricSam
 		taggerBuffer: tagset.NewHashlessTagsAccumulator(),
 		metricBuffer: tagset.NewHashlessTagsAccumulator(),
 
+		sketches: newNoAggSketches(),
+
 		stopChan:    make(chan trigger),
 		samplesChan: make(chan metrics.MetricSampleBatch, pkgconfigsetup.Datadog().GetInt("dogstatsd_queue_size")),
 
@@ -193,8 +200,11 @@ func (w *noAggregationStreamWorker) run() {
 
 						for _, sample := range samples {
 							mtype, supported := metricSampleAPIType(sample)
+							// the distributions of the Prometheus remote write receiver are
+							// streamed as sketches, when they are enabled
+							distribution := sample.Mtype == metrics.DistributionType && isRemoteWriteSample(sample) && w.sketchesSink != nil
 
-							if !supported {
+							if !supported && !distribution {
 								if !w.logThrottling.ShouldThrottle() {
 									log.Warnf("Discarding unsupported metric sample in the no-aggregation pipeline for sample '%s', sample type '%s'", sample.Name, sample.Mtype.String())
 								}
@@ -206,6 +216,14 @@ func (w *noAggregationStreamWorker) run() {
 							sample.GetTags(w.taggerBuffer, w.metricBuffer, w.tagger.EnrichTags)
 							w.metricBuffer.AppendHashlessAccumulator(w.taggerBuffer)
 
+							if distribution {
+								w.sketches.insert(&sample, w.metricBuffer.Get())
+								w.taggerBuffer.Reset()
+								w.metricBuffer.Reset()
+								countProcessed++
+								continue
+							}
+
 							// if the value is a rate, we have to account for the 10s interval
 							if mtype == metrics.APIRateType {
 								sample.Value /= bucketSize
@@ -226,6 +244,9 @@ func (w *noAggregationStreamWorker) run() {
 							countProcessed++
 						}
 
+						// the distributions of the batch are sent as sketches
+						w.sketches.flush(w.sketchesSink)
+
 						lastStream = time.Now()
 
 						serializedSamples += countProcessed
@@ -245,8 +266,15 @@ func (w *noAggregationStreamWorker) run() {
 				}
 			}, func(serieSource metrics.SerieSource) {
 				sendIterableSeries(w.serializer, start, serieSource)
-			}, func(_ metrics.SketchesSource) {
-				// noop: we do not support sketches in the no-agg pipeline.
+			}, func(sketches metrics.SketchesSource) {
+				// Don't send empty sketches payloads
+				if sketches.WaitForValue() {
+					err := w.serializer.SendSketch(sketches)
+					sketchesCount := sketches.Count()
+					log.Debugf("noAggregationStreamWorker: flushing %d sketches to the serializer", sketchesCount)
+					updateSketchTelemetry(start, sketchesCount, err)
+					addFlushCount("Sketches", int64(sketchesCount))
+				}
 			})
 
 		if stopped {
@@ -263,12 +291,19 @@ func (w *noAggregationStreamWorker) run() {
 
 // metricSampleAPIType returns the APIMetricType of the given sample, the second
 // return value informs the caller if the input type is supported by
-// the no-aggregation pipeline: APIMetricType only supports gauges, counts and rates.
+// the no-aggregation pipeline as a serie: APIMetricType only supports gauges, counts and rates.
 // This method will default on gauges for every other inputs and return false as a second return value.
+// Counts are only supported for the samples of the Prometheus remote write receiver, whose
+// distributions are not series but are streamed as sketches.
 func metricSampleAPIType(m metrics.MetricSample) (metrics.APIMetricType, bool) {
 	switch m.Mtype {
 	case metrics.GaugeType:
 		return metrics.APIGaugeType, true
+	case metrics.CountType:
+		if isRemoteWriteSample(m) {
+			return metrics.APICountType, true
+		}
+		return metrics.APIGaugeType, false
 	case metrics.CounterType:
 		return metrics.APIRateType, true
 	case metrics.RateType:
@@ -277,3 +312,72 @@ func metricSampleAPIType(m metrics.MetricSample) (metrics.APIMetricType, bool) {
 		return metrics.APIGaugeType, false
 	}
 }
+
+// isRemoteWriteSample returns whether the sample has been received by the
+// Prometheus remote write receiver, the only samples of this pipeline with the
+// Prometheus source.
+func isRemoteWriteSample(m metrics.MetricSample) bool {
+	return m.Source == metrics.MetricSourcePrometheus
+}
+
+// noAggSketches builds the sketches of the distribution samples of a batch:
+// the samples of a context with the same timestamp, truncated to the bucket
+// size, are inserted into the same sketch.
+type noAggSketches struct {
+	keyGenerator *ckey.KeyGenerator
+	tagsBuffer   *tagset.HashingTagsAccumulator
+	sketches     sketchMap
+	contexts     map[ckey.ContextKey]*metrics.SketchSeries
+}
+
+func newNoAggSketches() noAggSketches {
+	return noAggSketches{
+		keyGenerator: ckey.NewKeyGenerator(),
+		tagsBuffer:   tagset.NewHashingTagsAccumulator(),
+		sketches:     make(sketchMap),
+		contexts:     make(map[ckey.ContextKey]*metrics.SketchSeries),
+	}
+}
+
+// insert adds the value of the sample, weighted by its sample rate, to the
+// sketch of its context and timestamp.
+func (s *noAggSketches) insert(sample *metrics.MetricSample, tags []string) {
+	s.tagsBuffer.Reset()
+	s.tagsBuffer.Append(tags...)
+	ck := s.keyGenerator.Generate(sample.Name, sample.Host, s.tagsBuffer)
+
+	ts := int64(sample.Timestamp)
+	ts -= ts % bucketSize
+	sampleRate := sample.SampleRate
+	if sampleRate <= 0 {
+		sampleRate = 1
+	}
+	if !s.sketches.insert(ts, ck, sample.Value, sampleRate) {
+		return
+	}
+	if _, found := s.contexts[ck]; !found {
+		s.contexts[ck] = &metrics.SketchSeries{
+			Name:       sample.Name,
+			Tags:       tagset.CompositeTagsFromSlice(append([]string(nil), s.tagsBuffer.Get()...)),
+			Host:       sample.Host,
+			Interval:   bucketSize,
+			ContextKey: ck,
+			Source:     sample.Source,
+			NoIndex:    sample.NoIndex,
+		}
+	}
+}
+
+// flush appends the sketches to the sink, and resets them.
+func (s *noAggSketches) flush(sink metrics.SketchesSink) {
+	if len(s.contexts) == 0 {
+		return
+	}
+	s.sketches.flushBefore(math.MaxInt64, func(ck ckey.ContextKey, point metrics.SketchPoint) {
+		context := s.contexts[ck]
+		sketch := *context
+		sketch.Points = []metrics.SketchPoint{point}
+		sink.Append(&sketch)
+	})
+	clear(s.contexts)
+}
@@ -0,0 +1,311 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+const (
+	// rollupGaugeLast keeps the last value of the merged gauges
+	rollupGaugeLast = "last"
+	// rollupGaugeMax keeps the highest value of the merged gauges
+	rollupGaugeMax = "max"
+)
+
+// rollupRuleConfig is a rule of the aggregator_rollups setting.
+type rollupRuleConfig struct {
+	MetricNames      []string `mapstructure:"metric_names" json:"metric_names" yaml:"metric_names"`
+	DropTags         []string `mapstructure:"drop_tags" json:"drop_tags" yaml:"drop_tags"`
+	GaugeAggregation string   `mapstructure:"gauge_aggregation" json:"gauge_aggregation" yaml:"gauge_aggregation"`
+}
+
+// rollupRule drops tags from the metrics whose name matches one of its glob
+// patterns, and merges the resulting contexts.
+type rollupRule struct {
+	metricNames []string
+	dropTags    map[string]struct{}
+	gaugeMax    bool
+}
+
+// rollupRules are the rules of the rollups, the first rule matching the name
+// of a metric applies.
+type rollupRules []*rollupRule
+
+// getRollupRules returns the rules of the aggregator_rollups setting.
+func getRollupRules(cfg model.Reader) (rollupRules, error) {
+	if !cfg.IsSet("aggregator_rollups") {
+		return nil, nil
+	}
+	var configs []rollupRuleConfig
+	if err := structure.UnmarshalKey(cfg, "aggregator_rollups", &configs); err != nil {
+		return nil, fmt.Errorf("could not parse aggregator_rollups: %v", err)
+	}
+	return newRollupRules(configs)
+}
+
+func newRollupRules(configs []rollupRuleConfig) (rollupRules, error) {
+	rules := make(rollupRules, 0, len(configs))
+	for i, config := range configs {
+		if len(config.MetricNames) == 0 {
+			return nil, fmt.Errorf("rollup rule %d: metric_names is required", i)
+		}
+		if len(config.DropTags) == 0 {
+			return nil, fmt.Errorf("rollup rule %d: drop_tags is required", i)
+		}
+		for _, pattern := range config.MetricNames {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rollup rule %d: invalid metric name pattern %q: %v", i, pattern, err)
+			}
+		}
+
+		rule := &rollupRule{
+			metricNames: config.MetricNames,
+			dropTags:    make(map[string]struct{}, len(config.DropTags)),
+		}
+		for _, key := range config.DropTags {
+			rule.dropTags[key] = struct{}{}
+		}
+		switch config.GaugeAggregation {
+		case "", rollupGaugeLast:
+		case rollupGaugeMax:
+			rule.gaugeMax = true
+		default:
+			return nil, fmt.Errorf("rollup rule %d: unknown gauge_aggregation %q, expected %q or %q", i, config.GaugeAggregation, rollupGaugeLast, rollupGaugeMax)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// match returns the first rule matching the metric name, nil if none does.
+func (r rollupRules) match(name string) *rollupRule {
+	for _, rule := range r {
+		for _, pattern := range rule.metricNames {
+			if matched, _ := path.Match(pattern, name); matched {
+				return rule
+			}
+		}
+	}
+	return nil
+}
+
+// dropped returns whether the tag is dropped by the rule.
+func (r *rollupRule) dropped(tag string) bool {
+	key, _, _ := strings.Cut(tag, ":")
+	_, found := r.dropTags[key]
+	return found
+}
+
+// rollupSerieKey identifies a merged series
+type rollupSerieKey struct {
+	contextKey ckey.ContextKey
+	mtype      metrics.APIMetricType
+	device     string
+}
+
+type rolledUpSerie struct {
+	serie *metrics.Serie
+	rule  *rollupRule
+}
+
+// rollup merges the series and the sketches of the metrics matching the
+// rollup rules during a flush, once the tags of the rules have been dropped:
+// the points of the counts and rates are summed, the gauges keep their last or
+// highest value, and the sketches are merged.
+//
+// Not safe for concurrent usage, the samplers are flushed one after the other.
+type rollup struct {
+	rules        rollupRules
+	ruleByName   map[string]*rollupRule
+	keyGenerator *ckey.KeyGenerator
+	tagsBuffer   *tagset.HashingTagsAccumulator
+
+	series       map[rollupSerieKey]*rolledUpSerie
+	seriesKeys   []rollupSerieKey
+	sketches     sketchMap
+	sketchSeries map[ckey.ContextKey]*metrics.SketchSeries
+	sketchKeys   []ckey.ContextKey
+}
+
+func newRollup(rules rollupRules) *rollup {
+	return &rollup{
+		rules:        rules,
+		ruleByName:   map[string]*rollupRule{},
+		keyGenerator: ckey.NewKeyGenerator(),
+		tagsBuffer:   tagset.NewHashingTagsAccumulator(),
+		series:       map[rollupSerieKey]*rolledUpSerie{},
+		sketches:     make(sketchMap),
+		sketchSeries: map[ckey.ContextKey]*metrics.SketchSeries{},
+	}
+}
+
+// rule returns the rule matching the metric name, caching the result.
+func (r *rollup) rule(name string) *rollupRule {
+	rule, found := r.ruleByName[name]
+	if !found {
+		rule = r.rules.match(name)
+		r.ruleByName[name] = rule
+	}
+	return rule
+}
+
+// rolledUpContext returns the context key and the sorted tags of a metric
+// once the tags of the rule have been dropped.
+func (r *rollup) rolledUpContext(rule *rollupRule, name, host string, tags tagset.CompositeTags) (ckey.ContextKey, []string) {
+	defer r.tagsBuffer.Reset()
+	tags.ForEach(func(tag string) {
+		if !rule.dropped(tag) {
+			r.tagsBuffer.Append(tag)
+		}
+	})
+	key := r.keyGenerator.Generate(name, host, r.tagsBuffer)
+	kept := append([]string{}, r.tagsBuffer.Get()...)
+	sort.Strings(kept)
+	return key, kept
+}
+
+// addSerie merges the series if it matches a rule, and returns false if it
+// doesn't.
+func (r *rollup) addSerie(serie *metrics.Serie) bool {
+	rule := r.rule(strings.TrimSuffix(serie.Name, serie.NameSuffix))
+	if rule == nil {
+		return false
+	}
+
+	contextKey, tags := r.rolledUpContext(rule, serie.Name, serie.Host, serie.Tags)
+	key := rollupSerieKey{contextKey: contextKey, mtype: serie.MType, device: serie.Device}
+	rolledUp, found := r.series[key]
+	if !found {
+		merged := *serie
+		merged.Tags = tagset.CompositeTagsFromSlice(tags)
+		merged.ContextKey = contextKey
+		merged.Points = append([]metrics.Point(nil), serie.Points...)
+		sort.Slice(merged.Points, func(i, j int) bool { return merged.Points[i].Ts < merged.Points[j].Ts })
+		r.series[key] = &rolledUpSerie{serie: &merged, rule: rule}
+		r.seriesKeys = append(r.seriesKeys, key)
+		return true
+	}
+
+	merged := rolledUp.serie
+	gauge := merged.MType != metrics.APICountType && merged.MType != metrics.APIRateType
+	bucket := pointBucket(merged, gauge)
+	for _, point := range serie.Points {
+		i := sort.Search(len(merged.Points), func(i int) bool { return bucket(merged.Points[i].Ts) >= bucket(point.Ts) })
+		if i == len(merged.Points) || bucket(merged.Points[i].Ts) != bucket(point.Ts) {
+			merged.Points = append(merged.Points, point)
+			sort.Slice(merged.Points, func(i, j int) bool { return merged.Points[i].Ts < merged.Points[j].Ts })
+			continue
+		}
+		switch {
+		case !gauge:
+			merged.Points[i].Value += point.Value
+		case rolledUp.rule.gaugeMax:
+			merged.Points[i].Value = math.Max(merged.Points[i].Value, point.Value)
+			merged.Points[i].Ts = math.Max(merged.Points[i].Ts, point.Ts)
+		case point.Ts >= merged.Points[i].Ts:
+			// the gauges keep the value of their latest point
+			merged.Points[i] = point
+		}
+	}
+	return true
+}
+
+// pointBucket returns the function giving the bucket of the timestamp of a
+// point of the serie, the points of a bucket being merged together. The points
+// of counts and rates are merged when they have the same timestamp, the ones of
+// gauges when they fall into the same interval, as the gauges of different
+// contexts aren't sampled at the same time.
+func pointBucket(serie *metrics.Serie, gauge bool) func(ts float64) float64 {
+	if !gauge {
+		return func(ts float64) float64 { return ts }
+	}
+	interval := float64(serie.Interval)
+	if interval <= 0 {
+		interval = bucketSize
+	}
+	return func(ts float64) float64 { return ts - math.Mod(ts, interval) }
+}
+
+// addSketch merges the sketch series if it matches a rule, and returns false
+// if it doesn't.
+func (r *rollup) addSketch(ss *metrics.SketchSeries) bool {
+	rule := r.rule(ss.Name)
+	if rule == nil {
+		return false
+	}
+
+	contextKey, tags := r.rolledUpContext(rule, ss.Name, ss.Host, ss.Tags)
+	if _, found := r.sketchSeries[contextKey]; !found {
+		merged := *ss
+		merged.Tags = tagset.CompositeTagsFromSlice(tags)
+		merged.ContextKey = contextKey
+		merged.Points = nil
+		r.sketchSeries[contextKey] = &merged
+		r.sketchKeys = append(r.sketchKeys, contextKey)
+	}
+	for _, point := range ss.Points {
+		if point.Sketch != nil {
+			r.sketches.merge(point.Ts, contextKey, point.Sketch)
+		}
+	}
+	return true
+}
+
+// flush sends the merged series and sketches to the sinks.
+func (r *rollup) flush(series metrics.SerieSink, sketches metrics.SketchesSink) {
+	for _, key := range r.seriesKeys {
+		series.Append(r.series[key].serie)
+	}
+
+	r.sketches.flushBefore(math.MaxInt64, func(ck ckey.ContextKey, p metrics.SketchPoint) {
+		r.sketchSeries[ck].Points = append(r.sketchSeries[ck].Points, p)
+	})
+	for _, key := range r.sketchKeys {
+		ss := r.sketchSeries[key]
+		if len(ss.Points) == 0 {
+			continue
+		}
+		sort.Slice(ss.Points, func(i, j int) bool { return ss.Points[i].Ts < ss.Points[j].Ts })
+		sketches.Append(ss)
+	}
+}
+
+// rollupSerieSink merges the series matching the rollup rules, and forwards
+// the other ones to its sink.
+type rollupSerieSink struct {
+	rollup *rollup
+	sink   metrics.SerieSink
+}
+
+func (s rollupSerieSink) Append(serie *metrics.Serie) {
+	if !s.rollup.addSerie(serie) {
+		s.sink.Append(serie)
+	}
+}
+
+// rollupSketchesSink merges the sketches matching the rollup rules, and
+// forwards the other ones to its sink.
+type rollupSketchesSink struct {
+	rollup *rollup
+	sink   metrics.SketchesSink
+}
+
+func (s rollupSketchesSink) Append(ss *metrics.SketchSeries) {
+	if !s.rollup.addSketch(ss) {
+		s.sink.Append(ss)
+	}
+}
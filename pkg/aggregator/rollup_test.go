@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func TestNewRollupRules(t *testing.T) {
+	rules, err := newRollupRules([]rollupRuleConfig{
+		{MetricNames: []string{"http.*"}, DropTags: []string{"endpoint"}},
+		{MetricNames: []string{"queue.size"}, DropTags: []string{"queue"}, GaugeAggregation: "max"},
+	})
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Same(t, rules[0], rules.match("http.latency"))
+	assert.True(t, rules[1].gaugeMax)
+	assert.Nil(t, rules.match("other"))
+
+	for _, config := range []rollupRuleConfig{
+		{DropTags: []string{"endpoint"}},
+		{MetricNames: []string{"http.*"}},
+		{MetricNames: []string{"http.[latency"}, DropTags: []string{"endpoint"}},
+		{MetricNames: []string{"http.*"}, DropTags: []string{"endpoint"}, GaugeAggregation: "avg"},
+	} {
+		_, err := newRollupRules([]rollupRuleConfig{config})
+		assert.Error(t, err)
+	}
+}
+
+func rollupSerie(name string, mtype metrics.APIMetricType, value float64, tags ...string) *metrics.Serie {
+	return &metrics.Serie{
+		Name:     name,
+		MType:    mtype,
+		Tags:     tagset.CompositeTagsFromSlice(tags),
+		Host:     "host",
+		Interval: 10,
+		Points:   []metrics.Point{{Ts: 10000, Value: value}},
+	}
+}
+
+func TestRollupSeries(t *testing.T) {
+	rules, err := newRollupRules([]rollupRuleConfig{
+		{MetricNames: []string{"http.requests", "http.latency"}, DropTags: []string{"endpoint"}},
+		{MetricNames: []string{"queue.size"}, DropTags: []string{"queue"}, GaugeAggregation: "max"},
+	})
+	require.NoError(t, err)
+	r := newRollup(rules)
+
+	var forwarded, flushed metrics.Series
+	sink := rollupSerieSink{rollup: r, sink: &forwarded}
+	sink.Append(rollupSerie("http.requests", metrics.APICountType, 3, "endpoint:/a", "env:prod"))
+	sink.Append(rollupSerie("http.requests", metrics.APICountType, 4, "env:prod", "endpoint:/b"))
+	sink.Append(rollupSerie("http.requests", metrics.APICountType, 1, "endpoint:/a", "env:dev"))
+	sink.Append(rollupSerie("queue.size", metrics.APIGaugeType, 12, "queue:a"))
+	sink.Append(rollupSerie("queue.size", metrics.APIGaugeType, 7, "queue:b"))
+	sink.Append(rollupSerie("http.errors", metrics.APICountType, 1, "endpoint:/a"))
+
+	// the series of histograms are matched by the name of their metric
+	latency := rollupSerie("http.latency.max", metrics.APIGaugeType, 0.5, "endpoint:/a")
+	latency.NameSuffix = ".max"
+	sink.Append(latency)
+
+	require.Len(t, forwarded, 1)
+	assert.Equal(t, "http.errors", forwarded[0].Name)
+
+	r.flush(&flushed, &metrics.SketchSeriesList{})
+	require.Len(t, flushed, 4)
+
+	assert.Equal(t, "http.requests", flushed[0].Name)
+	assert.Equal(t, []string{"env:prod"}, flushed[0].Tags.UnsafeToReadOnlySliceString())
+	assert.Equal(t, []metrics.Point{{Ts: 10000, Value: 7}}, flushed[0].Points)
+	assert.Equal(t, "host", flushed[0].Host)
+	assert.Equal(t, metrics.APICountType, flushed[0].MType)
+
+	assert.Equal(t, []string{"env:dev"}, flushed[1].Tags.UnsafeToReadOnlySliceString())
+	assert.Equal(t, float64(1), flushed[1].Points[0].Value)
+
+	assert.Equal(t, "queue.size", flushed[2].Name)
+	assert.Equal(t, 0, flushed[2].Tags.Len())
+	assert.Equal(t, float64(12), flushed[2].Points[0].Value)
+
+	assert.Equal(t, "http.latency.max", flushed[3].Name)
+}
+
+func TestRollupGaugeLastAndPoints(t *testing.T) {
+	rules, err := newRollupRules([]rollupRuleConfig{{MetricNames: []string{"queue.size"}, DropTags: []string{"queue"}}})
+	require.NoError(t, err)
+	r := newRollup(rules)
+
+	first := rollupSerie("queue.size", metrics.APIGaugeType, 12, "queue:a")
+	second := rollupSerie("queue.size", metrics.APIGaugeType, 7, "queue:b")
+	second.Points = append(second.Points, metrics.Point{Ts: 10010, Value: 3})
+	assert.True(t, r.addSerie(first))
+	assert.True(t, r.addSerie(second))
+
+	var flushed metrics.Series
+	r.flush(&flushed, &metrics.SketchSeriesList{})
+	require.Len(t, flushed, 1)
+	assert.Equal(t, []metrics.Point{{Ts: 10000, Value: 7}, {Ts: 10010, Value: 3}}, flushed[0].Points)
+	// the original series are not modified
+	assert.Equal(t, float64(12), first.Points[0].Value)
+}
+
+func TestRollupGaugeLatestPoint(t *testing.T) {
+	rules, err := newRollupRules([]rollupRuleConfig{
+		{MetricNames: []string{"queue.size"}, DropTags: []string{"queue"}},
+		{MetricNames: []string{"queue.age"}, DropTags: []string{"queue"}, GaugeAggregation: "max"},
+	})
+	require.NoError(t, err)
+	r := newRollup(rules)
+
+	// the gauges of the checks are sampled at different times of the interval
+	later := rollupSerie("queue.size", metrics.APIGaugeType, 12, "queue:a")
+	later.Points[0].Ts = 10004.5
+	earlier := rollupSerie("queue.size", metrics.APIGaugeType, 7, "queue:b")
+	earlier.Points[0].Ts = 10002
+	assert.True(t, r.addSerie(later))
+	assert.True(t, r.addSerie(earlier))
+
+	oldest := rollupSerie("queue.age", metrics.APIGaugeType, 30, "queue:a")
+	oldest.Points[0].Ts = 10001
+	latest := rollupSerie("queue.age", metrics.APIGaugeType, 20, "queue:b")
+	latest.Points[0].Ts = 10008
+	assert.True(t, r.addSerie(oldest))
+	assert.True(t, r.addSerie(latest))
+
+	var flushed metrics.Series
+	r.flush(&flushed, &metrics.SketchSeriesList{})
+	require.Len(t, flushed, 2)
+	// the value of the latest point is kept, whatever the order of the series
+	assert.Equal(t, []metrics.Point{{Ts: 10004.5, Value: 12}}, flushed[0].Points)
+	assert.Equal(t, []metrics.Point{{Ts: 10008, Value: 30}}, flushed[1].Points)
+}
+
+func TestRollupSketches(t *testing.T) {
+	rules, err := newRollupRules([]rollupRuleConfig{{MetricNames: []string{"http.latency"}, DropTags: []string{"endpoint"}}})
+	require.NoError(t, err)
+	r := newRollup(rules)
+
+	newSketch := func(values ...float64) *quantile.Sketch {
+		s := &quantile.Sketch{}
+		s.Insert(quantile.Default(), values...)
+		return s
+	}
+	var forwarded, flushed metrics.SketchSeriesList
+	sink := rollupSketchesSink{rollup: r, sink: &forwarded}
+	sink.Append(&metrics.SketchSeries{
+		Name:   "http.latency",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"endpoint:/a", "env:prod"}),
+		Points: []metrics.SketchPoint{{Ts: 10000, Sketch: newSketch(1, 2)}},
+	})
+	sink.Append(&metrics.SketchSeries{
+		Name:   "http.latency",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"endpoint:/b", "env:prod"}),
+		Points: []metrics.SketchPoint{{Ts: 10000, Sketch: newSketch(3)}, {Ts: 10010, Sketch: newSketch(4)}},
+	})
+	sink.Append(&metrics.SketchSeries{
+		Name:   "other",
+		Points: []metrics.SketchPoint{{Ts: 10000, Sketch: newSketch(1)}},
+	})
+	require.Len(t, forwarded, 1)
+
+	r.flush(&metrics.Series{}, &flushed)
+	require.Len(t, flushed, 1)
+	assert.Equal(t, "http.latency", flushed[0].Name)
+	assert.Equal(t, []string{"env:prod"}, flushed[0].Tags.UnsafeToReadOnlySliceString())
+	require.Len(t, flushed[0].Points, 2)
+	assert.Equal(t, int64(10000), flushed[0].Points[0].Ts)
+	assert.Equal(t, int64(3), flushed[0].Points[0].Sketch.Basic.Cnt)
+	assert.Equal(t, float64(6), flushed[0].Points[0].Sketch.Basic.Sum)
+	assert.Equal(t, int64(1), flushed[0].Points[1].Sketch.Basic.Cnt)
+}
@@ -48,6 +48,11 @@ func (m sketchMap) insertInterp(ts int64, ck ckey.ContextKey, lower float64, upp
 	return true
 }
 
+// merge merges the sketch into the sketch for the given (ts, contextKey)
+func (m sketchMap) merge(ts int64, ck ckey.ContextKey, sketch *quantile.Sketch) {
+	m.getOrCreate(ts, ck).Sketch.Merge(quantile.Default(), sketch)
+}
+
 func (m sketchMap) getOrCreate(ts int64, ck ckey.ContextKey) *quantile.Agent {
 	// level 1: ts -> ctx
 	byCtx, ok := m[ts]
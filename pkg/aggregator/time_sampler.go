@@ -35,6 +35,8 @@ type TimeSampler struct {
 	metricsByTimestamp map[int64]metrics.ContextMetrics
 	lastCutOffTime     int64
 	sketchMap          sketchMap
+	// histogramOverrides replace the settings of some histograms
+	histogramOverrides histogramOverrides
 
 	// id is a number to differentiate multiple time samplers
 	// since we start running more than one with the demultiplexer introduction
@@ -45,7 +47,7 @@ type TimeSampler struct {
 }
 
 // NewTimeSampler returns a newly initialized TimeSampler
-func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger tagger.Component, hostname string) *TimeSampler {
+func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger tagger.Component, hostname string, metricsQuery bool) *TimeSampler {
 	if interval == 0 {
 		interval = bucketSize
 	}
@@ -56,11 +58,17 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger
 	contextExpireTime := pkgconfigsetup.Datadog().GetInt64("dogstatsd_context_expiry_seconds")
 	counterExpireTime := contextExpireTime + pkgconfigsetup.Datadog().GetInt64("dogstatsd_expiry_seconds")
 
+	histogramOverrides, err := getHistogramOverrides(pkgconfigsetup.Datadog())
+	if err != nil {
+		log.Errorf("TimeSampler #%s: ignoring the histogram overrides: %v", idString, err)
+	}
+
 	s := &TimeSampler{
 		interval:           interval,
-		contextResolver:    newTimestampContextResolver(tagger, cache, idString, contextExpireTime, counterExpireTime),
+		contextResolver:    newTimestampContextResolver(tagger, cache, idString, contextExpireTime, counterExpireTime, metricsQuery),
 		metricsByTimestamp: map[int64]metrics.ContextMetrics{},
 		sketchMap:          make(sketchMap),
+		histogramOverrides: histogramOverrides,
 		id:                 id,
 		idString:           idString,
 		hostname:           hostname,
@@ -84,10 +92,26 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 	}
 
 	// Keep track of the context
-	contextKey := s.contextResolver.trackContext(metricSample, int64(timestamp))
+	contextKey, tracked := s.contextResolver.trackContext(metricSample, int64(timestamp))
+	if !tracked {
+		// the context is over a limit of the context limiter
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
-	switch metricSample.Mtype {
+	var histogramConfig *metrics.HistogramConfig
+	mtype := metricSample.Mtype
+	if mtype == metrics.HistogramType && len(s.histogramOverrides) > 0 {
+		if override := s.histogramOverride(contextKey); override != nil {
+			if override.distribution {
+				mtype = metrics.DistributionType
+			} else {
+				histogramConfig = &override.config
+			}
+		}
+	}
+
+	switch mtype {
 	case metrics.DistributionType:
 		s.sketchMap.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate)
 	default:
@@ -98,12 +122,26 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 			s.metricsByTimestamp[bucketStart] = bucketMetrics
 		}
 		// Add sample to bucket
-		if err := bucketMetrics.AddSample(contextKey, metricSample, timestamp, s.interval, nil, pkgconfigsetup.Datadog()); err != nil {
+		if err := bucketMetrics.AddSampleWithHistogramConfig(contextKey, metricSample, timestamp, s.interval, nil, pkgconfigsetup.Datadog(), histogramConfig); err != nil {
 			log.Debugf("TimeSampler #%d Ignoring sample '%s' on host '%s' and tags '%s': %s", s.id, metricSample.Name, metricSample.Host, metricSample.Tags, err)
 		}
 	}
 }
 
+// histogramOverride returns the override of the histogram settings of the
+// context, matching its name against the overrides only once.
+func (s *TimeSampler) histogramOverride(contextKey ckey.ContextKey) *histogramOverride {
+	context, ok := s.contextResolver.get(contextKey)
+	if !ok {
+		return nil
+	}
+	if !context.histogramOverrideResolved {
+		context.histogramOverride = s.histogramOverrides.match(context.Name)
+		context.histogramOverrideResolved = true
+	}
+	return context.histogramOverride
+}
+
 func (s *TimeSampler) newSketchSeries(ck ckey.ContextKey, points []metrics.SketchPoint) *metrics.SketchSeries {
 	ctx, ok := s.contextResolver.get(ck)
 	if !ok {
@@ -195,6 +233,7 @@ func (s *TimeSampler) dedupSerieBySerieSignature(
 	}
 
 	for _, serie := range serieBySignature {
+		s.contextResolver.resolver.recordFlushedSerie(serie)
 		serieSink.Append(serie)
 	}
 }
@@ -214,6 +253,7 @@ func (s *TimeSampler) flushSketches(cutoffTime int64, sketchesSink metrics.Sketc
 			log.Errorf("TimeSampler #%d Ignoring all metrics on context key '%v': inconsistent context resolver state: the context is not tracked", s.id, ck)
 			continue
 		}
+		s.contextResolver.resolver.recordFlushedSketch(ss)
 		sketchesSink.Append(ss)
 	}
 }
@@ -305,3 +345,18 @@ func (s *TimeSampler) sendTelemetry(timestamp float64, series metrics.SerieSink)
 func (s *TimeSampler) dumpContexts(dest io.Writer) error {
 	return s.contextResolver.dumpContexts(dest)
 }
+
+// setContextLimiter sets the limiter capping the number of contexts of the sampler
+func (s *TimeSampler) setContextLimiter(limiter *contextLimiter) {
+	s.contextResolver.setLimiter(limiter)
+}
+
+func (s *TimeSampler) contextLimiterReport() ContextLimiterReport {
+	return s.contextResolver.limiterReport()
+}
+
+// queryMetrics returns the last flushed values of the contexts of the sampler
+// matching the query.
+func (s *TimeSampler) queryMetrics(query MetricsQuery) []QueriedMetric {
+	return s.contextResolver.queryMetrics(query, "time_sampler:"+s.idString)
+}
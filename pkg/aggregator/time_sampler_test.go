@@ -8,6 +8,7 @@
 package aggregator
 
 import (
+	"fmt"
 	"math"
 	"sort"
 	"testing"
@@ -20,6 +21,7 @@ import (
 	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
 )
@@ -34,7 +36,7 @@ func generateSerieContextKey(serie *metrics.Serie) ckey.ContextKey {
 }
 
 func testTimeSampler(store *tags.Store) *TimeSampler {
-	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, nooptagger.NewComponent(), "host")
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, nooptagger.NewComponent(), "host", false)
 	return sampler
 }
 
@@ -535,7 +537,7 @@ func TestFlushMissingContext(t *testing.T) {
 }
 
 func benchmarkTimeSampler(b *testing.B, store *tags.Store) {
-	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, nooptagger.NewComponent(), "host")
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, store, nooptagger.NewComponent(), "host", false)
 
 	sample := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -549,10 +551,128 @@ func benchmarkTimeSampler(b *testing.B, store *tags.Store) {
 		sampler.sample(&sample, 12345.0)
 	}
 }
+
+func TestQueryMetrics(t *testing.T) {
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(true, "test"), nooptagger.NewComponent(), "host", true)
+
+	for _, sample := range []metrics.MetricSample{
+		{Name: "app.requests", Value: 5, Mtype: metrics.GaugeType, Tags: []string{"env:prod"}, SampleRate: 1},
+		{Name: "app.requests", Value: 2, Mtype: metrics.GaugeType, Tags: []string{"env:dev"}, SampleRate: 1},
+		{Name: "app.latency", Value: 1, Mtype: metrics.DistributionType, Tags: []string{"env:prod"}, SampleRate: 1},
+		{Name: "app.latency", Value: 3, Mtype: metrics.DistributionType, Tags: []string{"env:prod"}, SampleRate: 1},
+		{Name: "other", Value: 1, Mtype: metrics.GaugeType, Tags: []string{"env:prod"}, SampleRate: 1},
+	} {
+		sampler.sample(&sample, 10001)
+	}
+
+	// nothing has been flushed yet
+	assert.Empty(t, sampler.queryMetrics(MetricsQuery{}))
+
+	flushSerie(sampler, 10020)
+
+	result := sampler.queryMetrics(MetricsQuery{Name: "app.*", Tags: []string{"env:p*"}})
+	require.Len(t, result, 2)
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	assert.Equal(t, "app.latency", result[0].Name)
+	assert.Equal(t, "sketch", result[0].Type)
+	assert.Equal(t, "time_sampler:0", result[0].Sampler)
+	assert.Equal(t, float64(10000), result[0].Timestamp)
+	assert.Nil(t, result[0].Value)
+	require.NotNil(t, result[0].Sketch)
+	assert.Equal(t, int64(2), result[0].Sketch.Count)
+	assert.Equal(t, float64(4), result[0].Sketch.Sum)
+	assert.Equal(t, float64(1), result[0].Sketch.Min)
+	assert.Equal(t, float64(3), result[0].Sketch.Max)
+
+	assert.Equal(t, "app.requests", result[1].Name)
+	assert.Equal(t, "gauge", result[1].Type)
+	assert.Equal(t, []string{"env:prod"}, result[1].Tags)
+	require.NotNil(t, result[1].Value)
+	assert.Equal(t, float64(5), *result[1].Value)
+
+	assert.Len(t, sampler.queryMetrics(MetricsQuery{Name: "app.requests"}), 2)
+	assert.Empty(t, sampler.queryMetrics(MetricsQuery{Tags: []string{"env:staging"}}))
+
+	// the values are forgotten with their context
+	sampler.flush(10020+float64(pkgconfigsetup.Datadog().GetInt64("dogstatsd_context_expiry_seconds"))+20, &metrics.Series{}, &metrics.SketchSeriesList{})
+	assert.Empty(t, sampler.queryMetrics(MetricsQuery{}))
+}
+
+func TestQueryMetricsDisabled(t *testing.T) {
+	sampler := testTimeSampler(tags.NewStore(true, "test"))
+	sampler.sample(&metrics.MetricSample{Name: "app.requests", Value: 5, Mtype: metrics.GaugeType, SampleRate: 1}, 10001)
+	sampler.sample(&metrics.MetricSample{Name: "app.latency", Value: 1, Mtype: metrics.DistributionType, SampleRate: 1}, 10001)
+	flushSerie(sampler, 10020)
+
+	// the flushed values are not recorded
+	assert.Nil(t, sampler.contextResolver.resolver.flushed)
+	assert.Empty(t, sampler.queryMetrics(MetricsQuery{}))
+}
+
+func TestMetricsQueryValidate(t *testing.T) {
+	assert.NoError(t, MetricsQuery{Name: "app.*", Tags: []string{"env:*"}}.Validate())
+	assert.Error(t, MetricsQuery{Name: "app.[requests"}.Validate())
+	assert.Error(t, MetricsQuery{Tags: []string{"env:[prod"}}.Validate())
+}
+
 func BenchmarkTimeSampler(b *testing.B) {
 	benchWithTagsStore(b, benchmarkTimeSampler)
 }
 
+func benchmarkTimeSamplerFlush(b *testing.B, queryEnabled bool) {
+	sampler := NewTimeSampler(TimeSamplerID(0), 10, tags.NewStore(true, "test"), nooptagger.NewComponent(), "host", queryEnabled)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		timestamp := float64(10000 + 10*n)
+		for i := 0; i < 1000; i++ {
+			sampler.sample(&metrics.MetricSample{Name: "app.requests", Value: 1, Mtype: metrics.GaugeType, Tags: []string{fmt.Sprintf("id:%d", i)}, SampleRate: 1}, timestamp)
+			sampler.sample(&metrics.MetricSample{Name: "app.latency", Value: 1, Mtype: metrics.DistributionType, Tags: []string{fmt.Sprintf("id:%d", i)}, SampleRate: 1}, timestamp)
+		}
+		b.StartTimer()
+		sampler.flush(timestamp+10, &metrics.Series{}, &metrics.SketchSeriesList{})
+	}
+}
+
+func BenchmarkTimeSamplerFlush(b *testing.B) {
+	b.Run("queryEnabled=false", func(b *testing.B) { benchmarkTimeSamplerFlush(b, false) })
+	b.Run("queryEnabled=true", func(b *testing.B) { benchmarkTimeSamplerFlush(b, true) })
+}
+
+func TestHistogramOverridesSampling(t *testing.T) {
+	sampler := testTimeSampler(tags.NewStore(true, "test"))
+	overrides, err := newHistogramOverrides([]histogramOverrideConfig{
+		{MetricNames: []string{"app.latency"}, Aggregates: []string{}, Percentiles: []float64{0.999}},
+		{MetricNames: []string{"app.size"}, Distribution: true},
+	}, metrics.HistogramConfig{})
+	require.NoError(t, err)
+	sampler.histogramOverrides = overrides
+
+	for _, name := range []string{"app.latency", "app.size", "other"} {
+		for i := 1; i <= 1000; i++ {
+			sampler.sample(&metrics.MetricSample{Name: name, Value: float64(i), Mtype: metrics.HistogramType, SampleRate: 1}, 10001)
+		}
+	}
+
+	series, sketches := flushSerie(sampler, 10020)
+	names := make([]string, 0, len(series))
+	for _, serie := range series {
+		names = append(names, serie.Name)
+	}
+	// the other histograms keep the aggregates and percentiles of the settings
+	assert.ElementsMatch(t, []string{
+		"app.latency.99_9percentile",
+		"other.max", "other.median", "other.avg", "other.count", "other.95percentile",
+	}, names)
+
+	require.Len(t, sketches, 1)
+	assert.Equal(t, "app.size", sketches[0].Name)
+	assert.Equal(t, int64(1000), sketches[0].Points[0].Sketch.Basic.Cnt)
+}
+
 func flushSerie(sampler *TimeSampler, timestamp float64) (metrics.Series, metrics.SketchSeriesList) {
 	var series metrics.Series
 	var sketches metrics.SketchSeriesList
@@ -39,6 +39,10 @@ type timeSamplerWorker struct {
 	stopChan chan struct{}
 	// channel to trigger interactive dump of the context resolver
 	dumpChan chan dumpTrigger
+	// channel to request the report of the context limiter
+	limiterReportChan chan chan ContextLimiterReport
+	// channel to query the last flushed values of the sampler
+	queryChan chan queryTrigger
 
 	// tagsStore shard used to store tag slices for this worker
 	tagsStore *tags.Store
@@ -49,6 +53,11 @@ type dumpTrigger struct {
 	done chan error
 }
 
+type queryTrigger struct {
+	query MetricsQuery
+	done  chan []QueriedMetric
+}
+
 func newTimeSamplerWorker(sampler *TimeSampler, flushInterval time.Duration, bufferSize int,
 	metricSamplePool *metrics.MetricSamplePool,
 	parallelSerialization FlushAndSerializeInParallel, tagsStore *tags.Store) *timeSamplerWorker {
@@ -65,6 +74,9 @@ func newTimeSamplerWorker(sampler *TimeSampler, flushInterval time.Duration, buf
 		flushChan:   make(chan flushTrigger),
 		dumpChan:    make(chan dumpTrigger),
 
+		limiterReportChan: make(chan chan ContextLimiterReport),
+		queryChan:         make(chan queryTrigger),
+
 		tagsStore: tagsStore,
 	}
 }
@@ -96,6 +108,10 @@ func (w *timeSamplerWorker) run() {
 			w.tagsStore.Shrink()
 		case trigger := <-w.dumpChan:
 			trigger.done <- w.sampler.dumpContexts(trigger.dest)
+		case done := <-w.limiterReportChan:
+			done <- w.sampler.contextLimiterReport()
+		case trigger := <-w.queryChan:
+			trigger.done <- w.sampler.queryMetrics(trigger.query)
 		}
 	}
 }
@@ -114,3 +130,15 @@ func (w *timeSamplerWorker) dumpContexts(dest io.Writer) error {
 	w.dumpChan <- dumpTrigger{dest: dest, done: done}
 	return <-done
 }
+
+func (w *timeSamplerWorker) contextLimiterReport() ContextLimiterReport {
+	done := make(chan ContextLimiterReport)
+	w.limiterReportChan <- done
+	return <-done
+}
+
+func (w *timeSamplerWorker) queryMetrics(query MetricsQuery) []QueriedMetric {
+	done := make(chan []QueriedMetric)
+	w.queryChan <- queryTrigger{query: query, done: done}
+	return <-done
+}
@@ -45,6 +45,11 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("apm_config.obfuscation.valkey.remove_all_args", false, "DD_APM_OBFUSCATION_VALKEY_REMOVE_ALL_ARGS")
 	config.BindEnvAndSetDefault("apm_config.obfuscation.memcached.enabled", true, "DD_APM_OBFUSCATION_MEMCACHED_ENABLED")
 	config.BindEnvAndSetDefault("apm_config.obfuscation.memcached.keep_command", false, "DD_APM_OBFUSCATION_MEMCACHED_KEEP_COMMAND")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.graphql.enabled", false, "DD_APM_OBFUSCATION_GRAPHQL_ENABLED")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.graphql.keep_values", []string{}, "DD_APM_OBFUSCATION_GRAPHQL_KEEP_VALUES")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.cassandra.enabled", false, "DD_APM_OBFUSCATION_CASSANDRA_ENABLED")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.aws.enabled", false, "DD_APM_OBFUSCATION_AWS_ENABLED")
+	config.BindEnvAndSetDefault("apm_config.obfuscation.aws.obfuscate_paths", []string{}, "DD_APM_OBFUSCATION_AWS_OBFUSCATE_PATHS")
 	config.BindEnvAndSetDefault("apm_config.obfuscation.cache.enabled", true, "DD_APM_OBFUSCATION_CACHE_ENABLED")
 	config.BindEnvAndSetDefault("apm_config.obfuscation.cache.max_size", 5000000, "DD_APM_OBFUSCATION_CACHE_MAX_SIZE")
 	config.SetKnown("apm_config.filter_tags.require")
@@ -137,6 +142,34 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnv("apm_config.probabilistic_sampler.sampling_percentage", "DD_APM_PROBABILISTIC_SAMPLER_SAMPLING_PERCENTAGE")
 	config.BindEnv("apm_config.probabilistic_sampler.hash_seed", "DD_APM_PROBABILISTIC_SAMPLER_HASH_SEED")
 	config.BindEnvAndSetDefault("apm_config.error_tracking_standalone.enabled", false, "DD_APM_ERROR_TRACKING_STANDALONE_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.enabled", "DD_APM_TAIL_SAMPLING_ENABLED")
+	config.BindEnv("apm_config.tail_sampling.decision_wait", "DD_APM_TAIL_SAMPLING_DECISION_WAIT")
+	config.BindEnv("apm_config.tail_sampling.max_traces", "DD_APM_TAIL_SAMPLING_MAX_TRACES")
+	config.BindEnv("apm_config.tail_sampling.max_bytes", "DD_APM_TAIL_SAMPLING_MAX_BYTES")
+	config.BindEnv("apm_config.tail_sampling.policies", "DD_APM_TAIL_SAMPLING_POLICIES")
+	config.ParseEnvAsSlice("apm_config.tail_sampling.policies", func(in string) []interface{} {
+		var policies []interface{}
+		if err := json.Unmarshal([]byte(in), &policies); err != nil {
+			log.Errorf(`"apm_config.tail_sampling.policies" can not be parsed: %v`, err)
+		}
+		return policies
+	})
+	config.BindEnv("apm_config.otlp_traces_output.enabled", "DD_APM_OTLP_TRACES_OUTPUT_ENABLED")
+	config.BindEnv("apm_config.otlp_traces_output.endpoint", "DD_APM_OTLP_TRACES_OUTPUT_ENDPOINT")
+	config.BindEnv("apm_config.otlp_traces_output.protocol", "DD_APM_OTLP_TRACES_OUTPUT_PROTOCOL")
+	config.BindEnv("apm_config.otlp_traces_output.insecure", "DD_APM_OTLP_TRACES_OUTPUT_INSECURE")
+	config.BindEnv("apm_config.otlp_traces_output.headers", "DD_APM_OTLP_TRACES_OUTPUT_HEADERS")
+	config.ParseEnvAsMapStringInterface("apm_config.otlp_traces_output.headers", func(in string) map[string]interface{} {
+		var headers map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &headers); err != nil {
+			log.Errorf(`"apm_config.otlp_traces_output.headers" can not be parsed: %v`, err)
+		}
+		return headers
+	})
+	config.BindEnv("apm_config.payload_capture.enabled", "DD_APM_PAYLOAD_CAPTURE_ENABLED")
+	config.BindEnv("apm_config.payload_capture.dir", "DD_APM_PAYLOAD_CAPTURE_DIR")
+	config.BindEnv("apm_config.payload_capture.max_file_size", "DD_APM_PAYLOAD_CAPTURE_MAX_FILE_SIZE")
+	config.BindEnv("apm_config.payload_capture.max_files", "DD_APM_PAYLOAD_CAPTURE_MAX_FILES")
 
 	config.BindEnv("apm_config.max_memory", "DD_APM_MAX_MEMORY")
 	config.BindEnv("apm_config.max_cpu_percent", "DD_APM_MAX_CPU_PERCENT")
@@ -150,6 +183,8 @@ func setupAPM(config pkgconfigmodel.Setup) {
 	config.BindEnv("apm_config.profiling_additional_endpoints", "DD_APM_PROFILING_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.additional_endpoints", "DD_APM_ADDITIONAL_ENDPOINTS")
 	config.BindEnv("apm_config.replace_tags", "DD_APM_REPLACE_TAGS")
+	config.BindEnv("apm_config.span_rules", "DD_APM_SPAN_RULES")
+	config.BindEnv("apm_config.span_rules_file", "DD_APM_SPAN_RULES_FILE")
 	config.BindEnv("apm_config.analyzed_spans", "DD_APM_ANALYZED_SPANS")
 	config.BindEnv("apm_config.ignore_resources", "DD_APM_IGNORE_RESOURCES", "DD_IGNORE_RESOURCE")
 	config.BindEnv("apm_config.instrumentation.targets", "DD_APM_INSTRUMENTATION_TARGETS")
@@ -226,6 +261,13 @@ func setupAPM(config pkgconfigmodel.Setup) {
 		}
 		return out
 	})
+	config.ParseEnvAsSlice("apm_config.span_rules", func(in string) []interface{} {
+		var out []interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"apm_config.span_rules" can not be parsed: %v`, err)
+		}
+		return out
+	})
 
 	config.ParseEnvAsMapStringInterface("apm_config.analyzed_spans", func(in string) map[string]interface{} {
 		out, err := parseAnalyzedSpans(in)
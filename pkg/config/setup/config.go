@@ -1377,9 +1377,19 @@ func aggregator(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("aggregator_stop_timeout", 2)
 	config.BindEnvAndSetDefault("aggregator_buffer_size", 100)
 	config.BindEnvAndSetDefault("aggregator_use_tags_store", true)
+	config.BindEnvAndSetDefault("aggregator_metrics_query_enabled", false)
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_chan_size", 200)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_buffer_size", 4000)
+
+	config.BindEnv("aggregator_rollups")
+	config.ParseEnvAsSlice("aggregator_rollups", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"aggregator_rollups" can not be parsed: %v`, err)
+		}
+		return rules
+	})
 }
 
 func serverless(config pkgconfigmodel.Setup) {
@@ -1432,6 +1442,18 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("use_dogstatsd", true)
 	config.BindEnvAndSetDefault("dogstatsd_port", 8125)    // Notice: 0 means UDP port closed
 	config.BindEnvAndSetDefault("dogstatsd_pipe_name", "") // experimental and not officially supported for now.
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)   // Notice: 0 means TCP port closed
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.cert_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.key_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.client_ca_file", "") // Notice: empty means client certificates aren't verified
+	config.BindEnvAndSetDefault("dogstatsd_graphite_port", 0)           // Notice: 0 means Graphite port closed
+	config.BindEnvAndSetDefault("dogstatsd_graphite_pickle_port", 0)    // Notice: 0 means Graphite pickle port closed
+
+	config.BindEnvAndSetDefault("dogstatsd_prometheus_remote_write.port", 0) // Notice: 0 means the receiver is disabled
+	config.BindEnvAndSetDefault("dogstatsd_prometheus_remote_write.namespace", "")
+	config.BindEnvAndSetDefault("dogstatsd_prometheus_remote_write.label_to_tag", map[string]string{})
+	config.BindEnvAndSetDefault("dogstatsd_prometheus_remote_write.exclude_labels", []string{})
+	config.BindEnvAndSetDefault("dogstatsd_prometheus_remote_write.max_request_size", 10*1024*1024)
 	// Experimental and not officially supported for now.
 	// Options are: udp, uds, named_pipe
 	config.BindEnvAndSetDefault("dogstatsd_eol_required", []string{})
@@ -1464,6 +1486,10 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_expiry_seconds", 300)
 	// Control how long we keep dogstatsd contexts in memory.
 	config.BindEnvAndSetDefault("dogstatsd_context_expiry_seconds", 20)
+	// Cap the number of dogstatsd contexts of each metric name and origin, 0 means no limit.
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.metric_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.origin_limit", 0)
+	config.BindEnvAndSetDefault("dogstatsd_context_limiter.strategy", "drop")
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection", false) // Only supported for socket traffic
 	config.BindEnvAndSetDefault("dogstatsd_origin_detection_client", false)
 	config.BindEnvAndSetDefault("dogstatsd_origin_optout_enabled", true)
@@ -1510,6 +1536,15 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 		return mappings
 	})
 
+	config.BindEnv("dogstatsd_tag_rules")
+	config.ParseEnvAsSlice("dogstatsd_tag_rules", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"dogstatsd_tag_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+
 	config.BindEnvAndSetDefault("statsd_forward_host", "")
 	config.BindEnvAndSetDefault("statsd_forward_port", 0)
 	config.BindEnvAndSetDefault("statsd_metric_namespace", "")
@@ -1521,6 +1556,14 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution_prefix", "")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
 	config.BindEnvAndSetDefault("histogram_percentiles", []string{"0.95"})
+	config.BindEnv("histogram_overrides")
+	config.ParseEnvAsSlice("histogram_overrides", func(in string) []interface{} {
+		var overrides []interface{}
+		if err := json.Unmarshal([]byte(in), &overrides); err != nil {
+			log.Errorf(`"histogram_overrides" can not be parsed: %v`, err)
+		}
+		return overrides
+	})
 }
 
 func logsagent(config pkgconfigmodel.Setup) {
@@ -1607,6 +1650,21 @@ func logsagent(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("logs_config.message_channel_size", 100)
 	config.BindEnvAndSetDefault("logs_config.payload_channel_size", 10)
 
+	// buffer payloads on disk while the logs intake is unreachable
+	config.BindEnvAndSetDefault("logs_config.disk_buffer.enabled", false)
+	config.BindEnvAndSetDefault("logs_config.disk_buffer.path", "")
+	config.BindEnvAndSetDefault("logs_config.disk_buffer.max_size_in_bytes", 100*1024*1024)
+
+	// OpenTelemetry collectors logs are dual-shipped to
+	config.BindEnv("logs_config.otlp_endpoints")
+
+	// read gzip and zstd compressed files matched by file sources once, from start to end
+	config.BindEnvAndSetDefault("logs_config.read_compressed_files", false)
+
+	// identify log files by a hash of their first bytes, in addition to their path
+	config.BindEnvAndSetDefault("logs_config.fingerprint.enabled", false)
+	config.BindEnvAndSetDefault("logs_config.fingerprint.byte_count", 1024)
+
 	// maximum time that the unix tailer will hold a log file open after it has been rotated
 	config.BindEnvAndSetDefault("logs_config.close_timeout", 60)
 	// maximum time that the windows tailer will hold a log file open, while waiting for
@@ -1618,6 +1676,7 @@ func logsagent(config pkgconfigmodel.Setup) {
 	config.BindEnv("logs_config.auto_multi_line_detection_custom_samples")
 	config.SetKnown("logs_config.auto_multi_line_detection_custom_samples")
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line.enable_json_detection", true)
+	config.BindEnvAndSetDefault("logs_config.auto_multi_line.enable_json_aggregation", false)
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line.enable_datetime_detection", true)
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line.timestamp_detector_match_threshold", 0.5)
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line.tokenizer_max_input_bytes", 60)
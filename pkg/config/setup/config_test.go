@@ -1497,6 +1497,11 @@ func TestAPMObfuscationDefaultValue(t *testing.T) {
 	assert.False(t, conf.GetBool("apm_config.obfuscation.redis.remove_all_args"))
 	assert.True(t, conf.GetBool("apm_config.obfuscation.memcached.enabled"))
 	assert.False(t, conf.GetBool("apm_config.obfuscation.memcached.keep_command"))
+	assert.False(t, conf.GetBool("apm_config.obfuscation.graphql.enabled"))
+	assert.Len(t, conf.GetStringSlice("apm_config.obfuscation.graphql.keep_values"), 0)
+	assert.False(t, conf.GetBool("apm_config.obfuscation.cassandra.enabled"))
+	assert.False(t, conf.GetBool("apm_config.obfuscation.aws.enabled"))
+	assert.Len(t, conf.GetStringSlice("apm_config.obfuscation.aws.obfuscate_paths"), 0)
 	assert.True(t, conf.GetBool("apm_config.obfuscation.credit_cards.enabled"))
 	assert.False(t, conf.GetBool("apm_config.obfuscation.credit_cards.luhn"))
 	assert.Len(t, conf.GetStringSlice("apm_config.obfuscation.credit_cards.keep_values"), 0)
@@ -33,6 +33,12 @@ const registryAPIVersion = 2
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	// GetFingerprint returns the fingerprint of the content the offset of the
+	// identifier refers to, if any.
+	GetFingerprint(identifier string) string
+	// KeepAlive prevents the entry of the identifier from expiring, for sources
+	// which don't send logs anymore but whose offset must be remembered.
+	KeepAlive(identifier string)
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -42,6 +48,7 @@ type RegistryEntry struct {
 	Offset             string
 	TailingMode        string
 	IngestionTimestamp int64
+	Fingerprint        string `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -150,6 +157,26 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetFingerprint returns the last committed fingerprint for a given identifier,
+// returns an empty string if it does not exist.
+func (a *RegistryAuditor) GetFingerprint(identifier string) string {
+	entry, exists := a.readOnlyRegistryEntryCopy(identifier)
+	if !exists {
+		return ""
+	}
+	return entry.Fingerprint
+}
+
+// KeepAlive refreshes the last update of the entry of the identifier, if any,
+// so that it doesn't expire.
+func (a *RegistryAuditor) KeepAlive(identifier string) {
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	if entry, exists := a.registry[identifier]; exists {
+		entry.LastUpdated = time.Now().UTC()
+	}
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -172,7 +199,7 @@ func (a *RegistryAuditor) run() {
 			}
 			// update the registry with new entry
 			for _, msg := range payload.MessageMetas {
-				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.IngestionTimestamp)
+				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.Origin.Fingerprint, msg.IngestionTimestamp)
 			}
 		case <-cleanUpTicker.C:
 			// remove expired offsets from registry
@@ -225,8 +252,8 @@ func (a *RegistryAuditor) cleanupRegistry() {
 	}
 }
 
-// updateRegistry updates the registry entry matching identifier with new the offset and timestamp
-func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, ingestionTimestamp int64) {
+// updateRegistry updates the registry entry matching identifier with new the offset, fingerprint and timestamp
+func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, fingerprint string, ingestionTimestamp int64) {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	if identifier == "" {
@@ -249,6 +276,7 @@ func (a *RegistryAuditor) updateRegistry(identifier string, offset string, taili
 		Offset:             offset,
 		TailingMode:        tailingMode,
 		IngestionTimestamp: ingestionTimestamp,
+		Fingerprint:        fingerprint,
 	}
 }
 
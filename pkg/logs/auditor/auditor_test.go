@@ -51,14 +51,33 @@ func (suite *AuditorTestSuite) TestAuditorStartStop() {
 func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.Equal(0, len(suite.a.registry))
-	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", 0)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", "", 0)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("end", suite.a.registry[suite.source.Config.Path].TailingMode)
-	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", 1)
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", "1024:c1b5d2e3f4a59687", 1)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("43", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("beginning", suite.a.registry[suite.source.Config.Path].TailingMode)
+	suite.Equal("1024:c1b5d2e3f4a59687", suite.a.GetFingerprint(suite.source.Config.Path))
+}
+
+func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversFingerprint() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+		TailingMode: "end",
+		Fingerprint: "1024:c1b5d2e3f4a59687",
+	}
+	suite.NoError(suite.a.flushRegistry())
+	r, err := os.ReadFile(suite.testRegistryPath)
+	suite.NoError(err)
+	suite.Equal("{\"Version\":2,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"IngestionTimestamp\":0,\"Fingerprint\":\"1024:c1b5d2e3f4a59687\"}}}", string(r))
+
+	suite.a.registry = suite.a.recoverRegistry()
+	suite.Equal("1024:c1b5d2e3f4a59687", suite.a.GetFingerprint(suite.source.Config.Path))
+	suite.Equal("", suite.a.GetFingerprint("anotherpath"))
 }
 
 func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
@@ -112,6 +131,21 @@ func (suite *AuditorTestSuite) TestAuditorCleansupRegistry() {
 	suite.Equal("43", suite.a.registry[otherpath].Offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorKeepAlive() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+	}
+
+	suite.a.KeepAlive(suite.source.Config.Path)
+	suite.a.KeepAlive("unknown")
+	suite.Equal(1, len(suite.a.registry))
+
+	suite.a.cleanupRegistry()
+	suite.Equal("42", suite.a.GetOffset(suite.source.Config.Path))
+}
+
 func TestScannerTestSuite(t *testing.T) {
 	suite.Run(t, new(AuditorTestSuite))
 }
@@ -10,6 +10,7 @@ package mock
 type Registry struct {
 	offset      string
 	tailingMode string
+	fingerprint string
 }
 
 // NewRegistry returns a new registry.
@@ -32,6 +33,19 @@ func (r *Registry) GetTailingMode(_ string) string {
 	return r.tailingMode
 }
 
+// GetFingerprint returns the fingerprint.
+func (r *Registry) GetFingerprint(_ string) string {
+	return r.fingerprint
+}
+
+// SetFingerprint sets the fingerprint.
+func (r *Registry) SetFingerprint(fingerprint string) {
+	r.fingerprint = fingerprint
+}
+
+// KeepAlive does nothing.
+func (r *Registry) KeepAlive(_ string) {}
+
 // SetTailingMode sets the tailing mode.
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
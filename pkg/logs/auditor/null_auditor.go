@@ -36,6 +36,12 @@ func (a *NullAuditor) GetOffset(_ string) string { return "" }
 //nolint:revive // TODO(AML) Fix revive linter
 func (a *NullAuditor) GetTailingMode(_ string) string { return "" }
 
+// GetFingerprint returns an empty string.
+func (a *NullAuditor) GetFingerprint(_ string) string { return "" }
+
+// KeepAlive does nothing.
+func (a *NullAuditor) KeepAlive(_ string) {}
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
@@ -0,0 +1,174 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const (
+	protobufContentType = "application/x-protobuf"
+	defaultHTTPPath     = "/v1/logs"
+)
+
+// logsClient exports logs to a collector with one of the OTLP transports.
+type logsClient interface {
+	export(ctx context.Context, request plogotlp.ExportRequest) error
+	close()
+}
+
+// newLogsClient returns the client of the endpoint and the address it sends logs to.
+func newLogsClient(endpoint config.OTLPEndpoint, cfg pkgconfigmodel.Reader) (logsClient, string, error) {
+	switch endpoint.Protocol {
+	case config.OTLPProtocolGRPC:
+		return newGRPCClient(endpoint)
+	case config.OTLPProtocolHTTP:
+		target, err := buildHTTPURL(endpoint)
+		if err != nil {
+			return nil, "", err
+		}
+		// reusing core agent HTTP transport to benefit from proxy settings.
+		return &httpClient{
+			url:     target,
+			headers: endpoint.Headers,
+			client:  &http.Client{Timeout: sendTimeout, Transport: httputils.CreateHTTPTransport(cfg)},
+		}, target, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported OTLP protocol %q", endpoint.Protocol)
+	}
+}
+
+// httpClient posts logs as protobuf over HTTP.
+type httpClient struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (c *httpClient) export(ctx context.Context, request plogotlp.ExportRequest) error {
+	body, err := request.MarshalProto()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", protobufContentType)
+	req.Header.Set("User-Agent", fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(response))
+	}
+	return nil
+}
+
+func (c *httpClient) close() {
+	c.client.CloseIdleConnections()
+}
+
+// grpcClient calls the export method of the OTLP logs service.
+type grpcClient struct {
+	conn    *grpc.ClientConn
+	client  plogotlp.GRPCClient
+	headers metadata.MD
+}
+
+func newGRPCClient(endpoint config.OTLPEndpoint) (logsClient, string, error) {
+	u, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, "", err
+	}
+	creds := insecure.NewCredentials()
+	if u.Scheme == "https" {
+		creds = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	conn, err := grpc.NewClient(u.Host,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUserAgent(fmt.Sprintf("datadog-agent/%s", version.AgentVersion)),
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid OTLP endpoint %s: %v", endpoint.Endpoint, err)
+	}
+	return &grpcClient{
+		conn:    conn,
+		client:  plogotlp.NewGRPCClient(conn),
+		headers: metadata.New(endpoint.Headers),
+	}, u.Host, nil
+}
+
+func (c *grpcClient) export(ctx context.Context, request plogotlp.ExportRequest) error {
+	_, err := c.client.Export(metadata.NewOutgoingContext(ctx, c.headers), request)
+	return err
+}
+
+func (c *grpcClient) close() {
+	c.conn.Close()
+}
+
+// buildHTTPURL returns the URL logs are posted to, defaulting to the standard
+// OTLP logs path when the endpoint has none.
+func buildHTTPURL(endpoint config.OTLPEndpoint) (string, error) {
+	u, err := parseEndpoint(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultHTTPPath
+	}
+	return u.String(), nil
+}
+
+// parseEndpoint parses the address of the endpoint, which may omit the scheme,
+// in which case TLS is used unless the endpoint is insecure.
+func parseEndpoint(endpoint config.OTLPEndpoint) (*url.URL, error) {
+	address := endpoint.Endpoint
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		if endpoint.Insecure {
+			address = "http://" + address
+		} else {
+			address = "https://" + address
+		}
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint %s: %v", endpoint.Endpoint, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %s: missing host", endpoint.Endpoint)
+	}
+	return u, nil
+}
@@ -0,0 +1,130 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// Attribute names of the resources and log records.
+const (
+	hostNameAttribute    = "host.name"
+	serviceNameAttribute = "service.name"
+	sourceAttribute      = "ddsource"
+	tagsAttribute        = "ddtags"
+	scopeNameValue       = "datadog-agent"
+)
+
+// logEntry holds the fields of a rendered message exported to OTLP, the same
+// ones the JSON encoder of the logs processor sends to Datadog.
+type logEntry struct {
+	message      string
+	status       string
+	timestamp    time.Time
+	observedTime time.Time
+	hostname     string
+	service      string
+	source       string
+	tags         string
+}
+
+// newLogEntry returns the log entry of a rendered message.
+func newLogEntry(msg *message.Message, hostname string, now time.Time) logEntry {
+	return logEntry{
+		message:      strings.ToValidUTF8(string(msg.GetContent()), "\uFFFD"),
+		status:       msg.GetStatus(),
+		timestamp:    msg.ServerlessExtra.Timestamp,
+		observedTime: now,
+		hostname:     hostname,
+		service:      msg.Origin.Service(),
+		source:       msg.Origin.Source(),
+		tags:         msg.TagsToString(),
+	}
+}
+
+// resource identifies the entity producing logs.
+type resource struct {
+	hostname string
+	service  string
+}
+
+// batch groups log entries by resource, in order of appearance.
+type batch struct {
+	logs    plog.Logs
+	records map[resource]plog.LogRecordSlice
+	size    int
+}
+
+func newBatch() *batch {
+	return &batch{
+		logs:    plog.NewLogs(),
+		records: make(map[resource]plog.LogRecordSlice),
+	}
+}
+
+// add appends the entry to the log records of its resource.
+func (b *batch) add(entry logEntry) {
+	r := resource{hostname: entry.hostname, service: entry.service}
+	records, found := b.records[r]
+	if !found {
+		resourceLogs := b.logs.ResourceLogs().AppendEmpty()
+		attributes := resourceLogs.Resource().Attributes()
+		if r.hostname != "" {
+			attributes.PutStr(hostNameAttribute, r.hostname)
+		}
+		if r.service != "" {
+			attributes.PutStr(serviceNameAttribute, r.service)
+		}
+		scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+		scopeLogs.Scope().SetName(scopeNameValue)
+		scopeLogs.Scope().SetVersion(version.AgentVersion)
+		records = scopeLogs.LogRecords()
+		b.records[r] = records
+	}
+
+	record := records.AppendEmpty()
+	if !entry.timestamp.IsZero() {
+		record.SetTimestamp(pcommon.NewTimestampFromTime(entry.timestamp))
+	}
+	record.SetObservedTimestamp(pcommon.NewTimestampFromTime(entry.observedTime))
+	record.SetSeverityNumber(severityNumber(entry.status))
+	record.SetSeverityText(entry.status)
+	record.Body().SetStr(entry.message)
+	if entry.source != "" {
+		record.Attributes().PutStr(sourceAttribute, entry.source)
+	}
+	if entry.tags != "" {
+		record.Attributes().PutStr(tagsAttribute, entry.tags)
+	}
+	b.size++
+}
+
+// severityNumber maps the status of a log to an OTLP severity number.
+func severityNumber(status string) plog.SeverityNumber {
+	switch status {
+	case message.StatusEmergency, message.StatusAlert, message.StatusCritical:
+		return plog.SeverityNumberFatal
+	case message.StatusError:
+		return plog.SeverityNumberError
+	case message.StatusWarning:
+		return plog.SeverityNumberWarn
+	case message.StatusNotice:
+		return plog.SeverityNumberInfo2
+	case message.StatusInfo:
+		return plog.SeverityNumberInfo
+	case message.StatusDebug:
+		return plog.SeverityNumberDebug
+	default:
+		return plog.SeverityNumberUnspecified
+	}
+}
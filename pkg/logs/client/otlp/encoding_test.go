@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// newRenderedMessage returns a rendered message of a source with the given service.
+func newRenderedMessage(content string, status string, service string) *message.Message {
+	source := sources.NewLogSource("", &config.LogsConfig{Service: service, Source: "go", Tags: []string{"env:prod"}})
+	msg := message.NewMessageWithSource(nil, status, source, 0)
+	msg.SetRendered([]byte(content))
+	return msg
+}
+
+func TestNewLogEntry(t *testing.T) {
+	now := time.Unix(1700000001, 0)
+	entry := newLogEntry(newRenderedMessage("hello \xff", message.StatusError, "api"), "host", now)
+	assert.Equal(t, logEntry{
+		message:      "hello \uFFFD",
+		status:       message.StatusError,
+		observedTime: now,
+		hostname:     "host",
+		service:      "api",
+		source:       "go",
+		tags:         "env:prod",
+	}, entry)
+}
+
+func TestBatch(t *testing.T) {
+	observed := time.Unix(1700000001, 0)
+	timestamp := time.Unix(1700000000, 0)
+	b := newBatch()
+	b.add(logEntry{message: "first", status: "error", timestamp: timestamp, observedTime: observed, hostname: "host", service: "api", source: "go", tags: "env:prod,team:a"})
+	b.add(logEntry{message: "other", status: "debug", observedTime: observed, hostname: "host", service: "worker"})
+	b.add(logEntry{message: "second", status: "warn", observedTime: observed, hostname: "host", service: "api"})
+	assert.Equal(t, 3, b.size)
+
+	// logs are grouped by resource
+	resourceLogs := b.logs.ResourceLogs()
+	require.Equal(t, 2, resourceLogs.Len())
+	assert.Equal(t, map[string]any{hostNameAttribute: "host", serviceNameAttribute: "api"}, resourceLogs.At(0).Resource().Attributes().AsRaw())
+	assert.Equal(t, map[string]any{hostNameAttribute: "host", serviceNameAttribute: "worker"}, resourceLogs.At(1).Resource().Attributes().AsRaw())
+	require.Equal(t, 1, resourceLogs.At(0).ScopeLogs().Len())
+	assert.Equal(t, scopeNameValue, resourceLogs.At(0).ScopeLogs().At(0).Scope().Name())
+
+	records := resourceLogs.At(0).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 2, records.Len())
+	first := records.At(0)
+	assert.Equal(t, pcommon.NewTimestampFromTime(timestamp), first.Timestamp())
+	assert.Equal(t, pcommon.NewTimestampFromTime(observed), first.ObservedTimestamp())
+	assert.Equal(t, plog.SeverityNumberError, first.SeverityNumber())
+	assert.Equal(t, "error", first.SeverityText())
+	assert.Equal(t, "first", first.Body().Str())
+	assert.Equal(t, map[string]any{sourceAttribute: "go", tagsAttribute: "env:prod,team:a"}, first.Attributes().AsRaw())
+
+	second := records.At(1)
+	assert.Equal(t, pcommon.Timestamp(0), second.Timestamp())
+	assert.Equal(t, 0, second.Attributes().Len())
+	assert.Equal(t, plog.SeverityNumberWarn, second.SeverityNumber())
+
+	other := resourceLogs.At(1).ScopeLogs().At(0).LogRecords()
+	require.Equal(t, 1, other.Len())
+	assert.Equal(t, plog.SeverityNumberDebug, other.At(0).SeverityNumber())
+}
+
+func TestSeverityNumber(t *testing.T) {
+	assert.Equal(t, plog.SeverityNumberFatal, severityNumber("emergency"))
+	assert.Equal(t, plog.SeverityNumberFatal, severityNumber("critical"))
+	assert.Equal(t, plog.SeverityNumberError, severityNumber("error"))
+	assert.Equal(t, plog.SeverityNumberWarn, severityNumber("warn"))
+	assert.Equal(t, plog.SeverityNumberInfo2, severityNumber("notice"))
+	assert.Equal(t, plog.SeverityNumberInfo, severityNumber("info"))
+	assert.Equal(t, plog.SeverityNumberDebug, severityNumber("debug"))
+	assert.Equal(t, plog.SeverityNumberUnspecified, severityNumber("unknown"))
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package otlp implements an exporter sending logs to an OpenTelemetry
+// collector using the OTLP protocol over HTTP or gRPC.
+package otlp
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	sendTimeout = 10 * time.Second
+	// queueSize is the number of logs waiting to be batched, logs exported
+	// while the queue is full are dropped.
+	queueSize = 1000
+)
+
+var (
+	tlmSend    = telemetry.NewCounter("logs_client_otlp_exporter", "send", []string{"endpoint", "error"}, "Payloads sent to OTLP endpoints")
+	tlmLogs    = telemetry.NewCounter("logs_client_otlp_exporter", "logs_sent", []string{"endpoint"}, "Logs sent to OTLP endpoints")
+	tlmDropped = telemetry.NewCounter("logs_client_otlp_exporter", "logs_dropped", []string{"endpoint"}, "Logs dropped because the OTLP exporter queue was full")
+)
+
+// Exporter sends logs to an OpenTelemetry collector, converting them to OTLP.
+// It's meant to be used as an additional, unreliable, output: logs which can't
+// be sent are dropped.
+type Exporter struct {
+	target              string
+	client              logsClient
+	destinationsContext *client.DestinationsContext
+	batchWait           time.Duration
+	batchMaxSize        int
+	input               chan logEntry
+	done                chan struct{}
+}
+
+// NewExporter returns a new exporter for the OTLP endpoint, sending logs in
+// batches of at most batchMaxSize logs, at least every batchWait.
+func NewExporter(endpoint config.OTLPEndpoint, destinationsContext *client.DestinationsContext, batchWait time.Duration, batchMaxSize int, cfg pkgconfigmodel.Reader) (*Exporter, error) {
+	c, target, err := newLogsClient(endpoint, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{
+		target:              target,
+		client:              c,
+		destinationsContext: destinationsContext,
+		batchWait:           batchWait,
+		batchMaxSize:        batchMaxSize,
+		input:               make(chan logEntry, queueSize),
+		done:                make(chan struct{}),
+	}, nil
+}
+
+// Target is the address of the collector.
+func (e *Exporter) Target() string {
+	return e.target
+}
+
+// Export queues a rendered message to be sent, dropping it when the queue is
+// full.  It must not be called once the exporter is stopped.
+func (e *Exporter) Export(msg *message.Message, hostname string) {
+	select {
+	case e.input <- newLogEntry(msg, hostname, time.Now()):
+	default:
+		tlmDropped.Inc(e.target)
+	}
+}
+
+// Start starts sending the exported logs.
+func (e *Exporter) Start() {
+	go e.run()
+}
+
+// Stop sends the queued logs and stops the exporter.
+func (e *Exporter) Stop() {
+	close(e.input)
+	<-e.done
+	e.client.close()
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(e.batchWait)
+	defer ticker.Stop()
+
+	b := newBatch()
+	for {
+		select {
+		case entry, ok := <-e.input:
+			if !ok {
+				e.send(b)
+				return
+			}
+			b.add(entry)
+			if b.size >= e.batchMaxSize {
+				e.send(b)
+				b = newBatch()
+			}
+		case <-ticker.C:
+			if b.size > 0 {
+				e.send(b)
+				b = newBatch()
+			}
+		}
+	}
+}
+
+func (e *Exporter) send(b *batch) {
+	if b.size == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(e.destinationsContext.Context(), sendTimeout)
+	defer cancel()
+	err := e.client.export(ctx, plogotlp.NewExportRequestFromLogs(b.logs))
+	if err != nil && e.destinationsContext.Context().Err() != nil {
+		// the agent is stopping
+		return
+	}
+	tlmSend.Inc(e.target, errorToTag(err))
+	if err != nil {
+		log.Warnf("Could not send logs to %s: %v", e.target, err)
+		return
+	}
+	tlmLogs.Add(float64(b.size), e.target)
+}
+
+func errorToTag(err error) string {
+	if err == nil {
+		return "none"
+	}
+	return "error"
+}
@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+type receivedRequest struct {
+	path    string
+	headers http.Header
+	request plogotlp.ExportRequest
+}
+
+// newExporter returns a started exporter for the endpoint, batching the given
+// number of logs.
+func newExporter(t *testing.T, endpoint config.OTLPEndpoint, batchMaxSize int) *Exporter {
+	destCtx := client.NewDestinationsContext()
+	destCtx.Start()
+	t.Cleanup(destCtx.Stop)
+
+	exporter, err := NewExporter(endpoint, destCtx, time.Hour, batchMaxSize, configmock.New(t))
+	require.NoError(t, err)
+	exporter.Start()
+	return exporter
+}
+
+func newHTTPServer(t *testing.T, statusCode int, requests chan receivedRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		request := plogotlp.NewExportRequest()
+		assert.NoError(t, request.UnmarshalProto(body))
+		requests <- receivedRequest{path: r.URL.Path, headers: r.Header, request: request}
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPExporter(t *testing.T) {
+	requests := make(chan receivedRequest, 1)
+	server := newHTTPServer(t, http.StatusOK, requests)
+
+	exporter := newExporter(t, config.OTLPEndpoint{
+		Endpoint: server.URL,
+		Protocol: config.OTLPProtocolHTTP,
+		Headers:  map[string]string{"x-token": "secret"},
+	}, 2)
+	defer exporter.Stop()
+
+	exporter.Export(newRenderedMessage("first", message.StatusInfo, "api"), "host")
+	exporter.Export(newRenderedMessage("second", message.StatusInfo, "worker"), "host")
+
+	request := <-requests
+	assert.Equal(t, defaultHTTPPath, request.path)
+	assert.Equal(t, protobufContentType, request.headers.Get("Content-Type"))
+	assert.Equal(t, "secret", request.headers.Get("X-Token"))
+
+	logs := request.request.Logs()
+	assert.Equal(t, 2, logs.LogRecordCount())
+	require.Equal(t, 2, logs.ResourceLogs().Len())
+	assert.Equal(t, map[string]any{hostNameAttribute: "host", serviceNameAttribute: "api"}, logs.ResourceLogs().At(0).Resource().Attributes().AsRaw())
+	assert.Equal(t, "first", logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+}
+
+func TestHTTPExporterDropsOnError(t *testing.T) {
+	requests := make(chan receivedRequest, 2)
+	server := newHTTPServer(t, http.StatusServiceUnavailable, requests)
+
+	exporter := newExporter(t, config.OTLPEndpoint{
+		Endpoint: server.URL + "/custom/path",
+		Protocol: config.OTLPProtocolHTTP,
+	}, 1)
+	exporter.Export(newRenderedMessage("first", message.StatusInfo, "api"), "host")
+	exporter.Export(newRenderedMessage("second", message.StatusInfo, "api"), "host")
+	exporter.Stop()
+
+	// the logs are not retried
+	assert.Equal(t, "first", (<-requests).request.Logs().ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+	assert.Equal(t, "second", (<-requests).request.Logs().ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+	assert.Empty(t, requests)
+}
+
+func TestExporterSendsOnStop(t *testing.T) {
+	requests := make(chan receivedRequest, 1)
+	server := newHTTPServer(t, http.StatusOK, requests)
+
+	exporter := newExporter(t, config.OTLPEndpoint{
+		Endpoint: server.URL,
+		Protocol: config.OTLPProtocolHTTP,
+	}, 100)
+	exporter.Export(newRenderedMessage("first", message.StatusInfo, "api"), "host")
+	exporter.Stop()
+
+	assert.Equal(t, 1, (<-requests).request.Logs().LogRecordCount())
+}
+
+type grpcServer struct {
+	plogotlp.UnimplementedGRPCServer
+	err      error
+	requests chan plogotlp.ExportRequest
+	headers  chan metadata.MD
+}
+
+func (s *grpcServer) Export(ctx context.Context, request plogotlp.ExportRequest) (plogotlp.ExportResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.headers <- md
+	s.requests <- request
+	return plogotlp.NewExportResponse(), s.err
+}
+
+// newGRPCServer starts a gRPC server answering export calls with the given error.
+func newGRPCServer(t *testing.T, err error) (*grpcServer, string) {
+	listener, lerr := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, lerr)
+	srv := &grpcServer{err: err, requests: make(chan plogotlp.ExportRequest, 1), headers: make(chan metadata.MD, 1)}
+	server := grpc.NewServer()
+	plogotlp.RegisterGRPCServer(server, srv)
+	go server.Serve(listener) //nolint:errcheck
+	t.Cleanup(server.Stop)
+	return srv, listener.Addr().String()
+}
+
+func TestGRPCExporter(t *testing.T) {
+	srv, address := newGRPCServer(t, nil)
+
+	exporter := newExporter(t, config.OTLPEndpoint{
+		Endpoint: address,
+		Protocol: config.OTLPProtocolGRPC,
+		Headers:  map[string]string{"x-token": "secret"},
+		Insecure: true,
+	}, 1)
+	defer exporter.Stop()
+	assert.Equal(t, address, exporter.Target())
+
+	exporter.Export(newRenderedMessage("hello", message.StatusWarning, "api"), "host")
+
+	assert.Equal(t, []string{"secret"}, (<-srv.headers).Get("x-token"))
+	logs := (<-srv.requests).Logs()
+	require.Equal(t, 1, logs.LogRecordCount())
+	assert.Equal(t, "hello", logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+}
+
+func TestGRPCExporterSendError(t *testing.T) {
+	_, address := newGRPCServer(t, status.Error(codes.Unavailable, "collector unavailable"))
+
+	c, _, err := newLogsClient(config.OTLPEndpoint{
+		Endpoint: "http://" + address,
+		Protocol: config.OTLPProtocolGRPC,
+	}, configmock.New(t))
+	require.NoError(t, err)
+	defer c.close()
+
+	b := newBatch()
+	b.add(newLogEntry(newRenderedMessage("hello", message.StatusInfo, "api"), "host", time.Now()))
+	err = c.export(context.Background(), plogotlp.NewExportRequestFromLogs(b.logs))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestBuildURL(t *testing.T) {
+	for _, tc := range []struct {
+		endpoint config.OTLPEndpoint
+		expected string
+	}{
+		{config.OTLPEndpoint{Endpoint: "https://collector:4318"}, "https://collector:4318/v1/logs"},
+		{config.OTLPEndpoint{Endpoint: "http://collector:4318/"}, "http://collector:4318/v1/logs"},
+		{config.OTLPEndpoint{Endpoint: "https://collector/custom/logs"}, "https://collector/custom/logs"},
+		{config.OTLPEndpoint{Endpoint: "collector:4318"}, "https://collector:4318/v1/logs"},
+		{config.OTLPEndpoint{Endpoint: "collector:4318", Insecure: true}, "http://collector:4318/v1/logs"},
+	} {
+		url, err := buildHTTPURL(tc.endpoint)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, url)
+	}
+
+	_, err := buildHTTPURL(config.OTLPEndpoint{Endpoint: "https://"})
+	assert.Error(t, err)
+	_, err = NewExporter(config.OTLPEndpoint{Endpoint: "collector:4317", Protocol: "zipkin"}, client.NewDestinationsContext(), time.Second, 1, configmock.New(t))
+	assert.Error(t, err)
+}
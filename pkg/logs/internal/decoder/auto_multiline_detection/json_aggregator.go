@@ -0,0 +1,238 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package automultilinedetection contains auto multiline detection and aggregation logic.
+package automultilinedetection
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+type jsonScanState int
+
+const (
+	jsonIncomplete jsonScanState = iota
+	jsonComplete
+	jsonInvalid
+)
+
+// JSONAggregator joins the lines of JSON documents spanning several lines, such
+// as pretty-printed objects, into a single message.  It tracks the depth of
+// braces and brackets, ignoring those found in strings, to find the end of a
+// document.  Lines which are not part of a multi-line document are labeled and
+// aggregated as usual.
+//
+// Documents are compacted, so that the message is valid JSON and can be parsed
+// by the intake.  When the lines turn out not to be a valid JSON document, or
+// the document is larger than the maximum content size, the lines are labeled
+// and aggregated one by one as if they had not been buffered.
+type JSONAggregator struct {
+	labeler          *Labeler
+	aggregator       *Aggregator
+	maxContentSize   int
+	tagMultiLineLogs bool
+	flushTimeout     time.Duration
+	flushTimer       *time.Timer
+	documentsInfo    *status.CountInfo
+
+	lines    []*message.Message
+	size     int
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+// NewJSONAggregator creates a new JSON aggregator, feeding the labeler and the
+// aggregator with the lines which are not part of a multi-line JSON document.
+func NewJSONAggregator(labeler *Labeler, aggregator *Aggregator, maxContentSize int, flushTimeout time.Duration, tagMultiLineLogs bool, tailerInfo *status.InfoRegistry) *JSONAggregator {
+	documentsInfo := status.NewCountInfo("JSON documents combined")
+	tailerInfo.Register(documentsInfo)
+
+	return &JSONAggregator{
+		labeler:          labeler,
+		aggregator:       aggregator,
+		maxContentSize:   maxContentSize,
+		tagMultiLineLogs: tagMultiLineLogs,
+		flushTimeout:     flushTimeout,
+		documentsInfo:    documentsInfo,
+	}
+}
+
+// Process processes a line, buffering it if it's part of a multi-line JSON
+// document.
+func (j *JSONAggregator) Process(msg *message.Message) {
+	j.stopFlushTimerIfNeeded()
+	defer j.startFlushTimerIfNeeded()
+
+	content := msg.GetContent()
+	if len(j.lines) == 0 {
+		if !startsJSONDocument(content) {
+			j.aggregate(msg)
+			return
+		}
+		j.depth, j.inString, j.escaped = 0, false, false
+		if j.scan(content) != jsonIncomplete {
+			// a document on a single line, or not a document at all
+			j.aggregate(msg)
+			return
+		}
+		j.lines = append(j.lines, msg)
+		j.size = len(content)
+		return
+	}
+
+	j.lines = append(j.lines, msg)
+	j.size += len(content)
+	switch j.scan(content) {
+	case jsonComplete:
+		j.flushDocument()
+	case jsonInvalid:
+		j.flushLines()
+	default:
+		if j.size >= j.maxContentSize {
+			j.flushLines()
+		}
+	}
+}
+
+// FlushChan returns the flush timer channel.
+func (j *JSONAggregator) FlushChan() <-chan time.Time {
+	if len(j.lines) > 0 && j.flushTimer != nil {
+		return j.flushTimer.C
+	}
+	return j.aggregator.FlushChan()
+}
+
+// Flush flushes the lines of an incomplete document and the aggregator.
+func (j *JSONAggregator) Flush() {
+	j.flushLines()
+	j.aggregator.Flush()
+}
+
+// startsJSONDocument returns whether the first non-whitespace character of the
+// content opens an object or an array.
+func startsJSONDocument(content []byte) bool {
+	trimmed := bytes.TrimLeft(content, " \t")
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+// scan updates the depth of the document with the content of a line and
+// returns whether the document is complete.
+func (j *JSONAggregator) scan(content []byte) jsonScanState {
+	for i, c := range content {
+		if j.inString {
+			switch {
+			case j.escaped:
+				j.escaped = false
+			case c == '\\':
+				j.escaped = true
+			case c == '"':
+				j.inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			j.inString = true
+		case '{', '[':
+			j.depth++
+		case '}', ']':
+			j.depth--
+			if j.depth < 0 {
+				return jsonInvalid
+			}
+			if j.depth == 0 {
+				// nothing but whitespace may follow the end of the document
+				if len(bytes.TrimSpace(content[i+1:])) > 0 {
+					return jsonInvalid
+				}
+				return jsonComplete
+			}
+		}
+	}
+	if j.inString {
+		// JSON strings can't span several lines
+		return jsonInvalid
+	}
+	return jsonIncomplete
+}
+
+// flushDocument compacts the buffered lines into a single message, which is
+// never aggregated with other lines.
+func (j *JSONAggregator) flushDocument() {
+	var raw bytes.Buffer
+	rawDataLen := 0
+	for _, line := range j.lines {
+		raw.Write(line.GetContent())
+		rawDataLen += line.RawDataLen
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, raw.Bytes()); err != nil {
+		j.flushLines()
+		return
+	}
+
+	msg := j.lines[0]
+	msg.SetContent(compacted.Bytes())
+	msg.RawDataLen = rawDataLen
+	msg.ParsingExtra.IsMultiLine = true
+	if j.tagMultiLineLogs {
+		msg.ParsingExtra.Tags = append(msg.ParsingExtra.Tags, message.MultiLineSourceTag("auto_multiline_json"))
+	}
+	j.reset()
+
+	j.documentsInfo.Add(1)
+	j.aggregator.Aggregate(msg, noAggregate)
+}
+
+// flushLines labels and aggregates the buffered lines one by one.
+func (j *JSONAggregator) flushLines() {
+	lines := j.lines
+	j.reset()
+	for _, line := range lines {
+		j.aggregate(line)
+	}
+}
+
+func (j *JSONAggregator) aggregate(msg *message.Message) {
+	j.aggregator.Aggregate(msg, j.labeler.Label(msg.GetContent()))
+}
+
+func (j *JSONAggregator) reset() {
+	j.lines = nil
+	j.size = 0
+	j.depth = 0
+	j.inString = false
+	j.escaped = false
+}
+
+func (j *JSONAggregator) stopFlushTimerIfNeeded() {
+	if j.flushTimer == nil || len(j.lines) == 0 {
+		return
+	}
+	// stop the flush timer, as we now have data
+	if !j.flushTimer.Stop() {
+		<-j.flushTimer.C
+	}
+}
+
+func (j *JSONAggregator) startFlushTimerIfNeeded() {
+	if len(j.lines) == 0 {
+		return
+	}
+	// since there's buffered data, start the flush timer to flush it
+	if j.flushTimer == nil {
+		j.flushTimer = time.NewTimer(j.flushTimeout)
+	} else {
+		j.flushTimer.Reset(j.flushTimeout)
+	}
+}
@@ -0,0 +1,153 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package automultilinedetection contains auto multiline detection and aggregation logic.
+package automultilinedetection
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+// newTestJSONAggregator returns a JSON aggregator whose labeler starts a group
+// on lines starting with a date.
+func newTestJSONAggregator(maxContentSize int) (chan *message.Message, *JSONAggregator) {
+	outputChan, outputFn := makeHandler()
+	labeler := NewLabeler([]Heuristic{
+		&mockHeuristic{
+			processFunc: func(context *messageContext) bool {
+				if bytes.HasPrefix(context.rawMessage, []byte("2025-")) {
+					context.label = startGroup
+				}
+				return true
+			},
+		},
+	}, []Heuristic{})
+	aggregator := NewAggregator(outputFn, maxContentSize, time.Second, false, false, status.NewInfoRegistry())
+	return outputChan, NewJSONAggregator(labeler, aggregator, maxContentSize, time.Second, true, status.NewInfoRegistry())
+}
+
+func TestJSONAggregatorCombinesDocument(t *testing.T) {
+	outputChan, j := newTestJSONAggregator(1000)
+
+	for _, line := range []string{
+		`{`,
+		`  "msg": "a {tricky} [string] with \"quotes\" and \\",`,
+		`  "nested": {`,
+		`    "list": [1, 2, {"a": "}"}]`,
+		`  }`,
+		`}`,
+	} {
+		j.Process(newMessage(line))
+	}
+
+	msg := <-outputChan
+	assert.Equal(t, `{"msg":"a {tricky} [string] with \"quotes\" and \\","nested":{"list":[1,2,{"a":"}"}]}}`, string(msg.GetContent()))
+	assert.True(t, msg.ParsingExtra.IsMultiLine)
+	assert.Contains(t, msg.ParsingExtra.Tags, message.MultiLineSourceTag("auto_multiline_json"))
+	assert.Equal(t, 102, msg.RawDataLen)
+	assert.Len(t, outputChan, 0)
+}
+
+func TestJSONAggregatorCombinesArray(t *testing.T) {
+	outputChan, j := newTestJSONAggregator(1000)
+
+	j.Process(newMessage(`[`))
+	j.Process(newMessage(`  {"a": 1},`))
+	j.Process(newMessage(`  {"b": 2}`))
+	j.Process(newMessage(`]`))
+
+	assertTrailingMultiline(t, <-outputChan, `[{"a":1},{"b":2}]`)
+}
+
+func TestJSONAggregatorEndsGroup(t *testing.T) {
+	outputChan, j := newTestJSONAggregator(1000)
+
+	j.Process(newMessage("2025-01-01 error"))
+	j.Process(newMessage("stack trace"))
+	j.Process(newMessage(`{`))
+	j.Process(newMessage(`"a": 1`))
+	j.Process(newMessage(`}`))
+	j.Process(newMessage("2025-01-01 next"))
+	j.Flush()
+
+	assertMessageContent(t, <-outputChan, "2025-01-01 error\\nstack trace")
+	assertTrailingMultiline(t, <-outputChan, `{"a":1}`)
+	assertMessageContent(t, <-outputChan, "2025-01-01 next")
+}
+
+func TestJSONAggregatorSingleLineDocuments(t *testing.T) {
+	outputChan, j := newTestJSONAggregator(1000)
+
+	j.Process(newMessage(`{"a": 1}`))
+	j.Process(newMessage(`[1, 2]`))
+	j.Process(newMessage(`[INFO] not json`))
+	j.Flush()
+
+	// single line documents are aggregated as usual
+	assertMessageContent(t, <-outputChan, `{"a": 1}`)
+	assertMessageContent(t, <-outputChan, `[1, 2]`)
+	assertMessageContent(t, <-outputChan, `[INFO] not json`)
+}
+
+func TestJSONAggregatorFallsBackOnInvalidDocument(t *testing.T) {
+	outputChan, j := newTestJSONAggregator(1000)
+
+	j.Process(newMessage("2025-01-01 config dump"))
+	j.Process(newMessage(`{`))
+	j.Process(newMessage(`  key = value`))
+	j.Process(newMessage(`} trailing`))
+	j.Flush()
+
+	// the lines are aggregated as if they had not been buffered
+	assertMessageContent(t, <-outputChan, "2025-01-01 config dump\\n{\\n  key = value\\n} trailing")
+}
+
+func TestJSONAggregatorFallsBackOnUnterminatedString(t *testing.T) {
+	outputChan, j := newTestJSONAggregator(1000)
+
+	j.Process(newMessage(`{`))
+	j.Process(newMessage(`"a": "unterminated`))
+	j.Process(newMessage(`}`))
+	j.Flush()
+
+	assertMessageContent(t, <-outputChan, `{`)
+	assertMessageContent(t, <-outputChan, `"a": "unterminated`)
+	assertMessageContent(t, <-outputChan, `}`)
+}
+
+func TestJSONAggregatorFallsBackOnLargeDocument(t *testing.T) {
+	outputChan, j := newTestJSONAggregator(20)
+
+	j.Process(newMessage(`{`))
+	j.Process(newMessage(`"a": "0123456789",`))
+	j.Process(newMessage(`"b": 1`))
+	j.Process(newMessage(`}`))
+	j.Flush()
+
+	assertMessageContent(t, <-outputChan, `{`)
+	assertMessageContent(t, <-outputChan, `"a": "0123456789",`)
+	assertMessageContent(t, <-outputChan, `"b": 1`)
+	assertMessageContent(t, <-outputChan, `}`)
+}
+
+func TestJSONAggregatorFlushesIncompleteDocument(t *testing.T) {
+	outputChan, j := newTestJSONAggregator(1000)
+
+	j.Process(newMessage(`{`))
+	j.Process(newMessage(`"a": 1,`))
+	assert.Len(t, outputChan, 0)
+	assert.NotNil(t, j.FlushChan())
+
+	j.Flush()
+	assertMessageContent(t, <-outputChan, `{`)
+	assertMessageContent(t, <-outputChan, `"a": 1,`)
+}
@@ -18,6 +18,8 @@ import (
 type AutoMultilineHandler struct {
 	labeler    *automultilinedetection.Labeler
 	aggregator *automultilinedetection.Aggregator
+	// jsonAggregator is nil when the aggregation of multi-line JSON documents is disabled
+	jsonAggregator *automultilinedetection.JSONAggregator
 }
 
 // NewAutoMultilineHandler creates a new auto multiline handler.
@@ -44,27 +46,53 @@ func NewAutoMultilineHandler(outputFn func(m *message.Message), maxContentSize i
 		tailerInfo),
 	}
 
-	return &AutoMultilineHandler{
-		labeler: automultilinedetection.NewLabeler(heuristics, analyticsHeuristics),
-		aggregator: automultilinedetection.NewAggregator(
-			outputFn,
+	labeler := automultilinedetection.NewLabeler(heuristics, analyticsHeuristics)
+	aggregator := automultilinedetection.NewAggregator(
+		outputFn,
+		maxContentSize,
+		flushTimeout,
+		pkgconfigsetup.Datadog().GetBool("logs_config.tag_truncated_logs"),
+		pkgconfigsetup.Datadog().GetBool("logs_config.tag_multi_line_logs"),
+		tailerInfo)
+
+	var jsonAggregator *automultilinedetection.JSONAggregator
+	if pkgconfigsetup.Datadog().GetBool("logs_config.auto_multi_line.enable_json_aggregation") {
+		jsonAggregator = automultilinedetection.NewJSONAggregator(
+			labeler,
+			aggregator,
 			maxContentSize,
 			flushTimeout,
-			pkgconfigsetup.Datadog().GetBool("logs_config.tag_truncated_logs"),
 			pkgconfigsetup.Datadog().GetBool("logs_config.tag_multi_line_logs"),
-			tailerInfo),
+			tailerInfo)
+	}
+
+	return &AutoMultilineHandler{
+		labeler:        labeler,
+		aggregator:     aggregator,
+		jsonAggregator: jsonAggregator,
 	}
 }
 
 func (a *AutoMultilineHandler) process(msg *message.Message) {
+	if a.jsonAggregator != nil {
+		a.jsonAggregator.Process(msg)
+		return
+	}
 	label := a.labeler.Label(msg.GetContent())
 	a.aggregator.Aggregate(msg, label)
 }
 
 func (a *AutoMultilineHandler) flushChan() <-chan time.Time {
+	if a.jsonAggregator != nil {
+		return a.jsonAggregator.FlushChan()
+	}
 	return a.aggregator.FlushChan()
 }
 
 func (a *AutoMultilineHandler) flush() {
+	if a.jsonAggregator != nil {
+		a.jsonAggregator.Flush()
+		return
+	}
 	a.aggregator.Flush()
 }
@@ -55,6 +55,10 @@ type Decoder struct {
 	// pass a multiline pattern up from the line handler in order to surface it to the tailer.
 	// The tailer uses this to determine if a pattern should be reused when a file rotates.
 	detectedPattern *DetectedPattern
+
+	// flushPartialFrame makes the decoder output the last, unterminated, frame
+	// of its input when it's stopped
+	flushPartialFrame bool
 }
 
 // InitializeDecoder returns a properly initialized Decoder
@@ -216,10 +220,20 @@ func (d *Decoder) Stop() {
 	close(d.InputChan)
 }
 
+// StopAndFlush stops the Decoder like Stop, also outputting the last line of
+// its input when it isn't terminated, as the end of the input has been reached.
+func (d *Decoder) StopAndFlush() {
+	d.flushPartialFrame = true
+	close(d.InputChan)
+}
+
 func (d *Decoder) run() {
 	defer func() {
 		// flush any remaining output in component order, and then close the
 		// output channel
+		if d.flushPartialFrame {
+			d.framer.Flush()
+		}
 		d.lineParser.flush()
 		d.lineHandler.flush()
 		close(d.OutputChan)
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/dockerfile"
@@ -341,3 +342,36 @@ func TestDecoderWithMultilineKubernetes(t *testing.T) {
 	assert.Equal(t, message.StatusError, output.Status)
 	assert.Equal(t, "2019-06-06T16:35:55.930852913Z", output.ParsingExtra.Timestamp)
 }
+
+func TestDecoderWithAutoMultilineJSON(t *testing.T) {
+	jsonAggregation := pkgconfigsetup.Datadog().GetBool("logs_config.auto_multi_line.enable_json_aggregation")
+	defer pkgconfigsetup.Datadog().SetWithoutSource("logs_config.auto_multi_line.enable_json_aggregation", jsonAggregation)
+	pkgconfigsetup.Datadog().SetWithoutSource("logs_config.auto_multi_line.enable_json_aggregation", true)
+
+	autoMultiLine := true
+	d := InitializeDecoderForTest(sources.NewLogSource("", &config.LogsConfig{AutoMultiLine: &autoMultiLine}), noop.New())
+	d.Start()
+	defer d.Stop()
+
+	document := "{\n  \"level\": \"info\",\n  \"ctx\": {\"tags\": [\"a\", \"b\"]},\n  \"msg\": \"ready {\"\n}\n"
+	// the decoder blocks until its outputs are read, the inputs are sent concurrently
+	go func() {
+		d.InputChan <- NewInput([]byte("2025-03-01 12:00:00 INFO starting\n"))
+		d.InputChan <- NewInput([]byte(document))
+		d.InputChan <- NewInput([]byte(`{"kind":"Event","apiVersion":"audit.k8s.io/v1","stage":"ResponseComplete"}` + "\n"))
+	}()
+
+	output := <-d.OutputChan
+	assert.Equal(t, "2025-03-01 12:00:00 INFO starting", string(output.GetContent()))
+
+	// the pretty-printed document is sent as a single compacted message
+	output = <-d.OutputChan
+	assert.Equal(t, `{"level":"info","ctx":{"tags":["a","b"]},"msg":"ready {"}`, string(output.GetContent()))
+	assert.Equal(t, len(document), output.RawDataLen)
+	assert.True(t, output.ParsingExtra.IsMultiLine)
+
+	// JSON lines are left untouched
+	output = <-d.OutputChan
+	assert.Equal(t, `{"kind":"Event","apiVersion":"audit.k8s.io/v1","stage":"ResponseComplete"}`, string(output.GetContent()))
+	assert.False(t, output.ParsingExtra.IsMultiLine)
+}
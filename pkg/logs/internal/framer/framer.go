@@ -43,6 +43,11 @@ const (
 	// headers are included in the log frame.  The size in those headers is not
 	// consulted.  The result does not include the trailing newlines.
 	DockerStream
+
+	// Syslog stream as described in RFC 6587.  Frames starting with a digit
+	// use octet-counting framing (`MSG-LEN SP SYSLOG-MSG`), any other frame
+	// is newline-terminated (non-transparent framing).
+	SyslogStream
 )
 
 // Framer gets chunks of bytes (via Process(..)) and uses an
@@ -69,6 +74,10 @@ type Framer struct {
 	// Over this size, the framer will break the bytes into individual frames
 	// of this size with no delimiting.
 	contentLenLimit int
+
+	// lastInput is the last input given to Process, whose metadata is used for
+	// the partial frame output by Flush.
+	lastInput *message.Message
 }
 
 // NewFramer initializes a Framer.
@@ -100,6 +109,8 @@ func NewFramer(
 		matcher = &oneByteNewLineMatcher{contentLenLimit}
 	case DockerStream:
 		matcher = &dockerStreamMatcher{contentLenLimit}
+	case SyslogStream:
+		matcher = &syslogStreamMatcher{contentLenLimit}
 	case NoFraming:
 		matcher = &noFramingMatcher{}
 	default:
@@ -146,6 +157,7 @@ func (fr *Framer) Process(input *message.Message) {
 	// as-yet un-recognized frames of data.  The `seen` offset indicates where the matcher
 	// left off in the last call to Process.
 
+	fr.lastInput = input
 	framed := fr.bytesFramed
 	seen := fr.buffer.Len()
 	fr.buffer.Write(input.GetContent())
@@ -200,6 +212,36 @@ func (fr *Framer) Process(input *message.Message) {
 	fr.normalizeBuffer()
 }
 
+// Flush outputs the remaining bytes given to Process as a frame, even though
+// they are not terminated. It's used once the end of the input is reached.
+func (fr *Framer) Flush() {
+	buf := fr.buffer.Bytes()[fr.bytesFramed:]
+	if len(buf) == 0 || fr.lastInput == nil {
+		return
+	}
+	owned := make([]byte, len(buf))
+	copy(owned, buf)
+
+	c := &message.Message{
+		MessageContent: message.MessageContent{
+			State: message.StateUnstructured,
+		},
+		MessageMetadata: message.MessageMetadata{
+			Origin:             fr.lastInput.Origin,
+			Status:             fr.lastInput.Status,
+			IngestionTimestamp: fr.lastInput.IngestionTimestamp,
+			ParsingExtra:       fr.lastInput.ParsingExtra,
+			ServerlessExtra:    fr.lastInput.ServerlessExtra,
+		},
+	}
+	c.SetContent(owned)
+
+	fr.outputFn(c, len(owned))
+	fr.frames.Inc()
+	fr.bytesFramed += len(owned)
+	fr.normalizeBuffer()
+}
+
 // normalizeBuffer makes the buffer ready for new data, while attempting to
 // minimize copying of data.
 func (fr *Framer) normalizeBuffer() {
@@ -404,3 +404,24 @@ func TestStructuredContent(t *testing.T) {
 
 	assert.Len(t, outputChan, 0)
 }
+
+func TestFlush(t *testing.T) {
+	outputFn, outputChan := framerOutput()
+	framer := NewFramer(outputFn, UTF8Newline, contentLenLimit)
+
+	// nothing is output before any input
+	framer.Flush()
+	assert.Empty(t, outputChan)
+
+	framer.Process(message.NewMessage([]byte("first\nsec"), nil, message.StatusInfo, 0))
+	framer.Process(message.NewMessage([]byte("ond"), nil, message.StatusInfo, 0))
+	assert.Equal(t, brokenLine{[]byte("first"), 6}, <-outputChan)
+	assert.Empty(t, outputChan)
+
+	// the unterminated frame is output once
+	framer.Flush()
+	assert.Equal(t, brokenLine{[]byte("second"), 6}, <-outputChan)
+	framer.Flush()
+	assert.Empty(t, outputChan)
+	assert.Equal(t, int64(2), framer.GetFrameCount())
+}
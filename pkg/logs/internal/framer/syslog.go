@@ -0,0 +1,83 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// maxOctetCountDigits is the maximum number of digits accepted in the MSG-LEN
+// prefix of an octet-counted syslog frame.
+const maxOctetCountDigits = 10
+
+// syslogStreamMatcher implements FrameMatcher for syslog streams sent over
+// TCP, as described in RFC 6587.  Both framing methods of the RFC are
+// supported and can be mixed within the same stream:
+//
+//   - octet-counting, where each frame is prefixed by its length in bytes
+//     followed by a space: `MSG-LEN SP SYSLOG-MSG`;
+//   - non-transparent framing, where each frame is terminated by a newline.
+//
+// A syslog message always starts with `<`, so a frame starting with a non-zero
+// digit can only be an octet-counted one.
+type syslogStreamMatcher struct {
+	// contentLenLimit is the maximum content length that will be returned for
+	// newline-terminated frames.  Octet-counted frames longer than this limit
+	// are broken by the Framer itself.
+	contentLenLimit int
+}
+
+// FindFrame implements EndLineMatcher#FindFrame.
+func (s *syslogStreamMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if len(buf) == 0 {
+		return nil, 0
+	}
+	if buf[0] < '1' || buf[0] > '9' {
+		return s.findNewlineFrame(buf, seen)
+	}
+
+	sp := bytes.IndexByte(buf, ' ')
+	if sp == -1 {
+		if len(buf) > maxOctetCountDigits {
+			// this can't be a MSG-LEN prefix anymore
+			return s.findNewlineFrame(buf, 0)
+		}
+		// wait for the rest of the MSG-LEN prefix
+		return nil, 0
+	}
+
+	length, err := strconv.Atoi(string(buf[:sp]))
+	if err != nil || sp > maxOctetCountDigits {
+		// not an octet-counted frame; `seen` can't be trusted here since the
+		// previous calls were looking for a space rather than a newline
+		return s.findNewlineFrame(buf, 0)
+	}
+
+	end := sp + 1 + length
+	if len(buf) < end {
+		return nil, 0
+	}
+	return buf[sp+1 : end], end
+}
+
+// findNewlineFrame looks for a newline-terminated frame in buf.
+func (s *syslogStreamMatcher) findNewlineFrame(buf []byte, seen int) ([]byte, int) {
+	nl := bytes.IndexByte(buf[seen:], '\n')
+	if nl == -1 {
+		return nil, 0
+	}
+
+	// limit the returned line to contentLenLimit bytes
+	eol := nl + seen
+	if eol > s.contentLenLimit {
+		return buf[:s.contentLenLimit], s.contentLenLimit
+	}
+
+	// return the content without the newline, but count the newline in the raw
+	// length
+	return buf[:eol], eol + 1
+}
@@ -0,0 +1,63 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestSyslogStreamFraming(t *testing.T) {
+	test := func(chunks []string, lines []string, rawLens []int) func(*testing.T) {
+		return func(t *testing.T) {
+			gotContent := []string{}
+			gotLens := []int{}
+			outputFn := func(msg *message.Message, rawDataLen int) {
+				gotContent = append(gotContent, string(msg.GetContent()))
+				gotLens = append(gotLens, rawDataLen)
+			}
+			fr := NewFramer(outputFn, SyslogStream, contentLenLimit)
+			for _, chunk := range chunks {
+				fr.Process(message.NewMessage([]byte(chunk), nil, "", 0))
+			}
+			assert.Equal(t, lines, gotContent)
+			assert.Equal(t, rawLens, gotLens)
+		}
+	}
+
+	t.Run("octet-counting", test(
+		[]string{"11 <34>1 - - -15 <13>1 - - - - -"},
+		[]string{"<34>1 - - -", "<13>1 - - - - -"},
+		[]int{14, 18}))
+
+	t.Run("octet-counting with embedded newline", test(
+		[]string{"8 <1>a\nb\nc"},
+		[]string{"<1>a\nb\nc"},
+		[]int{10}))
+
+	t.Run("octet-counting across chunks", test(
+		[]string{"1", "1 <34>1 -", " - -4 <1>a"},
+		[]string{"<34>1 - - -", "<1>a"},
+		[]int{14, 6}))
+
+	t.Run("non-transparent", test(
+		[]string{"<34>Oct 11 22:14:15 host app: hello\n<13>hi\n"},
+		[]string{"<34>Oct 11 22:14:15 host app: hello", "<13>hi"},
+		[]int{36, 7}))
+
+	t.Run("mixed", test(
+		[]string{"4 <1>a<2>b\n4 <3>c"},
+		[]string{"<1>a", "<2>b", "<3>c"},
+		[]int{6, 5, 6}))
+
+	t.Run("invalid length prefix", test(
+		[]string{"12\nfoo", " bar\n"},
+		[]string{"12", "foo bar"},
+		[]int{3, 8}))
+}
@@ -0,0 +1,267 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// nilValue is the RFC 5424 NILVALUE, used for empty header fields
+const nilValue = "-"
+
+// utf8BOM may prefix the MSG part of a RFC 5424 message
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+var (
+	errMissingPRI     = errors.New("syslog message must start with a PRI part")
+	errInvalidPRI     = errors.New("invalid syslog PRI part")
+	errInvalidHeader  = errors.New("invalid RFC 5424 header")
+	errInvalidSD      = errors.New("invalid RFC 5424 structured data")
+	errUnterminatedSD = errors.New("unterminated RFC 5424 structured data element")
+)
+
+// syslogMessage holds the fields extracted from a syslog message.  Header
+// fields which are absent or NILVALUE are left empty.
+type syslogMessage struct {
+	facility int
+	severity int
+	// version is the RFC 5424 version, it is 0 for RFC 3164 messages.
+	version        int
+	timestamp      string
+	hostname       string
+	appname        string
+	procid         string
+	msgid          string
+	structuredData map[string]map[string]string
+	msg            []byte
+}
+
+// parse parses a RFC 5424 or RFC 3164 syslog message.  The format is detected
+// from the byte following the PRI part: RFC 5424 messages carry a numeric
+// version there, while RFC 3164 messages carry a timestamp or a hostname.
+func parse(buf []byte) (*syslogMessage, error) {
+	pri, rest, err := parsePRI(buf)
+	if err != nil {
+		return nil, err
+	}
+	m := &syslogMessage{
+		facility: pri / 8,
+		severity: pri % 8,
+	}
+	if version, after, ok := parseVersion(rest); ok {
+		m.version = version
+		return m, m.parseRFC5424(after)
+	}
+	m.parseRFC3164(rest)
+	return m, nil
+}
+
+// parsePRI parses the `<PRIVAL>` prefix of a syslog message.
+func parsePRI(buf []byte) (int, []byte, error) {
+	if len(buf) == 0 || buf[0] != '<' {
+		return 0, buf, errMissingPRI
+	}
+	end := bytes.IndexByte(buf, '>')
+	// PRIVAL is at most 3 digits long
+	if end < 2 || end > 4 {
+		return 0, buf, errInvalidPRI
+	}
+	pri, err := strconv.Atoi(string(buf[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, buf, errInvalidPRI
+	}
+	return pri, buf[end+1:], nil
+}
+
+// parseVersion parses the RFC 5424 `VERSION SP` part following the PRI.
+func parseVersion(buf []byte) (int, []byte, bool) {
+	i := 0
+	for i < len(buf) && i < 3 && buf[i] >= '0' && buf[i] <= '9' {
+		i++
+	}
+	if i == 0 || buf[0] == '0' || i >= len(buf) || buf[i] != ' ' {
+		return 0, buf, false
+	}
+	version, err := strconv.Atoi(string(buf[:i]))
+	if err != nil {
+		return 0, buf, false
+	}
+	return version, buf[i+1:], true
+}
+
+// parseRFC5424 parses what follows `<PRI>VERSION SP`:
+//
+//	TIMESTAMP SP HOSTNAME SP APP-NAME SP PROCID SP MSGID SP STRUCTURED-DATA [SP MSG]
+func (m *syslogMessage) parseRFC5424(buf []byte) error {
+	fields := []*string{&m.timestamp, &m.hostname, &m.appname, &m.procid, &m.msgid}
+	for _, field := range fields {
+		var value []byte
+		var ok bool
+		value, buf, ok = nextField(buf)
+		if !ok {
+			return errInvalidHeader
+		}
+		if string(value) != nilValue {
+			*field = string(value)
+		}
+	}
+
+	rest, err := m.parseStructuredData(buf)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		if rest[0] != ' ' {
+			return errInvalidSD
+		}
+		m.msg = bytes.TrimPrefix(rest[1:], utf8BOM)
+	}
+	return nil
+}
+
+// nextField returns the next space-delimited header field of buf.
+func nextField(buf []byte) ([]byte, []byte, bool) {
+	sp := bytes.IndexByte(buf, ' ')
+	if sp <= 0 {
+		return nil, buf, false
+	}
+	return buf[:sp], buf[sp+1:], true
+}
+
+// parseStructuredData parses the STRUCTURED-DATA part of a RFC 5424 message,
+// either a NILVALUE or a sequence of `[SD-ID *(SP PARAM-NAME="PARAM-VALUE")]`
+// elements, and returns what follows it.
+func (m *syslogMessage) parseStructuredData(buf []byte) ([]byte, error) {
+	if len(buf) == 0 {
+		return buf, nil
+	}
+	if buf[0] == '-' {
+		return buf[1:], nil
+	}
+	if buf[0] != '[' {
+		return buf, errInvalidSD
+	}
+
+	m.structuredData = make(map[string]map[string]string)
+	for len(buf) > 0 && buf[0] == '[' {
+		end := bytes.IndexAny(buf, " ]")
+		if end <= 1 {
+			return buf, errInvalidSD
+		}
+		id := string(buf[1:end])
+		params := make(map[string]string)
+		buf = buf[end:]
+
+		for len(buf) > 0 && buf[0] == ' ' {
+			eq := bytes.IndexByte(buf, '=')
+			if eq <= 1 || eq+1 >= len(buf) || buf[eq+1] != '"' {
+				return buf, errInvalidSD
+			}
+			name := string(buf[1:eq])
+			value, rest, err := parseParamValue(buf[eq+2:])
+			if err != nil {
+				return buf, err
+			}
+			params[name] = value
+			buf = rest
+		}
+
+		if len(buf) == 0 || buf[0] != ']' {
+			return buf, errUnterminatedSD
+		}
+		m.structuredData[id] = params
+		buf = buf[1:]
+	}
+	return buf, nil
+}
+
+// parseParamValue parses a PARAM-VALUE up to its closing quote, unescaping
+// `\"`, `\\` and `\]`.
+func parseParamValue(buf []byte) (string, []byte, error) {
+	var value []byte
+	for i := 0; i < len(buf); i++ {
+		switch buf[i] {
+		case '\\':
+			if i+1 < len(buf) && (buf[i+1] == '"' || buf[i+1] == '\\' || buf[i+1] == ']') {
+				i++
+			}
+			value = append(value, buf[i])
+		case '"':
+			return string(value), buf[i+1:], nil
+		default:
+			value = append(value, buf[i])
+		}
+	}
+	return "", buf, errUnterminatedSD
+}
+
+// parseRFC3164 parses what follows the PRI part of a BSD syslog message:
+//
+//	TIMESTAMP SP HOSTNAME SP TAG[PID]: MSG
+//
+// RFC 3164 only describes observed behavior, so parsing is lenient: every
+// part of the header is optional and whatever can't be recognized is kept in
+// the message.
+func (m *syslogMessage) parseRFC3164(buf []byte) {
+	timestamp, rest, ok := parseBSDTimestamp(buf)
+	if ok {
+		m.timestamp = timestamp
+		buf = rest
+		// the hostname always follows the timestamp, unless the sender
+		// omitted it and went straight to the tag
+		if value, rest, ok := nextField(buf); ok && !bytes.HasSuffix(value, []byte(":")) && bytes.IndexByte(value, '[') == -1 {
+			m.hostname = string(value)
+			buf = rest
+		}
+	}
+
+	m.appname, m.procid, buf = parseTag(buf)
+	m.msg = buf
+}
+
+// parseBSDTimestamp parses a `Mmm dd hh:mm:ss` timestamp, or a RFC 3339 one as
+// sent by many modern implementations of RFC 3164.
+func parseBSDTimestamp(buf []byte) (string, []byte, bool) {
+	const layoutLen = len(time.Stamp)
+	if len(buf) > layoutLen && buf[layoutLen] == ' ' {
+		if _, err := time.Parse(time.Stamp, string(buf[:layoutLen])); err == nil {
+			return string(buf[:layoutLen]), buf[layoutLen+1:], true
+		}
+	}
+	if value, rest, ok := nextField(buf); ok {
+		if _, err := time.Parse(time.RFC3339Nano, string(value)); err == nil {
+			return string(value), rest, true
+		}
+	}
+	return "", buf, false
+}
+
+// parseTag parses the `TAG[PID]: ` or `TAG: ` prefix of a RFC 3164 message
+// content.  The content is returned unchanged when there is no such prefix.
+func parseTag(buf []byte) (string, string, []byte) {
+	end := bytes.IndexAny(buf, " :[")
+	if end <= 0 {
+		return "", "", buf
+	}
+	tag, rest := buf[:end], buf[end:]
+
+	var procid string
+	if rest[0] == '[' {
+		closing := bytes.IndexByte(rest, ']')
+		if closing == -1 {
+			return "", "", buf
+		}
+		procid = string(rest[1:closing])
+		rest = rest[closing+1:]
+	}
+	if len(rest) == 0 || rest[0] != ':' {
+		return "", "", buf
+	}
+	return string(tag), procid, bytes.TrimPrefix(rest[1:], []byte(" "))
+}
@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a parser for syslog messages following either
+// RFC 5424 or RFC 3164.
+package syslog
+
+import (
+	"bytes"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// severityToStatus maps syslog severities (0-7) to message statuses.
+var severityToStatus = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// New creates a new parser that parses syslog messages.
+//
+// Both RFC 5424 messages, e.g.
+// `<165>1 2003-10-11T22:14:15.003Z host app 1234 ID47 [exampleSDID@32473 iut="3"] An application event`,
+// and RFC 3164 (BSD) messages, e.g.
+// `<34>Oct 11 22:14:15 host su[1234]: 'su root' failed for lonvick on /dev/pts/8`,
+// are supported, the format being detected for each message.
+//
+// The severity is mapped to the message status, the MSG part becomes the
+// message content and the header fields and structured data are reported as
+// attributes under the `syslog` key of a structured message.
+func New() parsers.Parser {
+	return &syslogFormat{}
+}
+
+type syslogFormat struct{}
+
+// Parse implements Parser#Parse
+func (p *syslogFormat) Parse(msg *message.Message) (*message.Message, error) {
+	m, err := parse(bytes.TrimRight(msg.GetContent(), "\r\n\x00"))
+	if err != nil {
+		return msg, err
+	}
+
+	parsed := message.NewStructuredMessage(
+		&message.BasicStructuredContent{
+			Data: map[string]interface{}{
+				"message": string(m.msg),
+				"syslog":  m.attributes(),
+			},
+		},
+		msg.Origin,
+		severityToStatus[m.severity],
+		msg.IngestionTimestamp,
+	)
+	parsed.RawDataLen = msg.RawDataLen
+	parsed.ParsingExtra = msg.ParsingExtra
+	parsed.ServerlessExtra = msg.ServerlessExtra
+	return parsed, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *syslogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+// attributes returns the attributes of the message, omitting empty fields.
+func (m *syslogMessage) attributes() map[string]interface{} {
+	attrs := map[string]interface{}{
+		"facility": m.facility,
+		"severity": m.severity,
+	}
+	if m.version != 0 {
+		attrs["version"] = m.version
+	}
+	for key, value := range map[string]string{
+		"timestamp": m.timestamp,
+		"hostname":  m.hostname,
+		"appname":   m.appname,
+		"procid":    m.procid,
+		"msgid":     m.msgid,
+	} {
+		if value != "" {
+			attrs[key] = value
+		}
+	}
+	if len(m.structuredData) > 0 {
+		attrs["structured_data"] = m.structuredData
+	}
+	return attrs
+}
@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// parseString parses the input with the parser and returns the resulting
+// message along with the attributes extracted from the input.
+func parseString(t *testing.T, input string) (*message.Message, map[string]interface{}) {
+	msg, err := New().Parse(message.NewMessage([]byte(input), nil, "", 0))
+	require.NoError(t, err)
+	require.Equal(t, message.StateStructured, msg.State)
+
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(rendered, &payload))
+	require.Equal(t, string(msg.GetContent()), payload["message"])
+	require.Contains(t, payload, "syslog")
+
+	m, err := parse(bytes.TrimRight([]byte(input), "\n"))
+	require.NoError(t, err)
+	return msg, m.attributes()
+}
+
+func TestParseRFC5424(t *testing.T) {
+	msg, attrs := parseString(t, `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"] An application event log entry...`)
+
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, []byte("An application event log entry..."), msg.GetContent())
+	assert.Equal(t, map[string]interface{}{
+		"facility":  20,
+		"severity":  5,
+		"version":   1,
+		"timestamp": "2003-10-11T22:14:15.003Z",
+		"hostname":  "mymachine.example.com",
+		"appname":   "evntslog",
+		"procid":    "1234",
+		"msgid":     "ID47",
+		"structured_data": map[string]map[string]string{
+			"exampleSDID@32473": {
+				"iut":         "3",
+				"eventSource": "Application",
+				"eventID":     "1011",
+			},
+			"examplePriority@32473": {
+				"class": "high",
+			},
+		},
+	}, attrs)
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	msg, attrs := parseString(t, "<34>1 - - - - - -")
+
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Empty(t, msg.GetContent())
+	assert.Equal(t, map[string]interface{}{
+		"facility": 4,
+		"severity": 2,
+		"version":  1,
+	}, attrs)
+}
+
+func TestParseRFC5424BOMAndEscapes(t *testing.T) {
+	m, err := parse([]byte("<14>1 2003-08-24T05:14:15.000003-07:00 192.0.2.1 myproc 8710 - [id a=\"q\\\"uo\\]te\\\\\"] \xEF\xBB\xBFhello"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "192.0.2.1", m.hostname)
+	assert.Equal(t, "myproc", m.appname)
+	assert.Equal(t, "8710", m.procid)
+	assert.Empty(t, m.msgid)
+	assert.Equal(t, map[string]map[string]string{"id": {"a": `q"uo]te\`}}, m.structuredData)
+	assert.Equal(t, []byte("hello"), m.msg)
+}
+
+func TestParseRFC5424Invalid(t *testing.T) {
+	for _, input := range []string{
+		"<34>1 2003-10-11T22:14:15.003Z host",
+		"<34>1 - - - - - [unterminated a=\"b\"",
+		"<34>1 - - - - - [id a=\"b]",
+		"<34>1 - - - - - garbage",
+	} {
+		_, err := parse([]byte(input))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	msg, attrs := parseString(t, "<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8\n")
+
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Equal(t, []byte("'su root' failed for lonvick on /dev/pts/8"), msg.GetContent())
+	assert.Equal(t, map[string]interface{}{
+		"facility":  4,
+		"severity":  2,
+		"timestamp": "Oct 11 22:14:15",
+		"hostname":  "mymachine",
+		"appname":   "su",
+		"procid":    "230",
+	}, attrs)
+}
+
+func TestParseRFC3164Variants(t *testing.T) {
+	tests := []struct {
+		input     string
+		timestamp string
+		hostname  string
+		appname   string
+		procid    string
+		msg       string
+	}{
+		{"<13>Feb  5 17:32:18 10.0.0.99 Use the BFG!", "Feb  5 17:32:18", "10.0.0.99", "", "", "Use the BFG!"},
+		{"<13>Feb  5 17:32:18 sshd: no hostname", "Feb  5 17:32:18", "", "sshd", "", "no hostname"},
+		{"<13>2025-02-05T17:32:18.123+01:00 host cron[42]: rfc3339", "2025-02-05T17:32:18.123+01:00", "host", "cron", "42", "rfc3339"},
+		{"<13>kernel: no timestamp", "", "", "kernel", "", "no timestamp"},
+		{"<13>just a message", "", "", "", "", "just a message"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			m, err := parse([]byte(test.input))
+			require.NoError(t, err)
+			assert.Equal(t, test.timestamp, m.timestamp)
+			assert.Equal(t, test.hostname, m.hostname)
+			assert.Equal(t, test.appname, m.appname)
+			assert.Equal(t, test.procid, m.procid)
+			assert.Equal(t, test.msg, string(m.msg))
+			assert.Equal(t, 0, m.version)
+		})
+	}
+}
+
+func TestParseShouldFailWithoutPRI(t *testing.T) {
+	for _, input := range []string{"", "hello", "<>hello", "<1234>hello", "<192>hello", "<12"} {
+		input := message.NewMessage([]byte(input), nil, "", 0)
+		msg, err := New().Parse(input)
+		assert.Error(t, err)
+		assert.Equal(t, input, msg)
+	}
+}
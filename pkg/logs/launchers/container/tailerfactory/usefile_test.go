@@ -42,6 +42,16 @@ func (r *fakeRegistry) GetTailingMode(identifier string) string {
 	panic("unused")
 }
 
+// GetFingerprint implements auditor.Registry#GetFingerprint.
+func (r *fakeRegistry) GetFingerprint(_ string) string {
+	panic("unused")
+}
+
+// KeepAlive implements auditor.Registry#KeepAlive.
+func (r *fakeRegistry) KeepAlive(_ string) {
+	panic("unused")
+}
+
 func TestUseFile(t *testing.T) {
 	ctrs := containersorpods.LogContainers
 	pods := containersorpods.LogPods
@@ -7,13 +7,16 @@
 package file
 
 import (
+	"io"
 	"regexp"
 	"slices"
+	"strconv"
 	"time"
 
 	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	flareController "github.com/DataDog/datadog-agent/comp/logs/agent/flare"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers"
@@ -55,6 +58,17 @@ type Launcher struct {
 	scanPeriod             time.Duration
 	flarecontroller        *flareController.FlareController
 	tagger                 tagger.Component
+	// set to true to read compressed files once, from start to end, instead of
+	// tailing them. Use `logs_config.read_compressed_files`.
+	readCompressedFiles bool
+	// compressedFiles maps the scan keys of the compressed files which have been
+	// read, or are being read, to their registry identifier, so that they are not
+	// read again.
+	compressedFiles map[string]string
+	// fingerprintSize is the number of bytes at the beginning of files used to
+	// fingerprint them, 0 when fingerprinting is disabled.
+	// Use `logs_config.fingerprint.enabled` and `logs_config.fingerprint.byte_count`.
+	fingerprintSize int
 }
 
 // NewLauncher returns a new launcher.
@@ -71,6 +85,15 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		wildcardStrategy = fileprovider.WildcardUseFileName
 	}
 
+	var fingerprintSize int
+	if pkgconfigsetup.Datadog().GetBool("logs_config.fingerprint.enabled") {
+		fingerprintSize = pkgconfigsetup.Datadog().GetInt("logs_config.fingerprint.byte_count")
+		if fingerprintSize <= 0 {
+			log.Warnf("Invalid fingerprint byte count: %d, file fingerprinting is disabled.", fingerprintSize)
+			fingerprintSize = 0
+		}
+	}
+
 	return &Launcher{
 		tailingLimit:           tailingLimit,
 		fileProvider:           fileprovider.NewFileProvider(tailingLimit, wildcardStrategy),
@@ -83,6 +106,9 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		scanPeriod:             scanPeriod,
 		flarecontroller:        flarecontroller,
 		tagger:                 tagger,
+		readCompressedFiles:    pkgconfigsetup.Datadog().GetBool("logs_config.read_compressed_files"),
+		compressedFiles:        make(map[string]string),
+		fingerprintSize:        fingerprintSize,
 	}
 }
 
@@ -176,6 +202,12 @@ func (s *Launcher) scan() {
 			continue
 		}
 
+		if isTailed && tailer.IsCompressed() {
+			// compressed files are read once, they can't be rotated
+			filesTailed[scanKey] = true
+			continue
+		}
+
 		// If the file is currently being tailed, check for rotation and handle it appropriately.
 		if isTailed {
 			didRotate, err := tailer.DidRotate()
@@ -206,9 +238,15 @@ func (s *Launcher) scan() {
 		// stop all tailers which have not been selected
 		_, shouldTail := filesTailed[tailer.GetId()]
 		if !shouldTail {
+			if tailer.IsCompressed() && !tailer.IsFinished() {
+				// the file has not been read entirely, it will be read again from
+				// the committed offset if it's selected later on
+				delete(s.compressedFiles, tailer.GetId())
+			}
 			s.stopTailer(tailer)
 		}
 	}
+	s.keepCompressedFilesAlive(files)
 
 	tailersLen := s.tailers.Count()
 	log.Debugf("After stopping tailers, there are %d tailers running.\n", tailersLen)
@@ -312,12 +350,17 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 		return false
 	}
 
+	if s.readCompressedFiles && tailer.IsCompressed(file.Path) {
+		return s.startCompressedFileTailer(file)
+	}
+
 	channel, monitor := s.pipelineProvider.NextPipelineChanWithMonitor()
-	tailer := s.createTailer(file, channel, monitor)
+	tailer := s.createTailer(file, channel, monitor, false)
 
 	var offset int64
 	var whence int
 	mode := s.handleTailingModeChange(tailer.Identifier(), m)
+	mode = s.handleFingerprintChange(tailer.Identifier(), file.Path, mode)
 	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
@@ -334,6 +377,61 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 	return true
 }
 
+// startCompressedFileTailer creates a new tailer reading a compressed file once, from the beginning or the last
+// committed offset, unless the file has already been read. The tailing mode is ignored.
+// Returns true if a tailer has been started, false otherwise.
+func (s *Launcher) startCompressedFileTailer(file *tailer.File) bool {
+	scanKey := file.GetScanKey()
+	if _, isRead := s.compressedFiles[scanKey]; isRead {
+		return false
+	}
+
+	channel, monitor := s.pipelineProvider.NextPipelineChanWithMonitor()
+	compressedTailer := s.createTailer(file, channel, monitor, true)
+	identifier := compressedTailer.Identifier()
+
+	var offset int64
+	switch value := s.registry.GetOffset(identifier); value {
+	case tailer.CompletedOffset:
+		log.Debugf("Compressed file %s has already been read", file.Path)
+		s.compressedFiles[scanKey] = identifier
+		return false
+	case "":
+	default:
+		var err error
+		if offset, err = strconv.ParseInt(value, 10, 64); err != nil {
+			log.Warnf("Could not recover offset for compressed file with path %v: %v", file.Path, err)
+			offset = 0
+		}
+	}
+
+	log.Infof("Starting a new tailer for compressed file: %s (offset: %d) for tailer key %s", file.Path, offset, scanKey)
+	if err := compressedTailer.Start(offset, io.SeekStart); err != nil {
+		log.Warn(err)
+		return false
+	}
+
+	s.compressedFiles[scanKey] = identifier
+	s.tailers.Add(compressedTailer)
+	return true
+}
+
+// keepCompressedFilesAlive prevents the registry entries of the compressed files which have been read from expiring
+// as long as the files are found, and forgets about the files which are not found anymore.
+func (s *Launcher) keepCompressedFilesAlive(files []*tailer.File) {
+	found := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		found[file.GetScanKey()] = struct{}{}
+	}
+	for scanKey, identifier := range s.compressedFiles {
+		if _, isFound := found[scanKey]; !isFound {
+			delete(s.compressedFiles, scanKey)
+			continue
+		}
+		s.registry.KeepAlive(identifier)
+	}
+}
+
 // handleTailingModeChange determines the tailing behaviour when the tailing mode for a given file has its
 // configuration change. Two case may happen we can switch from "end" to "beginning" (1) and from "beginning" to
 // "end" (2). If the tailing mode is set to forceEnd or forceBeginning it will remain unchanged.
@@ -356,6 +454,30 @@ func (s *Launcher) handleTailingModeChange(tailerID string, currentTailingMode c
 	return currentTailingMode
 }
 
+// handleFingerprintChange determines the tailing behaviour when the fingerprint of a file differs from the one
+// registered with its offset. In that case the file has been replaced since its offset was committed, e.g. it has been
+// rotated while the Agent was not running, so the offset is ignored and the new file is read from the beginning, as
+// after a rotation. If the tailing mode is set to forceEnd or forceBeginning it will remain unchanged.
+func (s *Launcher) handleFingerprintChange(tailerID string, path string, currentTailingMode config.TailingMode) config.TailingMode {
+	if s.fingerprintSize == 0 || currentTailingMode == config.ForceBeginning || currentTailingMode == config.ForceEnd {
+		return currentTailingMode
+	}
+	previous := s.registry.GetFingerprint(tailerID)
+	if previous == "" {
+		return currentTailingMode
+	}
+	current, err := tailer.ComputeFingerprint(path, s.fingerprintSize)
+	if err != nil {
+		log.Debugf("Could not compute the fingerprint of %v: %v", path, err)
+		return currentTailingMode
+	}
+	if same, ok := tailer.SameFingerprint(previous, current); !ok || same {
+		return currentTailingMode
+	}
+	log.Infof("Fingerprint changed for %v, the file has been replaced since its offset was committed. Was: %v: Now: %v", tailerID, previous, current)
+	return config.ForceBeginning
+}
+
 // stopTailer stops the tailer
 func (s *Launcher) stopTailer(tailer *tailer.Tailer) {
 	go tailer.Stop()
@@ -384,7 +506,7 @@ func (s *Launcher) restartTailerAfterFileRotation(oldTailer *tailer.Tailer, file
 }
 
 // createTailer returns a new initialized tailer
-func (s *Launcher) createTailer(file *tailer.File, outputChan chan *message.Message, pipelineMonitor metrics.PipelineMonitor) *tailer.Tailer {
+func (s *Launcher) createTailer(file *tailer.File, outputChan chan *message.Message, pipelineMonitor metrics.PipelineMonitor, compressed bool) *tailer.Tailer {
 	tailerInfo := status.NewInfoRegistry()
 
 	tailerOptions := &tailer.TailerOptions{
@@ -395,6 +517,8 @@ func (s *Launcher) createTailer(file *tailer.File, outputChan chan *message.Mess
 		Info:            tailerInfo,
 		TagAdder:        s.tagger,
 		PipelineMonitor: pipelineMonitor,
+		Compressed:      compressed,
+		FingerprintSize: s.fingerprintSize,
 	}
 
 	return tailer.NewTailer(tailerOptions)
@@ -8,6 +8,8 @@
 package file
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"os"
 	"testing"
@@ -743,6 +745,118 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 	assert.True(t, launcher.tailers.Contains(path("b.log")))
 }
 
+func TestLauncherReadsCompressedFilesOnce(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerfxmock.SetupFakeTagger(t)
+	defer status.Clear()
+
+	path := fmt.Sprintf("%s/app.log.1.gz", testDir)
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte("first\nsecond\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	createLauncher := func(offset string) *Launcher {
+		sleepDuration := 20 * time.Millisecond
+		fc := flareController.NewFlareController()
+		launcher := NewLauncher(2, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+		launcher.readCompressedFiles = true
+		launcher.pipelineProvider = mock.NewMockProvider()
+		registry := auditor.NewRegistry()
+		registry.SetOffset(offset)
+		launcher.registry = registry
+		source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: fmt.Sprintf("%s/*.gz", testDir)})
+		launcher.activeSources = append(launcher.activeSources, source)
+		status.Clear()
+		status.InitStatus(pkgconfigsetup.Datadog(), util.CreateSources([]*sources.LogSource{source}))
+		return launcher
+	}
+
+	// the file is read from the beginning, regardless of the tailing mode
+	launcher := createLauncher("")
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	launcher.scan()
+	assert.Equal(t, 1, launcher.tailers.Count())
+	msg := <-outputChan
+	assert.Equal(t, "first", string(msg.GetContent()))
+	msg = <-outputChan
+	assert.Equal(t, "second", string(msg.GetContent()))
+	assert.Equal(t, filetailer.CompletedOffset, msg.Origin.Offset)
+
+	// once read, the file is not read again
+	compressedTailer, _ := launcher.tailers.Get(path)
+	assert.Eventually(t, compressedTailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	launcher.scan()
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	launcher.cleanup()
+
+	// the reading is resumed from the committed offset
+	launcher = createLauncher("6")
+	outputChan = launcher.pipelineProvider.NextPipelineChan()
+	launcher.scan()
+	msg = <-outputChan
+	assert.Equal(t, "second", string(msg.GetContent()))
+	launcher.cleanup()
+
+	// the completion is remembered across restarts
+	launcher = createLauncher(filetailer.CompletedOffset)
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	assert.Contains(t, launcher.compressedFiles, path)
+}
+
+func TestLauncherFingerprintChangeIgnoresOffset(t *testing.T) {
+	testDir := t.TempDir()
+	fakeTagger := taggerfxmock.SetupFakeTagger(t)
+	defer status.Clear()
+
+	path := fmt.Sprintf("%s/test.log", testDir)
+	assert.Nil(t, os.WriteFile(path, []byte("first line\nsecond line\n"), 0644))
+	fingerprint, err := filetailer.ComputeFingerprint(path, 8)
+	assert.Nil(t, err)
+
+	createLauncher := func(fingerprint string) *Launcher {
+		sleepDuration := 20 * time.Millisecond
+		fc := flareController.NewFlareController()
+		launcher := NewLauncher(2, sleepDuration, false, 10*time.Second, "by_name", fc, fakeTagger)
+		launcher.fingerprintSize = 8
+		launcher.pipelineProvider = mock.NewMockProvider()
+		registry := auditor.NewRegistry()
+		registry.SetOffset("11")
+		registry.SetTailingMode("beginning")
+		registry.SetFingerprint(fingerprint)
+		launcher.registry = registry
+		source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+		launcher.activeSources = append(launcher.activeSources, source)
+		status.Clear()
+		status.InitStatus(pkgconfigsetup.Datadog(), util.CreateSources([]*sources.LogSource{source}))
+		return launcher
+	}
+
+	// the file is the one whose offset was committed, tailing resumes from the offset
+	launcher := createLauncher(fingerprint)
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	launcher.scan()
+	msg := <-outputChan
+	assert.Equal(t, "second line", string(msg.GetContent()))
+	assert.Equal(t, fingerprint, msg.Origin.Fingerprint)
+	launcher.cleanup()
+
+	// the file has been replaced, it's tailed from the beginning
+	launcher = createLauncher("8:0000000000000000")
+	outputChan = launcher.pipelineProvider.NextPipelineChan()
+	launcher.scan()
+	msg = <-outputChan
+	assert.Equal(t, "first line", string(msg.GetContent()))
+	// the tailer must not be blocked forwarding a line to be stopped
+	msg = <-outputChan
+	assert.Equal(t, "second line", string(msg.GetContent()))
+	launcher.cleanup()
+}
+
 func getScanKey(path string, source *sources.LogSource) string {
 	return filetailer.NewFile(path, source, false).GetScanKey()
 }
@@ -94,6 +94,9 @@ type MessageMetadata struct {
 	ParsingExtra
 	// Extra information for Serverless Logs messages
 	ServerlessExtra
+	// Processing rules applied to the message, only recorded when the
+	// processor traces them (e.g. for the `analyze-logs` command)
+	ProcessingTrace *ProcessingTrace
 }
 
 // MessageContent contains the message and possibly the tailer internal representation
@@ -201,6 +204,35 @@ func (m *MessageContent) SetEncoded(content []byte) {
 	m.State = StateEncoded
 }
 
+// SetAttributes adds the given attributes to the structured content of the message.
+// An unstructured message is first turned into a structured one, its content
+// being stored in the "message" key; an attribute named "message" thus replaces
+// the content of the message.
+// It returns false if the message can't hold attributes, either because it has
+// already been rendered or because its structured content doesn't support it.
+func (m *MessageContent) SetAttributes(attributes map[string]interface{}) bool {
+	switch m.State {
+	case StateUnstructured:
+		m.structuredContent = &BasicStructuredContent{
+			Data: map[string]interface{}{"message": string(m.content)},
+		}
+		m.content = nil
+		m.State = StateStructured
+	case StateStructured:
+	default:
+		return false
+	}
+
+	content, ok := m.structuredContent.(*BasicStructuredContent)
+	if !ok {
+		return false
+	}
+	for key, value := range attributes {
+		content.Data[key] = value
+	}
+	return true
+}
+
 // ParsingExtra ships extra information parsers want to make available
 // to the rest of the pipeline.
 // E.g. Timestamp is used by the docker parsers to transmit a tailing offset.
@@ -213,10 +245,19 @@ type ParsingExtra struct {
 	Tags        []string
 }
 
+// ProcessingTrace records which processing rules applied to a message.
+type ProcessingTrace struct {
+	// Names of the rules which applied to the message, in order.
+	RuleHits []string
+	// Name of the rule which dropped the message, empty if it was kept.
+	DroppedBy string
+}
+
 // ServerlessExtra ships extra information from logs processing in serverless envs.
 type ServerlessExtra struct {
 	// Optional. Must be UTC. If not provided, time.Now().UTC() will be used
-	// Used in the Serverless Agent
+	// Used in the Serverless Agent, and set by processing rules extracting
+	// the timestamp from the message content.
 	Timestamp time.Time
 	// Optional.
 	// Used in the Serverless Agent
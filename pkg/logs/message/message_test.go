@@ -25,6 +25,24 @@ func TestMessage(t *testing.T) {
 	assert.Equal(t, StatusInfo, message.GetStatus())
 }
 
+func TestSetAttributes(t *testing.T) {
+	msg := NewMessage([]byte("hello"), nil, "", 0)
+	assert.True(t, msg.SetAttributes(map[string]interface{}{"foo": "bar"}))
+	assert.Equal(t, StateStructured, msg.State)
+	assert.Equal(t, "hello", string(msg.GetContent()))
+
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"message":"hello","foo":"bar"}`, string(rendered))
+
+	// a "message" attribute replaces the content
+	assert.True(t, msg.SetAttributes(map[string]interface{}{"message": "world"}))
+	assert.Equal(t, "world", string(msg.GetContent()))
+
+	msg.SetRendered([]byte("rendered"))
+	assert.False(t, msg.SetAttributes(map[string]interface{}{"foo": "bar"}))
+}
+
 func TestNewPayload(t *testing.T) {
 	messages := []*Message{
 		NewMessage([]byte("hello"), nil, "", 0),
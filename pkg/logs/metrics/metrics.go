@@ -63,6 +63,14 @@ var (
 	// TlmBytesMissed is the number of bytes lost before they could be consumed by the agent, such as after log rotation
 	TlmBytesMissed = telemetry.NewCounter("logs", "bytes_missed",
 		nil, "Total number of bytes lost before they could be consumed by the agent, such as after log rotation")
+	// LogsThrottled is the number of logs dropped by the sample, rate_limit and dedupe processing rules
+	LogsThrottled = expvar.Int{}
+	// TlmLogsThrottled is the number of logs dropped by the sample, rate_limit and dedupe processing rules
+	TlmLogsThrottled = telemetry.NewCounter("logs", "throttled",
+		[]string{"rule_type"}, "Total number of logs dropped by the sample, rate_limit and dedupe processing rules")
+	// TlmLogsToMetrics is the number of metric samples generated from logs by the log_to_metric processing rules
+	TlmLogsToMetrics = telemetry.NewCounter("logs", "logs_to_metrics",
+		[]string{"rule_name"}, "Total number of metric samples generated from logs by the log_to_metric processing rules")
 	// SenderLatency the last reported latency value from the http sender (ms)
 	SenderLatency = expvar.Int{}
 	// TlmSenderLatency a histogram of http sender latency (ms)
@@ -111,6 +119,7 @@ func init() {
 	LogsExpvars.Set("RetryTimeSpent", &RetryTimeSpent)
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
 	LogsExpvars.Set("BytesMissed", &BytesMissed)
+	LogsExpvars.Set("LogsThrottled", &LogsThrottled)
 	LogsExpvars.Set("SenderLatency", &SenderLatency)
 	LogsExpvars.Set("HttpDestinationStats", &DestinationExpVars)
 }
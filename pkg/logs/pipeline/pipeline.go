@@ -8,6 +8,8 @@ package pipeline
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 	"strconv"
 	"sync"
 
@@ -18,6 +20,7 @@ import (
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/http"
+	"github.com/DataDog/datadog-agent/pkg/logs/client/otlp"
 	"github.com/DataDog/datadog-agent/pkg/logs/client/tcp"
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -26,6 +29,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface"
 	compressioncommon "github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Pipeline processes and sends messages to the backend
@@ -35,6 +39,7 @@ type Pipeline struct {
 	processor       *processor.Processor
 	strategy        sender.Strategy
 	sender          *sender.Sender
+	exporters       []*otlp.Exporter
 	serverless      bool
 	flushWg         *sync.WaitGroup
 	pipelineMonitor metrics.PipelineMonitor
@@ -52,6 +57,8 @@ func NewPipeline(outputChan chan *message.Payload,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
+	diskBuffer bool,
 ) *Pipeline {
 
 	var senderDoneChan chan *sync.WaitGroup
@@ -62,7 +69,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	}
 	pipelineMonitor := metrics.NewTelemetryPipelineMonitor(strconv.Itoa(pipelineID))
 
-	mainDestinations := getDestinations(endpoints, destinationsContext, pipelineMonitor, serverless, senderDoneChan, status, cfg)
+	mainDestinations := getDestinations(endpoints, destinationsContext, pipelineMonitor, serverless, diskBuffer, senderDoneChan, status, cfg)
 
 	strategyInput := make(chan *message.Message, pkgconfigsetup.Datadog().GetInt("logs_config.message_channel_size"))
 	senderInput := make(chan *message.Payload, 1) // Only buffer 1 message since payloads can be large
@@ -80,6 +87,13 @@ func NewPipeline(outputChan chan *message.Payload,
 	} else {
 		encoder = processor.RawEncoder
 	}
+	var exporters []*otlp.Exporter
+	if !serverless {
+		exporters = getOTLPExporters(endpoints, destinationsContext, cfg)
+	}
+	if len(exporters) > 0 {
+		encoder = &otlpEncoder{Encoder: encoder, exporters: exporters}
+	}
 
 	strategy := getStrategy(strategyInput, senderInput, flushChan, endpoints, serverless, flushWg, pipelineMonitor, compression)
 	logsSender = sender.NewSender(cfg, senderInput, outputChan, mainDestinations, pkgconfigsetup.Datadog().GetInt("logs_config.payload_channel_size"), senderDoneChan, flushWg, pipelineMonitor)
@@ -87,7 +101,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	inputChan := make(chan *message.Message, pkgconfigsetup.Datadog().GetInt("logs_config.message_channel_size"))
 
 	processor := processor.New(cfg, inputChan, strategyInput, processingRules,
-		encoder, diagnosticMessageReceiver, hostname, pipelineMonitor)
+		encoder, diagnosticMessageReceiver, hostname, metricSender, pipelineMonitor)
 
 	return &Pipeline{
 		InputChan:       inputChan,
@@ -95,6 +109,7 @@ func NewPipeline(outputChan chan *message.Payload,
 		processor:       processor,
 		strategy:        strategy,
 		sender:          logsSender,
+		exporters:       exporters,
 		serverless:      serverless,
 		flushWg:         flushWg,
 		pipelineMonitor: pipelineMonitor,
@@ -103,6 +118,9 @@ func NewPipeline(outputChan chan *message.Payload,
 
 // Start launches the pipeline
 func (p *Pipeline) Start() {
+	for _, exporter := range p.exporters {
+		exporter.Start()
+	}
 	p.sender.Start()
 	p.strategy.Start()
 	p.processor.Start()
@@ -111,6 +129,9 @@ func (p *Pipeline) Start() {
 // Stop stops the pipeline
 func (p *Pipeline) Stop() {
 	p.processor.Stop()
+	for _, exporter := range p.exporters {
+		exporter.Stop()
+	}
 	p.strategy.Stop()
 	p.sender.Stop()
 }
@@ -126,7 +147,7 @@ func (p *Pipeline) Flush(ctx context.Context) {
 	}
 }
 
-func getDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, pipelineMonitor metrics.PipelineMonitor, serverless bool, senderDoneChan chan *sync.WaitGroup, status statusinterface.Status, cfg pkgconfigmodel.Reader) *client.Destinations {
+func getDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, pipelineMonitor metrics.PipelineMonitor, serverless bool, diskBuffer bool, senderDoneChan chan *sync.WaitGroup, status statusinterface.Status, cfg pkgconfigmodel.Reader) *client.Destinations {
 	reliable := []client.Destination{}
 	additionals := []client.Destination{}
 
@@ -136,7 +157,11 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 			if serverless {
 				reliable = append(reliable, http.NewSyncDestination(endpoint, http.JSONContentType, destinationsContext, senderDoneChan, destMeta, cfg))
 			} else {
-				reliable = append(reliable, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, true, destMeta, cfg, endpoints.BatchMaxConcurrentSend, endpoints.BatchMaxConcurrentSend, pipelineMonitor))
+				var destination client.Destination = http.NewDestination(endpoint, http.JSONContentType, destinationsContext, true, destMeta, cfg, endpoints.BatchMaxConcurrentSend, endpoints.BatchMaxConcurrentSend, pipelineMonitor)
+				if diskBuffer {
+					destination = newDiskBufferDestination(destination, endpoint, pipelineMonitor.ID(), i, cfg)
+				}
+				reliable = append(reliable, destination)
 			}
 		}
 		for i, endpoint := range endpoints.GetUnReliableEndpoints() {
@@ -159,6 +184,52 @@ func getDestinations(endpoints *config.Endpoints, destinationsContext *client.De
 	return client.NewDestinations(reliable, additionals)
 }
 
+// getOTLPExporters returns the exporters of the OpenTelemetry collectors logs are dual-shipped to.
+func getOTLPExporters(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, cfg pkgconfigmodel.Reader) []*otlp.Exporter {
+	var exporters []*otlp.Exporter
+	for _, endpoint := range endpoints.OTLPEndpoints {
+		exporter, err := otlp.NewExporter(endpoint, destinationsContext, endpoints.BatchWait, endpoints.BatchMaxSize, cfg)
+		if err != nil {
+			log.Warnf("Could not create the OTLP logs exporter: %v", err)
+			continue
+		}
+		exporters = append(exporters, exporter)
+	}
+	return exporters
+}
+
+// otlpEncoder exports the rendered messages to OpenTelemetry collectors, then
+// encodes them for Datadog.
+type otlpEncoder struct {
+	processor.Encoder
+	exporters []*otlp.Exporter
+}
+
+// Encode implements processor.Encoder
+func (e *otlpEncoder) Encode(msg *message.Message, hostname string) error {
+	for _, exporter := range e.exporters {
+		exporter.Export(msg, hostname)
+	}
+	return e.Encoder.Encode(msg, hostname)
+}
+
+// newDiskBufferDestination wraps the destination to buffer its payloads on disk
+// while it's retrying, each destination using its own directory named after its
+// intake, pipeline and index.
+func newDiskBufferDestination(destination client.Destination, endpoint config.Endpoint, pipelineID string, index int, cfg pkgconfigmodel.Reader) client.Destination {
+	path := cfg.GetString("logs_config.disk_buffer.path")
+	if path == "" {
+		path = filepath.Join(cfg.GetString("logs_config.run_path"), "disk_buffer")
+	}
+	maxSize := cfg.GetInt64("logs_config.disk_buffer.max_size_in_bytes")
+	if maxSize <= 0 {
+		log.Warnf("Invalid logs_config.disk_buffer.max_size_in_bytes %d, the logs disk buffer is disabled", maxSize)
+		return destination
+	}
+	name := fmt.Sprintf("logs_%s_%d_%s_reliable_%d", endpoint.Host, endpoint.Port, pipelineID, index)
+	return sender.NewDiskBufferDestination(destination, filepath.Join(path, name), maxSize)
+}
+
 //nolint:revive // TODO(AML) Fix revive linter
 func getStrategy(
 	inputChan chan *message.Message,
@@ -0,0 +1,56 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package pipeline
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestOTLPEncoderWithTCPEndpoints(t *testing.T) {
+	received := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		received <- struct{}{}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	destinationsContext := client.NewDestinationsContext()
+	destinationsContext.Start()
+	defer destinationsContext.Stop()
+
+	endpoints := config.NewEndpoints(config.Endpoint{}, nil, false, false)
+	endpoints.OTLPEndpoints = []config.OTLPEndpoint{
+		{Endpoint: server.URL, Protocol: config.OTLPProtocolHTTP},
+		{Endpoint: "https://", Protocol: config.OTLPProtocolHTTP},
+	}
+	// the invalid endpoint is ignored
+	exporters := getOTLPExporters(endpoints, destinationsContext, configmock.New(t))
+	require.Len(t, exporters, 1)
+	exporters[0].Start()
+
+	encoder := &otlpEncoder{Encoder: processor.RawEncoder, exporters: exporters}
+	msg := message.NewMessageWithSource(nil, message.StatusInfo, sources.NewLogSource("", &config.LogsConfig{}), 0)
+	msg.SetRendered([]byte("hello"))
+	require.NoError(t, encoder.Encode(msg, "host"))
+	exporters[0].Stop()
+
+	// the message is encoded for Datadog and exported
+	assert.Equal(t, message.StateEncoded, msg.State)
+	assert.Contains(t, string(msg.GetContent()), "hello")
+	assert.Len(t, received, 1)
+}
@@ -28,7 +28,8 @@ type processorOnlyProvider struct {
 }
 
 // NewProcessorOnlyProvider is used by the logs check subcommand as the feature does not require the functionalities of the log pipeline other then the processor.
-func NewProcessorOnlyProvider(diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, cfg pkgconfigmodel.Reader, hostname hostnameinterface.Component) Provider {
+// When traceRules is set, the messages dropped by processing rules are forwarded too, with the trace of the rules applied to them.
+func NewProcessorOnlyProvider(diagnosticMessageReceiver diagnostic.MessageReceiver, processingRules []*config.ProcessingRule, cfg pkgconfigmodel.Reader, hostname hostnameinterface.Component, traceRules bool) Provider {
 	chanSize := pkgconfigsetup.Datadog().GetInt("logs_config.message_channel_size")
 	outputChan := make(chan *message.Message, chanSize)
 	encoder := processor.JSONEncoder
@@ -36,7 +37,10 @@ func NewProcessorOnlyProvider(diagnosticMessageReceiver diagnostic.MessageReceiv
 	pipelineID := 0
 	pipelineMonitor := metrics.NewTelemetryPipelineMonitor(strconv.Itoa(pipelineID))
 	processor := processor.New(cfg, inputChan, outputChan, processingRules,
-		encoder, diagnosticMessageReceiver, hostname, pipelineMonitor)
+		encoder, diagnosticMessageReceiver, hostname, nil, pipelineMonitor)
+	if traceRules {
+		processor.TraceProcessingRules()
+	}
 
 	p := &processorOnlyProvider{
 		processor:       processor,
@@ -20,6 +20,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -55,13 +56,21 @@ type provider struct {
 
 	serverless bool
 
-	status      statusinterface.Status
-	hostname    hostnameinterface.Component
-	cfg         pkgconfigmodel.Reader
-	compression logscompression.Component
+	status       statusinterface.Status
+	hostname     hostnameinterface.Component
+	cfg          pkgconfigmodel.Reader
+	compression  logscompression.Component
+	metricSender processor.MetricSender
+	// diskBuffer is set when the reliable destinations buffer their payloads on disk while retrying.
+	diskBuffer bool
+	// metricCommitter commits the metrics generated from logs by all the pipelines.
+	metricCommitter *processor.MetricCommitter
 }
 
-// NewProvider returns a new Provider
+// NewProvider returns a new Provider, the payloads of its reliable destinations
+// are buffered on disk while retrying when diskBuffer is set.  The buffer
+// directories are named after the pipelines and destinations, so only one
+// component of a process may enable it.
 func NewProvider(numberOfPipelines int,
 	auditor auditor.Auditor,
 	diagnosticMessageReceiver diagnostic.MessageReceiver,
@@ -72,8 +81,10 @@ func NewProvider(numberOfPipelines int,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
+	diskBuffer bool,
 ) Provider {
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, status, hostname, cfg, compression)
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, status, hostname, cfg, compression, metricSender, diskBuffer)
 }
 
 // NewServerlessProvider returns a new Provider in serverless mode
@@ -87,9 +98,10 @@ func NewServerlessProvider(numberOfPipelines int,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
 ) Provider {
 
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, true, status, hostname, cfg, compression)
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, true, status, hostname, cfg, compression, metricSender, false)
 }
 
 // NewMockProvider creates a new provider that will not provide any pipelines.
@@ -108,6 +120,8 @@ func newProvider(numberOfPipelines int,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
+	diskBuffer bool,
 ) Provider {
 	return &provider{
 		numberOfPipelines:         numberOfPipelines,
@@ -123,6 +137,8 @@ func newProvider(numberOfPipelines int,
 		hostname:                  hostname,
 		cfg:                       cfg,
 		compression:               compression,
+		metricSender:              metricSender,
+		diskBuffer:                diskBuffer,
 	}
 }
 
@@ -132,10 +148,12 @@ func (p *provider) Start() {
 	p.outputChan = p.auditor.Channel()
 
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, p.status, p.hostname, p.cfg, p.compression)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, p.status, p.hostname, p.cfg, p.compression, p.metricSender, p.diskBuffer)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
+	p.metricCommitter = processor.NewMetricCommitter(p.metricSender)
+	p.metricCommitter.Start()
 }
 
 // Stop stops all pipelines in parallel,
@@ -146,6 +164,10 @@ func (p *provider) Stop() {
 		stopper.Add(pipeline)
 	}
 	stopper.Stop()
+	if p.metricCommitter != nil {
+		p.metricCommitter.Stop()
+		p.metricCommitter = nil
+	}
 	p.pipelines = p.pipelines[:0]
 	p.outputChan = nil
 }
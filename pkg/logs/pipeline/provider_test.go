@@ -63,6 +63,27 @@ func (suite *ProviderTestSuite) TestProvider() {
 	suite.Nil(suite.p.NextPipelineChan())
 }
 
+// countingMetricSender counts its commits.
+type countingMetricSender struct {
+	commits atomic.Int32
+}
+
+func (s *countingMetricSender) Count(string, float64, string, []string)        {}
+func (s *countingMetricSender) Distribution(string, float64, string, []string) {}
+func (s *countingMetricSender) Commit()                                        { s.commits.Inc() }
+
+func (suite *ProviderTestSuite) TestProviderCommitsMetricsOnce() {
+	sender := &countingMetricSender{}
+	suite.p.metricSender = sender
+	suite.a.Start()
+	suite.p.Start()
+	suite.p.Stop()
+	suite.a.Stop()
+
+	// the metrics of all the pipelines are committed together
+	suite.Equal(int32(1), sender.commits.Load())
+}
+
 func TestProviderTestSuite(t *testing.T) {
 	suite.Run(t, new(ProviderTestSuite))
 }
@@ -0,0 +1,90 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// statusAliases maps the usual spellings of log levels to message statuses.
+var statusAliases = map[string]string{
+	"emerg":         message.StatusEmergency,
+	"emergency":     message.StatusEmergency,
+	"panic":         message.StatusEmergency,
+	"alert":         message.StatusAlert,
+	"crit":          message.StatusCritical,
+	"critical":      message.StatusCritical,
+	"fatal":         message.StatusCritical,
+	"err":           message.StatusError,
+	"error":         message.StatusError,
+	"severe":        message.StatusError,
+	"warn":          message.StatusWarning,
+	"warning":       message.StatusWarning,
+	"notice":        message.StatusNotice,
+	"info":          message.StatusInfo,
+	"information":   message.StatusInfo,
+	"informational": message.StatusInfo,
+	"debug":         message.StatusDebug,
+	"trace":         message.StatusDebug,
+}
+
+// applyExtractFieldsRule adds the fields captured by the named groups of an
+// extract_fields rule to the message attributes, and promotes the configured
+// fields to the message status, service and timestamp. It returns whether the
+// rule matched the message.
+func applyExtractFieldsRule(rule *config.ProcessingRule, msg *message.Message) bool {
+	match := rule.Regex.FindSubmatch(msg.GetContent())
+	if match == nil {
+		return false
+	}
+
+	fields := make(map[string]interface{})
+	for i, name := range rule.Regex.SubexpNames() {
+		if name == "" || match[i] == nil {
+			continue
+		}
+		value := string(match[i])
+		fields[name] = value
+
+		switch name {
+		case rule.StatusField:
+			if status, found := statusAliases[strings.ToLower(value)]; found {
+				msg.Status = status
+			}
+		case rule.ServiceField:
+			msg.Origin.SetService(value)
+		case rule.TimestampField:
+			if ts, err := parseExtractedTimestamp(rule.TimestampFormat, value); err == nil {
+				msg.ServerlessExtra.Timestamp = ts
+			} else {
+				log.Debugf("Can't parse the timestamp extracted by processing rule %s: %v", rule.Name, err)
+			}
+		}
+	}
+
+	if !msg.SetAttributes(fields) {
+		log.Debugf("Can't add the fields extracted by processing rule %s to the message", rule.Name)
+	}
+	return true
+}
+
+// parseExtractedTimestamp parses a timestamp with the given layout, RFC 3339
+// being used when no layout is configured.
+func parseExtractedTimestamp(layout, value string) (time.Time, error) {
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	ts, err := time.Parse(layout, value)
+	if err != nil {
+		return ts, err
+	}
+	return ts.UTC(), nil
+}
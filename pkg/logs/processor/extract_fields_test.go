@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newExtractFieldsSource(t *testing.T, rules ...*config.ProcessingRule) *sources.LogSource {
+	require.NoError(t, config.ValidateProcessingRules(rules))
+	require.NoError(t, config.CompileProcessingRules(rules))
+	return sources.NewLogSource("", &config.LogsConfig{ProcessingRules: rules})
+}
+
+func TestExtractFields(t *testing.T) {
+	p := &Processor{}
+	source := newExtractFieldsSource(t, &config.ProcessingRule{
+		Type:           config.ExtractFields,
+		Name:           "access",
+		Pattern:        `^%{TIMESTAMP_ISO8601:ts} %{LOGLEVEL:level} %{WORD:app} %{HTTPMETHOD:method} %{URIPATH:path} %{INT:code}`,
+		StatusField:    "level",
+		ServiceField:   "app",
+		TimestampField: "ts",
+	})
+
+	msg := newMessage([]byte("2024-01-02T03:04:05.123Z WARNING checkout GET /api/cart 404"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+
+	assert.Equal(t, message.StatusWarning, msg.Status)
+	assert.Equal(t, "checkout", msg.Origin.Service())
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC), msg.ServerlessExtra.Timestamp)
+
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"message": "2024-01-02T03:04:05.123Z WARNING checkout GET /api/cart 404",
+		"ts": "2024-01-02T03:04:05.123Z",
+		"level": "WARNING",
+		"app": "checkout",
+		"method": "GET",
+		"path": "/api/cart",
+		"code": "404"
+	}`, string(rendered))
+}
+
+func TestExtractFieldsNoMatch(t *testing.T) {
+	p := &Processor{}
+	source := newExtractFieldsSource(t, &config.ProcessingRule{
+		Type:    config.ExtractFields,
+		Name:    "user",
+		Pattern: `user=(?P<user>\w+)`,
+	})
+
+	msg := newMessage([]byte("hello world"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, message.StateUnstructured, msg.State)
+	assert.Equal(t, []byte("hello world"), msg.GetContent())
+}
+
+func TestExtractFieldsAfterMasking(t *testing.T) {
+	p := &Processor{}
+	source := newExtractFieldsSource(t,
+		&config.ProcessingRule{
+			Type:    config.ExtractFields,
+			Name:    "key",
+			Pattern: `api_key=(?P<api_key>\S+) (?P<message>.*)`,
+		},
+		&config.ProcessingRule{
+			Type:               config.MaskSequences,
+			Name:               "mask",
+			Pattern:            `api_key=\w+`,
+			ReplacePlaceholder: "api_key=****",
+		},
+	)
+
+	msg := newStructuredMessage([]byte("api_key=abcdef request sent"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, []byte("request sent"), msg.GetContent())
+
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"message": "request sent", "api_key": "****"}`, string(rendered))
+}
+
+func TestExtractFieldsValidation(t *testing.T) {
+	for _, rule := range []*config.ProcessingRule{
+		{Type: config.ExtractFields, Name: "no capture", Pattern: `\w+`},
+		{Type: config.ExtractFields, Name: "unknown grok", Pattern: `%{FOO:bar}`},
+		{Type: config.ExtractFields, Name: "unknown field", Pattern: `(?P<a>\w+)`, StatusField: "b"},
+	} {
+		assert.Error(t, config.ValidateProcessingRules([]*config.ProcessingRule{rule}), rule.Name)
+	}
+}
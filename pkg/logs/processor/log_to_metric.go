@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// metricsCommitInterval is the interval at which the metrics generated from logs
+// are committed to the aggregator.
+const metricsCommitInterval = 15 * time.Second
+
+// MetricSender submits the metrics generated by log_to_metric rules, it's
+// implemented by the sender.Sender of the aggregator.  The processors of all the
+// pipelines share the sender, which is committed by a single MetricCommitter.
+type MetricSender interface {
+	Count(metric string, value float64, hostname string, tags []string)
+	Distribution(metric string, value float64, hostname string, tags []string)
+	Commit()
+}
+
+// MetricCommitter periodically commits the metrics generated from logs to the
+// aggregator, as checks do.
+type MetricCommitter struct {
+	sender MetricSender
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewMetricCommitter returns a committer of the metrics submitted to sender.  It
+// does nothing if sender is nil.
+func NewMetricCommitter(sender MetricSender) *MetricCommitter {
+	return &MetricCommitter{
+		sender: sender,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start starts committing the metrics.
+func (c *MetricCommitter) Start() {
+	if c.sender == nil {
+		return
+	}
+	go c.run()
+}
+
+// Stop commits the metrics one last time, and stops committing them.  The
+// processors must have been stopped.
+func (c *MetricCommitter) Stop() {
+	if c.sender == nil {
+		return
+	}
+	close(c.stop)
+	<-c.done
+}
+
+func (c *MetricCommitter) run() {
+	defer close(c.done)
+	ticker := time.NewTicker(metricsCommitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.sender.Commit()
+		case <-c.stop:
+			c.sender.Commit()
+			return
+		}
+	}
+}
+
+// applyLogToMetricRule submits the metric of a log_to_metric rule if the content
+// matches its pattern, and returns whether it matched and the metric could be
+// generated.
+func (p *Processor) applyLogToMetricRule(rule *config.ProcessingRule, msg *message.Message, content []byte) bool {
+	if !isMatchingLiteralPrefix(rule.Regex, content) {
+		return false
+	}
+	match := rule.Regex.FindSubmatch(content)
+	if match == nil {
+		return false
+	}
+	if p.metricSender == nil {
+		// the log is kept, as the metric can't be generated
+		log.Debugf("Can't generate the metric of processing rule %s, metrics can't be sent from this agent", rule.Name)
+		return false
+	}
+
+	value := 1.0
+	if rule.ValueIndex > 0 {
+		var err error
+		if value, err = strconv.ParseFloat(string(match[rule.ValueIndex]), 64); err != nil {
+			// the log is kept, as the metric can't be generated
+			log.Debugf("Can't parse the value of the metric of processing rule %s: %v", rule.Name, err)
+			return false
+		}
+	}
+
+	tags := logToMetricTags(msg)
+	switch rule.MetricType {
+	case config.MetricTypeDistribution:
+		p.metricSender.Distribution(rule.MetricName, value, msg.Hostname, tags)
+	default:
+		p.metricSender.Count(rule.MetricName, value, msg.Hostname, tags)
+	}
+	metrics.TlmLogsToMetrics.Inc(rule.Name)
+	return true
+}
+
+// logToMetricTags returns the tags of the source of the message, including its
+// service and source, to tag the metrics generated from it.
+func logToMetricTags(msg *message.Message) []string {
+	// the tags of the origin must not be modified
+	tags := append([]string(nil), msg.Origin.Tags(msg.ProcessingTags)...)
+	if service := msg.Origin.Service(); service != "" {
+		tags = append(tags, "service:"+service)
+	}
+	if source := msg.Origin.Source(); source != "" {
+		tags = append(tags, "source:"+source)
+	}
+	return tags
+}
@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+type sample struct {
+	metricType string
+	name       string
+	value      float64
+	hostname   string
+	tags       []string
+}
+
+// fakeMetricSender records the metrics it's sent.
+type fakeMetricSender struct {
+	samples []sample
+	commits int
+}
+
+func (s *fakeMetricSender) Count(metric string, value float64, hostname string, tags []string) {
+	s.samples = append(s.samples, sample{"count", metric, value, hostname, tags})
+}
+
+func (s *fakeMetricSender) Distribution(metric string, value float64, hostname string, tags []string) {
+	s.samples = append(s.samples, sample{"distribution", metric, value, hostname, tags})
+}
+
+func (s *fakeMetricSender) Commit() {
+	s.commits++
+}
+
+func newLogToMetricSource(t *testing.T, rules ...*config.ProcessingRule) *sources.LogSource {
+	for _, rule := range rules {
+		rule.Type = config.LogToMetric
+	}
+	require.NoError(t, config.ValidateProcessingRules(rules))
+	require.NoError(t, config.CompileProcessingRules(rules))
+	return sources.NewLogSource("", &config.LogsConfig{
+		Service:         "web",
+		Source:          "nginx",
+		Tags:            []string{"env:prod"},
+		ProcessingRules: rules,
+	})
+}
+
+func TestLogToMetricCount(t *testing.T) {
+	sender := &fakeMetricSender{}
+	p := &Processor{metricSender: sender}
+	source := newLogToMetricSource(t, &config.ProcessingRule{Name: "errors", Pattern: "ERROR", MetricName: "app.errors"})
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("ERROR something failed"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("INFO all good"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("ERROR something else failed"), source, "")))
+
+	expected := sample{"count", "app.errors", 1, "", []string{"env:prod", "service:web", "source:nginx"}}
+	assert.Equal(t, []sample{expected, expected}, sender.samples)
+}
+
+func TestLogToMetricDistribution(t *testing.T) {
+	sender := &fakeMetricSender{}
+	p := &Processor{metricSender: sender}
+	source := newLogToMetricSource(t, &config.ProcessingRule{
+		Name:       "duration",
+		Pattern:    `took (?P<duration>[0-9.]+)ms`,
+		MetricName: "app.request.duration",
+		MetricType: config.MetricTypeDistribution,
+		ValueGroup: "duration",
+	})
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("GET / took 12.5ms"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("GET / took 1e400ms"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("GET / failed"), source, "")))
+
+	// values which can't be parsed are ignored
+	require.Len(t, sender.samples, 1)
+	assert.Equal(t, "distribution", sender.samples[0].metricType)
+	assert.Equal(t, "app.request.duration", sender.samples[0].name)
+	assert.Equal(t, 12.5, sender.samples[0].value)
+}
+
+func TestLogToMetricDropLog(t *testing.T) {
+	sender := &fakeMetricSender{}
+	p := &Processor{metricSender: sender}
+	source := newLogToMetricSource(t, &config.ProcessingRule{
+		Name:       "bytes",
+		Pattern:    `sent ([0-9]+) bytes`,
+		MetricName: "app.bytes_sent",
+		ValueGroup: "1",
+		DropLog:    true,
+	})
+
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("sent 512 bytes"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("received 512 bytes"), source, "")))
+
+	require.Len(t, sender.samples, 1)
+	assert.Equal(t, "count", sender.samples[0].metricType)
+	assert.Equal(t, 512.0, sender.samples[0].value)
+}
+
+func TestLogToMetricDropLogInvalidValue(t *testing.T) {
+	sender := &fakeMetricSender{}
+	p := &Processor{metricSender: sender}
+	source := newLogToMetricSource(t, &config.ProcessingRule{
+		Name:       "duration",
+		Pattern:    `took ([0-9.]+)ms`,
+		MetricName: "app.request.duration",
+		ValueGroup: "1",
+		DropLog:    true,
+	})
+
+	// logs are not dropped when their value can't be parsed
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("GET / took 1.2.3ms"), source, "")))
+	assert.Empty(t, sender.samples)
+}
+
+func TestLogToMetricWithoutSender(t *testing.T) {
+	p := &Processor{}
+	source := newLogToMetricSource(t, &config.ProcessingRule{Name: "errors", Pattern: "ERROR", MetricName: "app.errors", DropLog: true})
+
+	// logs are not dropped when the metric can't be sent
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("ERROR something failed"), source, "")))
+}
+
+func TestMetricCommitterCommitsOnStop(t *testing.T) {
+	sender := &fakeMetricSender{}
+	c := NewMetricCommitter(sender)
+	c.Start()
+	c.Stop()
+	assert.Equal(t, 1, sender.commits)
+
+	// nothing is committed without a sender
+	c = NewMetricCommitter(nil)
+	c.Start()
+	c.Stop()
+}
@@ -39,6 +39,12 @@ type Processor struct {
 	diagnosticMessageReceiver diagnostic.MessageReceiver
 	mu                        sync.Mutex
 	hostname                  hostnameinterface.Component
+	// metricSender submits the metrics generated by log_to_metric rules, nil
+	// when metrics can't be sent.
+	metricSender MetricSender
+	// traceRules records the processing rules applied to each message, and
+	// forwards the messages dropped by a rule instead of discarding them.
+	traceRules bool
 
 	sds sdsProcessor
 
@@ -63,7 +69,7 @@ type sdsProcessor struct {
 // New returns an initialized Processor.
 func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule,
 	encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, hostname hostnameinterface.Component,
-	pipelineMonitor metrics.PipelineMonitor) *Processor {
+	metricSender MetricSender, pipelineMonitor metrics.PipelineMonitor) *Processor {
 
 	waitForSDSConfig := sds.ShouldBufferUntilSDSConfiguration(cfg)
 	maxBufferSize := sds.WaitForConfigurationBufferMaxSize(cfg)
@@ -77,6 +83,7 @@ func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message,
 		done:                      make(chan struct{}),
 		diagnosticMessageReceiver: diagnosticMessageReceiver,
 		hostname:                  hostname,
+		metricSender:              metricSender,
 		pipelineMonitor:           pipelineMonitor,
 		utilization:               pipelineMonitor.MakeUtilizationMonitor("processor"),
 
@@ -89,6 +96,14 @@ func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message,
 	}
 }
 
+// TraceProcessingRules makes the processor record the processing rules applied
+// to each message in its ProcessingTrace, and forward the messages dropped by a
+// rule instead of discarding them. It's used by the `analyze-logs` command and
+// must be called before the processor is started.
+func (p *Processor) TraceProcessingRules() {
+	p.traceRules = true
+}
+
 // Start starts the Processor.
 func (p *Processor) Start() {
 	go p.run()
@@ -229,38 +244,43 @@ func (p *Processor) processMessage(msg *message.Message) {
 	metrics.LogsDecoded.Add(1)
 	metrics.TlmLogsDecoded.Inc()
 
-	if toSend := p.applyRedactingRules(msg); toSend {
+	toSend := p.applyRedactingRules(msg)
+	if toSend {
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
+	} else if !p.traceRules {
+		return
+	}
 
-		// render the message
-		rendered, err := msg.Render()
-		if err != nil {
-			log.Error("can't render the msg", err)
-			return
-		}
-		msg.SetRendered(rendered)
+	// render the message
+	rendered, err := msg.Render()
+	if err != nil {
+		log.Error("can't render the msg", err)
+		return
+	}
+	msg.SetRendered(rendered)
 
+	if toSend {
 		// report this message to diagnostic receivers (e.g. `stream-logs` command)
 		p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
+	}
 
-		// encode the message to its final format, it is done in-place
-		if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
-			log.Error("unable to encode msg ", err)
-			return
-		}
-
-		p.utilization.Stop() // Explicitly call stop here to avoid counting writing on the output channel as processing time
-		p.outputChan <- msg
-		p.pipelineMonitor.ReportComponentIngress(msg, "strategy")
+	// encode the message to its final format, it is done in-place
+	if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
+		log.Error("unable to encode msg ", err)
+		return
 	}
 
+	p.utilization.Stop() // Explicitly call stop here to avoid counting writing on the output channel as processing time
+	p.outputChan <- msg
+	p.pipelineMonitor.ReportComponentIngress(msg, "strategy")
 }
 
 // applyRedactingRules returns given a message if we should process it or not,
 // it applies the change directly on the Message content.
 func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	var content []byte = msg.GetContent()
+	var extractRules []*config.ProcessingRule
 
 	// Use the internal scrubbing implementation of the Agent
 	// ---------------------------
@@ -271,17 +291,45 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 		case config.ExcludeAtMatch:
 			// if this message matches, we ignore it
 			if rule.Regex.Match(content) {
+				p.traceDrop(msg, rule)
 				return false
 			}
 		case config.IncludeAtMatch:
 			// if this message doesn't match, we ignore it
 			if !rule.Regex.Match(content) {
+				p.traceDrop(msg, rule)
 				return false
 			}
+			p.traceHit(msg, rule)
 		case config.MaskSequences:
 			if isMatchingLiteralPrefix(rule.Regex, content) {
+				if p.traceRules && rule.Regex.Match(content) {
+					p.traceHit(msg, rule)
+				}
 				content = rule.Regex.ReplaceAll(content, rule.Placeholder)
 			}
+		case config.Sample, config.RateLimit, config.Dedupe:
+			// without a pattern, the rule applies to every message
+			if rule.Regex != nil && !rule.Regex.Match(content) {
+				continue
+			}
+			if !getThrottler(rule, msg.Origin.LogSource).keep(content) {
+				p.traceDrop(msg, rule)
+				return false
+			}
+			p.traceHit(msg, rule)
+		case config.LogToMetric:
+			if p.applyLogToMetricRule(rule, msg, content) {
+				if rule.DropLog {
+					p.traceDrop(msg, rule)
+					return false
+				}
+				p.traceHit(msg, rule)
+			}
+		case config.ExtractFields:
+			// fields are extracted once every other rule and the SDS scanner
+			// have been applied, so that they never contain masked data
+			extractRules = append(extractRules, rule)
 		}
 	}
 
@@ -299,9 +347,36 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	}
 
 	msg.SetContent(content)
+	for _, rule := range extractRules {
+		if applyExtractFieldsRule(rule, msg) {
+			p.traceHit(msg, rule)
+		}
+	}
 	return true // we want to send this message
 }
 
+// traceHit records that the rule applied to the message, when tracing
+// processing rules.
+func (p *Processor) traceHit(msg *message.Message, rule *config.ProcessingRule) {
+	if !p.traceRules {
+		return
+	}
+	if msg.ProcessingTrace == nil {
+		msg.ProcessingTrace = &message.ProcessingTrace{}
+	}
+	msg.ProcessingTrace.RuleHits = append(msg.ProcessingTrace.RuleHits, rule.Name)
+}
+
+// traceDrop records that the rule dropped the message, when tracing processing
+// rules.
+func (p *Processor) traceDrop(msg *message.Message, rule *config.ProcessingRule) {
+	if !p.traceRules {
+		return
+	}
+	p.traceHit(msg, rule)
+	msg.ProcessingTrace.DroppedBy = rule.Name
+}
+
 // isMatchingLiteralPrefix uses a potential literal prefix from the given regex
 // to indicate if the contant even has a chance of matching the regex
 func isMatchingLiteralPrefix(r *regexp.Regexp, content []byte) bool {
@@ -287,6 +287,32 @@ func TestTruncate(t *testing.T) {
 	assert.Equal(t, []byte("hello"), msg.GetContent())
 }
 
+func TestTraceProcessingRules(t *testing.T) {
+	mask := newProcessingRule(config.MaskSequences, "[masked]", `secret=\w+`)
+	mask.Name = "mask_secrets"
+	exclude := newProcessingRule(config.ExcludeAtMatch, "", "healthcheck")
+	exclude.Name = "exclude_healthchecks"
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{mask, exclude}})
+
+	p := &Processor{}
+	msg := newMessage([]byte("login secret=1234"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Nil(t, msg.ProcessingTrace, "rules are only traced when enabled")
+
+	p.TraceProcessingRules()
+	msg = newMessage([]byte("login secret=1234"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, &message.ProcessingTrace{RuleHits: []string{"mask_secrets"}}, msg.ProcessingTrace)
+
+	msg = newMessage([]byte("GET /healthcheck"), source, "")
+	assert.False(t, p.applyRedactingRules(msg))
+	assert.Equal(t, &message.ProcessingTrace{RuleHits: []string{"exclude_healthchecks"}, DroppedBy: "exclude_healthchecks"}, msg.ProcessingTrace)
+
+	msg = newMessage([]byte("GET /"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Nil(t, msg.ProcessingTrace)
+}
+
 func TestGetHostnameLambda(t *testing.T) {
 	p := &Processor{}
 	m := message.NewMessage([]byte("hello"), nil, "", 0)
@@ -0,0 +1,190 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+// maxDedupeEntries bounds the number of distinct messages remembered by a
+// dedupe rule for a single source.
+const maxDedupeEntries = 10000
+
+// throttlersMu serializes the creation of throttlers, as the messages of a
+// source can be handled by several processors.
+var throttlersMu sync.Mutex
+
+// throttler holds the state of a sample, rate_limit or dedupe processing rule
+// for a single log source.  It is stored in the info registry of the source, so
+// that its state lives as long as the source and the number of messages it
+// dropped shows up on the status page.
+type throttler struct {
+	mu      sync.Mutex
+	rule    *config.ProcessingRule
+	key     string
+	dropped *status.CountInfo
+	now     func() time.Time
+
+	// sample
+	matched uint64
+
+	// rate_limit token buckets, refilled up to one second worth of tokens
+	messageTokens float64
+	byteTokens    float64
+	lastRefill    time.Time
+
+	// dedupe
+	window    time.Duration
+	lastSeen  map[uint64]time.Time
+	lastSweep time.Time
+}
+
+func newThrottler(rule *config.ProcessingRule, key string, now func() time.Time) *throttler {
+	// the window has already been validated with the rule
+	window, _ := time.ParseDuration(rule.Window)
+	return &throttler{
+		rule:          rule,
+		key:           key,
+		dropped:       status.NewCountInfo(key),
+		now:           now,
+		messageTokens: messageBucketSize(rule),
+		byteTokens:    rule.MaxBytesPerSecond,
+		lastRefill:    now(),
+		window:        window,
+		lastSeen:      make(map[uint64]time.Time),
+		lastSweep:     now(),
+	}
+}
+
+// getThrottler returns the throttler of the rule for the given source, creating
+// it on first use.
+func getThrottler(rule *config.ProcessingRule, source *sources.LogSource) *throttler {
+	key := fmt.Sprintf("Dropped by %s rule %s", rule.Type, rule.Name)
+	if t, ok := source.GetInfo(key).(*throttler); ok {
+		return t
+	}
+
+	throttlersMu.Lock()
+	defer throttlersMu.Unlock()
+	if t, ok := source.GetInfo(key).(*throttler); ok {
+		return t
+	}
+	t := newThrottler(rule, key, time.Now)
+	source.RegisterInfo(t)
+	return t
+}
+
+// InfoKey implements status.InfoProvider#InfoKey
+func (t *throttler) InfoKey() string {
+	return t.key
+}
+
+// Info implements status.InfoProvider#Info
+func (t *throttler) Info() []string {
+	return t.dropped.Info()
+}
+
+// keep returns whether a message with the given content should be kept.  The
+// content must already have been matched against the rule pattern.
+func (t *throttler) keep(content []byte) bool {
+	t.mu.Lock()
+	var keep bool
+	switch t.rule.Type {
+	case config.Sample:
+		keep = t.matched%uint64(t.rule.SampleRate) == 0
+		t.matched++
+	case config.RateLimit:
+		keep = t.takeTokens(len(content))
+	case config.Dedupe:
+		keep = t.firstSeen(content)
+	default:
+		keep = true
+	}
+	t.mu.Unlock()
+
+	if !keep {
+		t.dropped.Add(1)
+		metrics.LogsThrottled.Add(1)
+		metrics.TlmLogsThrottled.Inc(t.rule.Type)
+	}
+	return keep
+}
+
+// takeTokens refills the token buckets according to the time elapsed since the
+// last call, and takes one message and the size of the content from them.
+func (t *throttler) takeTokens(size int) bool {
+	now := t.now()
+	elapsed := now.Sub(t.lastRefill).Seconds()
+	t.lastRefill = now
+
+	if t.rule.MaxMessagesPerSecond > 0 {
+		t.messageTokens = math.Min(messageBucketSize(t.rule), t.messageTokens+elapsed*t.rule.MaxMessagesPerSecond)
+		if t.messageTokens < 1 {
+			return false
+		}
+	}
+	if t.rule.MaxBytesPerSecond > 0 {
+		t.byteTokens = math.Min(t.rule.MaxBytesPerSecond, t.byteTokens+elapsed*t.rule.MaxBytesPerSecond)
+		// a message larger than the bucket is kept once the bucket is full, leaving it
+		// in debt so that the rate is still honored on average
+		if t.byteTokens < float64(size) && t.byteTokens < t.rule.MaxBytesPerSecond {
+			return false
+		}
+		t.byteTokens -= float64(size)
+	}
+	if t.rule.MaxMessagesPerSecond > 0 {
+		t.messageTokens--
+	}
+	return true
+}
+
+// messageBucketSize returns the number of messages the bucket of a rate_limit rule
+// holds: one second worth of messages, and at least one message so that rates
+// below one message per second let messages through.
+func messageBucketSize(rule *config.ProcessingRule) float64 {
+	return math.Max(rule.MaxMessagesPerSecond, 1)
+}
+
+// firstSeen returns whether the content hasn't been seen during the dedupe window.
+func (t *throttler) firstSeen(content []byte) bool {
+	now := t.now()
+	if now.Sub(t.lastSweep) > t.window || len(t.lastSeen) >= maxDedupeEntries {
+		t.sweep(now)
+	}
+
+	h := fnv.New64a()
+	h.Write(content)
+	sum := h.Sum64()
+
+	if seen, found := t.lastSeen[sum]; found && now.Sub(seen) < t.window {
+		return false
+	}
+	t.lastSeen[sum] = now
+	return true
+}
+
+// sweep forgets the messages seen before the current window.  If there are still
+// too many messages left, all of them are forgotten to bound memory usage.
+func (t *throttler) sweep(now time.Time) {
+	for sum, seen := range t.lastSeen {
+		if now.Sub(seen) >= t.window {
+			delete(t.lastSeen, sum)
+		}
+	}
+	if len(t.lastSeen) >= maxDedupeEntries {
+		t.lastSeen = make(map[uint64]time.Time)
+	}
+	t.lastSweep = now
+}
@@ -0,0 +1,140 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// fakeClock returns a clock function and a function advancing it.
+func fakeClock() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestSample(t *testing.T) {
+	p := &Processor{}
+	rules := []*config.ProcessingRule{{Type: config.Sample, Name: "debug", Pattern: "DEBUG", SampleRate: 3}}
+	require.NoError(t, config.CompileProcessingRules(rules))
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: rules})
+
+	kept := 0
+	for i := 0; i < 9; i++ {
+		if p.applyRedactingRules(newMessage([]byte("DEBUG something"), source, "")) {
+			kept++
+		}
+	}
+	assert.Equal(t, 3, kept)
+
+	// messages not matching the pattern are not sampled
+	for i := 0; i < 9; i++ {
+		assert.True(t, p.applyRedactingRules(newMessage([]byte("INFO something"), source, "")))
+	}
+
+	assert.Equal(t, map[string][]string{"Dropped by sample rule debug": {"6"}}, filterInfo(source, "Dropped by"))
+}
+
+func TestRateLimitMessages(t *testing.T) {
+	now, advance := fakeClock()
+	th := newThrottler(&config.ProcessingRule{Type: config.RateLimit, Name: "rl", MaxMessagesPerSecond: 2}, "rl", now)
+
+	assert.True(t, th.keep([]byte("a")))
+	assert.True(t, th.keep([]byte("b")))
+	assert.False(t, th.keep([]byte("c")))
+
+	advance(500 * time.Millisecond)
+	assert.True(t, th.keep([]byte("d")))
+	assert.False(t, th.keep([]byte("e")))
+
+	// the bucket never holds more than one second worth of tokens
+	advance(time.Hour)
+	assert.True(t, th.keep([]byte("f")))
+	assert.True(t, th.keep([]byte("g")))
+	assert.False(t, th.keep([]byte("h")))
+
+	assert.Equal(t, int64(3), th.dropped.Get())
+}
+
+func TestRateLimitBytes(t *testing.T) {
+	now, advance := fakeClock()
+	th := newThrottler(&config.ProcessingRule{Type: config.RateLimit, Name: "rl", MaxBytesPerSecond: 10}, "rl", now)
+
+	assert.True(t, th.keep([]byte("123456")))
+	assert.False(t, th.keep([]byte("123456")))
+	assert.True(t, th.keep([]byte("1234")))
+
+	advance(time.Second)
+	assert.True(t, th.keep([]byte("1234567890")))
+	assert.False(t, th.keep([]byte("1")))
+}
+
+func TestRateLimitMessagesBelowOnePerSecond(t *testing.T) {
+	now, advance := fakeClock()
+	th := newThrottler(&config.ProcessingRule{Type: config.RateLimit, Name: "rl", MaxMessagesPerSecond: 0.5}, "rl", now)
+
+	assert.True(t, th.keep([]byte("a")))
+	assert.False(t, th.keep([]byte("b")))
+
+	advance(time.Second)
+	assert.False(t, th.keep([]byte("c")))
+	advance(time.Second)
+	assert.True(t, th.keep([]byte("d")))
+
+	// the bucket holds a single message
+	advance(time.Hour)
+	assert.True(t, th.keep([]byte("e")))
+	assert.False(t, th.keep([]byte("f")))
+}
+
+func TestRateLimitBytesLargerThanRate(t *testing.T) {
+	now, advance := fakeClock()
+	th := newThrottler(&config.ProcessingRule{Type: config.RateLimit, Name: "rl", MaxBytesPerSecond: 10}, "rl", now)
+
+	// a message larger than the rate is kept when the bucket is full
+	assert.True(t, th.keep([]byte("123456789012345")))
+	assert.False(t, th.keep([]byte("1")))
+
+	// the bucket is refilled from its debt
+	advance(time.Second)
+	assert.False(t, th.keep([]byte("1234567890")))
+	advance(500 * time.Millisecond)
+	assert.True(t, th.keep([]byte("123456789012345")))
+}
+
+func TestDedupe(t *testing.T) {
+	now, advance := fakeClock()
+	th := newThrottler(&config.ProcessingRule{Type: config.Dedupe, Name: "dd", Window: "10s"}, "dd", now)
+
+	assert.True(t, th.keep([]byte("crash")))
+	assert.False(t, th.keep([]byte("crash")))
+	assert.True(t, th.keep([]byte("other")))
+
+	advance(5 * time.Second)
+	assert.False(t, th.keep([]byte("crash")))
+
+	advance(10 * time.Second)
+	assert.True(t, th.keep([]byte("crash")))
+	assert.Len(t, th.lastSeen, 1)
+}
+
+// filterInfo returns the status info of the source whose key has the given prefix.
+func filterInfo(source *sources.LogSource, prefix string) map[string][]string {
+	info := make(map[string][]string)
+	for key, value := range source.GetInfoStatus() {
+		if strings.HasPrefix(key, prefix) {
+			info[key] = value
+		}
+	}
+	return info
+}
@@ -0,0 +1,450 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	diskBufferFileExt     = ".payload"
+	diskBufferTmpFileExt  = ".tmp"
+	diskBufferFileVersion = 1
+)
+
+var (
+	tlmDiskBufferPayloads = telemetry.NewCounter("logs_sender", "disk_buffer_payloads", []string{"destination", "action"}, "Payloads written to, replayed from or dropped from the disk buffer")
+	tlmDiskBufferBytes    = telemetry.NewGauge("logs_sender", "disk_buffer_bytes", []string{"destination"}, "Number of bytes held in the disk buffer")
+
+	errInvalidBufferedPayload = errors.New("invalid buffered payload")
+)
+
+// diskBufferEntry is a payload written to disk.
+type diskBufferEntry struct {
+	seq  uint64
+	size int64
+	// replaying is true once the payload has been handed over to the destination,
+	// the file is only removed once the destination reports it as sent.
+	replaying bool
+}
+
+// DiskBufferDestination wraps a reliable destination to write payloads to disk
+// instead of holding them in memory while the destination is retrying.
+//
+// Payloads written to disk are reported on the output channel right away so
+// that the auditor commits their offsets, and are replayed in order once the
+// destination recovers.  New payloads are written to disk as long as older ones
+// are waiting to be replayed, to keep payloads ordered.  Once the buffer reaches
+// its maximum size, it stops accepting payloads and the pipeline is blocked as
+// it would be without a buffer.
+//
+// Buffered payloads survive restarts of the agent: payloads left on disk are
+// replayed the next time the destination is started.  A payload being replayed
+// when the agent stops is replayed again on the next start, so it may be sent
+// twice.
+type DiskBufferDestination struct {
+	destination client.Destination
+	path        string
+	maxSize     int64
+	tlmName     string
+
+	mu        sync.Mutex
+	entries   []*diskBufferEntry
+	size      int64
+	nextSeq   uint64
+	replaying map[*message.Payload]*diskBufferEntry
+
+	isRetrying bool
+	// removed is notified when replayed payloads are removed from disk, to
+	// resume reading the input once the buffer isn't full anymore
+	removed chan struct{}
+}
+
+// NewDiskBufferDestination returns a destination buffering the payloads sent
+// to the given destination in the given directory, up to maxSize bytes.
+func NewDiskBufferDestination(destination client.Destination, path string, maxSize int64) *DiskBufferDestination {
+	return &DiskBufferDestination{
+		destination: destination,
+		path:        path,
+		maxSize:     maxSize,
+		tlmName:     destination.Metadata().TelemetryName(),
+		replaying:   make(map[*message.Payload]*diskBufferEntry),
+		removed:     make(chan struct{}, 1),
+	}
+}
+
+// IsMRF implements client.Destination#IsMRF
+func (d *DiskBufferDestination) IsMRF() bool {
+	return d.destination.IsMRF()
+}
+
+// Target implements client.Destination#Target
+func (d *DiskBufferDestination) Target() string {
+	return d.destination.Target()
+}
+
+// Metadata implements client.Destination#Metadata
+func (d *DiskBufferDestination) Metadata() *client.DestinationMetadata {
+	return d.destination.Metadata()
+}
+
+// Start starts the wrapped destination and reading the input channel.
+func (d *DiskBufferDestination) Start(input chan *message.Payload, output chan *message.Payload, isRetrying chan bool) (stopChan <-chan struct{}) {
+	if err := d.load(); err != nil {
+		log.Warnf("Could not load the logs disk buffer from %s: %v", d.path, err)
+	}
+
+	// the destination input is unbuffered so that a payload handed over to it is
+	// either being sent or retried by the destination
+	destinationInput := make(chan *message.Payload)
+	destinationOutput := make(chan *message.Payload)
+	destinationRetrying := make(chan bool, 1)
+	destinationStop := d.destination.Start(destinationInput, destinationOutput, destinationRetrying)
+
+	outputDone := make(chan struct{})
+	go d.forwardOutput(destinationOutput, output, outputDone)
+
+	stop := make(chan struct{})
+	go func() {
+		d.run(input, output, destinationInput, destinationRetrying)
+
+		close(destinationInput)
+		for waiting := true; waiting; {
+			select {
+			case <-destinationStop:
+				waiting = false
+			case <-destinationRetrying:
+			}
+		}
+		close(destinationOutput)
+		<-outputDone
+		stop <- struct{}{}
+	}()
+	return stop
+}
+
+// run hands over payloads to the destination, writing them to disk while the
+// destination is retrying, until the input channel is closed.
+func (d *DiskBufferDestination) run(input chan *message.Payload, output chan *message.Payload, destinationInput chan *message.Payload, destinationRetrying chan bool) {
+	var next *message.Payload
+	var nextEntry *diskBufferEntry
+
+	for {
+		var replay chan *message.Payload
+		if !d.isRetrying {
+			if next == nil {
+				next, nextEntry = d.nextReplay()
+			}
+			if next != nil {
+				replay = destinationInput
+			}
+		}
+
+		// stop reading the input when the buffer is full, so that the pipeline
+		// is blocked until payloads are replayed
+		in := input
+		if d.isFull() {
+			in = nil
+		}
+
+		// the payload is registered as replayed before it's handed over, as the
+		// destination may output it before the select returns
+		if replay != nil {
+			d.setReplaying(next, nextEntry, true)
+		}
+		select {
+		case payload, ok := <-in:
+			if replay != nil {
+				d.setReplaying(next, nextEntry, false)
+			}
+			if !ok {
+				return
+			}
+			d.send(payload, output, destinationInput, destinationRetrying)
+		case replay <- next:
+			tlmDiskBufferPayloads.Inc(d.tlmName, "replayed")
+			next, nextEntry = nil, nil
+		case retrying := <-destinationRetrying:
+			if replay != nil {
+				d.setReplaying(next, nextEntry, false)
+			}
+			d.isRetrying = retrying
+		case <-d.removed:
+			if replay != nil {
+				d.setReplaying(next, nextEntry, false)
+			}
+		}
+	}
+}
+
+// setReplaying registers the payload read from entry as handed over to the
+// destination, or unregisters it.
+func (d *DiskBufferDestination) setReplaying(payload *message.Payload, entry *diskBufferEntry, replaying bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry.replaying = replaying
+	if replaying {
+		d.replaying[payload] = entry
+	} else {
+		delete(d.replaying, payload)
+	}
+}
+
+// send hands over the payload to the destination if it's not retrying and no
+// payload is waiting to be replayed, or writes it to disk otherwise.
+func (d *DiskBufferDestination) send(payload *message.Payload, output chan *message.Payload, destinationInput chan *message.Payload, destinationRetrying chan bool) {
+	writeFailed := false
+	for {
+		if !writeFailed && (d.isRetrying || d.hasPending()) {
+			err := d.write(payload)
+			if err == nil {
+				tlmDiskBufferPayloads.Inc(d.tlmName, "written")
+				output <- payload
+				return
+			}
+			// keep the payload in memory and wait for the destination to accept
+			// it, as it would be without a buffer
+			log.Warnf("Could not write payload to the logs disk buffer, holding it in memory: %v", err)
+			tlmDiskBufferPayloads.Inc(d.tlmName, "write_error")
+			writeFailed = true
+		}
+
+		select {
+		case destinationInput <- payload:
+			return
+		case retrying := <-destinationRetrying:
+			d.isRetrying = retrying
+		}
+	}
+}
+
+// forwardOutput forwards the payloads sent by the destination to the output,
+// except for the replayed ones which have already been reported when written to
+// disk and are removed from it instead.
+func (d *DiskBufferDestination) forwardOutput(destinationOutput chan *message.Payload, output chan *message.Payload, done chan struct{}) {
+	for payload := range destinationOutput {
+		d.mu.Lock()
+		entry, replayed := d.replaying[payload]
+		if replayed {
+			delete(d.replaying, payload)
+			d.removeLocked(entry)
+		}
+		d.mu.Unlock()
+
+		if replayed {
+			select {
+			case d.removed <- struct{}{}:
+			default:
+			}
+		} else {
+			output <- payload
+		}
+	}
+	done <- struct{}{}
+}
+
+func (d *DiskBufferDestination) isFull() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.size >= d.maxSize
+}
+
+// hasPending returns whether payloads written to disk have not been handed over
+// to the destination yet.
+func (d *DiskBufferDestination) hasPending() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, entry := range d.entries {
+		if !entry.replaying {
+			return true
+		}
+	}
+	return false
+}
+
+// nextReplay reads the oldest payload not handed over to the destination yet.
+// Payloads that can't be read are dropped.
+func (d *DiskBufferDestination) nextReplay() (*message.Payload, *diskBufferEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := 0; i < len(d.entries); i++ {
+		entry := d.entries[i]
+		if entry.replaying {
+			continue
+		}
+		payload, err := d.read(entry)
+		if err == nil {
+			return payload, entry
+		}
+		log.Warnf("Dropping payload from the logs disk buffer: %v", err)
+		tlmDiskBufferPayloads.Inc(d.tlmName, "dropped")
+		d.removeLocked(entry)
+		i--
+	}
+	return nil, nil
+}
+
+// load lists the payloads left on disk by a previous run.
+func (d *DiskBufferDestination) load() error {
+	if err := os.MkdirAll(d.path, 0700); err != nil {
+		return err
+	}
+	files, err := os.ReadDir(d.path)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, diskBufferTmpFileExt) {
+			// incomplete write, the payload was never reported to the auditor
+			os.Remove(filepath.Join(d.path, name))
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, diskBufferFileExt), 10, 64)
+		if err != nil || !strings.HasSuffix(name, diskBufferFileExt) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		d.entries = append(d.entries, &diskBufferEntry{seq: seq, size: info.Size()})
+		d.size += info.Size()
+		if seq >= d.nextSeq {
+			d.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(d.entries, func(i, j int) bool { return d.entries[i].seq < d.entries[j].seq })
+
+	if len(d.entries) > 0 {
+		log.Infof("Replaying %d payloads (%d bytes) from the logs disk buffer %s", len(d.entries), d.size, d.path)
+	}
+	tlmDiskBufferBytes.Set(float64(d.size), d.tlmName)
+	return nil
+}
+
+// write durably writes the payload to disk.
+func (d *DiskBufferDestination) write(payload *message.Payload) error {
+	d.mu.Lock()
+	seq := d.nextSeq
+	d.nextSeq++
+	d.mu.Unlock()
+
+	data := encodeBufferedPayload(payload)
+	filename := d.filename(seq)
+	tmpFilename := filename + diskBufferTmpFileExt
+
+	f, err := os.OpenFile(tmpFilename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpFilename, filename)
+	}
+	if err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+
+	d.mu.Lock()
+	d.entries = append(d.entries, &diskBufferEntry{seq: seq, size: int64(len(data))})
+	d.size += int64(len(data))
+	tlmDiskBufferBytes.Set(float64(d.size), d.tlmName)
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *DiskBufferDestination) read(entry *diskBufferEntry) (*message.Payload, error) {
+	data, err := os.ReadFile(d.filename(entry.seq))
+	if err != nil {
+		return nil, err
+	}
+	return decodeBufferedPayload(data)
+}
+
+// removeLocked removes the payload from disk, d.mu must be held.
+func (d *DiskBufferDestination) removeLocked(entry *diskBufferEntry) {
+	for i, e := range d.entries {
+		if e == entry {
+			d.entries = append(d.entries[:i], d.entries[i+1:]...)
+			break
+		}
+	}
+	d.size -= entry.size
+	tlmDiskBufferBytes.Set(float64(d.size), d.tlmName)
+	if err := os.Remove(d.filename(entry.seq)); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Could not remove payload from the logs disk buffer: %v", err)
+	}
+}
+
+func (d *DiskBufferDestination) filename(seq uint64) string {
+	return filepath.Join(d.path, fmt.Sprintf("%020d%s", seq, diskBufferFileExt))
+}
+
+// encodeBufferedPayload serializes the parts of the payload needed to send it
+// again.  The message metadata is not kept, the auditor has already been
+// updated when the payload was written.
+func encodeBufferedPayload(payload *message.Payload) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(diskBufferFileVersion)
+	binary.Write(&buf, binary.BigEndian, uint16(len(payload.Encoding))) //nolint:errcheck
+	buf.WriteString(payload.Encoding)
+	binary.Write(&buf, binary.BigEndian, uint64(payload.UnencodedSize)) //nolint:errcheck
+	buf.Write(payload.Encoded)
+	return buf.Bytes()
+}
+
+func decodeBufferedPayload(data []byte) (*message.Payload, error) {
+	r := bytes.NewReader(data)
+	version, err := r.ReadByte()
+	if err != nil || version != diskBufferFileVersion {
+		return nil, errInvalidBufferedPayload
+	}
+	var encodingLen uint16
+	if err := binary.Read(r, binary.BigEndian, &encodingLen); err != nil {
+		return nil, errInvalidBufferedPayload
+	}
+	encoding := make([]byte, encodingLen)
+	if _, err := io.ReadFull(r, encoding); err != nil {
+		return nil, errInvalidBufferedPayload
+	}
+	var unencodedSize uint64
+	if err := binary.Read(r, binary.BigEndian, &unencodedSize); err != nil {
+		return nil, errInvalidBufferedPayload
+	}
+	encoded := make([]byte, r.Len())
+	r.Read(encoded) //nolint:errcheck
+	return &message.Payload{
+		Encoded:       encoded,
+		Encoding:      string(encoding),
+		UnencodedSize: int(unencodedSize),
+	}, nil
+}
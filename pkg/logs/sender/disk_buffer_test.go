@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newDiskBufferDestination(path string, maxSize int64) (*mockDestination, *DiskBufferDestination, chan *message.Payload, chan *message.Payload, <-chan struct{}) {
+	dest := &mockDestination{}
+	d := NewDiskBufferDestination(dest, path, maxSize)
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload, 10)
+	stop := d.Start(input, output, make(chan bool, 1))
+	return dest, d, input, output, stop
+}
+
+// setRetrying changes the retry state of the mock destination and waits for it
+// to be received by the disk buffer.
+func setRetrying(dest *mockDestination, retrying bool) {
+	// the channel has a buffer of one, so the first value has been received
+	// once the second one is sent
+	dest.isRetrying <- retrying
+	dest.isRetrying <- retrying
+}
+
+// stopDiskBufferDestination closes the input and stops the mock destination.
+func stopDiskBufferDestination(dest *mockDestination, input chan *message.Payload, stop <-chan struct{}) {
+	close(input)
+	for range dest.input {
+	}
+	dest.stopChan <- struct{}{}
+	<-stop
+}
+
+func bufferedFiles(t *testing.T, path string) []string {
+	entries, err := os.ReadDir(path)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestDiskBufferDestinationForwardsWhenNotRetrying(t *testing.T) {
+	path := t.TempDir()
+	dest, _, input, output, stop := newDiskBufferDestination(path, 1024)
+
+	payload := &message.Payload{Encoded: []byte("a")}
+	input <- payload
+	assert.Same(t, payload, <-dest.input)
+
+	dest.output <- payload
+	assert.Same(t, payload, <-output)
+	assert.Empty(t, bufferedFiles(t, path))
+
+	stopDiskBufferDestination(dest, input, stop)
+}
+
+func TestDiskBufferDestinationWritesAndReplays(t *testing.T) {
+	path := t.TempDir()
+	dest, _, input, output, stop := newDiskBufferDestination(path, 1024)
+
+	setRetrying(dest, true)
+
+	first := &message.Payload{Encoded: []byte("first"), Encoding: "gzip", UnencodedSize: 10, MessageMetas: []*message.MessageMetadata{{}}}
+	second := &message.Payload{Encoded: []byte("second"), Encoding: "gzip", UnencodedSize: 20, MessageMetas: []*message.MessageMetadata{{}}}
+	input <- first
+	input <- second
+
+	// buffered payloads are reported to the auditor right away
+	assert.Same(t, first, <-output)
+	assert.Same(t, second, <-output)
+	assert.Len(t, bufferedFiles(t, path), 2)
+
+	setRetrying(dest, false)
+
+	// and replayed in order once the destination recovers
+	for _, expected := range []*message.Payload{first, second} {
+		replayed := <-dest.input
+		assert.Equal(t, expected.Encoded, replayed.Encoded)
+		assert.Equal(t, expected.Encoding, replayed.Encoding)
+		assert.Equal(t, expected.UnencodedSize, replayed.UnencodedSize)
+		assert.Empty(t, replayed.MessageMetas)
+		dest.output <- replayed
+	}
+
+	// replayed payloads are removed from disk and not reported again
+	assert.Eventually(t, func() bool { return len(bufferedFiles(t, path)) == 0 }, 5*time.Second, 10*time.Millisecond)
+	select {
+	case p := <-output:
+		assert.Fail(t, "unexpected payload on the output", "%v", p)
+	default:
+	}
+
+	stopDiskBufferDestination(dest, input, stop)
+}
+
+func TestDiskBufferDestinationKeepsOrderWhileReplaying(t *testing.T) {
+	path := t.TempDir()
+	dest, _, input, output, stop := newDiskBufferDestination(path, 1024)
+
+	setRetrying(dest, true)
+	input <- &message.Payload{Encoded: []byte("first")}
+	<-output
+	setRetrying(dest, false)
+
+	// the first payload has not been replayed yet, so the second one goes to
+	// disk after it even though the destination is not retrying anymore
+	input <- &message.Payload{Encoded: []byte("second")}
+	<-output
+
+	assert.Equal(t, []byte("first"), (<-dest.input).Encoded)
+	assert.Equal(t, []byte("second"), (<-dest.input).Encoded)
+
+	stopDiskBufferDestination(dest, input, stop)
+}
+
+func TestDiskBufferDestinationBlocksWhenFull(t *testing.T) {
+	path := t.TempDir()
+	dest, _, input, output, stop := newDiskBufferDestination(path, 10)
+
+	setRetrying(dest, true)
+	input <- &message.Payload{Encoded: []byte("more than ten bytes")}
+	<-output
+
+	select {
+	case input <- &message.Payload{Encoded: []byte("blocked")}:
+		assert.Fail(t, "the disk buffer should not accept payloads when full")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	setRetrying(dest, false)
+	replayed := <-dest.input
+	dest.output <- replayed
+
+	// once the replayed payload is sent there is room for new payloads
+	input <- &message.Payload{Encoded: []byte("accepted")}
+	assert.Equal(t, []byte("accepted"), (<-dest.input).Encoded)
+
+	stopDiskBufferDestination(dest, input, stop)
+}
+
+func TestDiskBufferDestinationReplaysAfterRestart(t *testing.T) {
+	path := t.TempDir()
+	dest, _, input, output, stop := newDiskBufferDestination(path, 1024)
+
+	setRetrying(dest, true)
+	input <- &message.Payload{Encoded: []byte("persisted")}
+	<-output
+	stopDiskBufferDestination(dest, input, stop)
+	assert.Len(t, bufferedFiles(t, path), 1)
+
+	// leftovers of an incomplete write are discarded
+	require.NoError(t, os.WriteFile(path+"/00000000000000000042.payload.tmp", []byte("partial"), 0600))
+
+	dest, d, input, _, stop := newDiskBufferDestination(path, 1024)
+	replayed := <-dest.input
+	assert.Equal(t, []byte("persisted"), replayed.Encoded)
+	assert.Equal(t, uint64(1), d.nextSeq)
+	dest.output <- replayed
+
+	assert.Eventually(t, func() bool { return len(bufferedFiles(t, path)) == 0 }, 5*time.Second, 10*time.Millisecond)
+	stopDiskBufferDestination(dest, input, stop)
+}
+
+func TestBufferedPayloadEncoding(t *testing.T) {
+	payload := &message.Payload{Encoded: []byte("content"), Encoding: "zstd", UnencodedSize: 1234}
+	decoded, err := decodeBufferedPayload(encodeBufferedPayload(payload))
+	require.NoError(t, err)
+	assert.Equal(t, payload, decoded)
+
+	for _, data := range [][]byte{nil, {2}, {diskBufferFileVersion, 0, 10, 'a'}} {
+		_, err := decodeBufferedPayload(data)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// CompletedOffset is the offset committed to the registry once the last log of
+// a compressed file has been sent, so that the file is not read again.  Until
+// then, the committed offsets are offsets in the decompressed content.
+const CompletedOffset = "completed"
+
+// IsCompressed returns whether the file at path is compressed with a format the
+// tailer can read, based on its extension.
+func IsCompressed(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".zst", ".zstd":
+		return true
+	default:
+		return false
+	}
+}
+
+// newDecompressor returns a reader decompressing r according to the extension
+// of path.
+func newDecompressor(r io.Reader, path string) (io.ReadCloser, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".zst", ".zstd":
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported compressed file %s", path)
+	}
+}
+
+// setupCompressed opens the compressed file and skips the decompressed content
+// up to offset, as compressed streams can't be seeked.
+func (t *Tailer) setupCompressed(offset int64) error {
+	fullpath, err := filepath.Abs(t.file.Path)
+	if err != nil {
+		return err
+	}
+	t.fullpath = fullpath
+
+	// adds metadata to enable users to filter logs by filename
+	t.tags = t.buildTailerTags()
+
+	log.Info("Opening compressed file", t.file.Path, "for tailer key", t.file.GetScanKey())
+	f, err := filesystem.OpenShared(fullpath)
+	if err != nil {
+		return err
+	}
+	reader, err := newDecompressor(f, fullpath)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if offset > 0 {
+		if _, err := io.CopyN(io.Discard, reader, offset); err != nil {
+			reader.Close()
+			f.Close()
+			return fmt.Errorf("could not resume reading compressed file %s at offset %d: %v", t.file.Path, offset, err)
+		}
+	}
+
+	t.osFile = f
+	t.decompressor = reader
+	t.lastReadOffset.Store(offset)
+	t.decodedOffset.Store(offset)
+	return nil
+}
+
+// readCompressed reads the decompressed content of the file once, from the
+// offset it has been set up with to the end.
+func (t *Tailer) readCompressed() {
+	defer func() {
+		t.decompressor.Close()
+		t.osFile.Close()
+		if t.readCompleted.Load() {
+			// the file won't grow, its last line is complete even without
+			// a final newline
+			t.decoder.StopAndFlush()
+		} else {
+			t.decoder.Stop()
+		}
+		log.Info("Closed compressed file", t.file.Path, "for tailer key", t.file.GetScanKey(), "read", t.Source().BytesRead.Get(), "bytes and", t.decoder.GetLineCount(), "lines")
+	}()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		default:
+		}
+
+		inBuf := make([]byte, 4096)
+		n, err := t.decompressor.Read(inBuf)
+		if n > 0 {
+			t.lastReadOffset.Add(int64(n))
+			t.recordBytes(int64(n))
+			t.movingSum.Add(int64(n))
+			t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+		}
+		if err == io.EOF {
+			t.readCompleted.Store(true)
+			return
+		}
+		if err != nil {
+			t.file.Source.Status().Error(err)
+			log.Warnf("Unexpected error occurred while reading compressed file %s: %v", t.file.Path, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+func writeGzipFile(t *testing.T, path string, content string) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func writeZstdFile(t *testing.T, path string, content string) {
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func newCompressedTailer(path string, outputChan chan *message.Message) *Tailer {
+	source := sources.NewReplaceableSource(sources.NewLogSource("", &config.LogsConfig{
+		Type: config.FileType,
+		Path: path,
+	}))
+	info := status.NewInfoRegistry()
+	return NewTailer(&TailerOptions{
+		OutputChan:      outputChan,
+		File:            NewFile(path, source.UnderlyingSource(), false),
+		SleepDuration:   10 * time.Millisecond,
+		Decoder:         decoder.NewDecoderFromSource(source, info),
+		Info:            info,
+		PipelineMonitor: metrics.NewNoopPipelineMonitor(""),
+		Compressed:      true,
+	})
+}
+
+func TestIsCompressed(t *testing.T) {
+	assert.True(t, IsCompressed("/var/log/app.log.1.gz"))
+	assert.True(t, IsCompressed("/var/log/app.log.1.GZ"))
+	assert.True(t, IsCompressed("/var/log/app.log.zst"))
+	assert.True(t, IsCompressed("/var/log/app.log.zstd"))
+	assert.False(t, IsCompressed("/var/log/app.log"))
+	assert.False(t, IsCompressed("/var/log/app.log.1"))
+}
+
+func TestTailerReadsCompressedFiles(t *testing.T) {
+	dir := t.TempDir()
+	gzipPath := filepath.Join(dir, "app.log.1.gz")
+	writeGzipFile(t, gzipPath, "first\nsecond\nthird")
+	zstdPath := filepath.Join(dir, "app.log.2.zst")
+	writeZstdFile(t, zstdPath, "first\nsecond\nthird")
+
+	for _, path := range []string{gzipPath, zstdPath} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			outputChan := make(chan *message.Message, 10)
+			tailer := newCompressedTailer(path, outputChan)
+			require.NoError(t, tailer.StartFromBeginning())
+
+			for _, expected := range []struct {
+				content string
+				offset  string
+			}{
+				{"first", "6"},
+				{"second", "13"},
+				// the last message commits the completion of the file
+				{"third", CompletedOffset},
+			} {
+				msg := <-outputChan
+				assert.Equal(t, expected.content, string(msg.GetContent()))
+				assert.Equal(t, expected.offset, msg.Origin.Offset)
+				assert.Equal(t, "file:"+path, msg.Origin.Identifier)
+			}
+
+			// the tailer stops on its own once the file has been read
+			assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+			tailer.Stop()
+		})
+	}
+}
+
+func TestTailerResumesCompressedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeGzipFile(t, path, "first\nsecond\n")
+
+	outputChan := make(chan *message.Message, 10)
+	tailer := newCompressedTailer(path, outputChan)
+	require.NoError(t, tailer.Start(6, 0))
+
+	msg := <-outputChan
+	assert.Equal(t, "second", string(msg.GetContent()))
+	assert.Equal(t, CompletedOffset, msg.Origin.Offset)
+
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	tailer.Stop()
+
+	// an offset past the end of the content can't be resumed
+	tailer = newCompressedTailer(path, make(chan *message.Message, 10))
+	assert.Error(t, tailer.Start(100, 0))
+}
+
+func TestTailerDoesNotCompleteCorruptedCompressedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeGzipFile(t, path, "first\nsecond\n")
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// drop the gzip footer
+	require.NoError(t, os.WriteFile(path, content[:len(content)-8], 0644))
+
+	outputChan := make(chan *message.Message, 10)
+	tailer := newCompressedTailer(path, outputChan)
+	require.NoError(t, tailer.StartFromBeginning())
+
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	close(outputChan)
+	for msg := range outputChan {
+		assert.NotEqual(t, CompletedOffset, msg.Origin.Offset)
+	}
+	tailer.Stop()
+}
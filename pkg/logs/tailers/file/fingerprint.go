@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// ComputeFingerprint returns the fingerprint of the file at the given path, see
+// fingerprint.
+func ComputeFingerprint(path string, size int) (string, error) {
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return fingerprint(f, size)
+}
+
+// fingerprint returns a hash of the first size bytes of the file, which
+// identifies it regardless of its path or inode.  An empty string is returned
+// when the file is smaller than size, as its fingerprint would change as it
+// grows.
+//
+// The file is read with ReadAt, so that its offset is left unchanged.
+func fingerprint(f *os.File, size int) (string, error) {
+	if size <= 0 {
+		return "", nil
+	}
+	buf := make([]byte, size)
+	if _, err := f.ReadAt(buf, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", nil
+		}
+		return "", err
+	}
+	h := fnv.New64a()
+	h.Write(buf)
+	return fmt.Sprintf("%d:%016x", size, h.Sum64()), nil
+}
+
+// SameFingerprint returns whether two fingerprints identify the same file.
+// ok is false when either fingerprint is empty, or when they have been
+// computed over a different number of bytes, in which case the identity of the
+// file can't be decided from the fingerprints.
+func SameFingerprint(a, b string) (same bool, ok bool) {
+	if a == "" || b == "" {
+		return false, false
+	}
+	sizeA, _, _ := strings.Cut(a, ":")
+	sizeB, _, _ := strings.Cut(b, ":")
+	if sizeA != sizeB {
+		return false, false
+	}
+	return a == b, true
+}
+
+// updateFingerprint computes the fingerprint of the tailed file from f, unless
+// fingerprinting is disabled or the fingerprint is already known.
+func (t *Tailer) updateFingerprint(f *os.File) {
+	if t.fingerprintSize <= 0 || t.fingerprint.Load() != "" {
+		return
+	}
+	fp, err := fingerprint(f, t.fingerprintSize)
+	if err != nil {
+		log.Debugf("Could not compute the fingerprint of %s: %v", t.file.Path, err)
+		return
+	}
+	t.fingerprint.Store(fp)
+}
+
+// Fingerprint returns the fingerprint of the tailed file, or an empty string if
+// it's not known yet.
+func (t *Tailer) Fingerprint() string {
+	return t.fingerprint.Load()
+}
@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+func newFingerprintingTailer(path string, outputChan chan *message.Message, fingerprintSize int) *Tailer {
+	source := sources.NewReplaceableSource(sources.NewLogSource("", &config.LogsConfig{
+		Type: config.FileType,
+		Path: path,
+	}))
+	info := status.NewInfoRegistry()
+	return NewTailer(&TailerOptions{
+		OutputChan:      outputChan,
+		File:            NewFile(path, source.UnderlyingSource(), false),
+		SleepDuration:   10 * time.Millisecond,
+		Decoder:         decoder.NewDecoderFromSource(source, info),
+		Info:            info,
+		PipelineMonitor: metrics.NewNoopPipelineMonitor(""),
+		FingerprintSize: fingerprintSize,
+	})
+}
+
+func TestComputeFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	require.NoError(t, os.WriteFile(path, []byte("short"), 0644))
+	fp, err := ComputeFingerprint(path, 8)
+	require.NoError(t, err)
+	assert.Empty(t, fp, "files smaller than the fingerprint size have no fingerprint")
+
+	require.NoError(t, os.WriteFile(path, []byte("first line\n"), 0644))
+	first, err := ComputeFingerprint(path, 8)
+	require.NoError(t, err)
+	assert.Regexp(t, "^8:[0-9a-f]{16}$", first)
+
+	// only the first bytes are part of the fingerprint
+	require.NoError(t, os.WriteFile(path, []byte("first line\nsecond line\n"), 0644))
+	grown, err := ComputeFingerprint(path, 8)
+	require.NoError(t, err)
+	assert.Equal(t, first, grown)
+
+	require.NoError(t, os.WriteFile(path, []byte("other line\n"), 0644))
+	other, err := ComputeFingerprint(path, 8)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other)
+
+	_, err = ComputeFingerprint(filepath.Join(dir, "missing.log"), 8)
+	assert.Error(t, err)
+}
+
+func TestSameFingerprint(t *testing.T) {
+	same, ok := SameFingerprint("8:0000000000000001", "8:0000000000000001")
+	assert.True(t, same)
+	assert.True(t, ok)
+
+	same, ok = SameFingerprint("8:0000000000000001", "8:0000000000000002")
+	assert.False(t, same)
+	assert.True(t, ok)
+
+	_, ok = SameFingerprint("", "8:0000000000000002")
+	assert.False(t, ok)
+	_, ok = SameFingerprint("8:0000000000000001", "")
+	assert.False(t, ok)
+	_, ok = SameFingerprint("8:0000000000000001", "16:0000000000000001")
+	assert.False(t, ok)
+}
+
+func TestTailerCommitsFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("first line\n"), 0644))
+	outputChan := make(chan *message.Message, 10)
+	tailer := newFingerprintingTailer(path, outputChan, 8)
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	expected, err := ComputeFingerprint(path, 8)
+	require.NoError(t, err)
+	msg := <-outputChan
+	assert.Equal(t, "first line", string(msg.GetContent()))
+	assert.Equal(t, expected, msg.Origin.Fingerprint)
+	assert.Equal(t, expected, tailer.Fingerprint())
+}
+
+func TestTailerFingerprintsGrowingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("a\n"), 0644))
+	outputChan := make(chan *message.Message, 10)
+	tailer := newFingerprintingTailer(path, outputChan, 8)
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	<-outputChan
+	assert.Empty(t, tailer.Fingerprint())
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("bcdefgh\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	<-outputChan
+
+	// the fingerprint is computed once the file is large enough
+	didRotate, err := tailer.DidRotate()
+	require.NoError(t, err)
+	assert.False(t, didRotate)
+	assert.NotEmpty(t, tailer.Fingerprint())
+}
+
+func TestDidRotateDetectsFingerprintChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("first line\n"), 0644))
+	outputChan := make(chan *message.Message, 10)
+	tailer := newFingerprintingTailer(path, outputChan, 8)
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+	<-outputChan
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString("second line\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	<-outputChan
+
+	didRotate, err := tailer.DidRotate()
+	require.NoError(t, err)
+	assert.False(t, didRotate)
+
+	// the file is truncated in place, and has grown past the last read offset
+	// before the rotation is checked: its size and inode are of no help
+	require.NoError(t, os.WriteFile(path, []byte("another first line, longer than the previous content\n"), 0644))
+
+	didRotate, err = tailer.DidRotate()
+	require.NoError(t, err)
+	assert.True(t, didRotate)
+}
+
+func TestDidRotateDetectsRecreationWithSameHeader(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the tailed files aren't kept open on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("=== preamble ===\nfirst\n"), 0644))
+	outputChan := make(chan *message.Message, 10)
+	tailer := newFingerprintingTailer(path, outputChan, 8)
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+	<-outputChan
+	<-outputChan
+
+	// the file is recreated with the same preamble, its fingerprint is the same
+	require.NoError(t, os.Rename(path, path+".1"))
+	require.NoError(t, os.WriteFile(path, []byte("=== preamble ===\nsecond, longer than the first\n"), 0644))
+
+	didRotate, err := tailer.DidRotate()
+	require.NoError(t, err)
+	assert.True(t, didRotate)
+}
@@ -21,6 +21,10 @@ import (
 // - renamed and recreated
 // - removed and recreated
 // - truncated
+//
+// When fingerprinting is enabled and both the tailed file and the file at its
+// path are large enough to be fingerprinted, a change of fingerprint is also
+// a rotation, which catches the files recreated with a reused inode.
 func (t *Tailer) DidRotate() (bool, error) {
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
@@ -40,9 +44,22 @@ func (t *Tailer) DidRotate() (bool, error) {
 	}
 
 	fileSize := fi1.Size()
+	truncated := fileSize < lastReadOffset
+
+	if t.fingerprintSize > 0 {
+		// a same fingerprint doesn't mean the file is the same, a recreated
+		// file can start with the same header, so the inodes are checked too
+		t.updateFingerprint(t.osFile)
+		current, err := fingerprint(f, t.fingerprintSize)
+		if err != nil {
+			log.Debugf("Could not compute the fingerprint of %s: %v", t.fullpath, err)
+		} else if same, ok := SameFingerprint(t.fingerprint.Load(), current); ok && !same {
+			log.Debugf("File rotation detected due to fingerprint change, was: %s, now: %s", t.fingerprint.Load(), current)
+			return true, nil
+		}
+	}
 
 	recreated := !os.SameFile(fi1, fi2)
-	truncated := fileSize < lastReadOffset
 
 	if recreated {
 		log.Debugf("File rotation detected due to recreation, f1: %+v, f2: %+v", fi1, fi2)
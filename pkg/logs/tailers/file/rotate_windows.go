@@ -17,7 +17,8 @@ import (
 // DidRotate returns true if the file has been log-rotated.
 //
 // On Windows, log rotation is identified by the file size being smaller
-// than the last offset read.
+// than the last offset read, or by a change of its fingerprint when
+// fingerprinting is enabled.
 func (t *Tailer) DidRotate() (bool, error) {
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
@@ -43,5 +44,19 @@ func (t *Tailer) DidRotate() (bool, error) {
 		return true, nil
 	}
 
+	if t.fingerprintSize > 0 {
+		// the file is not kept open, so its fingerprint is computed from the
+		// file at its path the first time it's large enough
+		previous := t.fingerprint.Load()
+		t.updateFingerprint(f)
+		current, err := fingerprint(f, t.fingerprintSize)
+		if err != nil {
+			log.Debugf("Could not compute the fingerprint of %s: %v", t.fullpath, err)
+		} else if same, ok := SameFingerprint(previous, current); ok && !same {
+			log.Debugf("File rotation detected due to fingerprint change, was: %s, now: %s", previous, current)
+			return true, nil
+		}
+	}
+
 	return false, nil
 }
@@ -62,6 +62,24 @@ type Tailer struct {
 	// is platform-specific.
 	osFile *os.File
 
+	// compressed is true when the file is compressed.  Compressed files are read
+	// once from their decompressor, up to their end, instead of being tailed.
+	compressed bool
+
+	// decompressor reads the decompressed content of compressed files.
+	decompressor io.ReadCloser
+
+	// readCompleted is true once the whole content of a compressed file has been read.
+	readCompleted *atomic.Bool
+
+	// fingerprintSize is the number of bytes at the beginning of the file used
+	// to compute its fingerprint, fingerprinting is disabled when it's 0.
+	fingerprintSize int
+
+	// fingerprint identifies the tailed file by its content.  It's empty until
+	// the file is large enough to be fingerprinted.
+	fingerprint *atomic.String
+
 	// tags are the tags to be attached to each log message, excluding tags provided
 	// by the tag provider.
 	tags []string
@@ -130,6 +148,8 @@ type TailerOptions struct {
 	Decoder         *decoder.Decoder        // Required
 	Info            *status.InfoRegistry    // Required
 	Rotated         bool                    // Optional
+	Compressed      bool                    // Optional
+	FingerprintSize int                     // Optional
 	TagAdder        tag.EntityTagAdder      // Required
 	PipelineMonitor metrics.PipelineMonitor // Required
 }
@@ -181,6 +201,10 @@ func NewTailer(opts *TailerOptions) *Tailer {
 		stopForward:            stopForward,
 		isFinished:             atomic.NewBool(false),
 		didFileRotate:          atomic.NewBool(false),
+		compressed:             opts.Compressed,
+		readCompleted:          atomic.NewBool(false),
+		fingerprintSize:        opts.FingerprintSize,
+		fingerprint:            atomic.NewString(""),
 		info:                   opts.Info,
 		bytesRead:              bytesRead,
 		movingSum:              movingSum,
@@ -211,6 +235,7 @@ func (t *Tailer) NewRotatedTailer(file *File, outputChan chan *message.Message,
 		Decoder:         decoder,
 		Info:            info,
 		Rotated:         true,
+		FingerprintSize: t.fingerprintSize,
 		TagAdder:        tagAdder,
 		PipelineMonitor: pipelineMonitor,
 	}
@@ -230,9 +255,16 @@ func (t *Tailer) Identifier() string {
 	return fmt.Sprintf("file:%s", t.file.Path)
 }
 
-// Start begins the tailer's operation in a dedicated goroutine.
+// Start begins the tailer's operation in a dedicated goroutine.  Compressed
+// files are read from the offset in their decompressed content, whence is
+// ignored.
 func (t *Tailer) Start(offset int64, whence int) error {
-	err := t.setup(offset, whence)
+	var err error
+	if t.compressed {
+		err = t.setupCompressed(offset)
+	} else {
+		err = t.setup(offset, whence)
+	}
 	if err != nil {
 		t.file.Source.Status().Error(err)
 		return err
@@ -242,11 +274,21 @@ func (t *Tailer) Start(offset int64, whence int) error {
 
 	go t.forwardMessages()
 	t.decoder.Start()
-	go t.readForever()
+	if t.compressed {
+		go t.readCompressed()
+	} else {
+		go t.readForever()
+	}
 
 	return nil
 }
 
+// IsCompressed returns whether the tailer reads a compressed file once
+// instead of tailing it.
+func (t *Tailer) IsCompressed() bool {
+	return t.compressed
+}
+
 // StartFromBeginning is a shortcut to start the tailer at the beginning of the
 // file.
 func (t *Tailer) StartFromBeginning() error {
@@ -342,6 +384,19 @@ func (t *Tailer) forwardMessages() {
 		t.isFinished.Store(true)
 		close(t.done)
 	}()
+
+	// the last message of a compressed file is held back until the decoder is
+	// flushed, so that it commits the completion of the file once it's sent
+	var pending *message.Message
+	defer func() {
+		if pending != nil {
+			if t.readCompleted.Load() {
+				pending.Origin.Offset = CompletedOffset
+			}
+			t.forward(pending)
+		}
+	}()
+
 	for output := range t.decoder.OutputChan {
 		offset := t.decodedOffset.Load() + int64(output.RawDataLen)
 		identifier := t.Identifier()
@@ -353,6 +408,9 @@ func (t *Tailer) forwardMessages() {
 		origin := message.NewOrigin(t.file.Source.UnderlyingSource())
 		origin.Identifier = identifier
 		origin.Offset = strconv.FormatInt(offset, 10)
+		if identifier != "" {
+			origin.Fingerprint = t.fingerprint.Load()
+		}
 
 		tags := make([]string, len(t.tags))
 		copy(tags, t.tags)
@@ -365,15 +423,26 @@ func (t *Tailer) forwardMessages() {
 		}
 
 		msg := message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
-		// Make the write to the output chan cancellable to be able to stop the tailer
-		// after a file rotation when it is stuck on it.
-		// We don't return directly to keep the same shutdown sequence that in the
-		// normal case.
-		select {
-		case t.outputChan <- msg:
-			t.PipelineMonitor.ReportComponentIngress(msg, "processor")
-		case <-t.forwardContext.Done():
+		if t.compressed {
+			msg, pending = pending, msg
+			if msg == nil {
+				continue
+			}
 		}
+		t.forward(msg)
+	}
+}
+
+// forward sends a message to the output channel.
+func (t *Tailer) forward(msg *message.Message) {
+	// Make the write to the output chan cancellable to be able to stop the tailer
+	// after a file rotation when it is stuck on it.
+	// We don't return directly to keep the same shutdown sequence that in the
+	// normal case.
+	select {
+	case t.outputChan <- msg:
+		t.PipelineMonitor.ReportComponentIngress(msg, "processor")
+	case <-t.forwardContext.Done():
 	}
 }
 
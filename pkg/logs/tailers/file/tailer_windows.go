@@ -34,6 +34,7 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	if err != nil {
 		return err
 	}
+	t.updateFingerprint(f)
 	filePos, _ := f.Seek(offset, whence)
 	f.Close()
 
@@ -12,9 +12,13 @@ import (
 	"net"
 	"strings"
 
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/syslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
@@ -35,18 +39,32 @@ type Tailer struct {
 
 // NewTailer returns a new Tailer
 func NewTailer(source *sources.LogSource, conn net.Conn, outputChan chan *message.Message, read func(*Tailer) ([]byte, string, error)) *Tailer {
+	parser, framing := parserAndFraming(source)
 	return &Tailer{
 		source:     source,
 		Conn:       conn,
 		outputChan: outputChan,
 		read:       read,
 		// tailer info is currently unused for this tailer type.
-		decoder: decoder.InitializeDecoder(sources.NewReplaceableSource(source), noop.New(), status.NewInfoRegistry()),
+		decoder: decoder.NewDecoderWithFraming(sources.NewReplaceableSource(source), parser, framing, nil, status.NewInfoRegistry()),
 		stop:    make(chan struct{}, 1),
 		done:    make(chan struct{}, 1),
 	}
 }
 
+// parserAndFraming returns the parser and the framing to use for the given source.
+// Syslog messages received over TCP are framed following RFC 6587, while each UDP
+// datagram holds exactly one syslog message.
+func parserAndFraming(source *sources.LogSource) (parsers.Parser, framer.Framing) {
+	if source.Config.Format != config.SyslogFormat {
+		return noop.New(), framer.UTF8Newline
+	}
+	if source.Config.Type == config.UDPType {
+		return syslog.New(), framer.NoFraming
+	}
+	return syslog.New(), framer.SyslogStream
+}
+
 // Start prepares the tailer to read and decode data from the connection
 func (t *Tailer) Start() {
 	go t.forwardMessages()
@@ -71,6 +89,12 @@ func (t *Tailer) forwardMessages() {
 		if len(output.GetContent()) > 0 {
 			origin := message.NewOrigin(t.source)
 			origin.SetTags(output.ParsingExtra.Tags)
+			if output.State == message.StateStructured {
+				// keep the attributes extracted by the parser
+				output.Origin = origin
+				t.outputChan <- output
+				continue
+			}
 			t.outputChan <- message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
 		}
 	}
@@ -102,6 +102,39 @@ func TestSourceHostTagFlagDisabled(t *testing.T) {
 	tailer.Stop()
 }
 
+func TestReadAndForwardSyslogMessages(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	logsConfig := &config.LogsConfig{
+		Type:   config.TCPType,
+		Format: config.SyslogFormat,
+		Tags:   []string{"test:tag"},
+	}
+	tailer := NewTailer(sources.NewLogSource("", logsConfig), r, msgChan, read)
+	tailer.Start()
+
+	var msg *message.Message
+
+	// octet-counted RFC 5424 message
+	w.Write([]byte("54 <11>1 2003-10-11T22:14:15.003Z host app 42 ID47 - boom"))
+	msg = <-msgChan
+	assert.Equal(t, message.StateStructured, msg.State)
+	assert.Equal(t, "boom", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Equal(t, []string{"test:tag"}, msg.Tags())
+	rendered, err := msg.Render()
+	assert.NoError(t, err)
+	assert.Contains(t, string(rendered), `"appname":"app"`)
+
+	// newline-terminated RFC 3164 message
+	w.Write([]byte("<14>Oct 11 22:14:15 host su: hello\n"))
+	msg = <-msgChan
+	assert.Equal(t, "hello", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.Status)
+
+	tailer.Stop()
+}
+
 func read(tailer *Tailer) ([]byte, string, error) {
 	inBuf := make([]byte, 4096)
 	n, err := tailer.Conn.Read(inBuf)
@@ -44,6 +44,12 @@ func (a *AddSampleTelemetry) Inc(isStateful bool) {
 
 // AddSample add a sample to the current ContextMetrics and initialize a new metrics if needed.
 func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, t *AddSampleTelemetry, config pkgconfigmodel.Config) error {
+	return m.AddSampleWithHistogramConfig(contextKey, sample, timestamp, interval, t, config, nil)
+}
+
+// AddSampleWithHistogramConfig adds a sample like AddSample, a new histogram
+// using the given configuration instead of the default one when it isn't nil.
+func (m ContextMetrics) AddSampleWithHistogramConfig(contextKey ckey.ContextKey, sample *MetricSample, timestamp float64, interval int64, t *AddSampleTelemetry, config pkgconfigmodel.Config, histogramConfig *HistogramConfig) error {
 	if math.IsInf(sample.Value, 0) || math.IsNaN(sample.Value) {
 		return fmt.Errorf("sample with value '%v'", sample.Value)
 	}
@@ -58,7 +64,11 @@ func (m ContextMetrics) AddSample(contextKey ckey.ContextKey, sample *MetricSamp
 		case MonotonicCountType:
 			m[contextKey] = &MonotonicCount{}
 		case HistogramType:
-			m[contextKey] = NewHistogram(interval, config) // default histogram configuration (no call to `configure`) for now
+			if histogramConfig != nil {
+				m[contextKey] = NewHistogramWithConfig(interval, *histogramConfig)
+			} else {
+				m[contextKey] = NewHistogram(interval, config)
+			}
 		case HistorateType:
 			m[contextKey] = NewHistorate(interval, config) // internal histogram has the configuration for now
 		case SetType:
@@ -7,8 +7,10 @@ package metrics
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
+	"strings"
 
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/config/structure"
@@ -29,9 +31,9 @@ func (w weightSamples) Swap(i, j int)      { w[i], w[j] = w[j], w[i] }
 
 // Histogram tracks the distribution of samples added over one flush period
 type Histogram struct {
-	aggregates  []string // aggregates configured on this histogram
-	percentiles []int    // percentiles configured on this histogram, each in the 1-100 range
-	interval    int64    // interval over which the `count` value is normalized (bucket interval for Dogstatsd, 1 otherwise)
+	aggregates  []string  // aggregates configured on this histogram
+	percentiles []float64 // percentiles configured on this histogram, each in the 0-100 range
+	interval    int64     // interval over which the `count` value is normalized (bucket interval for Dogstatsd, 1 otherwise)
 	samples     weightSamples
 	sum         float64
 	count       int64
@@ -48,9 +50,19 @@ const (
 
 var (
 	defaultAggregates  = []string(nil)
-	defaultPercentiles = []int(nil)
+	defaultPercentiles = []float64(nil)
 )
 
+// HistogramConfig holds the aggregates and the percentiles computed by a
+// histogram.
+type HistogramConfig struct {
+	// Aggregates are among max, min, median, avg, sum and count
+	Aggregates []string
+	// Percentiles are sorted, in the 0-100 range, and can have a fractional
+	// part (99.9)
+	Percentiles []float64
+}
+
 func parsePercentiles(percentiles []string) []int {
 	res := []int{}
 	for _, p := range percentiles {
@@ -73,6 +85,12 @@ func parsePercentiles(percentiles []string) []int {
 
 // NewHistogram returns a newly initialized histogram
 func NewHistogram(interval int64, config pkgconfigmodel.Config) *Histogram {
+	return NewHistogramWithConfig(interval, DefaultHistogramConfig(config))
+}
+
+// DefaultHistogramConfig returns the configuration of the histograms from the
+// `histogram_aggregates` and `histogram_percentiles` settings.
+func DefaultHistogramConfig(config pkgconfigmodel.Config) HistogramConfig {
 	// we initialize default value on the first histogram creation
 	if defaultAggregates == nil {
 		defaultAggregates = config.GetStringSlice("histogram_aggregates")
@@ -83,21 +101,35 @@ func NewHistogram(interval int64, config pkgconfigmodel.Config) *Histogram {
 		if err != nil {
 			log.Errorf("Could not Unmarshal histogram configuration: %s", err)
 		} else {
-			defaultPercentiles = parsePercentiles(c)
-			sort.Ints(defaultPercentiles)
+			percentiles := parsePercentiles(c)
+			defaultPercentiles = make([]float64, 0, len(percentiles))
+			for _, p := range percentiles {
+				defaultPercentiles = append(defaultPercentiles, float64(p))
+			}
+			sort.Float64s(defaultPercentiles)
 		}
 	}
 
+	return HistogramConfig{
+		Aggregates:  defaultAggregates,
+		Percentiles: defaultPercentiles,
+	}
+}
+
+// NewHistogramWithConfig returns a newly initialized histogram computing the
+// aggregates and the percentiles of the configuration. The configuration must
+// not be modified afterwards, it is shared by the histograms.
+func NewHistogramWithConfig(interval int64, config HistogramConfig) *Histogram {
 	return &Histogram{
 		interval:    interval,
-		aggregates:  defaultAggregates,
-		percentiles: defaultPercentiles,
+		aggregates:  config.Aggregates,
+		percentiles: config.Percentiles,
 	}
 }
 
-func (h *Histogram) configure(aggregates []string, percentiles []int) {
+func (h *Histogram) configure(aggregates []string, percentiles []float64) {
 	h.aggregates = aggregates
-	sort.Ints(percentiles)
+	sort.Float64s(percentiles)
 	h.percentiles = percentiles
 }
 
@@ -163,7 +195,7 @@ func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
 	// Compute percentiles
 	target := make([]int64, 0, len(h.percentiles))
 	for _, percentile := range h.percentiles {
-		target = append(target, (int64(percentile)*h.count-1)/100)
+		target = append(target, int64((percentile*float64(h.count)-1)/100))
 	}
 
 	if len(target) > 0 {
@@ -175,7 +207,7 @@ func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
 				series = append(series, &Serie{
 					Points:     []Point{{Ts: timestamp, Value: s.value}},
 					MType:      APIGaugeType,
-					NameSuffix: fmt.Sprintf(".%dpercentile", h.percentiles[idx]),
+					NameSuffix: percentileSuffix(h.percentiles[idx]),
 				})
 				idx++
 			}
@@ -193,6 +225,16 @@ func (h *Histogram) flush(timestamp float64) ([]*Serie, error) {
 	return series, nil
 }
 
+// percentileSuffix returns the suffix of the name of a percentile, the dot of
+// the fractional part being replaced by an underscore (.99_9percentile) as it
+// separates the elements of the metric names.
+func percentileSuffix(percentile float64) string {
+	if percentile == math.Trunc(percentile) {
+		return fmt.Sprintf(".%dpercentile", int(percentile))
+	}
+	return "." + strings.Replace(strconv.FormatFloat(percentile, 'f', -1, 64), ".", "_", 1) + "percentile"
+}
+
 func (h *Histogram) isStateful() bool {
 	return false
 }
@@ -32,7 +32,7 @@ func TestConfigureDefault(t *testing.T) {
 	_, err := hist.flush(60)
 	require.Nil(t, err)
 	assert.Equal(t, []string{"max", "median", "avg", "count"}, hist.aggregates)
-	assert.Equal(t, []int{95}, hist.percentiles)
+	assert.Equal(t, []float64{95}, hist.percentiles)
 }
 
 func TestConfigure(t *testing.T) {
@@ -46,7 +46,7 @@ func TestConfigure(t *testing.T) {
 
 	hist := NewHistogram(10, mockConfig)
 	assert.Equal(t, aggregates, hist.aggregates)
-	assert.Equal(t, []int{30, 50, 98}, hist.percentiles)
+	assert.Equal(t, []float64{30, 50, 98}, hist.percentiles)
 }
 
 func TestDefaultHistogramSampling(t *testing.T) {
@@ -96,7 +96,7 @@ func TestCustomHistogramSampling(t *testing.T) {
 	// Initialize custom histogram, with an invalid aggregate
 	cfg := setupConfig(t)
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"min", "sum", "invalid"}, []int{})
+	mHistogram.configure([]string{"min", "sum", "invalid"}, []float64{})
 
 	// Empty flush
 	_, err := mHistogram.flush(50)
@@ -142,7 +142,7 @@ func TestHistogramPercentiles(t *testing.T) {
 	// Initialize custom histogram
 	cfg := setupConfig(t)
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"max", "median", "avg", "count", "min"}, []int{95, 80})
+	mHistogram.configure([]string{"max", "median", "avg", "count", "min"}, []float64{95, 80})
 
 	// Empty flush
 	_, err := mHistogram.flush(50)
@@ -191,7 +191,7 @@ func TestHistogramPercentiles(t *testing.T) {
 func TestHistogramSampleRate(t *testing.T) {
 	cfg := setupConfig(t)
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []float64{20, 95, 80})
 
 	mHistogram.addSample(&MetricSample{Value: 1}, 50)
 	mHistogram.addSample(&MetricSample{Value: 2, SampleRate: 0.5}, 50)
@@ -232,7 +232,7 @@ func TestHistogramSampleRate(t *testing.T) {
 func TestHistogramReset(t *testing.T) {
 	cfg := setupConfig(t)
 	mHistogram := NewHistogram(10, cfg)
-	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+	mHistogram.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []float64{20, 95, 80})
 
 	mHistogram.addSample(&MetricSample{Value: 1}, 50)
 	mHistogram.addSample(&MetricSample{Value: 2, SampleRate: 0.5}, 50)
@@ -271,6 +271,36 @@ func TestHistogramReset(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestHistogramWithConfig(t *testing.T) {
+	mHistogram := NewHistogramWithConfig(10, HistogramConfig{
+		Aggregates:  []string{"max"},
+		Percentiles: []float64{50, 99, 99.9},
+	})
+
+	for i := 1; i <= 1000; i++ {
+		mHistogram.addSample(&MetricSample{Value: float64(i)}, 50)
+	}
+	series, err := mHistogram.flush(60)
+	require.Nil(t, err)
+	require.Len(t, series, 4)
+
+	assert.Equal(t, ".max", series[0].NameSuffix)
+	assert.InEpsilon(t, 1000, series[0].Points[0].Value, epsilon)
+	assert.Equal(t, ".50percentile", series[1].NameSuffix)
+	assert.InEpsilon(t, 500, series[1].Points[0].Value, epsilon)
+	assert.Equal(t, ".99percentile", series[2].NameSuffix)
+	assert.InEpsilon(t, 990, series[2].Points[0].Value, epsilon)
+	assert.Equal(t, ".99_9percentile", series[3].NameSuffix)
+	assert.InEpsilon(t, 999, series[3].Points[0].Value, epsilon)
+
+	// without aggregates nor percentiles
+	mHistogram = NewHistogramWithConfig(10, HistogramConfig{})
+	mHistogram.addSample(&MetricSample{Value: 1}, 50)
+	series, err = mHistogram.flush(60)
+	require.Nil(t, err)
+	assert.Empty(t, series)
+}
+
 //
 // Benchmark
 //
@@ -279,7 +309,7 @@ func benchHistogram(b *testing.B, number int, sampleRate float64) {
 	cfg := setupConfig(b)
 	for n := 0; n < b.N; n++ {
 		h := NewHistogram(1, cfg)
-		h.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []int{20, 95, 80})
+		h.configure([]string{"max", "min", "median", "avg", "sum", "count"}, []float64{20, 95, 80})
 		m := MetricSample{Value: 21, SampleRate: sampleRate}
 
 		for i := 0; i < number; i++ {
@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// defaultAWSObfuscatePaths holds the paths of the values obfuscated in the parameters of
+// the AWS requests by default: the items, keys and attribute values of the DynamoDB ones.
+var defaultAWSObfuscatePaths = []string{
+	"$.Item",
+	"$.Key",
+	"$.ExclusiveStartKey",
+	"$.ExpressionAttributeValues",
+	"$.AttributeUpdates.*.Value",
+	"$.Expected",
+	"$.KeyConditions.*.AttributeValueList",
+	"$.QueryFilter.*.AttributeValueList",
+	"$.ScanFilter.*.AttributeValueList",
+	"$.RequestItems.*.Keys",
+	"$.RequestItems.*[*].PutRequest.Item",
+	"$.RequestItems.*[*].DeleteRequest.Key",
+	"$.TransactItems[*].*.Item",
+	"$.TransactItems[*].*.Key",
+	"$.TransactItems[*].*.ExpressionAttributeValues",
+	"$.Parameters",
+	"$.Statements[*].Parameters",
+	"$.TransactStatements[*].Parameters",
+}
+
+// jsonPath holds the segments of a JSON path: the object keys and the array indexes,
+// "*" matching any of them.
+type jsonPath []string
+
+// ObfuscateAWSRequestParameters obfuscates the values found at the configured paths of
+// the given JSON parameters of an AWS request, replacing them with "?". The whole
+// parameters are replaced with "?" if they aren't valid JSON.
+func (o *Obfuscator) ObfuscateAWSRequestParameters(params string) string {
+	if len(o.awsPaths) == 0 || params == "" {
+		return params
+	}
+	var out bytes.Buffer
+	out.Grow(len(params))
+	dec := json.NewDecoder(strings.NewReader(params))
+	dec.UseNumber()
+	if err := o.obfuscateAWSValue(dec, &out, nil, false); err != nil {
+		o.log.Debugf("Failed to obfuscate the AWS request parameters: %v", err)
+		return "?"
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		o.log.Debugf("Failed to obfuscate the AWS request parameters: unexpected data after the JSON value")
+		return "?"
+	}
+	return out.String()
+}
+
+// obfuscateAWSValue writes the next JSON value read from dec to out, replacing its
+// scalar values with "?" if obfuscate is true or if path, the path of the value, is
+// one of the paths to obfuscate.
+func (o *Obfuscator) obfuscateAWSValue(dec *json.Decoder, out *bytes.Buffer, path []string, obfuscate bool) error {
+	obfuscate = obfuscate || o.isAWSPathObfuscated(path)
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			out.WriteByte('{')
+			for i := 0; dec.More(); i++ {
+				tok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, ok := tok.(string)
+				if !ok {
+					return fmt.Errorf("unexpected object key %v", tok)
+				}
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				writeJSONString(out, key)
+				out.WriteByte(':')
+				if err := o.obfuscateAWSValue(dec, out, append(path, key), obfuscate); err != nil {
+					return err
+				}
+			}
+			out.WriteByte('}')
+		case '[':
+			out.WriteByte('[')
+			for i := 0; dec.More(); i++ {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				if err := o.obfuscateAWSValue(dec, out, append(path, strconv.Itoa(i)), obfuscate); err != nil {
+					return err
+				}
+			}
+			out.WriteByte(']')
+		default:
+			return fmt.Errorf("unexpected delimiter %v", v)
+		}
+		// consume the closing delimiter
+		_, err := dec.Token()
+		return err
+	}
+	if obfuscate {
+		out.WriteString(`"?"`)
+		return nil
+	}
+	switch v := tok.(type) {
+	case string:
+		writeJSONString(out, v)
+	case json.Number:
+		out.WriteString(v.String())
+	case bool:
+		out.WriteString(strconv.FormatBool(v))
+	case nil:
+		out.WriteString("null")
+	}
+	return nil
+}
+
+func (o *Obfuscator) isAWSPathObfuscated(path []string) bool {
+	for _, p := range o.awsPaths {
+		if p.match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p jsonPath) match(path []string) bool {
+	if len(p) != len(path) {
+		return false
+	}
+	for i, segment := range p {
+		if segment != "*" && segment != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeJSONString writes s to out as a JSON string.
+func writeJSONString(out *bytes.Buffer, s string) {
+	enc := json.NewEncoder(out)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s) //nolint:errcheck // encoding a string can't fail
+	// Encode terminates the value with a newline
+	out.Truncate(out.Len() - 1)
+}
+
+// compileJSONPaths compiles the given JSON paths, or the default ones if there are
+// none, logging and skipping the invalid ones.
+func compileJSONPaths(paths []string, o *Obfuscator) []jsonPath {
+	if len(paths) == 0 {
+		paths = defaultAWSObfuscatePaths
+	}
+	compiled := make([]jsonPath, 0, len(paths))
+	for _, path := range paths {
+		p, err := parseJSONPath(path)
+		if err != nil {
+			o.log.Debugf("Skipping invalid JSON path %q: %v", path, err)
+			continue
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled
+}
+
+// parseJSONPath parses JSON paths such as "$.a.*[0]['b c'][*]".
+func parseJSONPath(path string) (jsonPath, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.New(`JSON paths must start with "$"`)
+	}
+	var p jsonPath
+	for rest := path[1:]; rest != ""; {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return nil, errors.New("empty key")
+			}
+			p = append(p, rest[1:end+1])
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, errors.New(`missing "]"`)
+			}
+			segment := rest[1:end]
+			if len(segment) >= 2 && segment[0] == '\'' && segment[len(segment)-1] == '\'' {
+				segment = segment[1 : len(segment)-1]
+			} else if _, err := strconv.Atoi(segment); err != nil && segment != "*" {
+				return nil, fmt.Errorf("invalid index %q", segment)
+			}
+			p = append(p, segment)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf(`unexpected %q, expected "." or "["`, rest[0])
+		}
+	}
+	return p, nil
+}
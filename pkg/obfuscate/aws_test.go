@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObfuscateAWSRequestParameters(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		paths   []string
+		in, out string
+	}{
+		{
+			name: "get-item",
+			in:   `{"TableName": "users", "Key": {"id": {"S": "42"}}, "ProjectionExpression": "email"}`,
+			out:  `{"TableName":"users","Key":{"id":{"S":"?"}},"ProjectionExpression":"email"}`,
+		},
+		{
+			name: "query",
+			in:   `{"TableName":"users","KeyConditionExpression":"id = :id","ExpressionAttributeValues":{":id":{"N":"42"},":tags":{"L":[{"S":"<a&b>"}]}},"Limit":10,"ConsistentRead":true,"ExclusiveStartKey":null}`,
+			out:  `{"TableName":"users","KeyConditionExpression":"id = :id","ExpressionAttributeValues":{":id":{"N":"?"},":tags":{"L":[{"S":"?"}]}},"Limit":10,"ConsistentRead":true,"ExclusiveStartKey":"?"}`,
+		},
+		{
+			name: "batch-write-item",
+			in:   `{"RequestItems":{"users":[{"PutRequest":{"Item":{"id":{"S":"1"}}}},{"DeleteRequest":{"Key":{"id":{"S":"2"}}}}]}}`,
+			out:  `{"RequestItems":{"users":[{"PutRequest":{"Item":{"id":{"S":"?"}}}},{"DeleteRequest":{"Key":{"id":{"S":"?"}}}}]}}`,
+		},
+		{
+			name:  "custom-paths",
+			paths: []string{"$.Message", "$.MessageAttributes.*.StringValue", "$.Entries[1]", "$['Other key']", "invalid"},
+			in:    `{"QueueUrl":"https://sqs","Message":"secret","MessageAttributes":{"user":{"DataType":"String","StringValue":"jane"}},"Entries":[1,2],"Other key":3}`,
+			out:   `{"QueueUrl":"https://sqs","Message":"?","MessageAttributes":{"user":{"DataType":"String","StringValue":"?"}},"Entries":[1,"?"],"Other key":"?"}`,
+		},
+		{
+			name: "invalid",
+			in:   `{"Key": {"id": `,
+			out:  "?",
+		},
+		{
+			name: "trailing-data",
+			in:   `{"Key": {}} {}`,
+			out:  "?",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewObfuscator(Config{AWS: AWSConfig{Enabled: true, ObfuscatePaths: tt.paths}})
+			assert.Equal(t, tt.out, o.ObfuscateAWSRequestParameters(tt.in))
+		})
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	p, err := parseJSONPath("$.a.*[0]['b.c'][*]")
+	require.NoError(t, err)
+	assert.Equal(t, jsonPath{"a", "*", "0", "b.c", "*"}, p)
+
+	for _, path := range []string{"a.b", "$..a", "$[x]", "$[0", "$a"} {
+		_, err := parseJSONPath(path)
+		assert.Error(t, err, path)
+	}
+}
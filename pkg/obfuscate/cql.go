@@ -0,0 +1,24 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"regexp"
+)
+
+// cqlLiterals matches the CQL literals which the SQL tokenizer doesn't know about:
+// the UUIDs and the durations, which it would split into numbers and identifiers,
+// and the NaN and Infinity floats, which it would take for identifiers.
+var cqlLiterals = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b|-?\b(?:\d+(?:mo|ms|us|µs|ns|y|w|d|h|m|s))+\b|-?\b(?:NaN|Infinity)\b`)
+
+// ObfuscateCQLString quantizes and obfuscates the given Cassandra CQL query using the
+// SQL tokenizer, after having replaced the CQL specific literals with '?'.
+func (o *Obfuscator) ObfuscateCQLString(in string) (*ObfuscatedQuery, error) {
+	opts := o.opts.SQL
+	// the SQL lexer doesn't know about CQL, always use the tokenizer
+	opts.ObfuscationMode = ""
+	return o.ObfuscateSQLStringWithOptions(cqlLiterals.ReplaceAllLiteralString(in, "?"), &opts)
+}
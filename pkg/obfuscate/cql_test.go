@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObfuscateCQLString(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			"SELECT name FROM ks.users WHERE id = 123e4567-e89b-12d3-a456-426614174000",
+			"SELECT name FROM ks.users WHERE id = ?",
+		},
+		{
+			"SELECT * FROM metrics WHERE score > NaN AND low < -Infinity AND nan_count = 3 LIMIT 10",
+			"SELECT * FROM metrics WHERE score > ? AND low < ? AND nan_count = ? LIMIT ?",
+		},
+		{
+			"UPDATE sessions USING TTL 86400 SET idle = 1h30m, tags = tags + {'a', 'b'} WHERE id = 5",
+			"UPDATE sessions USING TTL ? SET idle = ? tags = tags + ? WHERE id = ?",
+		},
+		{
+			"INSERT INTO blobs (id, data, list) VALUES (uuid(), 0xCAFE, [1, 2]) IF NOT EXISTS",
+			"INSERT INTO blobs ( id, data, list ) VALUES ( uuid ( ), ? [ ? ] ) IF NOT EXISTS",
+		},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			oq, err := NewObfuscator(Config{CQL: CQLConfig{Enabled: true}}).ObfuscateCQLString(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.out, oq.Query)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+)
+
+// ObfuscateGraphQLString obfuscates the given GraphQL query: its string and number
+// literals are replaced with '?', and it is normalized by removing its comments and
+// collapsing its whitespaces. The references to variables, the names and the enum
+// values are kept.
+func (o *Obfuscator) ObfuscateGraphQLString(query string) string {
+	var (
+		out  strings.Builder
+		prev string // the previous token written
+	)
+	out.Grow(len(query))
+	write := func(token string) {
+		if prev != "" && graphQLNeedsSpace(prev, token) {
+			out.WriteByte(' ')
+		}
+		out.WriteString(token)
+		prev = token
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#':
+			// comments run until the end of the line
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+		case c == '"':
+			i = graphQLSkipString(query, i)
+			write("?")
+		case c == '-' || isDigit(rune(c)):
+			i++
+			for i < len(query) && graphQLIsNumberChar(query[i]) {
+				i++
+			}
+			write("?")
+		case c == '_' || isLeadingLetter(rune(c)):
+			start := i
+			for i < len(query) && (query[i] == '_' || isLeadingLetter(rune(query[i])) || isDigit(rune(query[i]))) {
+				i++
+			}
+			write(query[start:i])
+		case strings.HasPrefix(query[i:], "..."):
+			i += 3
+			write("...")
+		default:
+			i++
+			write(string(c))
+		}
+	}
+	return out.String()
+}
+
+// ObfuscateGraphQLVariable obfuscates the value of the GraphQL variable name, unless
+// its value should be kept.
+func (o *Obfuscator) ObfuscateGraphQLVariable(name, value string) string {
+	if o.graphQLKeepValues[name] {
+		return value
+	}
+	return "?"
+}
+
+// ObfuscateGraphQLVariables obfuscates the values of the given JSON object of GraphQL
+// variables, except the ones of the variables whose values should be kept.
+func (o *Obfuscator) ObfuscateGraphQLVariables(variables string) string {
+	return obfuscateJSONString(variables, o.graphQLVariables)
+}
+
+// graphQLSkipString returns the position following the string or block string
+// starting at i.
+func graphQLSkipString(query string, i int) int {
+	if strings.HasPrefix(query[i:], `"""`) {
+		for j := i + 3; j < len(query); j++ {
+			if query[j] == '\\' && strings.HasPrefix(query[j+1:], `"""`) {
+				j += 3
+				continue
+			}
+			if strings.HasPrefix(query[j:], `"""`) {
+				return j + 3
+			}
+		}
+		return len(query)
+	}
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			j++
+		case '"', '\n', '\r':
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+func graphQLIsNumberChar(c byte) bool {
+	return isDigit(rune(c)) || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-'
+}
+
+// graphQLNeedsSpace reports whether a space should separate the tokens prev and next
+// in the normalized query.
+func graphQLNeedsSpace(prev, next string) bool {
+	switch prev {
+	case "(", "[", "$", "@":
+		return false
+	case "...":
+		// inline fragments are spread with "... on Type"
+		return next == "on"
+	}
+	switch next {
+	case ")", "]", ":", "!", ",":
+		return false
+	case "(":
+		// arguments follow the names of fields and directives
+		return !graphQLIsName(prev)
+	}
+	return true
+}
+
+func graphQLIsName(token string) bool {
+	c := token[0]
+	return c == '_' || isLeadingLetter(rune(c))
+}
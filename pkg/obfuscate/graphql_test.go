@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQLString(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`{ user(id: 42) { name } }`,
+			`{ user(id: ?) { name } }`,
+		},
+		{
+			"query GetUser($id: ID!, $first: Int = 10) {\n  # the user\n  user(id: $id) {\n    friends(first: $first, role: ADMIN) { edges { node { name } } }\n  }\n}",
+			`query GetUser($id: ID!, $first: Int = ?) { user(id: $id) { friends(first: $first, role: ADMIN) { edges { node { name } } } } }`,
+		},
+		{
+			`mutation { createUser(input: {email: "jane@example.com", age: -3.5e2, tags: ["a", "b"], admin: false, manager: null}) { id } }`,
+			`mutation { createUser(input: { email: ?, age: ?, tags: [?, ?], admin: false, manager: null }) { id } }`,
+		},
+		{
+			`{ post(body: """multi ""line"" \""" text""") @include(if: $withPosts) { ...PostFields } }`,
+			`{ post(body: ?) @include(if: $withPosts) { ...PostFields } }`,
+		},
+		{
+			`{ search(text: "escaped \" quote") { ... on User { id } } }`,
+			`{ search(text: ?) { ... on User { id } } }`,
+		},
+		{
+			`{ user(id: "unterminated`, // this is invalid, but it shouldn't crash
+			`{ user(id: ?`,
+		},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.out, NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true}}).ObfuscateGraphQLString(tt.in))
+		})
+	}
+}
+
+func TestObfuscateGraphQLVariables(t *testing.T) {
+	o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true, KeepValues: []string{"first"}}})
+	assert.Equal(t, `{"id":"?","input":{"email":"?"},"first":10}`, o.ObfuscateGraphQLVariables(`{"id": "42", "input": {"email": "jane@example.com"}, "first": 10}`))
+	assert.Equal(t, "?", o.ObfuscateGraphQLVariable("id", "42"))
+	assert.Equal(t, "10", o.ObfuscateGraphQLVariable("first", "10"))
+}
@@ -34,6 +34,9 @@ type Obfuscator struct {
 	sqlExecPlan          *jsonObfuscator // nil if disabled
 	sqlExecPlanNormalize *jsonObfuscator // nil if disabled
 	ccObfuscator         *creditCard     // nil if disabled
+	graphQLVariables     *jsonObfuscator // nil if disabled
+	graphQLKeepValues    map[string]bool
+	awsPaths             []jsonPath
 	// sqlLiteralEscapes reports whether we should treat escape characters literally or as escape characters.
 	// Different SQL engines behave in different ways and the tokenizer needs to be generic.
 	sqlLiteralEscapes *atomic.Bool
@@ -101,6 +104,16 @@ type Config struct {
 	// Memcached holds the obfuscation settings for Memcached commands.
 	Memcached MemcachedConfig `mapstructure:"memcached"`
 
+	// GraphQL holds the obfuscation settings for GraphQL queries and variables.
+	GraphQL GraphQLConfig `mapstructure:"graphql"`
+
+	// CQL holds the obfuscation settings for Cassandra CQL queries.
+	CQL CQLConfig `mapstructure:"cassandra"`
+
+	// AWS holds the obfuscation settings for the parameters of AWS requests,
+	// such as the DynamoDB ones.
+	AWS AWSConfig `mapstructure:"aws"`
+
 	// Memcached holds the obfuscation settings for obfuscation of CC numbers in meta.
 	CreditCard CreditCardsConfig `mapstructure:"credit_cards"`
 
@@ -259,6 +272,36 @@ type MemcachedConfig struct {
 	KeepCommand bool `mapstructure:"keep_command"`
 }
 
+// GraphQLConfig holds the configuration settings for GraphQL obfuscation.
+type GraphQLConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// KeepValues specifies the names of the variables whose values
+	// should not be obfuscated.
+	KeepValues []string `mapstructure:"keep_values"`
+}
+
+// CQLConfig holds the configuration settings for Cassandra CQL obfuscation.
+type CQLConfig struct {
+	// Enabled specifies whether this feature should be enabled. When disabled,
+	// CQL queries are obfuscated as SQL ones.
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// AWSConfig holds the configuration settings for the obfuscation of the
+// parameters of AWS requests.
+type AWSConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// ObfuscatePaths specifies the JSON paths, such as "$.Item" or
+	// "$.RequestItems.*[*].PutRequest.Item", of the values to obfuscate.
+	// Defaults to the paths holding the items and key values of the
+	// DynamoDB requests.
+	ObfuscatePaths []string `mapstructure:"obfuscate_paths"`
+}
+
 // JSONConfig holds the obfuscation configuration for sensitive
 // data found in JSON objects.
 type JSONConfig struct {
@@ -328,6 +371,16 @@ func NewObfuscator(cfg Config) *Obfuscator {
 	if cfg.CreditCard.Enabled {
 		o.ccObfuscator = newCCObfuscator(&cfg.CreditCard)
 	}
+	if cfg.GraphQL.Enabled {
+		o.graphQLVariables = newJSONObfuscator(&JSONConfig{KeepValues: cfg.GraphQL.KeepValues}, &o)
+		o.graphQLKeepValues = make(map[string]bool, len(cfg.GraphQL.KeepValues))
+		for _, name := range cfg.GraphQL.KeepValues {
+			o.graphQLKeepValues[name] = true
+		}
+	}
+	if cfg.AWS.Enabled {
+		o.awsPaths = compileJSONPaths(cfg.AWS.ObfuscatePaths, &o)
+	}
 	if cfg.Statsd == nil {
 		cfg.Statsd = &statsd.NoOpClient{}
 	}
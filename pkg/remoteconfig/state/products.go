@@ -16,6 +16,7 @@ var validProducts = map[string]struct{}{
 	ProductAgentTask:                    {},
 	ProductAgentIntegrations:            {},
 	ProductAPMSampling:                  {},
+	ProductAPMSpanRules:                 {},
 	ProductCWSDD:                        {},
 	ProductCWSCustom:                    {},
 	ProductCWSProfiles:                  {},
@@ -58,6 +59,8 @@ const (
 	ProductAgentTask = "AGENT_TASK"
 	// ProductAPMSampling is the apm sampling product
 	ProductAPMSampling = "APM_SAMPLING"
+	// ProductAPMSpanRules is the apm span rules product, filtering the spans received by the trace-agent
+	ProductAPMSpanRules = "APM_SPAN_RULES"
 	// ProductCWSDD is the cloud workload security product managed by datadog employees
 	ProductCWSDD = "CWS_DD"
 	// ProductCWSCustom is the cloud workload security product managed by datadog customers
@@ -8,6 +8,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"runtime"
 	"strconv"
@@ -18,6 +19,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/capture"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/event"
 	"github.com/DataDog/datadog-agent/pkg/trace/filters"
@@ -71,6 +73,41 @@ type TraceWriter interface {
 	UpdateAPIKey(oldKey, newKey string)
 }
 
+// teeTraceWriter is a TraceWriter writing the trace chunks to all of its writers.
+type teeTraceWriter []TraceWriter
+
+// Stop stops all the writers.
+func (t teeTraceWriter) Stop() {
+	for _, w := range t {
+		w.Stop()
+	}
+}
+
+// WriteChunks writes the chunks to all the writers, which must not modify them.
+func (t teeTraceWriter) WriteChunks(pkg *writer.SampledChunks) {
+	for _, w := range t {
+		w.WriteChunks(pkg)
+	}
+}
+
+// FlushSync flushes all the writers, returning their errors.
+func (t teeTraceWriter) FlushSync() error {
+	var errs []error
+	for _, w := range t {
+		if err := w.FlushSync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// UpdateAPIKey updates the API key of all the writers.
+func (t teeTraceWriter) UpdateAPIKey(oldKey, newKey string) {
+	for _, w := range t {
+		w.UpdateAPIKey(oldKey, newKey)
+	}
+}
+
 // Concentrator accepts stats input, 'concentrating' them together into buckets before flushing them
 type Concentrator interface {
 	// Start starts the Concentrator
@@ -89,11 +126,13 @@ type Agent struct {
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	SpanRules             *filters.SpanRules
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
 	ProbabilisticSampler  *sampler.ProbabilisticSampler
+	TailSampler           *sampler.TailSampler
 	SamplerMetrics        *sampler.Metrics
 	EventProcessor        *event.Processor
 	TraceWriter           TraceWriter
@@ -101,6 +140,7 @@ type Agent struct {
 	RemoteConfigHandler   *remoteconfighandler.RemoteConfigHandler
 	TelemetryCollector    telemetry.TelemetryCollector
 	DebugServer           *api.DebugServer
+	Capture               *capture.Writer
 	Statsd                statsd.ClientInterface
 	Timing                timing.Reporter
 
@@ -147,11 +187,17 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 	}
 	timing := timing.New(statsd)
 	statsWriter := writer.NewStatsWriter(conf, telemetryCollector, statsd, timing)
+	spanRules, err := filters.NewSpanRules(conf.SpanRules)
+	if err != nil {
+		log.Errorf("Invalid span rules, ignoring them: %v", err)
+		spanRules, _ = filters.NewSpanRules(nil)
+	}
 	agnt := &Agent{
 		Concentrator:          stats.NewConcentrator(conf, statsWriter, time.Now(), statsd),
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsWriter, statsd),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		SpanRules:             spanRules,
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf),
 		RareSampler:           sampler.NewRareSampler(conf),
@@ -171,8 +217,29 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 	agnt.SamplerMetrics.Add(agnt.PrioritySampler, agnt.ErrorsSampler, agnt.NoPrioritySampler, agnt.RareSampler)
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt, telemetryCollector, statsd, timing)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf, statsd, timing)
-	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
+	if c := conf.PayloadCapture; c.Enabled {
+		if w, err := capture.NewWriter(c.Dir, c.MaxFileSize, c.MaxFiles); err != nil {
+			log.Errorf("Failed to start the payload capture to %s: %v", c.Dir, err)
+		} else {
+			agnt.Capture = w
+			agnt.Receiver.Capture = w
+			agnt.OTLPReceiver.Capture = w
+		}
+	}
+	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler, agnt.SpanRules)
 	agnt.TraceWriter = writer.NewTraceWriter(conf, agnt.PrioritySampler, agnt.ErrorsSampler, agnt.RareSampler, telemetryCollector, statsd, timing, comp)
+	if conf.OTLPTracesOutput.Enabled {
+		// the sampled traces are also exported to the OTLP endpoint
+		if w, err := writer.NewOTLPTraceWriter(conf, statsd); err != nil {
+			log.Errorf("Failed to create the OTLP traces writer, the traces won't be exported to %s: %v", conf.OTLPTracesOutput.Endpoint, err)
+		} else {
+			agnt.TraceWriter = teeTraceWriter{agnt.TraceWriter, w}
+		}
+	}
+	if conf.TailSampling.Enabled {
+		agnt.TailSampler = sampler.NewTailSampler(conf, agnt.writeTailSampledChunks, statsd)
+		agnt.SamplerMetrics.Add(agnt.TailSampler)
+	}
 	return agnt
 }
 
@@ -192,6 +259,10 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
+	info.UpdateSpanRules(a.SpanRules.Hits)
 
 	go a.StatsWriter.Run()
 
@@ -259,8 +330,10 @@ func (a *Agent) loop() {
 		log.Error(err)
 	}
 	for _, stopper := range []interface{ Stop() }{
+		a.Capture, // after the receivers, which write to it
 		a.Concentrator,
 		a.ClientStatsAggregator,
+		a.TailSampler, // before the TraceWriter, to write the buffered traces
 		a.TraceWriter,
 		a.StatsWriter,
 		a.SamplerMetrics,
@@ -313,6 +386,8 @@ func (a *Agent) Process(p *api.Payload) {
 	defer a.Timing.Since("datadog.trace_agent.internal.process_payload_ms", now)
 	ts := p.Source
 	sampledChunks := new(writer.SampledChunks)
+	// tailPayload holds the attributes of the payload of the chunks buffered by the tail sampler
+	var tailPayload *pb.TracerPayload
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats)
 
 	p.TracerPayload.Env = traceutil.NormalizeTagValue(p.TracerPayload.Env)
@@ -337,6 +412,23 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		if !a.SpanRules.Apply(chunk) {
+			log.Debugf("Trace rejected by span rules.")
+			ts.TracesFiltered.Inc()
+			ts.SpansFiltered.Add(tracen)
+			p.RemoveChunk(i)
+			continue
+		}
+		if dropped := tracen - int64(len(chunk.Spans)); dropped > 0 {
+			ts.SpansFiltered.Add(dropped)
+			tracen -= dropped
+			if tracen == 0 {
+				ts.TracesFiltered.Inc()
+				p.RemoveChunk(i)
+				continue
+			}
+		}
+
 		// Root span is used to carry some trace-level metadata, such as sampling rate and priority.
 		root := traceutil.GetRoot(chunk.Spans)
 		setChunkAttributes(chunk, root)
@@ -390,7 +482,24 @@ func (a *Agent) Process(p *api.Payload) {
 			statsInput.Traces = append(statsInput.Traces, *pt.Clone())
 		}
 
+		var spans []*pb.Span
+		if a.TailSampler != nil {
+			// the spans of the chunk are replaced when it is dropped
+			spans = pt.TraceChunk.Spans
+		}
 		keep, numEvents := a.sample(now, ts, pt)
+		if a.TailSampler != nil {
+			if keep {
+				a.TailSampler.KeepTrace(now, root.TraceID)
+			} else if !isUserDrop(pt) {
+				if tailPayload == nil {
+					tailPayload = tracerPayloadAttributes(p.TracerPayload)
+				}
+				a.bufferTailChunk(now, tailPayload, pt, spans, numEvents)
+				p.RemoveChunk(i)
+				continue
+			}
+		}
 		if !keep && len(pt.TraceChunk.Spans) == 0 {
 			// The entire trace was dropped and no spans were kept.
 			p.RemoveChunk(i)
@@ -83,6 +83,19 @@ func (m *mockTraceWriter) UpdateAPIKey(_, newKey string) {
 	m.apiKey = newKey
 }
 
+func TestTeeTraceWriter(t *testing.T) {
+	a, b := &mockTraceWriter{apiKey: "old"}, &mockTraceWriter{apiKey: "old"}
+	tee := teeTraceWriter{a, b}
+	pkg := &writer.SampledChunks{TracerPayload: &pb.TracerPayload{}}
+	tee.WriteChunks(pkg)
+	tee.UpdateAPIKey("old", "new")
+
+	for _, w := range []*mockTraceWriter{a, b} {
+		assert.Equal(t, []*writer.SampledChunks{pkg}, w.payloads)
+		assert.Equal(t, "new", w.apiKey)
+	}
+}
+
 type mockConcentrator struct {
 	stats []stats.Input
 	mu    sync.Mutex
@@ -513,6 +526,54 @@ func TestProcess(t *testing.T) {
 		assert.NotContains(t, payload.TracerPayload.Chunks[0].Spans[1].Meta, "irrelevant")
 	})
 
+	t.Run("SpanRules", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.SpanRules = []*config.SpanRule{
+			{
+				Name:    "health-checks",
+				Match:   config.SpanRuleMatch{Meta: map[string]string{"http.route": "/health*"}},
+				Actions: []*config.SpanRuleAction{{Type: "drop_span"}},
+			},
+			{
+				Name:    "internal",
+				Match:   config.SpanRuleMatch{Service: "internal-*"},
+				Actions: []*config.SpanRuleAction{{Type: "drop_trace"}},
+			},
+			{
+				Name:    "pii",
+				Actions: []*config.SpanRuleAction{{Type: "delete_tag", Key: "user.*"}},
+			},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+		defer cancel()
+
+		span1 := &pb.Span{TraceID: 1, SpanID: 1, Service: "a", Meta: map[string]string{"user.email": "jane@example.com"}}
+		span2 := &pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Service: "a", Meta: map[string]string{"http.route": "/health"}}
+		span3 := &pb.Span{TraceID: 2, SpanID: 3, Service: "internal-cron"}
+
+		c1 := spansToChunk(span1, span2)
+		c1.Priority = 1
+		c2 := spansToChunk(span3)
+		c2.Priority = 1
+		want := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunks([]*pb.TraceChunk{c1, c2}),
+			Source:        want,
+		})
+		payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+		require.Len(t, payloads, 1)
+		require.Len(t, payloads[0].TracerPayload.Chunks, 1)
+		spans := payloads[0].TracerPayload.Chunks[0].Spans
+		require.Len(t, spans, 1)
+		assert.Equal(t, uint64(1), spans[0].SpanID)
+		assert.NotContains(t, spans[0].Meta, "user.email")
+		assert.EqualValues(t, 1, want.TracesFiltered.Load())
+		assert.EqualValues(t, 2, want.SpansFiltered.Load())
+		assert.Equal(t, map[string]int64{"health-checks": 1, "internal": 1, "pii": 1}, agnt.SpanRules.Hits())
+	})
+
 	t.Run("chunking", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -1999,10 +2060,13 @@ func TestPartialSamplingFree(t *testing.T) {
 	dynConf := sampler.NewDynamicConfig()
 	in := make(chan *api.Payload, 1000)
 	statsd := &statsd.NoOpClient{}
+	spanRules, err := filters.NewSpanRules(cfg.SpanRules)
+	require.NoError(t, err)
 	agnt := &Agent{
 		Concentrator:      &mockConcentrator{},
 		Blacklister:       filters.NewBlacklister(cfg.Ignore["resource"]),
 		Replacer:          filters.NewReplacer(cfg.ReplaceTags),
+		SpanRules:         spanRules,
 		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
 		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
 		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
@@ -24,6 +24,7 @@ const (
 	tagSQLQuery         = transform.TagSQLQuery
 	tagHTTPURL          = transform.TagHTTPURL
 	tagDBMS             = transform.TagDBMS
+	tagAWSRequestParams = transform.TagAWSRequestParameters
 )
 
 const (
@@ -47,12 +48,22 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 		}
 	}
 
+	// AWS SDK integrations tag their client spans with various types (e.g. "http"),
+	// so the request parameters are obfuscated wherever they are found.
+	if a.conf.Obfuscation != nil && a.conf.Obfuscation.AWS.Enabled && span.Meta[tagAWSRequestParams] != "" {
+		span.Meta[tagAWSRequestParams] = o.ObfuscateAWSRequestParameters(span.Meta[tagAWSRequestParams])
+	}
+
 	switch span.Type {
 	case "sql", "cassandra":
 		if span.Resource == "" {
 			return
 		}
-		oq, err := transform.ObfuscateSQLSpan(o, span)
+		obfuscateQuery := transform.ObfuscateSQLSpan
+		if span.Type == "cassandra" && a.conf.Obfuscation.CQL.Enabled {
+			obfuscateQuery = transform.ObfuscateCQLSpan
+		}
+		oq, err := obfuscateQuery(o, span)
 		if err != nil {
 			// we have an error, discard the SQL to avoid polluting user resources.
 			log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
@@ -93,6 +104,11 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 			return
 		}
 		span.Meta[tagMongoDBQuery] = o.ObfuscateMongoDBString(span.Meta[tagMongoDBQuery])
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		transform.ObfuscateGraphQLSpan(o, span)
 	case "elasticsearch", "opensearch":
 		if span.Meta == nil {
 			return
@@ -161,7 +177,15 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 
 	switch b.Type {
 	case "sql", "cassandra":
-		oq, err := o.ObfuscateSQLStringForDBMS(b.Resource, b.DBType)
+		var (
+			oq  *obfuscate.ObfuscatedQuery
+			err error
+		)
+		if b.Type == "cassandra" && a.conf.Obfuscation.CQL.Enabled {
+			oq, err = o.ObfuscateCQLString(b.Resource)
+		} else {
+			oq, err = o.ObfuscateSQLStringForDBMS(b.Resource, b.DBType)
+		}
 		if err != nil {
 			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
 			b.Resource = textNonParsable
@@ -170,6 +194,10 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 		}
 	case "redis", "valkey":
 		b.Resource = o.QuantizeRedisString(b.Resource)
+	case "graphql":
+		if a.conf.Obfuscation.GraphQL.Enabled {
+			b.Resource = o.ObfuscateGraphQLString(b.Resource)
+		}
 	}
 }
 
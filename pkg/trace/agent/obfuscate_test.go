@@ -296,6 +296,76 @@ func TestObfuscateConfig(t *testing.T) {
 		&config.ObfuscationConfig{},
 	))
 
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query { user(id: "42") { name } }`,
+		`query { user(id: ?) { name } }`,
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/variables", testConfig(
+		"graphql",
+		"graphql.variables",
+		`{"id": "42", "first": 10}`,
+		`{"id":"?","first":10}`,
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{
+			Enabled:    true,
+			KeepValues: []string{"first"},
+		}},
+	))
+
+	t.Run("graphql/variable", testConfig(
+		"graphql",
+		"graphql.variables.id",
+		"42",
+		"?",
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query { user(id: "42") { name } }`,
+		`query { user(id: "42") { name } }`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("aws/enabled", testConfig(
+		"dynamodb",
+		"aws.request.parameters",
+		`{"TableName": "users", "Key": {"id": {"S": "42"}}}`,
+		`{"TableName":"users","Key":{"id":{"S":"?"}}}`,
+		&config.ObfuscationConfig{AWS: obfuscate.AWSConfig{Enabled: true}},
+	))
+
+	t.Run("aws/obfuscate_paths", testConfig(
+		"aws",
+		"aws.request.parameters",
+		`{"QueueUrl": "https://sqs", "MessageBody": "secret"}`,
+		`{"QueueUrl":"https://sqs","MessageBody":"?"}`,
+		&config.ObfuscationConfig{AWS: obfuscate.AWSConfig{
+			Enabled:        true,
+			ObfuscatePaths: []string{"$.MessageBody"},
+		}},
+	))
+
+	t.Run("aws/http", testConfig(
+		"http",
+		"aws.request.parameters",
+		`{"TableName": "users", "Key": {"id": {"S": "42"}}}`,
+		`{"TableName":"users","Key":{"id":{"S":"?"}}}`,
+		&config.ObfuscationConfig{AWS: obfuscate.AWSConfig{Enabled: true}},
+	))
+
+	t.Run("aws/disabled", testConfig(
+		"dynamodb",
+		"aws.request.parameters",
+		`{"TableName": "users", "Key": {"id": {"S": "42"}}}`,
+		`{"TableName": "users", "Key": {"id": {"S": "42"}}}`,
+		&config.ObfuscationConfig{},
+	))
+
 	t.Run("creditcard", func(t *testing.T) {
 		for _, tt := range []struct {
 			k, v string
@@ -322,6 +392,58 @@ func TestObfuscateConfig(t *testing.T) {
 	})
 }
 
+func TestObfuscateCQL(t *testing.T) {
+	query := "SELECT name FROM users WHERE id = 123e4567-e89b-12d3-a456-426614174000 AND idle > 1h30m"
+	for _, tt := range []struct {
+		name string
+		cql  bool
+		out  string
+	}{
+		{"enabled", true, "SELECT name FROM users WHERE id = ? AND idle > ?"},
+		{"disabled", false, "SELECT name FROM users WHERE id = ? - e89b ? d3 - a456 ? AND idle > ? h30m"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancelFunc := context.WithCancel(context.Background())
+			defer cancelFunc()
+			cfg := config.New()
+			cfg.Endpoints[0].APIKey = "test"
+			cfg.Obfuscation.CQL.Enabled = tt.cql
+			agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector(), &statsd.NoOpClient{}, gzip.NewComponent())
+
+			span := &pb.Span{Type: "cassandra", Resource: query}
+			agnt.obfuscateSpan(span)
+			assert.Equal(t, tt.out, span.Resource)
+			assert.Equal(t, tt.out, span.Meta["sql.query"])
+
+			stats := &pb.ClientGroupedStats{Type: "cassandra", Resource: query}
+			agnt.obfuscateStatsGroup(stats)
+			assert.Equal(t, tt.out, stats.Resource)
+		})
+	}
+}
+
+func TestObfuscateGraphQLStatsGroup(t *testing.T) {
+	query := `query { user(id: "42") { name } }`
+	for _, tt := range []struct {
+		name    string
+		enabled bool
+		out     string
+	}{
+		{"enabled", true, `query { user(id: ?) { name } }`},
+		{"disabled", false, query},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			agnt, stop := agentWithDefaults()
+			defer stop()
+			agnt.conf.Obfuscation.GraphQL.Enabled = tt.enabled
+
+			stats := &pb.ClientGroupedStats{Type: "graphql", Resource: query}
+			agnt.obfuscateStatsGroup(stats)
+			assert.Equal(t, tt.out, stats.Resource)
+		})
+	}
+}
+
 func SQLSpan(query string) *pb.Span {
 	return &pb.Span{
 		Resource: query,
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"maps"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+// isUserDrop returns whether the chunk was dropped by the user, with the user drop priority or
+// by manual sampling, in which case the tail sampler must not keep it.
+func isUserDrop(pt *traceutil.ProcessedTrace) bool {
+	if priority, ok := sampler.GetSamplingPriority(pt.TraceChunk); ok && priority == sampler.PriorityUserDrop {
+		return true
+	}
+	return pt.TraceChunk.Tags[tagDecisionMaker] == manualSampling
+}
+
+// bufferTailChunk hands a chunk dropped by the samplers to the tail sampler, along with all
+// its spans in case its trace is kept by a tail sampling policy.
+func (a *Agent) bufferTailChunk(now time.Time, payload *pb.TracerPayload, pt *traceutil.ProcessedTrace, spans []*pb.Span, numEvents int) {
+	a.TailSampler.Add(now, pt.Root.TraceID, &sampler.TailChunk{
+		Chunk: &pb.TraceChunk{
+			Priority:     pt.TraceChunk.Priority,
+			Origin:       pt.TraceChunk.Origin,
+			Spans:        spans,
+			Tags:         maps.Clone(pt.TraceChunk.Tags),
+			DroppedTrace: pt.TraceChunk.DroppedTrace,
+		},
+		Dropped: pt.TraceChunk,
+		Payload: payload,
+		Events:  int64(numEvents),
+	})
+}
+
+// writeTailSampledChunks writes the chunks of a trace decided by the tail sampler: the chunks
+// with all their spans when it is kept, or as left by the samplers otherwise.
+func (a *Agent) writeTailSampledChunks(chunks []*sampler.TailChunk, keep bool) {
+	payloads := make(map[*pb.TracerPayload]*writer.SampledChunks)
+	for _, c := range chunks {
+		chunk := c.Dropped
+		if keep {
+			chunk = c.Chunk
+			a.setFirstTraceTags(traceutil.GetRoot(chunk.Spans))
+		}
+		if len(chunk.Spans) == 0 {
+			continue
+		}
+
+		sampledChunks, ok := payloads[c.Payload]
+		if !ok {
+			sampledChunks = &writer.SampledChunks{TracerPayload: tracerPayloadAttributes(c.Payload)}
+			payloads[c.Payload] = sampledChunks
+		}
+		sampledChunks.TracerPayload.Chunks = append(sampledChunks.TracerPayload.Chunks, chunk)
+		if !chunk.DroppedTrace {
+			sampledChunks.SpanCount += int64(len(chunk.Spans))
+		}
+		sampledChunks.EventCount += c.Events
+		sampledChunks.Size += chunk.Msgsize()
+	}
+	for _, sampledChunks := range payloads {
+		a.TraceWriter.WriteChunks(sampledChunks)
+	}
+}
+
+// tracerPayloadAttributes returns a tracer payload with the attributes of p, without its chunks.
+func tracerPayloadAttributes(p *pb.TracerPayload) *pb.TracerPayload {
+	return &pb.TracerPayload{
+		ContainerID:     p.ContainerID,
+		LanguageName:    p.LanguageName,
+		LanguageVersion: p.LanguageVersion,
+		TracerVersion:   p.TracerVersion,
+		RuntimeID:       p.RuntimeID,
+		Tags:            p.Tags,
+		Env:             p.Env,
+		Hostname:        p.Hostname,
+		AppVersion:      p.AppVersion,
+	}
+}
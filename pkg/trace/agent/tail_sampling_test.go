@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+func TestTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.Policies = []*config.TailSamplingPolicy{{Name: "slow", Type: "latency", ThresholdMs: 500}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	require.NotNil(t, agnt.TailSampler)
+	agnt.TailSampler.Start()
+
+	now := time.Now()
+	process := func(traceID uint64, duration time.Duration, priority sampler.SamplingPriority) {
+		chunk := testutil.TraceChunkWithSpanAndPriority(&pb.Span{
+			Service:  "web",
+			Name:     "http.request",
+			Resource: "GET /",
+			TraceID:  traceID,
+			SpanID:   traceID,
+			Start:    now.Add(-duration).UnixNano(),
+			Duration: duration.Nanoseconds(),
+		}, int32(priority))
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+			Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+		})
+	}
+	process(1, time.Second, sampler.PriorityAutoDrop)
+	process(2, 10*time.Millisecond, sampler.PriorityAutoDrop)
+	process(3, 10*time.Millisecond, sampler.PriorityUserKeep)
+
+	// only the chunk kept by the priority sampler is written right away
+	payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+	require.Len(t, payloads, 1)
+	require.Len(t, payloads[0].TracerPayload.Chunks, 1)
+	assert.Equal(t, uint64(3), payloads[0].TracerPayload.Chunks[0].Spans[0].TraceID)
+
+	// the buffered traces are decided when stopping
+	agnt.TailSampler.Stop()
+	payloads = agnt.TraceWriter.(*mockTraceWriter).payloads
+	require.Len(t, payloads, 2)
+	require.Len(t, payloads[1].TracerPayload.Chunks, 1)
+	chunk := payloads[1].TracerPayload.Chunks[0]
+	assert.Equal(t, uint64(1), chunk.Spans[0].TraceID)
+	assert.Equal(t, "slow", chunk.Tags[sampler.KeyTailSamplingPolicy])
+	assert.False(t, chunk.DroppedTrace)
+	assert.Equal(t, int64(1), payloads[1].SpanCount)
+}
+
+func TestTailSamplingUserDrop(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSampling.Enabled = true
+	cfg.TailSampling.Policies = []*config.TailSamplingPolicy{{Name: "errors", Type: "error"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	agnt.TailSampler.Start()
+
+	chunk := testutil.TraceChunkWithSpanAndPriority(&pb.Span{
+		Service:  "web",
+		Name:     "http.request",
+		Resource: "GET /",
+		TraceID:  1,
+		SpanID:   1,
+		Start:    time.Now().UnixNano(),
+		Duration: time.Millisecond.Nanoseconds(),
+		Error:    1,
+	}, int32(sampler.PriorityUserDrop))
+	agnt.Process(&api.Payload{
+		TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+		Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+	})
+
+	// the error trace dropped by the user isn't buffered, and stays dropped
+	agnt.TailSampler.Stop()
+	assert.Empty(t, agnt.TraceWriter.(*mockTraceWriter).payloads)
+}
+
+func TestIsUserDrop(t *testing.T) {
+	chunk := func(priority sampler.SamplingPriority, tags map[string]string) *traceutil.ProcessedTrace {
+		return &traceutil.ProcessedTrace{TraceChunk: &pb.TraceChunk{Priority: int32(priority), Tags: tags}}
+	}
+	assert.True(t, isUserDrop(chunk(sampler.PriorityUserDrop, nil)))
+	assert.True(t, isUserDrop(chunk(sampler.PriorityAutoDrop, map[string]string{tagDecisionMaker: manualSampling})))
+	assert.False(t, isUserDrop(chunk(sampler.PriorityAutoDrop, map[string]string{tagDecisionMaker: "-1"})))
+	assert.False(t, isUserDrop(chunk(sampler.PriorityNone, nil)))
+}
@@ -31,6 +31,7 @@ import (
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/header"
+	"github.com/DataDog/datadog-agent/pkg/trace/capture"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/log"
@@ -119,6 +120,10 @@ type HTTPReceiver struct {
 	timing   timing.Reporter
 	info     *watchdog.CurrentInfo
 	Handlers map[string]http.Handler
+
+	// Capture records the payloads received by the endpoints which are captured, if non-nil.
+	// It must be set before the handlers are built.
+	Capture *capture.Writer
 }
 
 // NewHTTPReceiver returns a pointer to a new HTTPReceiver
@@ -202,7 +207,11 @@ func (r *HTTPReceiver) buildMux() *http.ServeMux {
 		if e.TimeoutOverride != nil {
 			timeout = e.TimeoutOverride(r.conf)
 		}
-		h := replyWithVersion(hash, r.conf.AgentVersion, timeoutMiddleware(timeout, e.Handler(r)))
+		h := e.Handler(r)
+		if e.Capture && r.Capture != nil {
+			h = r.captureHandler(e.Pattern, h)
+		}
+		h = replyWithVersion(hash, r.conf.AgentVersion, timeoutMiddleware(timeout, h))
 		r.Handlers[e.Pattern] = h
 		mux.Handle(e.Pattern, h)
 	}
@@ -212,6 +221,47 @@ func (r *HTTPReceiver) buildMux() *http.ServeMux {
 	return mux
 }
 
+// captureHandler returns an http.Handler which handles the requests to the endpoint pattern
+// with h, recording the payloads it accepts, as reported by the status of its responses,
+// with r.Capture.
+func (r *HTTPReceiver) captureHandler(pattern string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		now := time.Now()
+		// the body is copied as h reads it, h limiting its size
+		var body bytes.Buffer
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(req.Body, &body), req.Body}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(sw, req)
+		if sw.status >= 200 && sw.status < 300 {
+			r.Capture.Capture(now, pattern, req.Header, body.Bytes())
+		}
+	})
+}
+
+// statusWriter is an http.ResponseWriter which records the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(b)
+}
+
 // replyWithVersion returns an http.Handler which calls h with an addition of some
 // HTTP headers containing version and state information.
 func replyWithVersion(hash string, version string, h http.Handler) http.Handler {
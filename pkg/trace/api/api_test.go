@@ -23,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/header"
+	"github.com/DataDog/datadog-agent/pkg/trace/capture"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
@@ -1261,3 +1262,50 @@ func msgpTraces(t *testing.T, traces pb.Traces) []byte {
 	}
 	return bts
 }
+
+func TestReceiverCapture(t *testing.T) {
+	dir := t.TempDir()
+	w, err := capture.NewWriter(dir, 1<<20, 1)
+	require.NoError(t, err)
+	rcv := newTestReceiverFromConfig(newTestReceiverConfig())
+	rcv.Capture = w
+	mux := rcv.buildMux()
+
+	bts, err := testutil.GetTestTraces(2, 2, true).MarshalMsg(nil)
+	require.NoError(t, err)
+	for _, path := range []string{"/v0.4/traces", "/v0.3/traces"} {
+		req := httptest.NewRequest("POST", path, bytes.NewReader(bts))
+		req.Header.Set("Content-Type", "application/msgpack")
+		req.Header.Set(header.Lang, "go")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		// the payload is still handled
+		p := <-rcv.out
+		assert.Len(t, p.TracerPayload.Chunks, 2)
+	}
+	// the payloads rejected by the handler aren't captured
+	req := httptest.NewRequest("POST", "/v0.4/traces", bytes.NewReader([]byte("invalid")))
+	req.Header.Set("Content-Type", "application/msgpack")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	w.Stop()
+
+	files, err := capture.Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	f, err := os.Open(files[0])
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := capture.NewReader(f)
+	require.NoError(t, err)
+	record, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "/v0.4/traces", record.Endpoint)
+	assert.Equal(t, "go", record.Header.Get(header.Lang))
+	assert.Equal(t, bts, record.Body)
+	// the v0.3 endpoint isn't captured
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
@@ -31,6 +31,10 @@ type Endpoint struct {
 	// IsEnabled specifies a function which reports whether this endpoint should be enabled
 	// based on the given config conf.
 	IsEnabled func(conf *config.AgentConfig) bool
+
+	// Capture reports whether the payloads received by this endpoint are recorded
+	// when the payload capture is enabled.
+	Capture bool
 }
 
 // AttachEndpoint attaches an additional endpoint to the trace-agent. It is not thread-safe
@@ -81,6 +85,7 @@ var endpoints = []Endpoint{
 	{
 		Pattern: "/v0.4/traces",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.handleWithVersion(v04, r.handleTraces) },
+		Capture: true,
 	},
 	{
 		Pattern: "/v0.4/services",
@@ -89,10 +94,12 @@ var endpoints = []Endpoint{
 	{
 		Pattern: "/v0.5/traces",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.handleWithVersion(v05, r.handleTraces) },
+		Capture: true,
 	},
 	{
 		Pattern: "/v0.7/traces",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.handleWithVersion(V07, r.handleTraces) },
+		Capture: true,
 	},
 	{
 		Pattern: "/profiling/v1/input",
@@ -108,6 +115,7 @@ var endpoints = []Endpoint{
 	{
 		Pattern: "/v0.6/stats",
 		Handler: func(r *HTTPReceiver) http.Handler { return http.HandlerFunc(r.handleStats) },
+		Capture: true,
 	},
 	{
 		Pattern: "/v0.1/pipeline_stats",
@@ -21,6 +21,7 @@ import (
 
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/header"
+	"github.com/DataDog/datadog-agent/pkg/trace/capture"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
 	"github.com/DataDog/datadog-agent/pkg/trace/log"
@@ -59,6 +60,9 @@ type OTLPReceiver struct {
 	statsd         statsd.ClientInterface
 	timing         timing.Reporter
 	ignoreResNames map[string]struct{}
+
+	// Capture records the export requests received, if non-nil.
+	Capture *capture.Writer
 }
 
 // NewOTLPReceiver returns a new OTLPReceiver which sends any incoming traces down the out channel.
@@ -144,6 +148,11 @@ func (o *OTLPReceiver) Stop() {
 func (o *OTLPReceiver) Export(ctx context.Context, in ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
 	defer o.timing.Since("datadog.trace_agent.otlp.process_grpc_request_ms", time.Now())
 	md, _ := metadata.FromIncomingContext(ctx)
+	if o.Capture != nil {
+		if body, err := in.MarshalProto(); err == nil {
+			o.Capture.Capture(time.Now(), capture.EndpointOTLP, http.Header(md), body)
+		}
+	}
 	_ = o.statsd.Count("datadog.trace_agent.otlp.payload", 1, tagsFromHeaders(http.Header(md)), 1)
 	o.processRequest(ctx, http.Header(md), in)
 	return ptraceotlp.NewExportResponse(), nil
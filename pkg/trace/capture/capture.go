@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package capture records the payloads received by the trace-agent to rotating capture
+// files, and reads them back so that they can be replayed.
+//
+// A capture file starts with a magic header, followed by the records, each of them being
+// prefixed by its size as a big endian uint32.
+package capture
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EndpointOTLP is the endpoint of the records of the OTLP/gRPC export requests.
+const EndpointOTLP = "otlp"
+
+// magic is the header of the capture files, followed by the version of the format.
+var magic = []byte("DDTRACECAPTURE\x01")
+
+// maxRecordSize is the maximum size of a record, above which a capture file is
+// considered corrupted.
+const maxRecordSize = 1 << 30
+
+// sensitiveHeaders holds the lowercased headers which are never captured.
+var sensitiveHeaders = map[string]bool{
+	"dd-api-key":          true,
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+}
+
+// errMalformedRecord is returned when a record can't be decoded.
+var errMalformedRecord = errors.New("capture: malformed record")
+
+// Record is a payload received by the trace-agent.
+type Record struct {
+	// Time is the time the payload was received.
+	Time time.Time
+	// Endpoint is the pattern of the HTTP endpoint which received the payload, e.g.
+	// "/v0.4/traces", or EndpointOTLP.
+	Endpoint string
+	// Header holds the HTTP headers, or gRPC metadata, of the request.
+	Header http.Header
+	// Body is the payload, as received for the HTTP endpoints, or the OTLP export
+	// request marshalled to protobuf.
+	Body []byte
+}
+
+// appendRecord appends the encoded record r, without its size, to b.
+func appendRecord(b []byte, r *Record) []byte {
+	b = binary.AppendVarint(b, r.Time.UnixNano())
+	b = appendString(b, r.Endpoint)
+	var n int
+	for k := range r.Header {
+		if !sensitiveHeaders[strings.ToLower(k)] {
+			n++
+		}
+	}
+	b = binary.AppendUvarint(b, uint64(n))
+	for k, vs := range r.Header {
+		if sensitiveHeaders[strings.ToLower(k)] {
+			continue
+		}
+		b = appendString(b, k)
+		b = binary.AppendUvarint(b, uint64(len(vs)))
+		for _, v := range vs {
+			b = appendString(b, v)
+		}
+	}
+	return append(b, r.Body...)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// decodeRecord decodes the record encoded in b by appendRecord. The body of the
+// returned record references b.
+func decodeRecord(b []byte) (*Record, error) {
+	d := decoder{b: b}
+	r := &Record{
+		Time:     time.Unix(0, d.varint()),
+		Endpoint: d.string(),
+	}
+	if n := d.uvarint(); n > 0 && d.err == nil {
+		r.Header = make(http.Header, min(n, uint64(len(d.b))))
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			k := d.string()
+			nv := d.uvarint()
+			for j := uint64(0); j < nv && d.err == nil; j++ {
+				r.Header[k] = append(r.Header[k], d.string())
+			}
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	r.Body = d.b
+	return r, nil
+}
+
+// decoder decodes the fields of a record, keeping the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errMalformedRecord
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errMalformedRecord
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	n := d.uvarint()
+	if d.err != nil {
+		return ""
+	}
+	if n > uint64(len(d.b)) {
+		d.err = errMalformedRecord
+		return ""
+	}
+	s := string(d.b[:n])
+	d.b = d.b[n:]
+	return s
+}
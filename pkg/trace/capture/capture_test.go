@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package capture
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+func readFile(t *testing.T, path string) []*Record {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	r, err := NewReader(f)
+	require.NoError(t, err)
+	var records []*Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return records
+		}
+		require.NoError(t, err)
+		records = append(records, rec)
+	}
+}
+
+func TestWriterReader(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(dir, 1<<20, 2)
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 123456789)
+	w.Capture(now, "/v0.4/traces", http.Header{
+		"Content-Type":          {"application/msgpack"},
+		"X-Datadog-Trace-Count": {"3"},
+		"Datadog-Meta-Lang":     {"go"},
+		"Dd-Api-Key":            {"secret"},
+	}, []byte("traces"))
+	w.Capture(now.Add(time.Second), EndpointOTLP, http.Header{"authorization": {"Bearer secret"}}, []byte{0, 1, 2})
+	w.Capture(now.Add(2*time.Second), "/v0.6/stats", nil, nil)
+	w.Stop()
+	assert.ErrorIs(t, w.Write(&Record{}), os.ErrClosed)
+
+	files, err := Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	records := readFile(t, files[0])
+	require.Len(t, records, 3)
+
+	assert.True(t, now.Equal(records[0].Time))
+	assert.Equal(t, "/v0.4/traces", records[0].Endpoint)
+	assert.Equal(t, http.Header{
+		"Content-Type":          {"application/msgpack"},
+		"X-Datadog-Trace-Count": {"3"},
+		"Datadog-Meta-Lang":     {"go"},
+	}, records[0].Header)
+	assert.Equal(t, []byte("traces"), records[0].Body)
+
+	assert.Equal(t, EndpointOTLP, records[1].Endpoint)
+	assert.Empty(t, records[1].Header)
+	assert.Equal(t, []byte{0, 1, 2}, records[1].Body)
+
+	assert.Equal(t, "/v0.6/stats", records[2].Endpoint)
+	assert.Empty(t, records[2].Body)
+}
+
+func TestWriterRotation(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(dir, 100, 2)
+	require.NoError(t, err)
+	body := bytes.Repeat([]byte("a"), 100)
+	for i := 0; i < 5; i++ {
+		// each record fills a capture file
+		require.NoError(t, w.Write(&Record{Time: time.Unix(int64(i), 0), Endpoint: "/v0.4/traces", Body: body}))
+	}
+	w.Stop()
+
+	files, err := Files(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	for i, path := range files {
+		records := readFile(t, path)
+		require.Len(t, records, 1)
+		assert.Equal(t, int64(3+i), records[0].Time.Unix())
+	}
+}
+
+func TestWriterQueueFull(t *testing.T) {
+	w := &Writer{queue: make(chan *Record, 1), easylog: log.NewThrottled(5, 10*time.Second)}
+	w.Capture(time.Unix(1, 0), "/v0.4/traces", nil, []byte("first"))
+	// the queue is full, and not drained
+	w.Capture(time.Unix(2, 0), "/v0.4/traces", nil, []byte("second"))
+	require.Len(t, w.queue, 1)
+	assert.Equal(t, []byte("first"), (<-w.queue).Body)
+}
+
+func TestReaderErrors(t *testing.T) {
+	_, err := NewReader(bytes.NewReader([]byte("not a capture")))
+	assert.Error(t, err)
+
+	var buf bytes.Buffer
+	buf.Write(magic)
+	buf.Write([]byte{0, 0, 0, 10, 1, 2})
+	r, err := NewReader(&buf)
+	require.NoError(t, err)
+	_, err = r.Next()
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	buf.Reset()
+	buf.Write(magic)
+	buf.Write([]byte{0, 0, 0, 2, 0, 10})
+	r, err = NewReader(&buf)
+	require.NoError(t, err)
+	_, err = r.Next()
+	assert.ErrorIs(t, err, errMalformedRecord)
+}
@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Reader reads the records of a capture file.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader reading the records of the capture file read from r.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, header); err != nil || !bytes.Equal(header, magic) {
+		return nil, errors.New("capture: not a capture file")
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record, or io.EOF once all of them were read. A record cut
+// short, e.g. by a crash of the trace-agent, returns io.ErrUnexpectedEOF.
+func (r *Reader) Next() (*Record, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r.r, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxRecordSize {
+		return nil, fmt.Errorf("capture: record of %d bytes is too large", n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return decodeRecord(b)
+}
@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// filePrefix and fileExt name the capture files, their names holding the time they
+// were created at so that they are sorted chronologically.
+const (
+	filePrefix = "trace-capture-"
+	fileExt    = ".capture"
+)
+
+// maxQueued is the maximum number of captured records waiting to be written, above
+// which the new ones are dropped.
+const maxQueued = 64
+
+// Writer writes the records to the capture files of a directory, starting a new file
+// when the current one exceeds the maximum size, and removing the oldest ones.
+type Writer struct {
+	dir         string
+	maxFileSize int64
+	maxFiles    int
+
+	queue chan *Record  // the captured records, waiting to be written
+	exit  chan struct{} // closed to stop the writing routine
+	done  chan struct{} // closed once the writing routine returned
+
+	mu   sync.Mutex // guards the fields below
+	f    *os.File   // the current capture file, nil once stopped
+	size int64      // the size of the current capture file
+	buf  []byte     // reused to encode the records
+
+	easylog *log.ThrottledLogger
+}
+
+// NewWriter returns a new Writer writing the records to capture files of at most
+// maxFileSize bytes in dir, keeping at most maxFiles of them.
+func NewWriter(dir string, maxFileSize int64, maxFiles int) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	w := &Writer{
+		dir:         dir,
+		maxFileSize: maxFileSize,
+		maxFiles:    maxFiles,
+		queue:       make(chan *Record, maxQueued),
+		exit:        make(chan struct{}),
+		done:        make(chan struct{}),
+		easylog:     log.NewThrottled(5, 10*time.Second), // no more than 5 messages every 10 seconds
+	}
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	go w.run()
+	log.Infof("Capturing the received payloads to %s", dir)
+	return w, nil
+}
+
+// Capture queues the payload body received at the given time by endpoint to be written
+// to the current capture file, dropping it if the queue is full. The body must not be
+// modified afterwards.
+func (w *Writer) Capture(now time.Time, endpoint string, header http.Header, body []byte) {
+	select {
+	case w.queue <- &Record{Time: now, Endpoint: endpoint, Header: header, Body: body}:
+	default:
+		w.easylog.Warn("Capture queue is full, dropping the payload received by %s", endpoint)
+	}
+}
+
+// run writes the queued records until the writer is stopped, then writes the remaining
+// ones and closes the current capture file.
+func (w *Writer) run() {
+	defer close(w.done)
+	for {
+		select {
+		case r := <-w.queue:
+			w.write(r)
+		case <-w.exit:
+			for {
+				select {
+				case r := <-w.queue:
+					w.write(r)
+				default:
+					w.close()
+					return
+				}
+			}
+		}
+	}
+}
+
+// write writes the record r, logging the errors.
+func (w *Writer) write(r *Record) {
+	if err := w.Write(r); err != nil {
+		w.easylog.Warn("Failed to capture the payload received by %s: %v", r.Endpoint, err)
+	}
+}
+
+// Write synchronously writes the record r to the current capture file.
+func (w *Writer) Write(r *Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	if w.size > int64(len(magic)) && w.size >= w.maxFileSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	w.buf = append(w.buf[:0], 0, 0, 0, 0)
+	w.buf = appendRecord(w.buf, r)
+	if len(w.buf)-4 > maxRecordSize {
+		return fmt.Errorf("record of %d bytes is too large", len(w.buf)-4)
+	}
+	binary.BigEndian.PutUint32(w.buf, uint32(len(w.buf)-4))
+	n, err := w.f.Write(w.buf)
+	w.size += int64(n)
+	return err
+}
+
+// Stop writes the queued records and closes the current capture file. The records
+// captured afterwards are not written.
+func (w *Writer) Stop() {
+	close(w.exit)
+	<-w.done
+}
+
+// close closes the current capture file.
+func (w *Writer) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return
+	}
+	if err := w.f.Close(); err != nil {
+		log.Errorf("Error closing the capture file: %v", err)
+	}
+	w.f = nil
+}
+
+// rotate closes the current capture file, creates a new one and removes the oldest
+// ones. w must be locked.
+func (w *Writer) rotate() error {
+	if w.f != nil {
+		if err := w.f.Close(); err != nil {
+			log.Debugf("Error closing the capture file: %v", err)
+		}
+		w.f = nil
+	}
+	name := filePrefix + time.Now().UTC().Format("20060102T150405.000000000Z") + fileExt
+	f, err := os.OpenFile(filepath.Join(w.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(magic); err != nil {
+		f.Close()
+		return err
+	}
+	w.f, w.size = f, int64(len(magic))
+
+	files, err := Files(w.dir)
+	if err != nil {
+		return err
+	}
+	for len(files) > w.maxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			log.Debugf("Error removing the capture file %s: %v", files[0], err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// Files returns the paths of the capture files of dir, from the oldest to the newest.
+func Files(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, filePrefix+"*"+fileExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
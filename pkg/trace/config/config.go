@@ -128,6 +128,18 @@ type ObfuscationConfig struct {
 	// for spans of type "memcached".
 	Memcached obfuscate.MemcachedConfig `mapstructure:"memcached"`
 
+	// GraphQL holds the configuration for obfuscating the resource and the
+	// "graphql.source" and "graphql.variables" tags for spans of type "graphql".
+	GraphQL obfuscate.GraphQLConfig `mapstructure:"graphql"`
+
+	// CQL holds the configuration for obfuscating the resource of the spans of
+	// type "cassandra" as CQL rather than SQL queries.
+	CQL obfuscate.CQLConfig `mapstructure:"cassandra"`
+
+	// AWS holds the configuration for obfuscating the "aws.request.parameters"
+	// tag for spans of type "aws" and "dynamodb".
+	AWS obfuscate.AWSConfig `mapstructure:"aws"`
+
 	// CreditCards holds the configuration for obfuscating credit cards.
 	CreditCards obfuscate.CreditCardsConfig `mapstructure:"credit_cards"`
 
@@ -168,6 +180,9 @@ func (o *ObfuscationConfig) Export(conf *AgentConfig) obfuscate.Config {
 		Redis:                o.Redis,
 		Valkey:               o.Valkey,
 		Memcached:            o.Memcached,
+		GraphQL:              o.GraphQL,
+		CQL:                  o.CQL,
+		AWS:                  o.AWS,
 		CreditCard:           o.CreditCards,
 		Logger:               new(debugLogger),
 		Cache:                o.Cache,
@@ -185,6 +200,75 @@ type Enablable struct {
 	Enabled bool `mapstructure:"enabled"`
 }
 
+// TailSamplingConfig holds the configuration of the tail sampling, which
+// buffers the chunks dropped by the samplers to evaluate policies over whole
+// traces.
+type TailSamplingConfig struct {
+	Enabled bool
+	// DecisionWait is the time the chunks of a trace are buffered, from the
+	// arrival of its first chunk, before the policies are evaluated.
+	DecisionWait time.Duration
+	// MaxTraces and MaxBytes bound the buffer, its oldest traces being
+	// evaluated early when they are exceeded.
+	MaxTraces int
+	MaxBytes  int
+	// Policies keep the traces matching any of them, the first matching
+	// policy being reported.
+	Policies []*TailSamplingPolicy
+}
+
+// OTLPTracesOutputConfig holds the configuration of the writer mirroring the
+// sampled traces, converted to OTLP spans, to an OTLP endpoint.
+type OTLPTracesOutputConfig struct {
+	Enabled bool
+	// Endpoint is the host:port of the OTLP/gRPC endpoint, or the URL of the
+	// OTLP/HTTP one, "/v1/traces" being used when it has no path.
+	Endpoint string
+	// Protocol is either "grpc" or "http".
+	Protocol string
+	// Headers are added to the export requests, e.g. for authentication.
+	Headers map[string]string
+	// Insecure disables TLS for the OTLP/gRPC endpoints.
+	Insecure bool
+}
+
+// PayloadCaptureConfig holds the configuration of the capture of the payloads received
+// by the trace-agent, which can be replayed with the "trace-agent replay" command.
+type PayloadCaptureConfig struct {
+	Enabled bool
+	// Dir is the directory the capture files are written to.
+	Dir string
+	// MaxFileSize is the size in bytes after which a new capture file is started.
+	MaxFileSize int64
+	// MaxFiles is the number of capture files kept, the oldest ones being removed.
+	MaxFiles int
+}
+
+// TailSamplingPolicy is a policy of the tail sampling.
+type TailSamplingPolicy struct {
+	// Name identifies the policy in the kept traces and in the telemetry.
+	Name string `mapstructure:"name"`
+
+	// Type is one of "latency", "error", "attribute" and "rate".
+	Type string `mapstructure:"type"`
+
+	// Service restricts the policy to the traces whose root span has this
+	// service, when not empty.
+	Service string `mapstructure:"service"`
+
+	// ThresholdMs is the minimal duration of the traces kept by the "latency"
+	// policies, in milliseconds.
+	ThresholdMs float64 `mapstructure:"threshold_ms"`
+
+	// Key and Values select the traces kept by the "attribute" policies,
+	// those with a span having the Key tag, with one of the Values if any.
+	Key    string   `mapstructure:"key"`
+	Values []string `mapstructure:"values"`
+
+	// Rate is the rate of traces kept by the "rate" policies, between 0 and 1.
+	Rate float64 `mapstructure:"rate"`
+}
+
 // TelemetryConfig holds Instrumentation telemetry Endpoints information
 type TelemetryConfig struct {
 	Enabled   bool `mapstructure:"enabled"`
@@ -209,6 +293,50 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// SpanRule is a rule of the span_rules setting: its actions are applied to the
+// spans matching all the conditions of its Match.
+type SpanRule struct {
+	// Name identifies the rule in the hit counters.
+	Name string `mapstructure:"name" json:"name" yaml:"name"`
+
+	// Match holds the conditions of the rule.
+	Match SpanRuleMatch `mapstructure:"match" json:"match" yaml:"match"`
+
+	// Actions are applied in order to the matching spans.
+	Actions []*SpanRuleAction `mapstructure:"actions" json:"actions" yaml:"actions"`
+}
+
+// SpanRuleMatch holds the conditions of a span rule. The empty ones match all
+// the spans.
+type SpanRuleMatch struct {
+	// Service, Name and Resource are glob patterns, where '*' matches any
+	// sequence of characters and '?' any single character.
+	Service  string `mapstructure:"service" json:"service" yaml:"service"`
+	Name     string `mapstructure:"name" json:"name" yaml:"name"`
+	Resource string `mapstructure:"resource" json:"resource" yaml:"resource"`
+
+	// Meta maps tags to glob patterns their value must match.
+	Meta map[string]string `mapstructure:"meta" json:"meta" yaml:"meta"`
+
+	// Metrics maps numeric tags to comparisons their value must satisfy, such
+	// as ">= 500" or "1".
+	Metrics map[string]string `mapstructure:"metrics" json:"metrics" yaml:"metrics"`
+}
+
+// SpanRuleAction is an action of a span rule.
+type SpanRuleAction struct {
+	// Type is one of "drop_span", "drop_trace", "delete_tag", "set_tag" and
+	// "hash_tag".
+	Type string `mapstructure:"type" json:"type" yaml:"type"`
+
+	// Key is the tag set by "set_tag", or the glob pattern of the tags
+	// deleted by "delete_tag" and hashed by "hash_tag".
+	Key string `mapstructure:"key" json:"key" yaml:"key"`
+
+	// Value is the value of the tag set by "set_tag".
+	Value string `mapstructure:"value" json:"value" yaml:"value"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -355,9 +483,19 @@ type AgentConfig struct {
 	ProbabilisticSamplerHashSeed           uint32
 	ProbabilisticSamplerSamplingPercentage float32
 
+	// Tail Sampling configuration
+	TailSampling TailSamplingConfig
+
 	// Error Tracking Standalone
 	ErrorTrackingStandalone bool
 
+	// OTLPTracesOutput configures the export of the sampled traces to an OTLP endpoint,
+	// in addition to the Datadog intake.
+	OTLPTracesOutput OTLPTracesOutputConfig
+
+	// PayloadCapture configures the recording of the received payloads to capture files.
+	PayloadCapture PayloadCaptureConfig
+
 	// Receiver
 	ReceiverEnabled bool // specifies whether Receiver listeners are enabled. Unless OTLPReceiver is used, this should always be true.
 	ReceiverHost    string
@@ -419,6 +557,10 @@ type AgentConfig struct {
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// SpanRules are applied to the spans of the received traces to drop them or
+	// change their tags, along with the rules received through remote configuration.
+	SpanRules []*SpanRule
+
 	// GlobalTags list metadata that will be added to all spans
 	GlobalTags map[string]string
 
@@ -559,8 +701,22 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSampling: TailSamplingConfig{
+			DecisionWait: 10 * time.Second,
+			MaxTraces:    50_000,
+			MaxBytes:     64 * 1024 * 1024, // 64MB
+		},
+
 		ErrorTrackingStandalone: false,
 
+		OTLPTracesOutput: OTLPTracesOutputConfig{
+			Protocol: "grpc",
+		},
+		PayloadCapture: PayloadCaptureConfig{
+			MaxFileSize: 64 * 1024 * 1024, // 64MB
+			MaxFiles:    5,
+		},
+
 		ReceiverEnabled:        true,
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
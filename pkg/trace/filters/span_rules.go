@@ -0,0 +1,322 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/atomic"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+const (
+	spanRuleDropSpan  = "drop_span"
+	spanRuleDropTrace = "drop_trace"
+	spanRuleDeleteTag = "delete_tag"
+	spanRuleSetTag    = "set_tag"
+	spanRuleHashTag   = "hash_tag"
+)
+
+// SpanRules is a filter which applies the span_rules settings, followed by the
+// span rules received through remote configuration, to the spans of a trace:
+// it drops them, or the whole trace, or changes their tags.
+type SpanRules struct {
+	static []*spanRule
+	rules  *atomic.Pointer[[]*spanRule] // static followed by the remote ones
+
+	mu   sync.Mutex
+	hits map[string]*atomic.Int64 // by rule name, kept across the updates
+}
+
+type spanRule struct {
+	name     string
+	service  *regexp.Regexp
+	spanName *regexp.Regexp
+	resource *regexp.Regexp
+	meta     map[string]*regexp.Regexp
+	metrics  map[string]metricCondition
+	actions  []spanRuleAction
+	hits     *atomic.Int64
+}
+
+type spanRuleAction struct {
+	typ   string
+	key   string
+	keyRe *regexp.Regexp
+	value string
+}
+
+type metricCondition struct {
+	op    string
+	value float64
+}
+
+// NewSpanRules returns a new SpanRules applying the given rules, or an error if
+// any of them is invalid.
+func NewSpanRules(rules []*config.SpanRule) (*SpanRules, error) {
+	r := &SpanRules{
+		rules: atomic.NewPointer(&[]*spanRule{}),
+		hits:  make(map[string]*atomic.Int64),
+	}
+	static, err := r.compile(rules)
+	if err != nil {
+		return nil, err
+	}
+	r.static = static
+	r.rules.Store(&static)
+	return r, nil
+}
+
+// UpdateRemoteRules replaces the rules received through remote configuration,
+// which are applied after the ones of the configuration. The rules in place
+// are kept if any of the new ones is invalid.
+func (r *SpanRules) UpdateRemoteRules(rules []*config.SpanRule) error {
+	remote, err := r.compile(rules)
+	if err != nil {
+		return err
+	}
+	all := make([]*spanRule, 0, len(r.static)+len(remote))
+	all = append(all, r.static...)
+	all = append(all, remote...)
+	r.rules.Store(&all)
+	return nil
+}
+
+// Hits returns the number of spans matched by each rule, by rule name.
+func (r *SpanRules) Hits() map[string]int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	hits := make(map[string]int64, len(r.hits))
+	for name, n := range r.hits {
+		hits[name] = n.Load()
+	}
+	return hits
+}
+
+// Apply applies the rules to the spans of the chunk, removing the dropped
+// spans from it. It returns false if the whole trace must be dropped.
+func (r *SpanRules) Apply(chunk *pb.TraceChunk) bool {
+	rules := *r.rules.Load()
+	if len(rules) == 0 {
+		return true
+	}
+	n := 0
+spans:
+	for _, span := range chunk.Spans {
+		for _, rule := range rules {
+			if !rule.match(span) {
+				continue
+			}
+			rule.hits.Inc()
+			for _, action := range rule.actions {
+				switch action.typ {
+				case spanRuleDropTrace:
+					return false
+				case spanRuleDropSpan:
+					continue spans
+				default:
+					action.apply(span)
+				}
+			}
+		}
+		chunk.Spans[n] = span
+		n++
+	}
+	// set everything at the back of the array to nil to avoid memory leaking
+	for i := n; i < len(chunk.Spans); i++ {
+		chunk.Spans[i] = nil
+	}
+	chunk.Spans = chunk.Spans[:n]
+	return true
+}
+
+func (rule *spanRule) match(span *pb.Span) bool {
+	if rule.service != nil && !rule.service.MatchString(span.Service) {
+		return false
+	}
+	if rule.spanName != nil && !rule.spanName.MatchString(span.Name) {
+		return false
+	}
+	if rule.resource != nil && !rule.resource.MatchString(span.Resource) {
+		return false
+	}
+	for k, re := range rule.meta {
+		v, ok := span.Meta[k]
+		if !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	for k, cond := range rule.metrics {
+		v, ok := span.Metrics[k]
+		if !ok || !cond.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a spanRuleAction) apply(span *pb.Span) {
+	switch a.typ {
+	case spanRuleSetTag:
+		if span.Meta == nil {
+			span.Meta = make(map[string]string)
+		}
+		span.Meta[a.key] = a.value
+	case spanRuleDeleteTag:
+		for k := range span.Meta {
+			if a.keyRe.MatchString(k) {
+				delete(span.Meta, k)
+			}
+		}
+		for k := range span.Metrics {
+			if a.keyRe.MatchString(k) {
+				delete(span.Metrics, k)
+			}
+		}
+	case spanRuleHashTag:
+		for k, v := range span.Meta {
+			if a.keyRe.MatchString(k) {
+				sum := sha256.Sum256([]byte(v))
+				span.Meta[k] = hex.EncodeToString(sum[:])
+			}
+		}
+	}
+}
+
+func (c metricCondition) match(v float64) bool {
+	switch c.op {
+	case "!=":
+		return v != c.value
+	case "<":
+		return v < c.value
+	case "<=":
+		return v <= c.value
+	case ">":
+		return v > c.value
+	case ">=":
+		return v >= c.value
+	default:
+		return v == c.value
+	}
+}
+
+func (r *SpanRules) compile(rules []*config.SpanRule) ([]*spanRule, error) {
+	compiled := make([]*spanRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compileSpanRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range compiled {
+		if _, ok := r.hits[c.name]; !ok {
+			r.hits[c.name] = atomic.NewInt64(0)
+		}
+		c.hits = r.hits[c.name]
+	}
+	return compiled, nil
+}
+
+func compileSpanRule(rule *config.SpanRule) (*spanRule, error) {
+	if rule.Name == "" {
+		return nil, errors.New(`all rules must have a "name"`)
+	}
+	if len(rule.Actions) == 0 {
+		return nil, fmt.Errorf("rule %q: no actions", rule.Name)
+	}
+	c := &spanRule{
+		name:     rule.Name,
+		service:  compileGlob(rule.Match.Service),
+		spanName: compileGlob(rule.Match.Name),
+		resource: compileGlob(rule.Match.Resource),
+	}
+	if len(rule.Match.Meta) > 0 {
+		c.meta = make(map[string]*regexp.Regexp, len(rule.Match.Meta))
+		for k, pattern := range rule.Match.Meta {
+			c.meta[k] = compileGlob(pattern)
+			if c.meta[k] == nil {
+				// an empty pattern only matches the spans having the tag
+				c.meta[k] = compileGlob("*")
+			}
+		}
+	}
+	if len(rule.Match.Metrics) > 0 {
+		c.metrics = make(map[string]metricCondition, len(rule.Match.Metrics))
+		for k, cond := range rule.Match.Metrics {
+			mc, err := parseMetricCondition(cond)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: metric %q: %v", rule.Name, k, err)
+			}
+			c.metrics[k] = mc
+		}
+	}
+	for _, action := range rule.Actions {
+		a := spanRuleAction{typ: action.Type, key: action.Key, value: action.Value}
+		switch action.Type {
+		case spanRuleDropSpan, spanRuleDropTrace:
+		case spanRuleSetTag:
+			if action.Key == "" {
+				return nil, fmt.Errorf("rule %q: the %s actions must have a \"key\"", rule.Name, action.Type)
+			}
+		case spanRuleDeleteTag, spanRuleHashTag:
+			if action.Key == "" {
+				return nil, fmt.Errorf("rule %q: the %s actions must have a \"key\"", rule.Name, action.Type)
+			}
+			a.keyRe = compileGlob(action.Key)
+		default:
+			return nil, fmt.Errorf("rule %q: unknown action %q, expected drop_span, drop_trace, delete_tag, set_tag or hash_tag", rule.Name, action.Type)
+		}
+		c.actions = append(c.actions, a)
+	}
+	return c, nil
+}
+
+// compileGlob returns a regular expression matching the whole strings matched
+// by the glob pattern, nil if the pattern is empty.
+func compileGlob(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return regexp.MustCompile("(?s)^" + expr + "$")
+}
+
+var metricConditionOps = []string{"==", "!=", "<=", ">=", "<", ">", "="}
+
+// parseMetricCondition parses comparisons such as ">= 500", the values without
+// an operator being compared for equality.
+func parseMetricCondition(cond string) (metricCondition, error) {
+	cond = strings.TrimSpace(cond)
+	var c metricCondition
+	for _, op := range metricConditionOps {
+		if strings.HasPrefix(cond, op) {
+			c.op = op
+			cond = strings.TrimSpace(cond[len(op):])
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(cond, 64)
+	if err != nil {
+		return c, fmt.Errorf("invalid comparison, expected an optional operator (==, !=, <, <=, >, >=) followed by a number: %v", err)
+	}
+	c.value = v
+	return c, nil
+}
@@ -0,0 +1,198 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func TestSpanRules(t *testing.T) {
+	newChunk := func() *pb.TraceChunk {
+		return &pb.TraceChunk{Spans: []*pb.Span{
+			{
+				SpanID:   1,
+				Service:  "web",
+				Name:     "http.request",
+				Resource: "GET /users/:id",
+				Meta:     map[string]string{"http.route": "/users/:id", "user.email": "jane@example.com", "user.phone": "555"},
+				Metrics:  map[string]float64{"http.status_code": 200},
+			},
+			{
+				SpanID:   2,
+				ParentID: 1,
+				Service:  "web",
+				Name:     "http.request",
+				Resource: "GET /health",
+				Meta:     map[string]string{"http.route": "/health"},
+				Metrics:  map[string]float64{"http.status_code": 503},
+			},
+		}}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		rules  []*config.SpanRule
+		keep   bool
+		spans  []uint64
+		assert func(t *testing.T, chunk *pb.TraceChunk)
+	}{
+		{
+			name: "drop_span",
+			rules: []*config.SpanRule{{
+				Name:    "health-checks",
+				Match:   config.SpanRuleMatch{Service: "we?", Meta: map[string]string{"http.route": "/health*"}},
+				Actions: []*config.SpanRuleAction{{Type: "drop_span"}},
+			}},
+			keep:  true,
+			spans: []uint64{1},
+		},
+		{
+			name: "drop_trace",
+			rules: []*config.SpanRule{{
+				Name:    "errors",
+				Match:   config.SpanRuleMatch{Metrics: map[string]string{"http.status_code": ">= 500"}},
+				Actions: []*config.SpanRuleAction{{Type: "drop_trace"}},
+			}},
+		},
+		{
+			name: "no-match",
+			rules: []*config.SpanRule{{
+				Name:    "other-service",
+				Match:   config.SpanRuleMatch{Service: "api", Resource: "*"},
+				Actions: []*config.SpanRuleAction{{Type: "drop_trace"}},
+			}},
+			keep:  true,
+			spans: []uint64{1, 2},
+		},
+		{
+			name: "tags",
+			rules: []*config.SpanRule{{
+				Name:  "pii",
+				Match: config.SpanRuleMatch{Resource: "GET /users/*", Metrics: map[string]string{"http.status_code": "200"}},
+				Actions: []*config.SpanRuleAction{
+					{Type: "hash_tag", Key: "user.email"},
+					{Type: "delete_tag", Key: "user.p*"},
+					{Type: "set_tag", Key: "pii.scrubbed", Value: "true"},
+				},
+			}},
+			keep:  true,
+			spans: []uint64{1, 2},
+			assert: func(t *testing.T, chunk *pb.TraceChunk) {
+				meta := chunk.Spans[0].Meta
+				assert.Equal(t, "8c87b489ce35cf2e2f39f80e282cb2e804932a56a213983eeeb428407d43b52d", meta["user.email"])
+				assert.NotContains(t, meta, "user.phone")
+				assert.Equal(t, "true", meta["pii.scrubbed"])
+				assert.NotContains(t, chunk.Spans[1].Meta, "pii.scrubbed")
+			},
+		},
+		{
+			name: "rules-in-order",
+			rules: []*config.SpanRule{
+				{
+					Name:    "mark",
+					Match:   config.SpanRuleMatch{Name: "http.*"},
+					Actions: []*config.SpanRuleAction{{Type: "set_tag", Key: "team", Value: "checkout"}},
+				},
+				{
+					Name:    "drop-marked",
+					Match:   config.SpanRuleMatch{Meta: map[string]string{"team": ""}, Metrics: map[string]string{"http.status_code": "!= 200"}},
+					Actions: []*config.SpanRuleAction{{Type: "drop_span"}},
+				},
+			},
+			keep:  true,
+			spans: []uint64{1},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := NewSpanRules(tc.rules)
+			require.NoError(t, err)
+			chunk := newChunk()
+			assert.Equal(t, tc.keep, rules.Apply(chunk))
+			if !tc.keep {
+				return
+			}
+			var spans []uint64
+			for _, span := range chunk.Spans {
+				spans = append(spans, span.SpanID)
+			}
+			assert.Equal(t, tc.spans, spans)
+			if tc.assert != nil {
+				tc.assert(t, chunk)
+			}
+		})
+	}
+}
+
+func TestSpanRulesHashTag(t *testing.T) {
+	rules, err := NewSpanRules([]*config.SpanRule{{
+		Name:    "hash",
+		Actions: []*config.SpanRuleAction{{Type: "hash_tag", Key: "user.id"}},
+	}})
+	require.NoError(t, err)
+	chunk := &pb.TraceChunk{Spans: []*pb.Span{{Meta: map[string]string{"user.id": "abc"}}}}
+	rules.Apply(chunk)
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", chunk.Spans[0].Meta["user.id"])
+}
+
+func TestSpanRulesInvalid(t *testing.T) {
+	for name, rule := range map[string]*config.SpanRule{
+		"no-name":    {Actions: []*config.SpanRuleAction{{Type: "drop_span"}}},
+		"no-actions": {Name: "a"},
+		"no-key":     {Name: "a", Actions: []*config.SpanRuleAction{{Type: "delete_tag"}}},
+		"bad-action": {Name: "a", Actions: []*config.SpanRuleAction{{Type: "rename_tag"}}},
+		"bad-metric": {Name: "a", Match: config.SpanRuleMatch{Metrics: map[string]string{"x": "> five"}}, Actions: []*config.SpanRuleAction{{Type: "drop_span"}}},
+		"set-no-key": {Name: "a", Actions: []*config.SpanRuleAction{{Type: "set_tag"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewSpanRules([]*config.SpanRule{rule})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSpanRulesUpdateRemoteRules(t *testing.T) {
+	rules, err := NewSpanRules([]*config.SpanRule{{
+		Name:    "static",
+		Match:   config.SpanRuleMatch{Name: "db.query"},
+		Actions: []*config.SpanRuleAction{{Type: "drop_span"}},
+	}})
+	require.NoError(t, err)
+	newChunk := func() *pb.TraceChunk {
+		return &pb.TraceChunk{Spans: []*pb.Span{{SpanID: 1, Name: "http.request"}, {SpanID: 2, Name: "db.query"}}}
+	}
+
+	chunk := newChunk()
+	assert.True(t, rules.Apply(chunk))
+	assert.Len(t, chunk.Spans, 1)
+
+	require.NoError(t, rules.UpdateRemoteRules([]*config.SpanRule{{
+		Name:    "remote",
+		Match:   config.SpanRuleMatch{Name: "http.*"},
+		Actions: []*config.SpanRuleAction{{Type: "drop_span"}},
+	}}))
+	chunk = newChunk()
+	assert.True(t, rules.Apply(chunk))
+	assert.Empty(t, chunk.Spans)
+
+	// the rules in place are kept when the update is invalid
+	assert.Error(t, rules.UpdateRemoteRules([]*config.SpanRule{{Name: "invalid"}}))
+	chunk = newChunk()
+	assert.True(t, rules.Apply(chunk))
+	assert.Empty(t, chunk.Spans)
+
+	require.NoError(t, rules.UpdateRemoteRules(nil))
+	chunk = newChunk()
+	assert.True(t, rules.Apply(chunk))
+	assert.Len(t, chunk.Spans, 1)
+
+	assert.Equal(t, map[string]int64{"static": 4, "remote": 2}, rules.Hits())
+}
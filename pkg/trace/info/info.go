@@ -37,6 +37,7 @@ var (
 
 	traceWriterInfo TraceWriterInfo
 	statsWriterInfo StatsWriterInfo
+	spanRuleHits    func() map[string]int64 // by span rule name
 
 	watchdogInfo  watchdog.Info
 	rateByService map[string]float64
@@ -182,6 +183,23 @@ func publishWatchdogInfo() interface{} {
 	return watchdogInfo
 }
 
+// UpdateSpanRules sets the function returning the number of spans matched by
+// each span rule, by rule name.
+func UpdateSpanRules(hits func() map[string]int64) {
+	infoMu.Lock()
+	defer infoMu.Unlock()
+	spanRuleHits = hits
+}
+
+func publishSpanRules() interface{} {
+	infoMu.RLock()
+	defer infoMu.RUnlock()
+	if spanRuleHits == nil {
+		return map[string]int64{}
+	}
+	return spanRuleHits()
+}
+
 func publishUptime() interface{} {
 	return int(time.Since(start) / time.Second)
 }
@@ -339,6 +357,7 @@ func initInfo(conf *config.AgentConfig) error {
 	expvar.Publish("ratebyservice", expvar.Func(publishRateByService))
 	expvar.Publish("ratebyservice_filtered", expvar.Func(publishRateByServiceFiltered))
 	expvar.Publish("watchdog", expvar.Func(publishWatchdogInfo))
+	expvar.Publish("span_rules", expvar.Func(publishSpanRules))
 
 	// copy the config to ensure we don't expose sensitive data such as API keys
 	c := *conf
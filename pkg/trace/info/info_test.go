@@ -579,6 +579,15 @@ func TestPublishWatchdogInfo(t *testing.T) {
 		})
 }
 
+func TestPublishSpanRules(t *testing.T) {
+	UpdateSpanRules(nil)
+	testExpvarPublish(t, publishSpanRules, map[string]interface{}{})
+
+	UpdateSpanRules(func() map[string]int64 { return map[string]int64{"health-checks": 3} })
+	defer UpdateSpanRules(nil)
+	testExpvarPublish(t, publishSpanRules, map[string]interface{}{"health-checks": 3.0})
+}
+
 func TestScrubCreds(t *testing.T) {
 	assert := assert.New(t)
 	conf := testInit(t)
@@ -6,6 +6,7 @@ package remoteconfighandler
 import (
 	reflect "reflect"
 
+	config "github.com/DataDog/datadog-agent/pkg/trace/config"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -113,3 +114,40 @@ func (mr *MockrareSamplerMockRecorder) SetEnabled(enabled interface{}) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEnabled", reflect.TypeOf((*MockrareSampler)(nil).SetEnabled), enabled)
 }
+
+// MockspanRules is a mock of spanRules interface.
+type MockspanRules struct {
+	ctrl     *gomock.Controller
+	recorder *MockspanRulesMockRecorder
+}
+
+// MockspanRulesMockRecorder is the mock recorder for MockspanRules.
+type MockspanRulesMockRecorder struct {
+	mock *MockspanRules
+}
+
+// NewMockspanRules creates a new mock instance.
+func NewMockspanRules(ctrl *gomock.Controller) *MockspanRules {
+	mock := &MockspanRules{ctrl: ctrl}
+	mock.recorder = &MockspanRulesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockspanRules) EXPECT() *MockspanRulesMockRecorder {
+	return m.recorder
+}
+
+// UpdateRemoteRules mocks base method.
+func (m *MockspanRules) UpdateRemoteRules(rules []*config.SpanRule) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRemoteRules", rules)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRemoteRules indicates an expected call of UpdateRemoteRules.
+func (mr *MockspanRulesMockRecorder) UpdateRemoteRules(rules interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRemoteRules", reflect.TypeOf((*MockspanRules)(nil).UpdateRemoteRules), rules)
+}
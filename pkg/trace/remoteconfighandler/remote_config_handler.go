@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
@@ -34,12 +35,22 @@ type rareSampler interface {
 	SetEnabled(enabled bool)
 }
 
-// RemoteConfigHandler holds pointers to samplers that need to be updated when APM remote config changes
+type spanRules interface {
+	UpdateRemoteRules(rules []*config.SpanRule) error
+}
+
+// spanRulesConfig is the payload of the span rules remote configurations.
+type spanRulesConfig struct {
+	SpanRules []*config.SpanRule `json:"span_rules"`
+}
+
+// RemoteConfigHandler holds pointers to samplers and span rules that need to be updated when APM remote config changes
 type RemoteConfigHandler struct {
 	remoteClient                  config.RemoteClient
 	prioritySampler               prioritySampler
 	errorsSampler                 errorsSampler
 	rareSampler                   rareSampler
+	spanRules                     spanRules
 	agentConfig                   *config.AgentConfig
 	configState                   *state.AgentConfigState
 	configHTTPClient              *http.Client
@@ -47,7 +58,7 @@ type RemoteConfigHandler struct {
 }
 
 // New creates a new RemoteConfigHandler
-func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler rareSampler, errorsSampler errorsSampler) *RemoteConfigHandler {
+func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler rareSampler, errorsSampler errorsSampler, spanRules spanRules) *RemoteConfigHandler {
 	if conf.RemoteConfigClient == nil {
 		return nil
 	}
@@ -68,6 +79,7 @@ func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler
 		prioritySampler: prioritySampler,
 		rareSampler:     rareSampler,
 		errorsSampler:   errorsSampler,
+		spanRules:       spanRules,
 		agentConfig:     conf,
 		configState: &state.AgentConfigState{
 			FallbackLogLevel: level.String(),
@@ -92,6 +104,46 @@ func (h *RemoteConfigHandler) Start() {
 	h.remoteClient.Start()
 	h.remoteClient.Subscribe(state.ProductAPMSampling, h.onUpdate)
 	h.remoteClient.Subscribe(state.ProductAgentConfig, h.onAgentConfigUpdate)
+	if h.spanRules != nil {
+		h.remoteClient.Subscribe(state.ProductAPMSpanRules, h.onSpanRulesUpdate)
+	}
+}
+
+// onSpanRulesUpdate replaces the span rules received through remote configuration, by the
+// ones of all the configurations in the order of their path.
+func (h *RemoteConfigHandler) onSpanRulesUpdate(updates map[string]state.RawConfig, applyStateCallback func(string, state.ApplyStatus)) {
+	paths := make([]string, 0, len(updates))
+	for path := range updates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var rules []*config.SpanRule
+	parsed := paths[:0]
+	for _, path := range paths {
+		var payload spanRulesConfig
+		if err := json.Unmarshal(updates[path].Config, &payload); err != nil {
+			log.Errorf("couldn't parse the span rules from remote configuration: %s", err)
+			applyStateCallback(path, state.ApplyStatus{State: state.ApplyStateError, Error: err.Error()})
+			continue
+		}
+		rules = append(rules, payload.SpanRules...)
+		parsed = append(parsed, path)
+	}
+
+	err := h.spanRules.UpdateRemoteRules(rules)
+	if err != nil {
+		log.Errorf("couldn't apply the span rules from remote configuration: %s", err)
+	} else {
+		log.Debugf("updated the span rules with remote configuration: %d rules", len(rules))
+	}
+	for _, path := range parsed {
+		if err == nil {
+			applyStateCallback(path, state.ApplyStatus{State: state.ApplyStateAcknowledged})
+		} else {
+			applyStateCallback(path, state.ApplyStatus{State: state.ApplyStateError, Error: err.Error()})
+		}
+	}
 }
 
 func (h *RemoteConfigHandler) onAgentConfigUpdate(updates map[string]state.RawConfig, applyStateCallback func(string, state.ApplyStatus)) {
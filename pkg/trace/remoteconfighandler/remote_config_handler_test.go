@@ -35,7 +35,7 @@ func TestStart(t *testing.T) {
 	rareSampler := NewMockrareSampler(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, nil)
 
 	remoteClient.EXPECT().Subscribe(state.ProductAPMSampling, gomock.Any()).Times(1)
 	remoteClient.EXPECT().Subscribe(state.ProductAgentConfig, gomock.Any()).Times(1)
@@ -60,7 +60,7 @@ func TestPrioritySampler(t *testing.T) {
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, nil)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -91,7 +91,7 @@ func TestErrorsSampler(t *testing.T) {
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, nil)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -122,7 +122,7 @@ func TestRareSampler(t *testing.T) {
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, nil)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -153,7 +153,7 @@ func TestEnvPrecedence(t *testing.T) {
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DefaultEnv: "agent-env", DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, nil)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -208,7 +208,7 @@ func TestLogLevel(t *testing.T) {
 			return "fakeToken"
 		},
 	}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, nil)
 
 	layer := state.RawConfig{Config: []byte(`{"name": "layer1", "config": {"log_level": "debug"}}`)}
 	configOrder := state.RawConfig{Config: []byte(`{"internal_order": ["layer1", "layer2"]}`)}
@@ -229,3 +229,69 @@ func TestLogLevel(t *testing.T) {
 
 	ctrl.Finish()
 }
+
+func TestStartSpanRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	remoteClient := NewMockRemoteClient(ctrl)
+	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, DebugServerPort: 1}
+	prioritySampler := NewMockprioritySampler(ctrl)
+	errorsSampler := NewMockerrorsSampler(ctrl)
+	rareSampler := NewMockrareSampler(ctrl)
+	spanRules := NewMockspanRules(ctrl)
+	pkglog.SetupLogger(pkglog.Default(), "debug")
+
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanRules)
+
+	remoteClient.EXPECT().Subscribe(state.ProductAPMSampling, gomock.Any()).Times(1)
+	remoteClient.EXPECT().Subscribe(state.ProductAgentConfig, gomock.Any()).Times(1)
+	remoteClient.EXPECT().Subscribe(state.ProductAPMSpanRules, gomock.Any()).Times(1)
+	remoteClient.EXPECT().Start().Times(1)
+
+	h.Start()
+
+	ctrl.Finish()
+}
+
+func TestSpanRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	remoteClient := NewMockRemoteClient(ctrl)
+	prioritySampler := NewMockprioritySampler(ctrl)
+	errorsSampler := NewMockerrorsSampler(ctrl)
+	rareSampler := NewMockrareSampler(ctrl)
+	spanRules := NewMockspanRules(ctrl)
+	pkglog.SetupLogger(pkglog.Default(), "debug")
+
+	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, DebugServerPort: 1}
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanRules)
+
+	health := state.RawConfig{Config: []byte(`{"span_rules": [{"name": "health", "match": {"resource": "GET /health"}, "actions": [{"type": "drop_span"}]}]}`)}
+	pii := state.RawConfig{Config: []byte(`{"span_rules": [{"name": "pii", "actions": [{"type": "delete_tag", "key": "user.*"}]}]}`)}
+	invalid := state.RawConfig{Config: []byte(`{"span_rules": {}}`)}
+
+	spanRules.EXPECT().UpdateRemoteRules([]*config.SpanRule{
+		{
+			Name:    "health",
+			Match:   config.SpanRuleMatch{Resource: "GET /health"},
+			Actions: []*config.SpanRuleAction{{Type: "drop_span"}},
+		},
+		{
+			Name:    "pii",
+			Actions: []*config.SpanRuleAction{{Type: "delete_tag", Key: "user.*"}},
+		},
+	}).Return(nil).Times(1)
+	statuses := make(map[string]state.ApplyState)
+	h.onSpanRulesUpdate(map[string]state.RawConfig{
+		"datadog/2/APM_SPAN_RULES/b/config": pii,
+		"datadog/2/APM_SPAN_RULES/a/config": health,
+		"datadog/2/APM_SPAN_RULES/c/config": invalid,
+	}, func(path string, status state.ApplyStatus) {
+		statuses[path] = status.State
+	})
+	assert.Equal(t, map[string]state.ApplyState{
+		"datadog/2/APM_SPAN_RULES/a/config": state.ApplyStateAcknowledged,
+		"datadog/2/APM_SPAN_RULES/b/config": state.ApplyStateAcknowledged,
+		"datadog/2/APM_SPAN_RULES/c/config": state.ApplyStateError,
+	}, statuses)
+
+	ctrl.Finish()
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/tinylib/msgp/msgp"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+// The paths of the intake endpoints the trace and stats writers send their payloads to.
+const (
+	pathTraces = "/api/v0.2/traces"
+	pathStats  = "/api/v0.2/stats"
+)
+
+// intake is a stand-in for the Datadog intake, in the manner of the writer's testServer:
+// it accepts all the payloads, and decodes the trace and stats ones.
+type intake struct {
+	server *httptest.Server
+	URL    string
+
+	mu     sync.Mutex // guards the fields below
+	traces []*pb.AgentPayload
+	stats  []*pb.StatsPayload
+	err    error // the first error decoding a payload
+}
+
+// newIntake returns a new, started, intake stand-in. Its URL is available as a field.
+func newIntake() *intake {
+	in := &intake{}
+	in.server = httptest.NewServer(in)
+	in.URL = in.server.URL
+	return in
+}
+
+// ServeHTTP decodes the payload of req.
+func (in *intake) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	// the payloads are accepted anyway, the writers would otherwise retry them
+	w.WriteHeader(http.StatusOK)
+	switch req.URL.Path {
+	case pathTraces:
+		var p pb.AgentPayload
+		in.decode(req, func(r io.Reader) error {
+			b, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			return proto.Unmarshal(b, &p)
+		}, func() { in.traces = append(in.traces, &p) })
+	case pathStats:
+		var p pb.StatsPayload
+		in.decode(req, func(r io.Reader) error {
+			return msgp.Decode(r, &p)
+		}, func() { in.stats = append(in.stats, &p) })
+	}
+}
+
+// decode decodes the body of req with decode, then keeps the payload with keep.
+func (in *intake) decode(req *http.Request, decode func(io.Reader) error, keep func()) {
+	var r io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			in.setError(req, err)
+			return
+		}
+		defer gz.Close()
+		r = gz
+	}
+	if err := decode(r); err != nil {
+		in.setError(req, err)
+		return
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	keep()
+}
+
+func (in *intake) setError(req *http.Request, err error) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.err == nil {
+		in.err = fmt.Errorf("can't decode the payload sent to %s: %v", req.URL.Path, err)
+	}
+}
+
+// Close shuts the intake stand-in down.
+func (in *intake) Close() { in.server.Close() }
@@ -0,0 +1,357 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package replay replays the payloads of capture files through a trace-agent, reporting
+// its sampling decisions and the stats it computed, so that the behavior of different
+// versions of the trace-agent can be compared.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc/metadata"
+
+	gzip "github.com/DataDog/datadog-agent/comp/trace/compression/impl-gzip"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/agent"
+	"github.com/DataDog/datadog-agent/pkg/trace/capture"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+const (
+	// tagDecisionMaker is the chunk tag holding the sampling decision maker.
+	tagDecisionMaker = "_dd.p.dm"
+	// tagTraceIDUpper is the tag holding the high 64 bits of 128-bit trace IDs.
+	tagTraceIDUpper = "_dd.p.tid"
+	// tagReplayRecord is the chunk tag holding the index of the record of the trace.
+	tagReplayRecord = "_dd.replay.record"
+)
+
+// Result is the outcome of a replay.
+type Result struct {
+	// Records is the number of records replayed.
+	Records int `json:"records"`
+	// Rejected is the number of records which weren't accepted by the trace-agent.
+	Rejected int `json:"rejected"`
+	// RateByService holds the last sampling rates returned to the tracers.
+	RateByService map[string]float64 `json:"rate_by_service,omitempty"`
+	// Traces holds the sampling decisions of the traces, in the order they were received.
+	Traces []*TraceDecision `json:"traces"`
+	// Stats holds the stats payloads sent by the trace-agent, computed by the trace-agent
+	// or aggregated from the client stats.
+	Stats []*pb.StatsPayload `json:"stats"`
+}
+
+// TraceDecision is the sampling decision of a trace.
+type TraceDecision struct {
+	TraceID  uint64 `json:"trace_id"`
+	Service  string `json:"service"`
+	Name     string `json:"name"`
+	Resource string `json:"resource"`
+	// Priority is the sampling priority of the trace, as received.
+	Priority int32 `json:"priority"`
+	// Kept reports whether the trace was sent to the intake.
+	Kept bool `json:"kept"`
+	// DecisionMaker is the sampling mechanism which decided to keep the trace, if known.
+	DecisionMaker string `json:"decision_maker,omitempty"`
+}
+
+// Options configure a replay.
+type Options struct {
+	// Realtime replays the records with the delays they were received with, instead
+	// of as fast as possible.
+	Realtime bool
+}
+
+// Run replays the records of the capture files at paths through a trace-agent configured
+// with cfg, its payloads being sent to a stand-in of the intake instead of Datadog.
+func Run(ctx context.Context, cfg *config.AgentConfig, paths []string, opts Options) (*Result, error) {
+	srv := newIntake()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	a := agent.NewAgent(ctx, replayConfig(cfg, srv.URL), telemetry.NewNoopCollector(), &statsd.NoOpClient{}, gzip.NewComponent())
+	decisions := newDecisionRecorder()
+	a.SpanModifier = decisions
+	// the handlers are called directly, the receivers aren't started
+	a.Receiver.BuildHandlers()
+	for _, starter := range []interface{ Start() }{
+		a.Concentrator,
+		a.ClientStatsAggregator,
+		a.EventProcessor,
+	} {
+		starter.Start()
+	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
+	go a.StatsWriter.Run()
+
+	r := &replayer{agent: a, opts: opts, decisions: decisions, processed: make(chan struct{}), result: &Result{}}
+	go r.work()
+	var err error
+	for _, path := range paths {
+		if err = r.replayFile(ctx, path); err != nil {
+			break
+		}
+	}
+
+	// wait for the client stats to be aggregated, then flush everything
+	for len(a.ClientStatsAggregator.In) > 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, stopper := range []interface{ Stop() }{
+		a.Concentrator,
+		a.ClientStatsAggregator,
+		a.TailSampler, // before the TraceWriter, to write the buffered traces
+		a.TraceWriter,
+		a.StatsWriter,
+		a.EventProcessor,
+	} {
+		// Fun with golang nil checks
+		if stopper != nil && !reflect.ValueOf(stopper).IsNil() {
+			stopper.Stop()
+		}
+	}
+	close(a.In)
+	if err != nil {
+		return nil, err
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.err != nil {
+		return nil, srv.err
+	}
+	r.result.Traces = decisions.resolve(srv.traces)
+	r.result.Stats = srv.stats
+	return r.result, nil
+}
+
+// replayConfig returns a copy of cfg sending the payloads to the intake at url, without
+// any of the listeners and side outputs of the trace-agent.
+func replayConfig(cfg *config.AgentConfig, url string) *config.AgentConfig {
+	c := *cfg
+	c.Endpoints = []*config.Endpoint{{Host: url, APIKey: "replay"}}
+	c.Proxy = nil
+	c.ReceiverEnabled = false
+	c.DebugServerPort = 0
+	c.SynchronousFlushing = false
+	if c.DecoderTimeout <= 0 {
+		// the payloads are replayed one at a time, they must never be refused as
+		// if the trace-agent was overwhelmed
+		c.DecoderTimeout = 1000
+	}
+	c.PayloadCapture.Enabled = false
+	c.OTLPTracesOutput.Enabled = false
+	if cfg.OTLPReceiver != nil {
+		otlp := *cfg.OTLPReceiver
+		otlp.GRPCPort = 0
+		c.OTLPReceiver = &otlp
+	}
+	return &c
+}
+
+// replayer feeds the records to the agent.
+type replayer struct {
+	agent     *agent.Agent
+	opts      Options
+	decisions *decisionRecorder
+
+	// processed is signalled by work once the payloads sent to the agent before a nil
+	// payload were processed.
+	processed chan struct{}
+	last      time.Time // the time of the last record replayed
+	result    *Result
+}
+
+// work processes the payloads sent to the agent, one at a time so that the replays are
+// reproducible.
+func (r *replayer) work() {
+	for p := range r.agent.In {
+		if p == nil {
+			r.processed <- struct{}{}
+			continue
+		}
+		r.agent.Process(p)
+	}
+}
+
+// replayFile replays the records of the capture file at path.
+func (r *replayer) replayFile(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	cr, err := capture.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	for {
+		rec, err := cr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if r.opts.Realtime && !r.last.IsZero() {
+			select {
+			case <-time.After(rec.Time.Sub(r.last)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		r.last = rec.Time
+		r.decisions.setRecord(r.result.Records)
+		r.replay(ctx, rec)
+		// wait for the payloads of the record to be processed
+		r.agent.In <- nil
+		<-r.processed
+	}
+}
+
+// replay sends the record rec to the handler of its endpoint.
+func (r *replayer) replay(ctx context.Context, rec *capture.Record) {
+	r.result.Records++
+	if rec.Endpoint == capture.EndpointOTLP {
+		req := ptraceotlp.NewExportRequest()
+		if err := req.UnmarshalProto(rec.Body); err != nil {
+			log.Warnf("Skipping an invalid OTLP record: %v", err)
+			r.result.Rejected++
+			return
+		}
+		if _, err := r.agent.OTLPReceiver.Export(metadata.NewIncomingContext(ctx, metadata.MD(rec.Header)), req); err != nil {
+			r.result.Rejected++
+		}
+		return
+	}
+	h, ok := r.agent.Receiver.Handlers[rec.Endpoint]
+	if !ok {
+		log.Warnf("Skipping a record of the unknown endpoint %q", rec.Endpoint)
+		r.result.Rejected++
+		return
+	}
+	req := httptest.NewRequest(http.MethodPost, rec.Endpoint, bytes.NewReader(rec.Body)).WithContext(ctx)
+	for k, vs := range rec.Header {
+		req.Header[k] = vs
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code/100 != 2 {
+		r.result.Rejected++
+		return
+	}
+	var resp struct {
+		Rates map[string]float64 `json:"rate_by_service"`
+	}
+	if json.Unmarshal(w.Body.Bytes(), &resp) == nil && resp.Rates != nil {
+		r.result.RateByService = resp.Rates
+	}
+}
+
+// decisionRecorder records the traces processed by the agent, as a SpanModifier.
+type decisionRecorder struct {
+	mu     sync.Mutex
+	record int // the index of the record being replayed
+	traces []*TraceDecision
+	byKey  map[traceKey]*TraceDecision
+}
+
+// traceKey identifies a trace of a record: the same trace can be replayed by
+// several records, and 128-bit traces can share their low 64 bits.
+type traceKey struct {
+	record  int
+	upper   string // the _dd.p.tid tag, holding the high 64 bits of 128-bit trace IDs
+	traceID uint64
+}
+
+func newDecisionRecorder() *decisionRecorder {
+	return &decisionRecorder{byKey: make(map[traceKey]*TraceDecision)}
+}
+
+// setRecord sets the index of the record whose traces are processed next.
+func (d *decisionRecorder) setRecord(record int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.record = record
+}
+
+// ModifySpan implements agent.SpanModifier.
+func (d *decisionRecorder) ModifySpan(chunk *pb.TraceChunk, span *pb.Span) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := chunk.Tags[tagReplayRecord]; ok {
+		return
+	}
+	if chunk.Tags == nil {
+		chunk.Tags = make(map[string]string)
+	}
+	// the record index is sent along with the chunk, to find its trace once received
+	// by the intake
+	chunk.Tags[tagReplayRecord] = strconv.Itoa(d.record)
+	root := traceutil.GetRoot(chunk.Spans)
+	key := traceKey{record: d.record, upper: root.Meta[tagTraceIDUpper], traceID: root.TraceID}
+	if _, ok := d.byKey[key]; ok {
+		return
+	}
+	t := &TraceDecision{
+		TraceID:  span.TraceID,
+		Service:  root.Service,
+		Name:     root.Name,
+		Resource: root.Resource,
+		Priority: chunk.Priority,
+	}
+	d.traces = append(d.traces, t)
+	d.byKey[key] = t
+}
+
+// resolve returns the decisions of the traces recorded, the kept ones being the ones
+// of the payloads received by the intake.
+func (d *decisionRecorder) resolve(payloads []*pb.AgentPayload) []*TraceDecision {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, p := range payloads {
+		for _, tp := range p.TracerPayloads {
+			for _, chunk := range tp.Chunks {
+				if chunk.DroppedTrace || len(chunk.Spans) == 0 {
+					continue
+				}
+				record, err := strconv.Atoi(chunk.Tags[tagReplayRecord])
+				if err != nil {
+					continue
+				}
+				root := traceutil.GetRoot(chunk.Spans)
+				t, ok := d.byKey[traceKey{record: record, upper: root.Meta[tagTraceIDUpper], traceID: root.TraceID}]
+				if !ok {
+					continue
+				}
+				t.Kept = true
+				if dm := chunk.Tags[tagDecisionMaker]; dm != "" {
+					t.DecisionMaker = dm
+				}
+			}
+		}
+	}
+	return d.traces
+}
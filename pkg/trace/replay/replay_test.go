@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replay
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/capture"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func TestRun(t *testing.T) {
+	now := time.Now()
+	traces := pb.Traces{
+		{{Service: "web", Name: "http.request", Resource: "GET /", TraceID: 1, SpanID: 1, Start: now.UnixNano(), Duration: 1000, Metrics: map[string]float64{"_sampling_priority_v1": 2}}},
+		{{Service: "batch", Name: "job", Resource: "cleanup", TraceID: 2, SpanID: 2, Start: now.UnixNano(), Duration: 1000, Metrics: map[string]float64{"_sampling_priority_v1": -1}}},
+	}
+	tracesBody, err := traces.MarshalMsg(nil)
+	require.NoError(t, err)
+	clientStats := &pb.ClientStatsPayload{
+		Hostname: "client-host",
+		Env:      "prod",
+		Stats: []*pb.ClientStatsBucket{{
+			Start:    uint64(now.UnixNano()),
+			Duration: uint64(10 * time.Second),
+			Stats:    []*pb.ClientGroupedStats{{Service: "client", Name: "op", Resource: "r", Hits: 5, Duration: 5000}},
+		}},
+	}
+	statsBody, err := clientStats.MarshalMsg(nil)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	w, err := capture.NewWriter(dir, 1<<20, 1)
+	require.NoError(t, err)
+	w.Capture(now, "/v0.4/traces", http.Header{
+		"Content-Type":          {"application/msgpack"},
+		"X-Datadog-Trace-Count": {"2"},
+		"Datadog-Meta-Lang":     {"go"},
+	}, tracesBody)
+	w.Capture(now, "/v0.6/stats", http.Header{"Datadog-Meta-Lang": {"go"}}, statsBody)
+	w.Capture(now, "/unknown", nil, nil)
+	w.Stop()
+	files, err := capture.Files(dir)
+	require.NoError(t, err)
+
+	cfg := config.New()
+	cfg.Hostname = "replay-host"
+	cfg.Endpoints[0].APIKey = "test"
+	res, err := Run(context.Background(), cfg, files, Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, res.Records)
+	assert.Equal(t, 1, res.Rejected)
+	// no rates are computed by the priority sampler yet
+	assert.NotNil(t, res.RateByService)
+	require.Len(t, res.Traces, 2)
+	assert.Equal(t, &TraceDecision{TraceID: 1, Service: "web", Name: "http.request", Resource: "GET /", Priority: 2, Kept: true, DecisionMaker: res.Traces[0].DecisionMaker}, res.Traces[0])
+	assert.Equal(t, &TraceDecision{TraceID: 2, Service: "batch", Name: "job", Resource: "cleanup", Priority: -1}, res.Traces[1])
+
+	services := make(map[string]uint64)
+	for _, sp := range res.Stats {
+		assert.Equal(t, "replay-host", sp.AgentHostname)
+		for _, csp := range sp.Stats {
+			for _, b := range csp.Stats {
+				for _, gs := range b.Stats {
+					services[gs.Service] += gs.Hits
+				}
+			}
+		}
+	}
+	assert.Equal(t, map[string]uint64{"web": 1, "batch": 1, "client": 5}, services)
+}
+
+func TestRunInvalidFile(t *testing.T) {
+	_, err := Run(context.Background(), config.New(), []string{"testdata/missing.capture"}, Options{})
+	assert.Error(t, err)
+}
+
+func TestRunTraceKeys(t *testing.T) {
+	now := time.Now()
+	span := func(tid string, priority float64) *pb.Span {
+		return &pb.Span{Service: "web", Name: "http.request", Resource: "GET /", TraceID: 1, SpanID: 1, Start: now.UnixNano(), Duration: 1000, Meta: map[string]string{"_dd.p.tid": tid}, Metrics: map[string]float64{"_sampling_priority_v1": priority}}
+	}
+	// two 128-bit traces sharing their low 64 bits, the first one being replayed again
+	first, err := pb.Traces{{span("640cfd8d00000000", 2)}, {span("640cfd8e00000000", -1)}}.MarshalMsg(nil)
+	require.NoError(t, err)
+	again, err := pb.Traces{{span("640cfd8d00000000", 2)}}.MarshalMsg(nil)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	w, err := capture.NewWriter(dir, 1<<20, 1)
+	require.NoError(t, err)
+	header := http.Header{"Content-Type": {"application/msgpack"}, "Datadog-Meta-Lang": {"go"}}
+	w.Capture(now, "/v0.4/traces", header, first)
+	w.Capture(now, "/v0.4/traces", header, again)
+	w.Stop()
+	files, err := capture.Files(dir)
+	require.NoError(t, err)
+
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	res, err := Run(context.Background(), cfg, files, Options{})
+	require.NoError(t, err)
+
+	require.Len(t, res.Traces, 3)
+	var kept []bool
+	for _, d := range res.Traces {
+		kept = append(kept, d.Kept)
+	}
+	assert.Equal(t, []bool{true, false, true}, kept)
+}
@@ -0,0 +1,375 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"go.uber.org/atomic"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+)
+
+const (
+	// MetricsTailKept is the metric name for the number of traces kept by the tail sampler.
+	MetricsTailKept = "datadog.trace_agent.sampler.tail.kept"
+	// MetricsTailDropped is the metric name for the number of traces dropped by the tail sampler.
+	MetricsTailDropped = "datadog.trace_agent.sampler.tail.dropped"
+	// MetricsTailEvicted is the metric name for the number of traces evaluated before the end of
+	// their decision wait, as the buffer of the tail sampler was full.
+	MetricsTailEvicted = "datadog.trace_agent.sampler.tail.evicted"
+	// MetricsTailBufferedTraces is the metric name for the number of traces buffered by the tail sampler.
+	MetricsTailBufferedTraces = "datadog.trace_agent.sampler.tail.buffered_traces"
+	// MetricsTailBufferedBytes is the metric name for the size of the chunks buffered by the tail sampler.
+	MetricsTailBufferedBytes = "datadog.trace_agent.sampler.tail.buffered_bytes"
+
+	// KeyTailSamplingPolicy is the tag of the chunks kept by the tail sampler, holding the name of the
+	// policy which kept them.
+	KeyTailSamplingPolicy = "_dd.tail_sampling.policy"
+
+	tailSamplerTickPeriod = time.Second
+)
+
+// TailChunk is a chunk buffered by the tail sampler.
+type TailChunk struct {
+	// Chunk holds all the spans of the chunk, it is released when its trace is kept.
+	Chunk *pb.TraceChunk
+	// Dropped is the chunk as left by the samplers which dropped it, holding the spans kept by
+	// single span sampling or the analytics events, it is released when its trace is dropped.
+	Dropped *pb.TraceChunk
+	// Payload holds the attributes of the tracer payload of the chunk, without its chunks.
+	Payload *pb.TracerPayload
+	// Events is the number of analytics events extracted from the chunk.
+	Events int64
+
+	size int
+}
+
+// TailReleaseFunc receives the chunks of a trace once the tail sampler has decided to keep it or
+// not.
+type TailReleaseFunc func(chunks []*TailChunk, keep bool)
+
+type tailTrace struct {
+	id        uint64
+	firstSeen time.Time
+	chunks    []*TailChunk
+	size      int
+	element   *list.Element
+}
+
+type tailDecision struct {
+	keep   bool
+	expire time.Time
+}
+
+// TailSampler buffers by trace ID the chunks dropped by the other samplers, and evaluates its
+// policies over the whole traces once their decision wait is over. A trace is kept as soon as
+// one of its chunks is kept by the other samplers. The decisions are remembered for the chunks
+// arriving late.
+type TailSampler struct {
+	decisionWait time.Duration
+	maxTraces    int
+	maxBytes     int
+	policies     []*config.TailSamplingPolicy
+	release      TailReleaseFunc
+
+	mu        sync.Mutex
+	traces    map[uint64]*tailTrace
+	order     *list.List // of *tailTrace, by arrival of their first chunk
+	bytes     int
+	decisions map[uint64]tailDecision
+	kept      map[string]int64 // by policy name
+
+	dropped *atomic.Int64
+	evicted *atomic.Int64
+
+	statsd statsd.ClientInterface
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewTailSampler returns a tail sampler releasing the chunks of the decided traces to release.
+func NewTailSampler(conf *config.AgentConfig, release TailReleaseFunc, statsd statsd.ClientInterface) *TailSampler {
+	return &TailSampler{
+		decisionWait: conf.TailSampling.DecisionWait,
+		maxTraces:    conf.TailSampling.MaxTraces,
+		maxBytes:     conf.TailSampling.MaxBytes,
+		policies:     conf.TailSampling.Policies,
+		release:      release,
+		traces:       make(map[uint64]*tailTrace),
+		order:        list.New(),
+		decisions:    make(map[uint64]tailDecision),
+		kept:         make(map[string]int64),
+		dropped:      atomic.NewInt64(0),
+		evicted:      atomic.NewInt64(0),
+		statsd:       statsd,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Start starts evaluating the traces whose decision wait is over.
+func (s *TailSampler) Start() {
+	go func() {
+		defer watchdog.LogOnPanic(s.statsd)
+		defer close(s.done)
+		ticker := time.NewTicker(tailSamplerTickPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				s.flush(now, false)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the sampler, evaluating all the buffered traces.
+func (s *TailSampler) Stop() {
+	close(s.stop)
+	<-s.done
+	s.flush(time.Now(), true)
+}
+
+// Add buffers a chunk dropped by the other samplers, or releases it right away if its trace has
+// already been decided.
+func (s *TailSampler) Add(now time.Time, traceID uint64, chunk *TailChunk) {
+	chunk.size = chunk.Chunk.Msgsize()
+
+	s.mu.Lock()
+	if d, ok := s.decisions[traceID]; ok && now.Before(d.expire) {
+		s.mu.Unlock()
+		s.releaseChunks(tailRelease{chunks: []*TailChunk{chunk}, keep: d.keep})
+		return
+	}
+
+	t, ok := s.traces[traceID]
+	if !ok {
+		t = &tailTrace{id: traceID, firstSeen: now}
+		t.element = s.order.PushBack(t)
+		s.traces[traceID] = t
+	}
+	t.chunks = append(t.chunks, chunk)
+	t.size += chunk.size
+	s.bytes += chunk.size
+
+	var releases []tailRelease
+	for s.order.Len() > 0 && (len(s.traces) > s.maxTraces || s.bytes > s.maxBytes) {
+		s.evicted.Inc()
+		releases = append(releases, s.decide(s.order.Front().Value.(*tailTrace), now))
+	}
+	s.mu.Unlock()
+
+	for _, r := range releases {
+		s.releaseChunks(r)
+	}
+}
+
+// KeepTrace releases the buffered chunks of a trace of which a chunk has been kept by the other
+// samplers, and keeps its chunks arriving later. Nothing is recorded for the traces without
+// buffered chunks, not to fill the decisions with all the traces kept by the other samplers.
+func (s *TailSampler) KeepTrace(now time.Time, traceID uint64) {
+	s.mu.Lock()
+	t, ok := s.traces[traceID]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	s.remove(t)
+	s.remember(traceID, true, now)
+	s.mu.Unlock()
+
+	s.releaseChunks(tailRelease{chunks: t.chunks, keep: true})
+}
+
+// flush decides the traces whose decision wait is over, or all of them, and forgets the expired
+// decisions.
+func (s *TailSampler) flush(now time.Time, all bool) {
+	var releases []tailRelease
+	s.mu.Lock()
+	for s.order.Len() > 0 {
+		t := s.order.Front().Value.(*tailTrace)
+		if !all && now.Sub(t.firstSeen) < s.decisionWait {
+			break
+		}
+		releases = append(releases, s.decide(t, now))
+	}
+	for id, d := range s.decisions {
+		if !now.Before(d.expire) {
+			delete(s.decisions, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, r := range releases {
+		s.releaseChunks(r)
+	}
+}
+
+type tailRelease struct {
+	chunks []*TailChunk
+	keep   bool
+	policy string
+}
+
+// decide removes a trace from the buffer and evaluates the policies over it. It must be called
+// with the lock held, the chunks being released once it is released.
+func (s *TailSampler) decide(t *tailTrace, now time.Time) tailRelease {
+	s.remove(t)
+	policy, keep := s.evaluate(t)
+	s.remember(t.id, keep, now)
+	if keep {
+		s.kept[policy]++
+	} else {
+		s.dropped.Inc()
+	}
+	return tailRelease{chunks: t.chunks, keep: keep, policy: policy}
+}
+
+// remove removes a trace from the buffer. It must be called with the lock held.
+func (s *TailSampler) remove(t *tailTrace) {
+	s.order.Remove(t.element)
+	delete(s.traces, t.id)
+	s.bytes -= t.size
+}
+
+// remember records the decision of a trace for its chunks arriving late, as long as it fits in
+// the limits of the buffer. It must be called with the lock held.
+func (s *TailSampler) remember(traceID uint64, keep bool, now time.Time) {
+	if _, ok := s.decisions[traceID]; ok || len(s.decisions) < s.maxTraces {
+		s.decisions[traceID] = tailDecision{keep: keep, expire: now.Add(s.decisionWait)}
+	}
+}
+
+func (s *TailSampler) releaseChunks(r tailRelease) {
+	if r.keep {
+		for _, c := range r.chunks {
+			c.Chunk.DroppedTrace = false
+			// the priority is raised as for the traces kept by the other agent samplers
+			if c.Chunk.Priority < int32(PriorityAutoKeep) {
+				c.Chunk.Priority = int32(PriorityAutoKeep)
+			}
+			if r.policy != "" {
+				if c.Chunk.Tags == nil {
+					c.Chunk.Tags = make(map[string]string)
+				}
+				c.Chunk.Tags[KeyTailSamplingPolicy] = r.policy
+			}
+		}
+	}
+	s.release(r.chunks, r.keep)
+}
+
+// evaluate returns the name of the first policy matching the trace.
+func (s *TailSampler) evaluate(t *tailTrace) (string, bool) {
+	root := tailTraceRoot(t.chunks)
+	for _, p := range s.policies {
+		if p.Service != "" && (root == nil || root.Service != p.Service) {
+			continue
+		}
+		var matched bool
+		switch p.Type {
+		case "latency":
+			matched = tailTraceDuration(t.chunks) >= time.Duration(p.ThresholdMs*float64(time.Millisecond))
+		case "error":
+			matched = tailTraceHasSpan(t.chunks, func(span *pb.Span) bool { return span.Error != 0 })
+		case "attribute":
+			matched = tailTraceHasSpan(t.chunks, func(span *pb.Span) bool {
+				v, ok := span.Meta[p.Key]
+				if !ok || len(p.Values) == 0 {
+					return ok
+				}
+				for _, value := range p.Values {
+					if v == value {
+						return true
+					}
+				}
+				return false
+			})
+		case "rate":
+			matched = SampleByRate(t.id, p.Rate)
+		default:
+			log.Debugf("Unknown tail sampling policy type %q", p.Type)
+		}
+		if matched {
+			return p.Name, true
+		}
+	}
+	return "", false
+}
+
+// tailTraceRoot returns the root span of the trace, or the root of its first chunk when the
+// chunk holding the root span hasn't been buffered.
+func tailTraceRoot(chunks []*TailChunk) *pb.Span {
+	for _, c := range chunks {
+		for _, span := range c.Chunk.Spans {
+			if span.ParentID == 0 {
+				return span
+			}
+		}
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	return traceutil.GetRoot(chunks[0].Chunk.Spans)
+}
+
+// tailTraceDuration returns the time between the start of the first span of the trace and the
+// end of its last span.
+func tailTraceDuration(chunks []*TailChunk) time.Duration {
+	var start, end int64
+	for _, c := range chunks {
+		for _, span := range c.Chunk.Spans {
+			if start == 0 || span.Start < start {
+				start = span.Start
+			}
+			if span.Start+span.Duration > end {
+				end = span.Start + span.Duration
+			}
+		}
+	}
+	return time.Duration(end - start)
+}
+
+func tailTraceHasSpan(chunks []*TailChunk, match func(*pb.Span) bool) bool {
+	for _, c := range chunks {
+		for _, span := range c.Chunk.Spans {
+			if match(span) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *TailSampler) report(statsd statsd.ClientInterface) {
+	s.mu.Lock()
+	kept := s.kept
+	s.kept = make(map[string]int64)
+	traces, bytes := len(s.traces), s.bytes
+	s.mu.Unlock()
+
+	for policy, count := range kept {
+		var tags []string
+		if policy != "" {
+			tags = []string{"policy:" + policy}
+		}
+		_ = statsd.Count(MetricsTailKept, count, tags, 1)
+	}
+	_ = statsd.Count(MetricsTailDropped, s.dropped.Swap(0), nil, 1)
+	_ = statsd.Count(MetricsTailEvicted, s.evicted.Swap(0), nil, 1)
+	_ = statsd.Gauge(MetricsTailBufferedTraces, float64(traces), nil, 1)
+	_ = statsd.Gauge(MetricsTailBufferedBytes, float64(bytes), nil, 1)
+}
@@ -0,0 +1,212 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+type releasedTrace struct {
+	chunks []*TailChunk
+	keep   bool
+}
+
+func newTestTailSampler(policies ...*config.TailSamplingPolicy) (*TailSampler, *[]releasedTrace) {
+	conf := config.New()
+	conf.TailSampling.Policies = policies
+	var released []releasedTrace
+	s := NewTailSampler(conf, func(chunks []*TailChunk, keep bool) {
+		released = append(released, releasedTrace{chunks, keep})
+	}, nil)
+	return s, &released
+}
+
+func tailChunk(spans ...*pb.Span) *TailChunk {
+	return &TailChunk{
+		Chunk:   &pb.TraceChunk{Spans: spans},
+		Dropped: &pb.TraceChunk{DroppedTrace: true},
+	}
+}
+
+func TestTailSamplerPolicies(t *testing.T) {
+	start := time.Now().UnixNano()
+	for _, tc := range []struct {
+		name   string
+		policy *config.TailSamplingPolicy
+		spans  []*pb.Span
+		keep   bool
+	}{
+		{
+			name:   "latency",
+			policy: &config.TailSamplingPolicy{Type: "latency", ThresholdMs: 500},
+			spans: []*pb.Span{
+				{SpanID: 1, Start: start, Duration: int64(100 * time.Millisecond)},
+				{SpanID: 2, ParentID: 1, Start: start + int64(time.Second), Duration: int64(100 * time.Millisecond)},
+			},
+			keep: true,
+		},
+		{
+			name:   "latency-fast",
+			policy: &config.TailSamplingPolicy{Type: "latency", ThresholdMs: 500},
+			spans:  []*pb.Span{{SpanID: 1, Start: start, Duration: int64(100 * time.Millisecond)}},
+		},
+		{
+			name:   "error",
+			policy: &config.TailSamplingPolicy{Type: "error"},
+			spans:  []*pb.Span{{SpanID: 1}, {SpanID: 2, ParentID: 1, Error: 1}},
+			keep:   true,
+		},
+		{
+			name:   "no-error",
+			policy: &config.TailSamplingPolicy{Type: "error"},
+			spans:  []*pb.Span{{SpanID: 1}},
+		},
+		{
+			name:   "attribute",
+			policy: &config.TailSamplingPolicy{Type: "attribute", Key: "customer.tier", Values: []string{"gold", "platinum"}},
+			spans:  []*pb.Span{{SpanID: 1}, {SpanID: 2, ParentID: 1, Meta: map[string]string{"customer.tier": "gold"}}},
+			keep:   true,
+		},
+		{
+			name:   "attribute-other-value",
+			policy: &config.TailSamplingPolicy{Type: "attribute", Key: "customer.tier", Values: []string{"gold"}},
+			spans:  []*pb.Span{{SpanID: 1, Meta: map[string]string{"customer.tier": "free"}}},
+		},
+		{
+			name:   "attribute-any-value",
+			policy: &config.TailSamplingPolicy{Type: "attribute", Key: "customer.tier"},
+			spans:  []*pb.Span{{SpanID: 1, Meta: map[string]string{"customer.tier": "free"}}},
+			keep:   true,
+		},
+		{
+			name:   "rate",
+			policy: &config.TailSamplingPolicy{Type: "rate", Rate: 1},
+			spans:  []*pb.Span{{SpanID: 1}},
+			keep:   true,
+		},
+		{
+			name:   "rate-zero",
+			policy: &config.TailSamplingPolicy{Type: "rate", Rate: 0},
+			spans:  []*pb.Span{{SpanID: 1}},
+		},
+		{
+			name:   "service",
+			policy: &config.TailSamplingPolicy{Type: "error", Service: "web"},
+			spans:  []*pb.Span{{SpanID: 1, Service: "web"}, {SpanID: 2, ParentID: 1, Service: "db", Error: 1}},
+			keep:   true,
+		},
+		{
+			name:   "other-service",
+			policy: &config.TailSamplingPolicy{Type: "error", Service: "web"},
+			spans:  []*pb.Span{{SpanID: 1, Service: "api"}, {SpanID: 2, ParentID: 1, Service: "db", Error: 1}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.policy.Name = tc.name
+			s, released := newTestTailSampler(tc.policy)
+			now := time.Now()
+			// the spans of the trace arrive in distinct chunks
+			for _, span := range tc.spans {
+				s.Add(now, 42, tailChunk(span))
+			}
+			assert.Empty(t, *released)
+
+			s.flush(now.Add(s.decisionWait), false)
+			require.Len(t, *released, 1)
+			r := (*released)[0]
+			assert.Equal(t, tc.keep, r.keep)
+			assert.Len(t, r.chunks, len(tc.spans))
+			if tc.keep {
+				assert.Equal(t, tc.name, r.chunks[0].Chunk.Tags[KeyTailSamplingPolicy])
+				assert.False(t, r.chunks[0].Chunk.DroppedTrace)
+				assert.Equal(t, int32(PriorityAutoKeep), r.chunks[0].Chunk.Priority)
+			}
+		})
+	}
+}
+
+func TestTailSamplerDecisionWait(t *testing.T) {
+	s, released := newTestTailSampler(&config.TailSamplingPolicy{Name: "errors", Type: "error"})
+	now := time.Now()
+	s.Add(now, 1, tailChunk(&pb.Span{SpanID: 1, Error: 1}))
+	s.Add(now.Add(time.Second), 2, tailChunk(&pb.Span{SpanID: 2}))
+
+	s.flush(now.Add(s.decisionWait-time.Nanosecond), false)
+	assert.Empty(t, *released)
+
+	s.flush(now.Add(s.decisionWait), false)
+	require.Len(t, *released, 1)
+	assert.True(t, (*released)[0].keep)
+
+	// the chunks arriving after the decision follow it
+	s.Add(now.Add(s.decisionWait), 1, tailChunk(&pb.Span{SpanID: 3, ParentID: 1}))
+	require.Len(t, *released, 2)
+	assert.True(t, (*released)[1].keep)
+
+	s.flush(now.Add(s.decisionWait+time.Second), false)
+	require.Len(t, *released, 3)
+	assert.False(t, (*released)[2].keep)
+	s.Add(now.Add(s.decisionWait+time.Second), 2, tailChunk(&pb.Span{SpanID: 4, ParentID: 2}))
+	require.Len(t, *released, 4)
+	assert.False(t, (*released)[3].keep)
+}
+
+func TestTailSamplerKeepTrace(t *testing.T) {
+	s, released := newTestTailSampler()
+	now := time.Now()
+	s.Add(now, 1, tailChunk(&pb.Span{SpanID: 2, ParentID: 1}))
+	assert.Empty(t, *released)
+
+	// a chunk of the trace has been kept by the other samplers
+	s.KeepTrace(now, 1)
+	require.Len(t, *released, 1)
+	assert.True(t, (*released)[0].keep)
+	assert.Empty(t, s.traces)
+
+	s.Add(now, 1, tailChunk(&pb.Span{SpanID: 3, ParentID: 1}))
+	require.Len(t, *released, 2)
+	assert.True(t, (*released)[1].keep)
+
+	// the traces without buffered chunks are not remembered
+	s.KeepTrace(now, 2)
+	assert.Len(t, *released, 2)
+	assert.NotContains(t, s.decisions, uint64(2))
+}
+
+func TestTailSamplerEviction(t *testing.T) {
+	s, released := newTestTailSampler()
+	s.maxTraces = 2
+	now := time.Now()
+	s.Add(now, 1, tailChunk(&pb.Span{SpanID: 1}))
+	s.Add(now, 2, tailChunk(&pb.Span{SpanID: 2}))
+	assert.Empty(t, *released)
+
+	s.Add(now, 3, tailChunk(&pb.Span{SpanID: 3}))
+	require.Len(t, *released, 1)
+	assert.Equal(t, uint64(1), (*released)[0].chunks[0].Chunk.Spans[0].SpanID)
+	assert.Equal(t, int64(1), s.evicted.Load())
+	assert.Len(t, s.traces, 2)
+
+	// the size of the chunks is bounded too
+	s.maxBytes = s.bytes
+	s.Add(now, 3, tailChunk(&pb.Span{SpanID: 4, ParentID: 3}))
+	require.Len(t, *released, 2)
+	assert.Equal(t, uint64(2), (*released)[1].chunks[0].Chunk.Spans[0].SpanID)
+	assert.Equal(t, int64(2), s.evicted.Load())
+
+	// all the traces are decided when stopping
+	s.flush(now, true)
+	require.Len(t, *released, 3)
+	assert.Len(t, (*released)[2].chunks, 2)
+	assert.Zero(t, s.bytes)
+}
@@ -6,6 +6,8 @@
 package transform
 
 import (
+	"strings"
+
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
@@ -30,6 +32,14 @@ const (
 	TagHTTPURL = "http.url"
 	// TagDBMS represents a DBMS tag
 	TagDBMS = "db.type"
+	// TagGraphQLSource represents a GraphQL query tag
+	TagGraphQLSource = "graphql.source"
+	// TagGraphQLVariables represents a GraphQL variables tag, holding them as a JSON object
+	TagGraphQLVariables = "graphql.variables"
+	// TagGraphQLVariablePrefix prefixes the tags holding the GraphQL variables one by one
+	TagGraphQLVariablePrefix = "graphql.variables."
+	// TagAWSRequestParameters represents an AWS request parameters tag
+	TagAWSRequestParameters = "aws.request.parameters"
 )
 
 const (
@@ -57,6 +67,40 @@ func ObfuscateSQLSpan(o *obfuscate.Obfuscator, span *pb.Span) (*obfuscate.Obfusc
 	return oq, nil
 }
 
+// ObfuscateCQLSpan obfuscates a Cassandra CQL span using pkg/obfuscate logic
+func ObfuscateCQLSpan(o *obfuscate.Obfuscator, span *pb.Span) (*obfuscate.ObfuscatedQuery, error) {
+	if span.Resource == "" {
+		return nil, nil
+	}
+	oq, err := o.ObfuscateCQLString(span.Resource)
+	if err != nil {
+		// we have an error, discard the CQL to avoid polluting user resources.
+		span.Resource = TextNonParsable
+		traceutil.SetMeta(span, TagSQLQuery, TextNonParsable)
+		return nil, err
+	}
+	span.Resource = oq.Query
+	traceutil.SetMeta(span, TagSQLQuery, oq.Query)
+	return oq, nil
+}
+
+// ObfuscateGraphQLSpan obfuscates a GraphQL span using pkg/obfuscate logic
+func ObfuscateGraphQLSpan(o *obfuscate.Obfuscator, span *pb.Span) {
+	if span.Resource != "" {
+		span.Resource = o.ObfuscateGraphQLString(span.Resource)
+	}
+	for k, v := range span.Meta {
+		switch {
+		case k == TagGraphQLSource:
+			span.Meta[k] = o.ObfuscateGraphQLString(v)
+		case k == TagGraphQLVariables:
+			span.Meta[k] = o.ObfuscateGraphQLVariables(v)
+		case strings.HasPrefix(k, TagGraphQLVariablePrefix):
+			span.Meta[k] = o.ObfuscateGraphQLVariable(k[len(TagGraphQLVariablePrefix):], v)
+		}
+	}
+}
+
 // ObfuscateRedisSpan obfuscates a Redis span using pkg/obfuscate logic
 func ObfuscateRedisSpan(o *obfuscate.Obfuscator, span *pb.Span, removeAllArgs bool) {
 	if span.Meta == nil || span.Meta[TagRedisRawCommand] == "" {
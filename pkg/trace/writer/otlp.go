@@ -0,0 +1,533 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+// pathOTLPTraces is the default path of the OTLP/HTTP traces endpoints.
+const pathOTLPTraces = "/v1/traces"
+
+// otlpExportTimeout is the timeout of the OTLP/gRPC export requests.
+const otlpExportTimeout = 10 * time.Second
+
+// OTLPTraceWriter buffers the sampled traces, converted to OTLP spans, and exports
+// them to an OTLP/gRPC or OTLP/HTTP endpoint.
+type OTLPTraceWriter struct {
+	flushTicker *time.Ticker
+
+	hostname string
+	env      string
+	headers  map[string]string // added to the OTLP/HTTP requests
+	senders  []*sender
+	conn     *grpc.ClientConn // nil unless the OTLP/gRPC protocol is used
+	stop     chan struct{}
+	wg       sync.WaitGroup // waits flusher
+	tick     time.Duration  // flush frequency
+
+	traces       ptrace.Traces // traces buffered
+	bufferedSize int           // estimated buffer size
+
+	// syncMode reports whether the writer should flush on its own or only when FlushSync is called
+	syncMode bool
+
+	easylog *log.ThrottledLogger
+	statsd  statsd.ClientInterface
+	mu      sync.Mutex
+}
+
+// NewOTLPTraceWriter returns a new OTLPTraceWriter exporting the traces to the
+// endpoint of the apm_config.otlp_traces_output settings.
+func NewOTLPTraceWriter(cfg *config.AgentConfig, statsd statsd.ClientInterface) (*OTLPTraceWriter, error) {
+	out := cfg.OTLPTracesOutput
+	w := &OTLPTraceWriter{
+		hostname: cfg.Hostname,
+		env:      cfg.DefaultEnv,
+		headers:  out.Headers,
+		stop:     make(chan struct{}),
+		tick:     5 * time.Second,
+		traces:   ptrace.NewTraces(),
+		syncMode: cfg.SynchronousFlushing,
+		easylog:  log.NewThrottled(5, 10*time.Second), // no more than 5 messages every 10 seconds
+		statsd:   statsd,
+	}
+	if s := cfg.TraceWriter.FlushPeriodSeconds; s != 0 {
+		w.tick = time.Duration(s*1000) * time.Millisecond
+	}
+	scfg := &senderConfig{
+		client:     cfg.NewHTTPClient(),
+		maxConns:   defaultConnectionLimit,
+		maxQueued:  1,
+		maxRetries: cfg.MaxSenderRetries,
+		recorder:   w,
+		userAgent:  fmt.Sprintf("Datadog Trace Agent/%s/%s", cfg.AgentVersion, cfg.GitCommit),
+	}
+	switch out.Protocol {
+	case "http":
+		u, err := url.Parse(out.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP/HTTP endpoint %q: %v", out.Endpoint, err)
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = pathOTLPTraces
+		}
+		scfg.url = u
+	case "grpc":
+		creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+		if out.Insecure {
+			creds = insecure.NewCredentials()
+		}
+		conn, err := grpc.NewClient(out.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("invalid OTLP/gRPC endpoint %q: %v", out.Endpoint, err)
+		}
+		w.conn = conn
+		scfg.url = &url.URL{Scheme: "grpc", Host: out.Endpoint}
+		scfg.send = newOTLPGRPCSend(ptraceotlp.NewGRPCClient(conn), out.Headers)
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %q, expected grpc or http", out.Protocol)
+	}
+	w.senders = []*sender{newSender(scfg, statsd)}
+	w.flushTicker = time.NewTicker(w.tick)
+
+	log.Infof("OTLP trace writer initialized (endpoint=%s protocol=%s)", out.Endpoint, out.Protocol)
+	if !w.syncMode {
+		w.wg.Add(1)
+		go w.timeFlush()
+	}
+	return w, nil
+}
+
+// newOTLPGRPCSend returns a function exporting the payloads with the given OTLP/gRPC client.
+func newOTLPGRPCSend(client ptraceotlp.GRPCClient, headers map[string]string) func(p *payload) error {
+	md := metadata.New(headers)
+	return func(p *payload) error {
+		req := ptraceotlp.NewExportRequest()
+		if err := req.UnmarshalProto(p.body.Bytes()); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), md), otlpExportTimeout)
+		defer cancel()
+		_, err := client.Export(ctx, req)
+		switch status.Code(err) {
+		case codes.OK:
+			return nil
+		case codes.Canceled, codes.DeadlineExceeded, codes.Aborted, codes.OutOfRange, codes.Unavailable, codes.DataLoss, codes.ResourceExhausted:
+			// retryable status codes, as per the OTLP specification
+			return &retriableError{err}
+		default:
+			return err
+		}
+	}
+}
+
+func (w *OTLPTraceWriter) timeFlush() {
+	defer w.wg.Done()
+	for {
+		select {
+		case <-w.flushTicker.C:
+			w.flush()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Stop stops the OTLPTraceWriter and attempts to flush whatever is left in the senders buffers.
+func (w *OTLPTraceWriter) Stop() {
+	log.Debug("Exiting OTLP trace writer. Trying to flush whatever is left...")
+	close(w.stop)
+	w.wg.Wait()
+	w.flush()
+	stopSenders(w.senders)
+	w.flushTicker.Stop()
+	if w.conn != nil {
+		if err := w.conn.Close(); err != nil {
+			log.Debugf("Error closing the OTLP/gRPC connection: %v", err)
+		}
+	}
+}
+
+// FlushSync blocks and sends pending payloads when syncMode is true
+func (w *OTLPTraceWriter) FlushSync() error {
+	if !w.syncMode {
+		return errors.New("not flushing; sync mode not enabled")
+	}
+	w.flush()
+	return nil
+}
+
+// UpdateAPIKey does nothing, no Datadog API key being sent to the OTLP endpoints.
+func (w *OTLPTraceWriter) UpdateAPIKey(_, _ string) {}
+
+// WriteChunks converts the provided chunks to OTLP spans, enqueueing them to be sent
+func (w *OTLPTraceWriter) WriteChunks(pkg *SampledChunks) {
+	_ = w.statsd.Count("datadog.trace_agent.otlp_trace_writer.spans", pkg.SpanCount, nil, 1)
+	_ = w.statsd.Count("datadog.trace_agent.otlp_trace_writer.traces", int64(len(pkg.TracerPayload.Chunks)), nil, 1)
+
+	var (
+		toflush ptrace.Traces
+		full    bool
+	)
+	w.mu.Lock()
+	if pkg.Size+w.bufferedSize > MaxPayloadSize {
+		// reached maximum allowed buffered size
+		toflush, full = w.resetBuffer(), true
+	}
+	appendTracerPayloadToOTLP(w.traces, pkg.TracerPayload, w.hostname, w.env)
+	w.bufferedSize += pkg.Size
+	w.mu.Unlock()
+
+	if full {
+		w.flushTraces(toflush)
+	}
+}
+
+// resetBuffer returns the buffered traces after having replaced them with empty
+// ones. w must be locked.
+func (w *OTLPTraceWriter) resetBuffer() ptrace.Traces {
+	traces := w.traces
+	w.traces = ptrace.NewTraces()
+	w.bufferedSize = 0
+	return traces
+}
+
+func (w *OTLPTraceWriter) flush() {
+	w.mu.Lock()
+	traces := w.resetBuffer()
+	w.mu.Unlock()
+	w.flushTraces(traces)
+}
+
+func (w *OTLPTraceWriter) flushTraces(traces ptrace.Traces) {
+	w.flushTicker.Reset(w.tick) // reset the flush timer whenever we flush
+	if traces.SpanCount() == 0 {
+		// nothing to do
+		return
+	}
+	b, err := ptraceotlp.NewExportRequestFromTraces(traces).MarshalProto()
+	if err != nil {
+		log.Errorf("Failed to serialize OTLP traces, data dropped: %v", err)
+		return
+	}
+	headers := make(map[string]string, len(w.headers)+1)
+	maps.Copy(headers, w.headers)
+	headers["Content-Type"] = "application/x-protobuf"
+	p := newPayload(headers)
+	p.body.Write(b)
+	sendPayloads(w.senders, p, w.syncMode)
+}
+
+var _ eventRecorder = (*OTLPTraceWriter)(nil)
+
+// recordEvent implements eventRecorder.
+func (w *OTLPTraceWriter) recordEvent(t eventType, data *eventData) {
+	switch t {
+	case eventTypeRetry:
+		log.Debugf("Retrying to export OTLP traces; error: %s", data.err)
+		_ = w.statsd.Count("datadog.trace_agent.otlp_trace_writer.retries", 1, nil, 1)
+
+	case eventTypeSent:
+		log.Debugf("Exported OTLP traces; time: %s, bytes: %d", data.duration, data.bytes)
+		_ = w.statsd.Count("datadog.trace_agent.otlp_trace_writer.payloads", 1, nil, 1)
+		_ = w.statsd.Count("datadog.trace_agent.otlp_trace_writer.bytes", int64(data.bytes), nil, 1)
+
+	case eventTypeRejected:
+		w.easylog.Warn("OTLP traces rejected by the endpoint: %v", data.err)
+		_ = w.statsd.Count("datadog.trace_agent.otlp_trace_writer.errors", 1, nil, 1)
+
+	case eventTypeDropped:
+		w.easylog.Warn("OTLP traces dropped (%.2fKB).", float64(data.bytes)/1024)
+		_ = w.statsd.Count("datadog.trace_agent.otlp_trace_writer.dropped", 1, nil, 1)
+		_ = w.statsd.Count("datadog.trace_agent.otlp_trace_writer.dropped_bytes", int64(data.bytes), nil, 1)
+	}
+}
+
+// otlpResourceKey identifies the OTLP resource of a span.
+type otlpResourceKey struct {
+	service, env, version string
+}
+
+// otlpIgnoredMeta holds the span tags which aren't converted to OTLP attributes, being
+// converted to other OTLP fields or to resource attributes.
+var otlpIgnoredMeta = map[string]bool{
+	"env":                           true,
+	"version":                       true,
+	"span.kind":                     true,
+	"otel.trace_id":                 true,
+	"events":                        true,
+	"_dd.span_links":                true,
+	"w3c.tracestate":                true,
+	semconv.OtelLibraryName:         true,
+	semconv.OtelLibraryVersion:      true,
+	semconv.OtelStatusCode:          true,
+	semconv.OtelStatusDescription:   true,
+	"_dd.p.tid":                     true,
+	"_dd.hostname":                  true,
+	semconv.AttributeContainerID:    true,
+	semconv.AttributeServiceName:    true,
+	semconv.AttributeServiceVersion: true,
+}
+
+// appendTracerPayloadToOTLP converts the spans of the chunks of tp to OTLP spans, appending
+// them to traces, grouped by resource. The chunks dropped by the samplers, which are only
+// sent to Datadog for stats computation, are skipped.
+func appendTracerPayloadToOTLP(traces ptrace.Traces, tp *pb.TracerPayload, hostname, env string) {
+	if tp.Hostname != "" {
+		hostname = tp.Hostname
+	}
+	if tp.Env != "" {
+		env = tp.Env
+	}
+	resources := make(map[otlpResourceKey]ptrace.ResourceSpans)
+	scopes := make(map[otlpResourceKey]map[[2]string]ptrace.ScopeSpans)
+	for _, chunk := range tp.Chunks {
+		if chunk.DroppedTrace {
+			continue
+		}
+		var traceIDHigh string
+		for _, span := range chunk.Spans {
+			if tid := span.Meta["_dd.p.tid"]; tid != "" {
+				traceIDHigh = tid
+				break
+			}
+		}
+		for _, span := range chunk.Spans {
+			key := otlpResourceKey{service: span.Service, env: env, version: tp.AppVersion}
+			if v := span.Meta["env"]; v != "" {
+				key.env = v
+			}
+			if v := span.Meta["version"]; v != "" {
+				key.version = v
+			}
+			rs, ok := resources[key]
+			if !ok {
+				rs = traces.ResourceSpans().AppendEmpty()
+				setOTLPResource(rs.Resource(), key, tp, hostname)
+				resources[key] = rs
+				scopes[key] = make(map[[2]string]ptrace.ScopeSpans)
+			}
+			scope := [2]string{span.Meta[semconv.OtelLibraryName], span.Meta[semconv.OtelLibraryVersion]}
+			ss, ok := scopes[key][scope]
+			if !ok {
+				ss = rs.ScopeSpans().AppendEmpty()
+				ss.Scope().SetName(scope[0])
+				ss.Scope().SetVersion(scope[1])
+				scopes[key][scope] = ss
+			}
+			convertSpanToOTLP(ss.Spans().AppendEmpty(), chunk, span, traceIDHigh)
+		}
+	}
+}
+
+func setOTLPResource(res pcommon.Resource, key otlpResourceKey, tp *pb.TracerPayload, hostname string) {
+	attrs := res.Attributes()
+	attrs.PutStr(semconv.AttributeServiceName, key.service)
+	putNonEmpty := func(k, v string) {
+		if v != "" {
+			attrs.PutStr(k, v)
+		}
+	}
+	putNonEmpty(semconv.AttributeDeploymentEnvironment, key.env)
+	putNonEmpty(semconv.AttributeServiceVersion, key.version)
+	putNonEmpty(semconv.AttributeHostName, hostname)
+	putNonEmpty(semconv.AttributeContainerID, tp.ContainerID)
+	putNonEmpty(semconv.AttributeTelemetrySDKLanguage, tp.LanguageName)
+	putNonEmpty(semconv.AttributeTelemetrySDKVersion, tp.TracerVersion)
+	putNonEmpty(semconv.AttributeProcessRuntimeVersion, tp.LanguageVersion)
+}
+
+var otlpSpanKinds = map[string]ptrace.SpanKind{
+	"internal": ptrace.SpanKindInternal,
+	"server":   ptrace.SpanKindServer,
+	"client":   ptrace.SpanKindClient,
+	"producer": ptrace.SpanKindProducer,
+	"consumer": ptrace.SpanKindConsumer,
+}
+
+// convertSpanToOTLP converts the span in of the given chunk to the OTLP span out. The Datadog
+// span name, resource and type are kept in the "operation.name", "resource.name" and
+// "span.type" attributes, the OTLP span being named after the resource.
+func convertSpanToOTLP(out ptrace.Span, chunk *pb.TraceChunk, in *pb.Span, traceIDHigh string) {
+	out.SetTraceID(otlpTraceID(in, traceIDHigh))
+	out.SetSpanID(otlpSpanID(in.SpanID))
+	if in.ParentID != 0 {
+		out.SetParentSpanID(otlpSpanID(in.ParentID))
+	}
+	out.SetName(in.Resource)
+	out.SetKind(otlpSpanKinds[strings.ToLower(in.Meta["span.kind"])])
+	out.SetStartTimestamp(pcommon.Timestamp(in.Start))
+	out.SetEndTimestamp(pcommon.Timestamp(in.Start + in.Duration))
+	if ts := in.Meta["w3c.tracestate"]; ts != "" {
+		out.TraceState().FromRaw(ts)
+	}
+
+	attrs := out.Attributes()
+	attrs.EnsureCapacity(len(in.Meta) + len(in.Metrics) + 3)
+	attrs.PutStr("operation.name", in.Name)
+	attrs.PutStr("resource.name", in.Resource)
+	if in.Type != "" {
+		attrs.PutStr("span.type", in.Type)
+	}
+	if chunk.Origin != "" {
+		attrs.PutStr("_dd.origin", chunk.Origin)
+	}
+	if chunk.Priority != int32(sampler.PriorityNone) {
+		attrs.PutInt("sampling.priority", int64(chunk.Priority))
+	}
+	for k, v := range in.Meta {
+		if !otlpIgnoredMeta[k] {
+			attrs.PutStr(k, v)
+		}
+	}
+	for k, v := range in.Metrics {
+		attrs.PutDouble(k, v)
+	}
+
+	switch {
+	case in.Error != 0:
+		out.Status().SetCode(ptrace.StatusCodeError)
+		msg := in.Meta[semconv.OtelStatusDescription]
+		if msg == "" {
+			msg = in.Meta["error.msg"]
+		}
+		out.Status().SetMessage(msg)
+	case in.Meta[semconv.OtelStatusCode] == ptrace.StatusCodeOk.String():
+		out.Status().SetCode(ptrace.StatusCodeOk)
+	}
+
+	for _, e := range in.SpanEvents {
+		event := out.Events().AppendEmpty()
+		event.SetName(e.Name)
+		event.SetTimestamp(pcommon.Timestamp(e.TimeUnixNano))
+		for k, v := range e.Attributes {
+			putOTLPAttribute(event.Attributes(), k, v)
+		}
+	}
+	if events := in.Meta["events"]; events != "" && len(in.SpanEvents) == 0 {
+		appendOTLPEvents(out.Events(), events)
+	}
+	for _, l := range in.SpanLinks {
+		link := out.Links().AppendEmpty()
+		var traceID [16]byte
+		binary.BigEndian.PutUint64(traceID[:8], l.TraceIDHigh)
+		binary.BigEndian.PutUint64(traceID[8:], l.TraceID)
+		link.SetTraceID(traceID)
+		link.SetSpanID(otlpSpanID(l.SpanID))
+		if l.Tracestate != "" {
+			link.TraceState().FromRaw(l.Tracestate)
+		}
+		for k, v := range l.Attributes {
+			link.Attributes().PutStr(k, v)
+		}
+	}
+}
+
+// otlpTraceID returns the 128 bits trace ID of the span: the one it had if it was received
+// through OTLP, otherwise its 64 bits trace ID preceded by the high bits of the trace.
+func otlpTraceID(span *pb.Span, traceIDHigh string) pcommon.TraceID {
+	var traceID [16]byte
+	if v := span.Meta["otel.trace_id"]; len(v) == 32 {
+		if _, err := hex.Decode(traceID[:], []byte(v)); err == nil {
+			return traceID
+		}
+	}
+	if high, err := strconv.ParseUint(traceIDHigh, 16, 64); err == nil {
+		binary.BigEndian.PutUint64(traceID[:8], high)
+	}
+	binary.BigEndian.PutUint64(traceID[8:], span.TraceID)
+	return traceID
+}
+
+func otlpSpanID(id uint64) pcommon.SpanID {
+	var spanID [8]byte
+	binary.BigEndian.PutUint64(spanID[:], id)
+	return spanID
+}
+
+// putOTLPAttribute puts the span event attribute v to attrs.
+func putOTLPAttribute(attrs pcommon.Map, k string, v *pb.AttributeAnyValue) {
+	switch v.Type {
+	case pb.AttributeAnyValue_STRING_VALUE:
+		attrs.PutStr(k, v.StringValue)
+	case pb.AttributeAnyValue_BOOL_VALUE:
+		attrs.PutBool(k, v.BoolValue)
+	case pb.AttributeAnyValue_INT_VALUE:
+		attrs.PutInt(k, v.IntValue)
+	case pb.AttributeAnyValue_DOUBLE_VALUE:
+		attrs.PutDouble(k, v.DoubleValue)
+	case pb.AttributeAnyValue_ARRAY_VALUE:
+		s := attrs.PutEmptySlice(k)
+		if v.ArrayValue == nil {
+			return
+		}
+		for _, e := range v.ArrayValue.Values {
+			switch e.Type {
+			case pb.AttributeArrayValue_STRING_VALUE:
+				s.AppendEmpty().SetStr(e.StringValue)
+			case pb.AttributeArrayValue_BOOL_VALUE:
+				s.AppendEmpty().SetBool(e.BoolValue)
+			case pb.AttributeArrayValue_INT_VALUE:
+				s.AppendEmpty().SetInt(e.IntValue)
+			case pb.AttributeArrayValue_DOUBLE_VALUE:
+				s.AppendEmpty().SetDouble(e.DoubleValue)
+			}
+		}
+	}
+}
+
+// appendOTLPEvents appends to out the span events marshalled in the "events" tag of
+// the spans received through OTLP.
+func appendOTLPEvents(out ptrace.SpanEventSlice, events string) {
+	var parsed []struct {
+		TimeUnixNano uint64         `json:"time_unix_nano"`
+		Name         string         `json:"name"`
+		Attributes   map[string]any `json:"attributes"`
+	}
+	if err := json.Unmarshal([]byte(events), &parsed); err != nil {
+		log.Debugf("Failed to parse the span events %q: %v", events, err)
+		return
+	}
+	for _, e := range parsed {
+		event := out.AppendEmpty()
+		event.SetName(e.Name)
+		event.SetTimestamp(pcommon.Timestamp(e.TimeUnixNano))
+		if err := event.Attributes().FromRaw(e.Attributes); err != nil {
+			log.Debugf("Failed to convert the attributes of the span event %q: %v", e.Name, err)
+		}
+	}
+}
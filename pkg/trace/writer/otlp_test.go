@@ -0,0 +1,295 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+)
+
+func TestOTLPTraceWriterHTTP(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	cfg := &config.AgentConfig{
+		Hostname:    testHostname,
+		DefaultEnv:  testEnv,
+		TraceWriter: &config.WriterConfig{},
+		OTLPTracesOutput: config.OTLPTracesOutputConfig{
+			Enabled:  true,
+			Endpoint: srv.URL,
+			Protocol: "http",
+			Headers:  map[string]string{"x-tenant": "acme"},
+		},
+	}
+	w, err := NewOTLPTraceWriter(cfg, &statsd.NoOpClient{})
+	require.NoError(t, err)
+	assert.Equal(t, pathOTLPTraces, w.senders[0].cfg.url.Path)
+
+	w.WriteChunks(randomSampledSpans(20, 0))
+	w.WriteChunks(randomSampledSpans(10, 0))
+	w.Stop()
+
+	require.Equal(t, 1, srv.Accepted())
+	p := srv.Payloads()[0]
+	assert.Equal(t, "acme", p.headers["X-Tenant"])
+	assert.Equal(t, "application/x-protobuf", p.headers["Content-Type"])
+	assert.NotContains(t, p.headers, "Dd-Api-Key")
+
+	req := ptraceotlp.NewExportRequest()
+	require.NoError(t, req.UnmarshalProto(p.body.Bytes()))
+	assert.Equal(t, 30, req.Traces().SpanCount())
+	host, ok := req.Traces().ResourceSpans().At(0).Resource().Attributes().Get("host.name")
+	require.True(t, ok)
+	assert.Equal(t, testHostname, host.Str())
+}
+
+func TestOTLPTraceWriterFlushThreshold(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	cfg := &config.AgentConfig{
+		TraceWriter: &config.WriterConfig{},
+		OTLPTracesOutput: config.OTLPTracesOutputConfig{
+			Enabled:  true,
+			Endpoint: srv.URL,
+			Protocol: "http",
+		},
+	}
+	testSpans := []*SampledChunks{
+		randomSampledSpans(20, 0),
+		randomSampledSpans(10, 0),
+		randomSampledSpans(40, 0),
+	}
+	defer useFlushThreshold(testSpans[0].Size + testSpans[1].Size + 10)()
+	w, err := NewOTLPTraceWriter(cfg, &statsd.NoOpClient{})
+	require.NoError(t, err)
+	for _, ss := range testSpans {
+		w.WriteChunks(ss)
+	}
+	w.Stop()
+
+	// one payload is flushed when the threshold is overflowed, the second on stop
+	require.Equal(t, 2, srv.Accepted())
+	var spans int
+	for _, p := range srv.Payloads() {
+		req := ptraceotlp.NewExportRequest()
+		require.NoError(t, req.UnmarshalProto(p.body.Bytes()))
+		spans += req.Traces().SpanCount()
+	}
+	assert.Equal(t, 70, spans)
+}
+
+func TestOTLPTraceWriterSyncMode(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	cfg := &config.AgentConfig{
+		TraceWriter:         &config.WriterConfig{FlushPeriodSeconds: 0.01},
+		SynchronousFlushing: true,
+		OTLPTracesOutput: config.OTLPTracesOutputConfig{
+			Enabled:  true,
+			Endpoint: srv.URL,
+			Protocol: "http",
+		},
+	}
+	w, err := NewOTLPTraceWriter(cfg, &statsd.NoOpClient{})
+	require.NoError(t, err)
+	defer w.Stop()
+
+	w.WriteChunks(randomSampledSpans(20, 0))
+	// the writer doesn't flush on its own in sync mode
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 0, srv.Accepted())
+
+	require.NoError(t, w.FlushSync())
+	assert.Equal(t, 1, srv.Accepted())
+}
+
+func TestOTLPTraceWriterSkipsDroppedTraces(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	cfg := &config.AgentConfig{
+		TraceWriter: &config.WriterConfig{},
+		OTLPTracesOutput: config.OTLPTracesOutputConfig{
+			Enabled:  true,
+			Endpoint: srv.URL,
+			Protocol: "http",
+		},
+	}
+	w, err := NewOTLPTraceWriter(cfg, &statsd.NoOpClient{})
+	require.NoError(t, err)
+
+	dropped := randomSampledSpans(10, 0)
+	dropped.TracerPayload.Chunks[0].DroppedTrace = true
+	w.WriteChunks(randomSampledSpans(20, 0))
+	w.WriteChunks(dropped)
+	w.Stop()
+
+	require.Equal(t, 1, srv.Accepted())
+	req := ptraceotlp.NewExportRequest()
+	require.NoError(t, req.UnmarshalProto(srv.Payloads()[0].body.Bytes()))
+	assert.Equal(t, 20, req.Traces().SpanCount())
+}
+
+func TestNewOTLPTraceWriterErrors(t *testing.T) {
+	for _, out := range []config.OTLPTracesOutputConfig{
+		{Enabled: true, Endpoint: "localhost:4317", Protocol: "thrift"},
+		{Enabled: true, Endpoint: "://collector", Protocol: "http"},
+	} {
+		cfg := &config.AgentConfig{TraceWriter: &config.WriterConfig{}, OTLPTracesOutput: out}
+		_, err := NewOTLPTraceWriter(cfg, &statsd.NoOpClient{})
+		assert.Error(t, err, out.Protocol)
+	}
+}
+
+func TestAppendTracerPayloadToOTLP(t *testing.T) {
+	tp := &pb.TracerPayload{
+		ContainerID:     "abc123",
+		LanguageName:    "go",
+		LanguageVersion: "1.23",
+		TracerVersion:   "1.70.0",
+		AppVersion:      "v1",
+		Chunks: []*pb.TraceChunk{{
+			Priority: 2,
+			Origin:   "rum",
+			Spans: []*pb.Span{
+				{
+					Service:  "web",
+					Name:     "http.request",
+					Resource: "GET /users",
+					Type:     "web",
+					TraceID:  42,
+					SpanID:   1,
+					Start:    1000,
+					Duration: 500,
+					Meta: map[string]string{
+						"_dd.p.tid":      "6560a1b200000000",
+						"span.kind":      "server",
+						"http.method":    "GET",
+						"w3c.tracestate": "dd=s:2",
+					},
+					Metrics: map[string]float64{"_sampling_priority_v1": 2},
+				},
+				{
+					Service:  "db",
+					Name:     "postgres.query",
+					Resource: "SELECT ?",
+					TraceID:  42,
+					SpanID:   2,
+					ParentID: 1,
+					Start:    1100,
+					Duration: 200,
+					Error:    1,
+					Meta: map[string]string{
+						"env":       "staging",
+						"span.kind": "client",
+						"error.msg": "connection reset",
+					},
+					SpanEvents: []*pb.SpanEvent{{
+						Name:         "exception",
+						TimeUnixNano: 1200,
+						Attributes: map[string]*pb.AttributeAnyValue{
+							"exception.escaped": {Type: pb.AttributeAnyValue_BOOL_VALUE, BoolValue: true},
+						},
+					}},
+					SpanLinks: []*pb.SpanLink{{TraceID: 7, SpanID: 8, Attributes: map[string]string{"link.reason": "retry"}}},
+				},
+			},
+		}},
+	}
+	traces := ptrace.NewTraces()
+	appendTracerPayloadToOTLP(traces, tp, testHostname, testEnv)
+
+	require.Equal(t, 2, traces.ResourceSpans().Len())
+	web, db := traces.ResourceSpans().At(0), traces.ResourceSpans().At(1)
+
+	t.Run("resource", func(t *testing.T) {
+		assert.Equal(t, map[string]any{
+			"service.name":            "web",
+			"deployment.environment":  testEnv,
+			"service.version":         "v1",
+			"host.name":               testHostname,
+			"container.id":            "abc123",
+			"telemetry.sdk.language":  "go",
+			"telemetry.sdk.version":   "1.70.0",
+			"process.runtime.version": "1.23",
+		}, web.Resource().Attributes().AsRaw())
+		env, _ := db.Resource().Attributes().Get("deployment.environment")
+		assert.Equal(t, "staging", env.Str())
+	})
+
+	t.Run("span", func(t *testing.T) {
+		span := web.ScopeSpans().At(0).Spans().At(0)
+		assert.Equal(t, pcommon.TraceID{0x65, 0x60, 0xa1, 0xb2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 42}, span.TraceID())
+		assert.Equal(t, pcommon.SpanID{0, 0, 0, 0, 0, 0, 0, 1}, span.SpanID())
+		assert.True(t, span.ParentSpanID().IsEmpty())
+		assert.Equal(t, "GET /users", span.Name())
+		assert.Equal(t, ptrace.SpanKindServer, span.Kind())
+		assert.Equal(t, pcommon.Timestamp(1000), span.StartTimestamp())
+		assert.Equal(t, pcommon.Timestamp(1500), span.EndTimestamp())
+		assert.Equal(t, "dd=s:2", span.TraceState().AsRaw())
+		assert.Equal(t, ptrace.StatusCodeUnset, span.Status().Code())
+		assert.Equal(t, map[string]any{
+			"operation.name":        "http.request",
+			"resource.name":         "GET /users",
+			"span.type":             "web",
+			"_dd.origin":            "rum",
+			"sampling.priority":     int64(2),
+			"http.method":           "GET",
+			"_sampling_priority_v1": float64(2),
+		}, span.Attributes().AsRaw())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		span := db.ScopeSpans().At(0).Spans().At(0)
+		// the high bits of the trace ID are shared by the spans of the chunk
+		assert.Equal(t, pcommon.TraceID{0x65, 0x60, 0xa1, 0xb2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 42}, span.TraceID())
+		assert.Equal(t, pcommon.SpanID{0, 0, 0, 0, 0, 0, 0, 1}, span.ParentSpanID())
+		assert.Equal(t, ptrace.SpanKindClient, span.Kind())
+		assert.Equal(t, ptrace.StatusCodeError, span.Status().Code())
+		assert.Equal(t, "connection reset", span.Status().Message())
+
+		require.Equal(t, 1, span.Events().Len())
+		event := span.Events().At(0)
+		assert.Equal(t, "exception", event.Name())
+		assert.Equal(t, map[string]any{"exception.escaped": true}, event.Attributes().AsRaw())
+
+		require.Equal(t, 1, span.Links().Len())
+		link := span.Links().At(0)
+		assert.Equal(t, pcommon.TraceID{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 7}, link.TraceID())
+		assert.Equal(t, pcommon.SpanID{0, 0, 0, 0, 0, 0, 0, 8}, link.SpanID())
+		assert.Equal(t, map[string]any{"link.reason": "retry"}, link.Attributes().AsRaw())
+	})
+}
+
+func TestOTLPTraceIDFromOTLP(t *testing.T) {
+	span := &pb.Span{
+		TraceID: 42,
+		Meta:    map[string]string{"otel.trace_id": "0102030405060708090a0b0c0d0e0f10"},
+	}
+	assert.Equal(t, pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}, otlpTraceID(span, ""))
+}
+
+func TestAppendOTLPEvents(t *testing.T) {
+	events := ptrace.NewSpanEventSlice()
+	appendOTLPEvents(events, `[{"time_unix_nano":123,"name":"retry","attributes":{"attempt":2,"reason":"timeout"}}]`)
+	require.Equal(t, 1, events.Len())
+	assert.Equal(t, "retry", events.At(0).Name())
+	assert.Equal(t, pcommon.Timestamp(123), events.At(0).Timestamp())
+	assert.Equal(t, map[string]any{"attempt": float64(2), "reason": "timeout"}, events.At(0).Attributes().AsRaw())
+
+	appendOTLPEvents(events, "not json")
+	assert.Equal(t, 1, events.Len())
+}
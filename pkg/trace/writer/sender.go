@@ -160,6 +160,9 @@ type senderConfig struct {
 	isMRF bool
 	// IsMRFEnabled determines whether Multi-Region Failover is enabled.
 	isMRFEnabled func() bool
+	// send, when set, sends the payloads instead of the HTTP requests made to
+	// url. It must return a *retriableError for the errors which may be retried.
+	send func(p *payload) error
 }
 
 // sender is responsible for sending payloads to a given URL. It uses a size-limited
@@ -268,13 +271,23 @@ func (s *sender) sendPayload(p *payload) {
 // whether or not the payload is "finished" either because it was
 // sent, or because sending encountered a non-retryable error.
 func (s *sender) sendOnce(p *payload) bool {
-	req, err := p.httpRequest(s.cfg.url)
-	if err != nil {
-		log.Errorf("http.Request: %s", err)
-		return true
+	var (
+		req *http.Request
+		err error
+	)
+	if s.cfg.send == nil {
+		req, err = p.httpRequest(s.cfg.url)
+		if err != nil {
+			log.Errorf("http.Request: %s", err)
+			return true
+		}
 	}
 	start := time.Now()
-	err = s.do(req)
+	if s.cfg.send != nil {
+		err = s.cfg.send(p)
+	} else {
+		err = s.do(req)
+	}
 	stats := &eventData{
 		bytes:    p.body.Len(),
 		count:    1,
@@ -391,7 +404,10 @@ const (
 )
 
 func (s *sender) do(req *http.Request) error {
-	req.Header.Set(headerAPIKey, s.cfg.apiKey)
+	if s.cfg.apiKey != "" {
+		// no API key is sent to the endpoints which aren't Datadog ones
+		req.Header.Set(headerAPIKey, s.cfg.apiKey)
+	}
 	req.Header.Set(headerUserAgent, s.cfg.userAgent)
 	resp, err := s.cfg.client.Do(req)
 	if err != nil {